@@ -127,6 +127,10 @@ start_service() {
     procd_close_instance
 }
 
+reload_service() {
+    procd_send_signal $APP_NAME
+}
+
 stop_service() {
     killall $APP_NAME
 }
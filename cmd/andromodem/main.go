@@ -2,16 +2,21 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"os"
-	"time"
+	"path/filepath"
 
+	"github.com/basiooo/andromodem/internal/config"
+	"github.com/basiooo/andromodem/internal/lifecycle"
 	"github.com/basiooo/andromodem/internal/router"
 	"github.com/basiooo/andromodem/internal/server"
 	"github.com/basiooo/andromodem/internal/utils"
-	"github.com/basiooo/andromodem/pkg/cache"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	"github.com/basiooo/andromodem/pkg/certificate"
 	"github.com/basiooo/andromodem/pkg/logger"
+	"github.com/basiooo/andromodem/pkg/simulator"
 	adb "github.com/basiooo/goadb"
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
@@ -22,6 +27,8 @@ var Version = "dev"
 func main() {
 	versionFlag := flag.Bool("version", false, "Show version information")
 	vFlag := flag.Bool("v", false, "Show version information")
+	simulateFlag := flag.Bool("simulate", false, "Serve simulated devices instead of the devices attached to the ADB server")
+	configFlags := config.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
 	if *versionFlag || *vFlag {
@@ -29,40 +36,125 @@ func main() {
 		os.Exit(0)
 	}
 
+	cfg, err := config.Load(configFlags, nil)
+	if err != nil {
+		fmt.Printf("Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Create log directories
-	logDirs := []string{
-		"andromodem_logs",
-		"andromodem_logs/monitoring",
+	// Create data and log directories
+	dirs := []string{
+		filepath.Dir(cfg.Path(cfg.Log.File)),
+		cfg.Path(cfg.Monitoring.LogDir),
+		filepath.Dir(cfg.Path(cfg.Monitoring.ConfigFile)),
 	}
-	for _, dir := range logDirs {
+	if cfg.DataDir != "" {
+		dirs = append([]string{cfg.DataDir}, dirs...)
+	}
+	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			fmt.Printf("Failed to create log directory %s: %v\n", dir, err)
+			fmt.Printf("Failed to create directory %s: %v\n", dir, err)
 		}
 	}
 
-	appLogger := logger.NewLogger()
-	appLogger.Info("Application starting", zap.String("version", Version))
-
-	_ = cache.NewCache(5*time.Minute, 10*time.Minute)
+	logLevel := logger.NewAtomicLevel(cfg.Log.Level)
+	appLogger := logger.NewLoggerWithLevel(cfg.Path(cfg.Log.File), logLevel)
+	appLogger.Info("Application starting",
+		zap.String("version", Version),
+		zap.String("config_file", cfg.File),
+		zap.String("address", cfg.Address()))
 
-	adbClient, err := adb.New()
-	if err != nil {
-		appLogger.Error("Failed create ADB client", zap.String("error", err.Error()))
+	var adbClient *adb.Adb
+	if *simulateFlag {
+		adbClient, err = newSimulatedADB(ctx, appLogger)
+		if err != nil {
+			appLogger.Fatal("Failed to start the device simulator", zap.Error(err))
+		}
+	} else {
+		adbClient, err = adb.New()
+		if err != nil {
+			appLogger.Error("Failed create ADB client", zap.String("error", err.Error()))
+		}
+		// "Some OpenWRT devices do not detect connected Android devices automatically. Running adb devices is required for them to be recognized."
+		if err := utils.InitializeADB(appLogger); err != nil {
+			appLogger.Error("Failed to initialize ADB daemon",
+				zap.String("error", err.Error()))
+		}
 	}
 	validator := validator.New()
 	validator.RegisterTagNameFunc(utils.GetJSONFieldName)
 
-	// "Some OpenWRT devices do not detect connected Android devices automatically. Running adb devices is required for them to be recognized."
-	if err := utils.InitializeADB(appLogger); err != nil {
-		appLogger.Error("Failed to initialize ADB daemon",
-			zap.String("error", err.Error()))
+	serverOptions := server.Options{Addr: cfg.Address()}
+	if cfg.TLS.Enabled {
+		cert, generated, err := certificate.LoadOrGenerate(cfg.Path(cfg.TLS.CertFile), cfg.Path(cfg.TLS.KeyFile), cfg.TLS.Hosts)
+		if err != nil {
+			appLogger.Fatal("Failed to load TLS certificate", zap.Error(err))
+		}
+		if generated {
+			appLogger.Info("Generated self-signed TLS certificate",
+				zap.String("cert_file", cfg.Path(cfg.TLS.CertFile)),
+				zap.String("sha256_fingerprint", certificate.Fingerprint(cert)))
+		}
+		serverOptions.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		if cfg.TLS.RedirectPort != 0 {
+			serverOptions.RedirectAddr = cfg.RedirectAddress()
+		}
 	}
 
-	router := router.NewRouter(adbClient, appLogger, ctx, validator)
-	server := server.NewServer(router.GetRouters(), appLogger, ctx, cancel)
-	if err := server.Start(); err != nil {
+	lifecycleManager := lifecycle.NewManager(appLogger, cancel, cfg.Server.ShutdownTimeout.Duration(), func() (*config.Config, error) {
+		return config.Load(configFlags, nil)
+	})
+	activeLogLevel := cfg.Log.Level
+	lifecycleManager.OnReload("log level", func(next *config.Config) error {
+		if next.Log.Level != activeLogLevel {
+			logger.SetLevel(logLevel, next.Log.Level)
+			appLogger.Info("Log level changed",
+				zap.String("from", activeLogLevel),
+				zap.String("to", next.Log.Level))
+			activeLogLevel = next.Log.Level
+		}
+		return nil
+	})
+	router := router.NewRouter(adbClient, appLogger, ctx, validator, cfg, lifecycleManager)
+	// Compared with the startup configuration, which is what is still in use.
+	lifecycleManager.OnReload("restart check", func(next *config.Config) error {
+		if keys := cfg.RestartRequired(next); len(keys) > 0 {
+			appLogger.Warn("Changed settings take effect after a restart", zap.Strings("settings", keys))
+		}
+		return nil
+	})
+	server := server.NewServer(serverOptions, router.GetRouters(), appLogger)
+	// Registered last so it runs first: stop accepting requests and let open
+	// ones finish before the services behind them are torn down.
+	lifecycleManager.OnShutdown("http server", server.Shutdown)
+
+	err = lifecycleManager.Run(server.Start)
+	_ = appLogger.Sync()
+	if err != nil {
 		fmt.Println(err.Error())
+		os.Exit(1)
 	}
 }
+
+// newSimulatedADB returns a client of a simulator serving the bundled
+// fixture profiles, one of them is unplugged and plugged back in every few
+// minutes until ctx is done.
+func newSimulatedADB(ctx context.Context, appLogger *zap.Logger) (*adb.Adb, error) {
+	profiles, err := fixture.Profiles()
+	if err != nil {
+		return nil, err
+	}
+	sim := simulator.New(profiles...)
+	go func() {
+		if err := sim.Play(ctx, simulator.DemoScript(profiles), true); err != nil && ctx.Err() == nil {
+			appLogger.Error("Device simulator script stopped", zap.Error(err))
+		}
+	}()
+	appLogger.Warn("Serving simulated devices", zap.Strings("profiles", fixture.ProfileNames()))
+	return &adb.Adb{Server: sim}, nil
+}
@@ -0,0 +1,101 @@
+// Command fixture-recorder runs the device, network, messages and process
+// services on the connected devices and saves the outputs of the commands
+// they ran as fixtures, one file per device and Android version.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
+	"github.com/basiooo/andromodem/internal/service/devices_service"
+	"github.com/basiooo/andromodem/internal/service/messages_service"
+	network_service "github.com/basiooo/andromodem/internal/service/network"
+	"github.com/basiooo/andromodem/internal/service/process_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	adb "github.com/basiooo/goadb"
+	"go.uber.org/zap"
+)
+
+func main() {
+	serialFlag := flag.String("serial", "", "Serial of the device to record, every online device when empty")
+	outFlag := flag.String("out", "pkg/adb_processor/fixture/profiles", "Directory the fixtures are written to")
+	flag.Parse()
+
+	if err := record(*serialFlag, *outFlag); err != nil {
+		fmt.Printf("Failed to record fixtures: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func record(serial, out string) error {
+	adbClient, err := adb.New()
+	if err != nil {
+		return err
+	}
+	serials := []string{serial}
+	if serial == "" {
+		if serials, err = adbClient.ListDeviceSerials(); err != nil {
+			return err
+		}
+	}
+	if len(serials) == 0 {
+		return fmt.Errorf("no device connected")
+	}
+
+	ctx := context.Background()
+	logger := zap.NewNop()
+	recorder := fixture.NewRecorder(executor.NewExecutor)
+	adbProcessor := processor.NewProcessorWithExecutor(logger, recorder.Executor)
+	// Capabilities are kept in memory, every device is probed once.
+	capabilityService, err := capability_service.NewCapabilityService(adbClient, adbProcessor, logger, "")
+	if err != nil {
+		return err
+	}
+	devicesService := devices_service.NewDevicesService(adbClient, adbProcessor, capabilityService, logger, ctx)
+	networkService := network_service.NewNetworkService(adbClient, adbProcessor, capabilityService, logger, ctx)
+	messagesService := messages_service.NewMessagesService(adbClient, adbProcessor, capabilityService, logger, ctx)
+	processService := process_service.NewProcessService(adbClient, adbProcessor, capabilityService, logger, ctx)
+
+	for _, serial := range serials {
+		fmt.Printf("Recording %s\n", serial)
+		if _, err := capabilityService.ProbeCapabilities(ctx, serial); err != nil {
+			fmt.Printf("  capabilities: %v\n", err)
+		}
+		// Failures are recorded too, replaying them is as useful as
+		// replaying the outputs of working commands.
+		if _, err := devicesService.GetDeviceInfo(ctx, serial); err != nil {
+			fmt.Printf("  device info: %v\n", err)
+		}
+		if _, err := devicesService.GetDeviceFeatureAvailabilities(ctx, serial); err != nil {
+			fmt.Printf("  feature availabilities: %v\n", err)
+		}
+		if _, err := networkService.GetNetworkInfo(ctx, serial); err != nil {
+			fmt.Printf("  network info: %v\n", err)
+		}
+		if _, err := networkService.GetTraffic(ctx, serial); err != nil {
+			fmt.Printf("  network traffic: %v\n", err)
+		}
+		if _, err := networkService.GetAppsUsage(ctx, serial, time.Time{}, time.Now()); err != nil {
+			fmt.Printf("  apps usage: %v\n", err)
+		}
+		if _, err := messagesService.GetMessages(ctx, serial); err != nil {
+			fmt.Printf("  messages: %v\n", err)
+		}
+		if _, err := processService.GetProcesses(ctx, serial, &model.ProcessQuery{}); err != nil {
+			fmt.Printf("  processes: %v\n", err)
+		}
+	}
+
+	paths, err := recorder.Save(ctx, out)
+	for _, path := range paths {
+		fmt.Printf("Saved %s\n", path)
+	}
+	return err
+}
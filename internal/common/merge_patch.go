@@ -0,0 +1,61 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// MergePatch returns the JSON merge patch (RFC 7386) turning previous into
+// current: changed fields with their new value and removed fields as null.
+// Arrays are replaced as a whole. An empty patch means nothing changed.
+func MergePatch(previous any, current any) (map[string]any, error) {
+	previousMap, err := toJSONObject(previous)
+	if err != nil {
+		return nil, err
+	}
+	currentMap, err := toJSONObject(current)
+	if err != nil {
+		return nil, err
+	}
+	return diffObjects(previousMap, currentMap), nil
+}
+
+func toJSONObject(value any) (map[string]any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	object := make(map[string]any)
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func diffObjects(previous map[string]any, current map[string]any) map[string]any {
+	patch := make(map[string]any)
+	for key, previousValue := range previous {
+		if _, ok := current[key]; !ok {
+			patch[key] = nil
+			continue
+		}
+		currentValue := current[key]
+		previousObject, previousIsObject := previousValue.(map[string]any)
+		currentObject, currentIsObject := currentValue.(map[string]any)
+		if previousIsObject && currentIsObject {
+			if nested := diffObjects(previousObject, currentObject); len(nested) > 0 {
+				patch[key] = nested
+			}
+			continue
+		}
+		if !reflect.DeepEqual(previousValue, currentValue) {
+			patch[key] = currentValue
+		}
+	}
+	for key, currentValue := range current {
+		if _, ok := previous[key]; !ok {
+			patch[key] = currentValue
+		}
+	}
+	return patch
+}
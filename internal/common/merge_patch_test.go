@@ -0,0 +1,38 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/basiooo/andromodem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	t.Parallel()
+
+	type battery struct {
+		Level       int     `json:"level"`
+		Temperature float64 `json:"temperature"`
+	}
+	type device struct {
+		Battery battery  `json:"battery"`
+		Routes  []string `json:"routes"`
+		Apn     *string  `json:"apn,omitempty"`
+	}
+	apn := "internet"
+	previous := device{Battery: battery{Level: 80, Temperature: 31.5}, Routes: []string{"rmnet0"}, Apn: &apn}
+	current := device{Battery: battery{Level: 79, Temperature: 31.5}, Routes: []string{"rmnet0", "wlan0"}}
+
+	patch, err := common.MergePatch(previous, current)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"battery": map[string]any{"level": float64(79)},
+		"routes":  []any{"rmnet0", "wlan0"},
+		"apn":     nil,
+	}, patch)
+
+	patch, err = common.MergePatch(current, current)
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+}
@@ -1,9 +1,13 @@
 package common
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
 	"github.com/basiooo/andromodem/internal/model"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -11,9 +15,58 @@ func DeviceNotFoundResponse(writer http.ResponseWriter) {
 	WriteToResponseBody(writer, model.BaseResponse{
 		Success: false,
 		Message: "Device not found",
+		Code:    model.ErrorCodeDeviceNotFound,
 	}, http.StatusNotFound)
 }
 
+// deviceErrorClass is how a class of device errors is reported.
+type deviceErrorClass struct {
+	err        error
+	code       model.ErrorCode
+	statusCode int
+	message    string
+}
+
+// deviceErrorClasses are checked in order, the first one err is of wins.
+// Devices that cannot be reached are unavailable, devices lacking root or
+// a command cannot process the request and devices waiting for the user
+// conflict with it until the user acted.
+var deviceErrorClasses = []deviceErrorClass{
+	{andromodemError.ErrorDeviceNotFound, model.ErrorCodeDeviceNotFound, http.StatusNotFound, "Device not found"},
+	{adbErrors.ErrorDeviceOffline, model.ErrorCodeDeviceOffline, http.StatusServiceUnavailable, "Device is offline"},
+	{adbErrors.ErrorDeviceUnauthorized, model.ErrorCodeDeviceUnauthorized, http.StatusConflict, "Device is unauthorized, accept the USB debugging prompt on the device"},
+	{adbErrors.ErrorNeedRoot, model.ErrorCodeNeedRoot, http.StatusUnprocessableEntity, "Device needs root for this action"},
+	{adbErrors.ErrorNeedShellSuperUserPermission, model.ErrorCodeNeedSuperUserPermission, http.StatusUnprocessableEntity, "Allow super-user access for 'com.android.shell' on the device"},
+	{adbErrors.ErrorMinimumAndroidVersionNotSupport, model.ErrorCodeAndroidVersionUnsupported, http.StatusUnprocessableEntity, "Android version of the device does not support this action"},
+	{adbErrors.ErrorCommandNotFound, model.ErrorCodeCommandNotFound, http.StatusUnprocessableEntity, "Device is missing a command needed for this action"},
+	{adbErrors.ErrorCommandTimeout, model.ErrorCodeCommandTimeout, http.StatusGatewayTimeout, "Device did not respond in time"},
+	{adbErrors.ErrorTransportReset, model.ErrorCodeTransportReset, http.StatusBadGateway, "Connection to the device was reset"},
+	{adbErrors.ErrorAdbServerUnavailable, model.ErrorCodeAdbServerUnavailable, http.StatusServiceUnavailable, "ADB server is not available"},
+	{andromodemError.ErrorAirplaneModeActive, model.ErrorCodeAirplaneModeActive, http.StatusConflict, "Airplane mode is active, disable it first"},
+	{andromodemError.ErrorProcessNotFound, model.ErrorCodeProcessNotFound, http.StatusNotFound, "Process not found"},
+}
+
+// DeviceErrorResponse reports an error of a request to a device with the
+// status code and error code of its class. Errors of no known class are
+// internal errors reported with message.
+func DeviceErrorResponse(writer http.ResponseWriter, err error, message string) {
+	for _, class := range deviceErrorClasses {
+		if errors.Is(err, class.err) {
+			WriteToResponseBody(writer, model.BaseResponse{
+				Success: false,
+				Message: class.message,
+				Code:    class.code,
+			}, class.statusCode)
+			return
+		}
+	}
+	WriteToResponseBody(writer, model.BaseResponse{
+		Success: false,
+		Message: message,
+		Code:    model.ErrorCodeInternal,
+	}, http.StatusInternalServerError)
+}
+
 func ErrorResponse(writter http.ResponseWriter, message string, statusCode int) {
 	WriteToResponseBody(writter, model.BaseResponse{
 		Success: false,
@@ -84,3 +137,15 @@ func SSESetResponseHeader(writer http.ResponseWriter) {
 	writer.Header().Set("Access-Control-Expose-Headers", "Content-Type")
 	writer.Header().Set("Content-Type", "text/event-stream")
 }
+
+// SSEWriteShutdownEvent tells a stream client the server is going away, so it
+// can reconnect later instead of treating the closed stream as an error.
+func SSEWriteShutdownEvent(writer http.ResponseWriter) error {
+	if _, err := fmt.Fprint(writer, "event: shutdown\ndata: server shutting down\n\n"); err != nil {
+		return err
+	}
+	if flusher, ok := writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -2,13 +2,18 @@ package common_test
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/basiooo/andromodem/internal/common"
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
 	"github.com/basiooo/andromodem/internal/model"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDeviceNotFoundResponse(t *testing.T) {
@@ -27,6 +32,7 @@ func TestDeviceNotFoundResponse(t *testing.T) {
 	expectedResponse := model.BaseResponse{
 		Success: false,
 		Message: "Device not found",
+		Code:    model.ErrorCodeDeviceNotFound,
 	}
 	assert.Equal(t, expectedResponse, actualResponse, "Expected response body to match")
 }
@@ -111,3 +117,53 @@ func TestSuccessResponse(t *testing.T) {
 		assert.Nil(t, response.Data)
 	})
 }
+
+func TestSSEWriteShutdownEvent(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	err := common.SSEWriteShutdownEvent(recorder)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "event: shutdown\ndata: server shutting down\n\n", recorder.Body.String())
+	assert.True(t, recorder.Flushed)
+}
+
+func TestDeviceErrorResponse(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		code       model.ErrorCode
+		message    string
+	}{
+		{"device not found", andromodemError.ErrorDeviceNotFound, http.StatusNotFound, model.ErrorCodeDeviceNotFound, "Device not found"},
+		{"device offline", fmt.Errorf("%w: 'getprop': device offline", adbErrors.ErrorDeviceOffline), http.StatusServiceUnavailable, model.ErrorCodeDeviceOffline, "Device is offline"},
+		{"device unauthorized", adbErrors.ErrorDeviceUnauthorized, http.StatusConflict, model.ErrorCodeDeviceUnauthorized, "Device is unauthorized, accept the USB debugging prompt on the device"},
+		{"need root", adbErrors.ErrorNeedRoot, http.StatusUnprocessableEntity, model.ErrorCodeNeedRoot, "Device needs root for this action"},
+		{"command not found", fmt.Errorf("%w: 'iptables'", adbErrors.ErrorCommandNotFound), http.StatusUnprocessableEntity, model.ErrorCodeCommandNotFound, "Device is missing a command needed for this action"},
+		{"command timeout", adbErrors.ErrorCommandTimeout, http.StatusGatewayTimeout, model.ErrorCodeCommandTimeout, "Device did not respond in time"},
+		{"transport reset", adbErrors.ErrorTransportReset, http.StatusBadGateway, model.ErrorCodeTransportReset, "Connection to the device was reset"},
+		{"adb server unavailable", adbErrors.ErrorAdbServerUnavailable, http.StatusServiceUnavailable, model.ErrorCodeAdbServerUnavailable, "ADB server is not available"},
+		{"airplane mode active", andromodemError.ErrorAirplaneModeActive, http.StatusConflict, model.ErrorCodeAirplaneModeActive, "Airplane mode is active, disable it first"},
+		{"process not found", andromodemError.ErrorProcessNotFound, http.StatusNotFound, model.ErrorCodeProcessNotFound, "Process not found"},
+		{"unknown", errors.New("unexpected output"), http.StatusInternalServerError, model.ErrorCodeInternal, "Error getting device info"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			recorder := httptest.NewRecorder()
+
+			common.DeviceErrorResponse(recorder, test.err, "Error getting device info")
+
+			assert.Equal(t, test.statusCode, recorder.Code)
+			var response model.BaseResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Equal(t, model.BaseResponse{
+				Success: false,
+				Message: test.message,
+				Code:    test.code,
+			}, response)
+		})
+	}
+}
@@ -0,0 +1,576 @@
+// Package config builds the runtime configuration of AndroModem.
+//
+// Values are resolved in the following order, each step overriding the
+// previous one:
+//
+//  1. built-in defaults (see Default)
+//  2. the config file (YAML or JSON, selected by extension) passed with
+//     --config or ANDROMODEM_CONFIG
+//  3. ANDROMODEM_* environment variables
+//  4. command line flags
+//
+// Relative file paths are resolved against data_dir when it is set, otherwise
+// against the current working directory.
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const EnvPrefix = "ANDROMODEM_"
+
+type ServerConfig struct {
+	Host string `json:"host" yaml:"host"`
+	Port int    `json:"port" yaml:"port"`
+	// ShutdownTimeout bounds how long open requests, streams and shutdown
+	// hooks may take once a stop signal is received.
+	ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+}
+
+type LogConfig struct {
+	File  string `json:"file" yaml:"file"`
+	Level string `json:"level" yaml:"level"`
+}
+
+type MonitoringConfig struct {
+	ConfigFile string `json:"config_file" yaml:"config_file"`
+	LogDir     string `json:"log_dir" yaml:"log_dir"`
+}
+
+type DevicesConfig struct {
+	// CapabilitiesFile stores the features probed on every device.
+	CapabilitiesFile string `json:"capabilities_file" yaml:"capabilities_file"`
+}
+
+type CacheConfig struct {
+	DefaultExpiration Duration `json:"default_expiration" yaml:"default_expiration"`
+	CleanupInterval   Duration `json:"cleanup_interval" yaml:"cleanup_interval"`
+}
+
+type AuthConfig struct {
+	// Enabled is on by default, turning it off opens every route to anyone
+	// who can reach the server.
+	Enabled   bool   `json:"enabled" yaml:"enabled"`
+	UsersFile string `json:"users_file" yaml:"users_file"`
+	// AdminUsername and AdminPassword seed the first account when the users
+	// file is empty. When unset a random password is generated and written to
+	// UsersFile with the ".admin_password" suffix.
+	AdminUsername string   `json:"admin_username" yaml:"admin_username"`
+	AdminPassword string   `json:"admin_password" yaml:"admin_password"`
+	SessionTTL    Duration `json:"session_ttl" yaml:"session_ttl"`
+}
+
+type TLSConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// CertFile and KeyFile are loaded when both exist, otherwise a self-signed
+	// certificate is generated and written to them.
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+	// Hosts are extra DNS names or IPs added to a generated certificate.
+	Hosts []string `json:"hosts" yaml:"hosts"`
+	// RedirectPort starts a plain HTTP listener redirecting to HTTPS, 0 disables it.
+	RedirectPort int `json:"redirect_port" yaml:"redirect_port"`
+}
+
+type MetricsConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// MinInterval is how long collected device values are reused before ADB
+	// is queried again, however often /metrics is scraped.
+	MinInterval Duration `json:"min_interval" yaml:"min_interval"`
+}
+
+type TelemetryConfig struct {
+	// Enabled turns on the background sampler recording the history.
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Dir     string `json:"dir" yaml:"dir"`
+	// Interval is how often battery, memory, CPU, thermal and signal values are sampled.
+	Interval Duration `json:"interval" yaml:"interval"`
+	// Retention and MaxPoints bound every stored series, whichever is reached first.
+	Retention Duration `json:"retention" yaml:"retention"`
+	MaxPoints int      `json:"max_points" yaml:"max_points"`
+	// LiveInterval is how often a device is sampled while the live telemetry
+	// stream has subscribers.
+	LiveInterval Duration `json:"live_interval" yaml:"live_interval"`
+	// TrafficInterval is how often the throughput of a device is sampled
+	// while the live traffic stream has subscribers.
+	TrafficInterval Duration `json:"traffic_interval" yaml:"traffic_interval"`
+}
+
+type UsageConfig struct {
+	// Enabled turns on the background sampler keeping the data usage ledger.
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	File    string `json:"file" yaml:"file"`
+	// Interval is how often the cellular traffic counters are read.
+	Interval Duration `json:"interval" yaml:"interval"`
+	// CycleStartDay is the billing cycle start day of SIM slots without a quota.
+	CycleStartDay int `json:"cycle_start_day" yaml:"cycle_start_day"`
+}
+
+type Config struct {
+	// File is the config file the values were loaded from, empty when none was used.
+	File       string           `json:"-" yaml:"-"`
+	DataDir    string           `json:"data_dir" yaml:"data_dir"`
+	Server     ServerConfig     `json:"server" yaml:"server"`
+	Log        LogConfig        `json:"log" yaml:"log"`
+	Monitoring MonitoringConfig `json:"monitoring" yaml:"monitoring"`
+	Devices    DevicesConfig    `json:"devices" yaml:"devices"`
+	Cache      CacheConfig      `json:"cache" yaml:"cache"`
+	Auth       AuthConfig       `json:"auth" yaml:"auth"`
+	TLS        TLSConfig        `json:"tls" yaml:"tls"`
+	Metrics    MetricsConfig    `json:"metrics" yaml:"metrics"`
+	Telemetry  TelemetryConfig  `json:"telemetry" yaml:"telemetry"`
+	Usage      UsageConfig      `json:"usage" yaml:"usage"`
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Host:            "0.0.0.0",
+			Port:            49153,
+			ShutdownTimeout: Duration(15 * time.Second),
+		},
+		Log: LogConfig{
+			File:  "andromodem_logs/andromodem.log",
+			Level: "info",
+		},
+		Monitoring: MonitoringConfig{
+			ConfigFile: "andromodem_monitoring_config.json",
+			LogDir:     "andromodem_logs/monitoring",
+		},
+		Devices: DevicesConfig{
+			CapabilitiesFile: "andromodem_capabilities.json",
+		},
+		Cache: CacheConfig{
+			DefaultExpiration: Duration(5 * time.Minute),
+			CleanupInterval:   Duration(10 * time.Minute),
+		},
+		Auth: AuthConfig{
+			Enabled:       true,
+			UsersFile:     "andromodem_users.json",
+			AdminUsername: "admin",
+			SessionTTL:    Duration(24 * time.Hour),
+		},
+		TLS: TLSConfig{
+			CertFile: "andromodem_tls/cert.pem",
+			KeyFile:  "andromodem_tls/key.pem",
+		},
+		Metrics: MetricsConfig{
+			Enabled:     true,
+			MinInterval: Duration(30 * time.Second),
+		},
+		Telemetry: TelemetryConfig{
+			Enabled:         true,
+			Dir:             "andromodem_telemetry",
+			Interval:        Duration(time.Minute),
+			Retention:       Duration(7 * 24 * time.Hour),
+			MaxPoints:       10080,
+			LiveInterval:    Duration(5 * time.Second),
+			TrafficInterval: Duration(2 * time.Second),
+		},
+		Usage: UsageConfig{
+			Enabled:       true,
+			File:          "andromodem_usage.json",
+			Interval:      Duration(time.Minute),
+			CycleStartDay: 1,
+		},
+	}
+}
+
+// Address returns the host:port the HTTP server listens on.
+func (c *Config) Address() string {
+	return net.JoinHostPort(c.Server.Host, strconv.Itoa(c.Server.Port))
+}
+
+// RedirectAddress returns the host:port of the HTTP to HTTPS redirect listener.
+func (c *Config) RedirectAddress() string {
+	return net.JoinHostPort(c.Server.Host, strconv.Itoa(c.TLS.RedirectPort))
+}
+
+// Path resolves p against DataDir. Absolute paths are returned unchanged.
+func (c *Config) Path(p string) string {
+	if p == "" || filepath.IsAbs(p) || c.DataDir == "" {
+		return p
+	}
+	return filepath.Join(c.DataDir, p)
+}
+
+// Validate reports values that cannot be used to start the application.
+func (c *Config) Validate() error {
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		return fmt.Errorf("invalid server port %d", c.Server.Port)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		return fmt.Errorf("server shutdown timeout must be positive")
+	}
+	if c.Log.File == "" {
+		return fmt.Errorf("log file must not be empty")
+	}
+	if c.Monitoring.ConfigFile == "" || c.Monitoring.LogDir == "" {
+		return fmt.Errorf("monitoring config file and log dir must not be empty")
+	}
+	if c.Devices.CapabilitiesFile == "" {
+		return fmt.Errorf("devices capabilities file must not be empty")
+	}
+	if c.Cache.DefaultExpiration <= 0 || c.Cache.CleanupInterval <= 0 {
+		return fmt.Errorf("cache durations must be positive")
+	}
+	if c.Auth.Enabled && (c.Auth.UsersFile == "" || c.Auth.AdminUsername == "") {
+		return fmt.Errorf("auth users file and admin username must not be empty")
+	}
+	if c.TLS.Enabled && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		return fmt.Errorf("tls cert file and key file must not be empty")
+	}
+	if c.TLS.RedirectPort < 0 || c.TLS.RedirectPort > 65535 || (c.TLS.Enabled && c.TLS.RedirectPort == c.Server.Port) {
+		return fmt.Errorf("invalid tls redirect port %d", c.TLS.RedirectPort)
+	}
+	if c.Auth.SessionTTL <= 0 {
+		return fmt.Errorf("auth session ttl must be positive")
+	}
+	if c.Metrics.MinInterval < 0 {
+		return fmt.Errorf("metrics min interval must not be negative")
+	}
+	if c.Telemetry.Enabled && c.Telemetry.Dir == "" {
+		return fmt.Errorf("telemetry dir must not be empty")
+	}
+	if c.Telemetry.Interval <= 0 || c.Telemetry.Retention <= 0 || c.Telemetry.MaxPoints <= 0 {
+		return fmt.Errorf("telemetry interval, retention and max points must be positive")
+	}
+	if c.Telemetry.LiveInterval <= 0 || c.Telemetry.TrafficInterval <= 0 {
+		return fmt.Errorf("telemetry live and traffic intervals must be positive")
+	}
+	if c.Usage.Enabled && c.Usage.File == "" {
+		return fmt.Errorf("usage file must not be empty")
+	}
+	if c.Usage.Interval <= 0 {
+		return fmt.Errorf("usage interval must be positive")
+	}
+	if c.Usage.CycleStartDay < 1 || c.Usage.CycleStartDay > 28 {
+		return fmt.Errorf("invalid usage cycle start day %d", c.Usage.CycleStartDay)
+	}
+	return nil
+}
+
+// RestartRequired lists the settings that differ in next but are only read at
+// startup. log.level and the content of the monitoring task file are applied
+// on reload and are therefore not reported.
+func (c *Config) RestartRequired(next *Config) []string {
+	checks := []struct {
+		key     string
+		changed bool
+	}{
+		{"data_dir", c.DataDir != next.DataDir},
+		{"server.host", c.Server.Host != next.Server.Host},
+		{"server.port", c.Server.Port != next.Server.Port},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout != next.Server.ShutdownTimeout},
+		{"log.file", c.Log.File != next.Log.File},
+		{"monitoring.config_file", c.Monitoring.ConfigFile != next.Monitoring.ConfigFile},
+		{"monitoring.log_dir", c.Monitoring.LogDir != next.Monitoring.LogDir},
+		{"devices.capabilities_file", c.Devices.CapabilitiesFile != next.Devices.CapabilitiesFile},
+		{"cache.default_expiration", c.Cache.DefaultExpiration != next.Cache.DefaultExpiration},
+		{"cache.cleanup_interval", c.Cache.CleanupInterval != next.Cache.CleanupInterval},
+		{"auth.enabled", c.Auth.Enabled != next.Auth.Enabled},
+		{"auth.users_file", c.Auth.UsersFile != next.Auth.UsersFile},
+		{"auth.admin_username", c.Auth.AdminUsername != next.Auth.AdminUsername},
+		{"auth.admin_password", c.Auth.AdminPassword != next.Auth.AdminPassword},
+		{"auth.session_ttl", c.Auth.SessionTTL != next.Auth.SessionTTL},
+		{"tls.enabled", c.TLS.Enabled != next.TLS.Enabled},
+		{"tls.cert_file", c.TLS.CertFile != next.TLS.CertFile},
+		{"tls.key_file", c.TLS.KeyFile != next.TLS.KeyFile},
+		{"tls.hosts", !slices.Equal(c.TLS.Hosts, next.TLS.Hosts)},
+		{"tls.redirect_port", c.TLS.RedirectPort != next.TLS.RedirectPort},
+		{"metrics.enabled", c.Metrics.Enabled != next.Metrics.Enabled},
+		{"metrics.min_interval", c.Metrics.MinInterval != next.Metrics.MinInterval},
+		{"telemetry.enabled", c.Telemetry.Enabled != next.Telemetry.Enabled},
+		{"telemetry.dir", c.Telemetry.Dir != next.Telemetry.Dir},
+		{"telemetry.interval", c.Telemetry.Interval != next.Telemetry.Interval},
+		{"telemetry.retention", c.Telemetry.Retention != next.Telemetry.Retention},
+		{"telemetry.max_points", c.Telemetry.MaxPoints != next.Telemetry.MaxPoints},
+		{"telemetry.live_interval", c.Telemetry.LiveInterval != next.Telemetry.LiveInterval},
+		{"telemetry.traffic_interval", c.Telemetry.TrafficInterval != next.Telemetry.TrafficInterval},
+		{"usage.enabled", c.Usage.Enabled != next.Usage.Enabled},
+		{"usage.file", c.Usage.File != next.Usage.File},
+		{"usage.interval", c.Usage.Interval != next.Usage.Interval},
+		{"usage.cycle_start_day", c.Usage.CycleStartDay != next.Usage.CycleStartDay},
+	}
+
+	keys := make([]string, 0)
+	for _, check := range checks {
+		if check.changed {
+			keys = append(keys, check.key)
+		}
+	}
+	return keys
+}
+
+// Flags holds the command line flags registered by RegisterFlags.
+type Flags struct {
+	fs         *flag.FlagSet
+	configFile string
+	dataDir    string
+	host       string
+	port       int
+	shutdown   time.Duration
+	logFile    string
+	logLevel   string
+	monConfig  string
+	monLogDir  string
+	capsFile   string
+	cacheTTL   time.Duration
+	cacheClean time.Duration
+	auth       bool
+	usersFile  string
+	tls        bool
+	tlsCert    string
+	tlsKey     string
+	redirect   int
+}
+
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.configFile, "config", "", "Path to a YAML or JSON config file")
+	fs.StringVar(&f.dataDir, "data-dir", "", "Base directory for relative file paths")
+	fs.StringVar(&f.host, "host", "", "Address the HTTP server binds to")
+	fs.IntVar(&f.port, "port", 0, "Port the HTTP server listens on")
+	fs.DurationVar(&f.shutdown, "shutdown-timeout", 0, "Time allowed for a graceful shutdown")
+	fs.StringVar(&f.logFile, "log-file", "", "Application log file")
+	fs.StringVar(&f.logLevel, "log-level", "", "Log level (debug, info, warn, error)")
+	fs.StringVar(&f.monConfig, "monitoring-config", "", "Monitoring task config file")
+	fs.StringVar(&f.monLogDir, "monitoring-log-dir", "", "Directory for monitoring logs")
+	fs.StringVar(&f.capsFile, "capabilities-file", "", "File storing the features probed on every device")
+	fs.DurationVar(&f.cacheTTL, "cache-ttl", 0, "Default cache expiration")
+	fs.DurationVar(&f.cacheClean, "cache-cleanup-interval", 0, "Cache cleanup interval")
+	fs.BoolVar(&f.auth, "auth", true, "Require login for the API, events, websockets and debug routes, --auth=false disables it")
+	fs.StringVar(&f.usersFile, "auth-users-file", "", "File storing users and API tokens")
+	fs.BoolVar(&f.tls, "tls", false, "Serve HTTPS")
+	fs.StringVar(&f.tlsCert, "tls-cert", "", "TLS certificate file")
+	fs.StringVar(&f.tlsKey, "tls-key", "", "TLS private key file")
+	fs.IntVar(&f.redirect, "tls-redirect-port", 0, "Port redirecting plain HTTP to HTTPS, 0 disables it")
+	return f
+}
+
+// Load builds the configuration from defaults, the config file, the
+// environment and the parsed flags, in that order.
+func Load(flags *Flags, lookupEnv func(string) (string, bool)) (*Config, error) {
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	cfg := Default()
+
+	file := ""
+	if v, ok := lookupEnv(EnvPrefix + "CONFIG"); ok {
+		file = v
+	}
+	if flags != nil && flags.isSet("config") {
+		file = flags.configFile
+	}
+	if file != "" {
+		if err := cfg.loadFile(file); err != nil {
+			return nil, err
+		}
+		cfg.File = file
+	}
+
+	if err := cfg.applyEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+	if flags != nil {
+		flags.apply(cfg)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		err = json.Unmarshal(data, c)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported config file extension %q", filepath.Ext(file))
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", file, err)
+	}
+	return nil
+}
+
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	stringValues := map[string]*string{
+		"DATA_DIR":           &c.DataDir,
+		"HOST":               &c.Server.Host,
+		"LOG_FILE":           &c.Log.File,
+		"LOG_LEVEL":          &c.Log.Level,
+		"MONITORING_CONFIG":  &c.Monitoring.ConfigFile,
+		"MONITORING_LOG_DIR": &c.Monitoring.LogDir,
+		"CAPABILITIES_FILE":  &c.Devices.CapabilitiesFile,
+		"AUTH_USERS_FILE":    &c.Auth.UsersFile,
+		"ADMIN_USERNAME":     &c.Auth.AdminUsername,
+		"ADMIN_PASSWORD":     &c.Auth.AdminPassword,
+		"TLS_CERT_FILE":      &c.TLS.CertFile,
+		"TLS_KEY_FILE":       &c.TLS.KeyFile,
+		"TELEMETRY_DIR":      &c.Telemetry.Dir,
+		"USAGE_FILE":         &c.Usage.File,
+	}
+	for key, target := range stringValues {
+		if v, ok := lookupEnv(EnvPrefix + key); ok {
+			*target = v
+		}
+	}
+
+	ints := map[string]*int{
+		"PORT":                  &c.Server.Port,
+		"TLS_REDIRECT_PORT":     &c.TLS.RedirectPort,
+		"TELEMETRY_MAX_POINTS":  &c.Telemetry.MaxPoints,
+		"USAGE_CYCLE_START_DAY": &c.Usage.CycleStartDay,
+	}
+	for key, target := range ints {
+		if v, ok := lookupEnv(EnvPrefix + key); ok {
+			parsed, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s%s: %w", EnvPrefix, key, err)
+			}
+			*target = parsed
+		}
+	}
+
+	bools := map[string]*bool{
+		"AUTH_ENABLED":      &c.Auth.Enabled,
+		"TLS_ENABLED":       &c.TLS.Enabled,
+		"METRICS_ENABLED":   &c.Metrics.Enabled,
+		"TELEMETRY_ENABLED": &c.Telemetry.Enabled,
+		"USAGE_ENABLED":     &c.Usage.Enabled,
+	}
+	for key, target := range bools {
+		if v, ok := lookupEnv(EnvPrefix + key); ok {
+			parsed, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s%s: %w", EnvPrefix, key, err)
+			}
+			*target = parsed
+		}
+	}
+
+	durations := map[string]*Duration{
+		"SHUTDOWN_TIMEOUT":           &c.Server.ShutdownTimeout,
+		"CACHE_TTL":                  &c.Cache.DefaultExpiration,
+		"CACHE_CLEANUP_INTERVAL":     &c.Cache.CleanupInterval,
+		"AUTH_SESSION_TTL":           &c.Auth.SessionTTL,
+		"METRICS_MIN_INTERVAL":       &c.Metrics.MinInterval,
+		"TELEMETRY_INTERVAL":         &c.Telemetry.Interval,
+		"TELEMETRY_RETENTION":        &c.Telemetry.Retention,
+		"TELEMETRY_LIVE_INTERVAL":    &c.Telemetry.LiveInterval,
+		"TELEMETRY_TRAFFIC_INTERVAL": &c.Telemetry.TrafficInterval,
+		"USAGE_INTERVAL":             &c.Usage.Interval,
+	}
+	for key, target := range durations {
+		if v, ok := lookupEnv(EnvPrefix + key); ok {
+			if err := target.UnmarshalText([]byte(v)); err != nil {
+				return fmt.Errorf("invalid %s%s: %w", EnvPrefix, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Flags) isSet(name string) bool {
+	set := false
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func (f *Flags) apply(c *Config) {
+	if f.isSet("data-dir") {
+		c.DataDir = f.dataDir
+	}
+	if f.isSet("host") {
+		c.Server.Host = f.host
+	}
+	if f.isSet("port") {
+		c.Server.Port = f.port
+	}
+	if f.isSet("shutdown-timeout") {
+		c.Server.ShutdownTimeout = Duration(f.shutdown)
+	}
+	if f.isSet("log-file") {
+		c.Log.File = f.logFile
+	}
+	if f.isSet("log-level") {
+		c.Log.Level = f.logLevel
+	}
+	if f.isSet("monitoring-config") {
+		c.Monitoring.ConfigFile = f.monConfig
+	}
+	if f.isSet("monitoring-log-dir") {
+		c.Monitoring.LogDir = f.monLogDir
+	}
+	if f.isSet("capabilities-file") {
+		c.Devices.CapabilitiesFile = f.capsFile
+	}
+	if f.isSet("cache-ttl") {
+		c.Cache.DefaultExpiration = Duration(f.cacheTTL)
+	}
+	if f.isSet("cache-cleanup-interval") {
+		c.Cache.CleanupInterval = Duration(f.cacheClean)
+	}
+	if f.isSet("auth") {
+		c.Auth.Enabled = f.auth
+	}
+	if f.isSet("auth-users-file") {
+		c.Auth.UsersFile = f.usersFile
+	}
+	if f.isSet("tls") {
+		c.TLS.Enabled = f.tls
+	}
+	if f.isSet("tls-cert") {
+		c.TLS.CertFile = f.tlsCert
+	}
+	if f.isSet("tls-key") {
+		c.TLS.KeyFile = f.tlsKey
+	}
+	if f.isSet("tls-redirect-port") {
+		c.TLS.RedirectPort = f.redirect
+	}
+}
+
+// Duration is a time.Duration written as "5m" or "30s" in config files and env vars.
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
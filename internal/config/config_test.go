@@ -0,0 +1,176 @@
+package config_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envFrom(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func parseFlags(t *testing.T, args ...string) *config.Flags {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := config.RegisterFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return flags
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.Load(parseFlags(t), envFrom(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, "0.0.0.0:49153", cfg.Address())
+	assert.Equal(t, 15*time.Second, cfg.Server.ShutdownTimeout.Duration())
+	assert.Equal(t, "andromodem_logs/andromodem.log", cfg.Log.File)
+	assert.Equal(t, "info", cfg.Log.Level)
+	assert.Equal(t, "andromodem_monitoring_config.json", cfg.Monitoring.ConfigFile)
+	assert.Equal(t, "andromodem_logs/monitoring", cfg.Monitoring.LogDir)
+	assert.Equal(t, 5*time.Minute, cfg.Cache.DefaultExpiration.Duration())
+	assert.Equal(t, 10*time.Minute, cfg.Cache.CleanupInterval.Duration())
+	assert.True(t, cfg.Auth.Enabled)
+	assert.True(t, cfg.Metrics.Enabled)
+	assert.Equal(t, 30*time.Second, cfg.Metrics.MinInterval.Duration())
+	assert.Equal(t, time.Minute, cfg.Telemetry.Interval.Duration())
+	assert.Equal(t, 7*24*time.Hour, cfg.Telemetry.Retention.Duration())
+	assert.Equal(t, "andromodem_usage.json", cfg.Usage.File)
+	assert.Equal(t, 1, cfg.Usage.CycleStartDay)
+	assert.Empty(t, cfg.File)
+}
+
+func TestLoad_AuthOptOut(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.Load(parseFlags(t, "-auth=false"), envFrom(nil))
+	require.NoError(t, err)
+	assert.False(t, cfg.Auth.Enabled)
+
+	cfg, err = config.Load(parseFlags(t), envFrom(map[string]string{"ANDROMODEM_AUTH_ENABLED": "false"}))
+	require.NoError(t, err)
+	assert.False(t, cfg.Auth.Enabled)
+}
+
+func TestLoad_YAMLFile(t *testing.T) {
+	t.Parallel()
+
+	file := writeFile(t, "andromodem.yaml", `
+data_dir: /etc/andromodem
+server:
+  host: 192.168.1.1
+  port: 8080
+log:
+  level: debug
+cache:
+  default_expiration: 30s
+`)
+
+	cfg, err := config.Load(parseFlags(t, "-config", file), envFrom(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, file, cfg.File)
+	assert.Equal(t, "192.168.1.1:8080", cfg.Address())
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, 30*time.Second, cfg.Cache.DefaultExpiration.Duration())
+	assert.Equal(t, 10*time.Minute, cfg.Cache.CleanupInterval.Duration())
+	assert.Equal(t, "/etc/andromodem/andromodem_logs/andromodem.log", cfg.Path(cfg.Log.File))
+}
+
+func TestLoad_JSONFileFromEnv(t *testing.T) {
+	t.Parallel()
+
+	file := writeFile(t, "andromodem.json", `{"server": {"port": 9000}, "monitoring": {"log_dir": "/tmp/monitoring"}}`)
+
+	cfg, err := config.Load(parseFlags(t), envFrom(map[string]string{"ANDROMODEM_CONFIG": file}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 9000, cfg.Server.Port)
+	assert.Equal(t, "/tmp/monitoring", cfg.Monitoring.LogDir)
+	assert.Equal(t, "/tmp/monitoring", cfg.Path(cfg.Monitoring.LogDir))
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	t.Parallel()
+
+	file := writeFile(t, "andromodem.yml", `
+server:
+  host: 10.0.0.1
+  port: 8000
+log:
+  level: warn
+`)
+	env := envFrom(map[string]string{
+		"ANDROMODEM_PORT":      "8001",
+		"ANDROMODEM_LOG_LEVEL": "error",
+		"ANDROMODEM_CACHE_TTL": "1m",
+	})
+
+	cfg, err := config.Load(parseFlags(t, "-config", file, "-port", "8002"), env)
+	require.NoError(t, err)
+
+	assert.Equal(t, "10.0.0.1", cfg.Server.Host, "file overrides defaults")
+	assert.Equal(t, "error", cfg.Log.Level, "env overrides file")
+	assert.Equal(t, 8002, cfg.Server.Port, "flags override env")
+	assert.Equal(t, time.Minute, cfg.Cache.DefaultExpiration.Duration())
+}
+
+func TestLoad_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		flags []string
+		env   map[string]string
+	}{
+		{"missing file", []string{"-config", "/nonexistent/andromodem.yaml"}, nil},
+		{"unsupported extension", []string{"-config", writeFile(t, "andromodem.toml", "")}, nil},
+		{"invalid yaml", []string{"-config", writeFile(t, "bad.yaml", "server: [")}, nil},
+		{"invalid env port", nil, map[string]string{"ANDROMODEM_PORT": "abc"}},
+		{"invalid env duration", nil, map[string]string{"ANDROMODEM_CACHE_TTL": "soon"}},
+		{"port out of range", []string{"-port", "70000"}, nil},
+		{"usage cycle start day out of range", nil, map[string]string{"ANDROMODEM_USAGE_CYCLE_START_DAY": "31"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := config.Load(parseFlags(t, tt.flags...), envFrom(tt.env))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	t.Parallel()
+
+	current := config.Default()
+	next := config.Default()
+	assert.Empty(t, current.RestartRequired(next))
+
+	next.Log.Level = "debug"
+	assert.Empty(t, current.RestartRequired(next), "log level is applied on reload")
+
+	next.Server.Port = 8080
+	next.TLS.Enabled = true
+	next.TLS.Hosts = []string{"router.lan"}
+	assert.Equal(t, []string{"server.port", "tls.enabled", "tls.hosts"}, current.RestartRequired(next))
+}
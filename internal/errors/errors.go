@@ -21,4 +21,14 @@ var (
 	ErrorInvalidMonitoringTask      = _errors.New("invalid monitoring task configuration")
 	ErrorTaskNotFoundInConfig       = _errors.New("task not found in configuration file")
 	ErrorMonitoringTaskAlreadyRunning = _errors.New("monitoring task is already running")
+	// Process service errors
+	ErrorProcessNotFound = _errors.New("process not found")
+	// Auth service errors
+	ErrorInvalidCredentials = _errors.New("invalid username or password")
+	ErrorUnauthenticated    = _errors.New("authentication required")
+	ErrorApiTokenNotFound   = _errors.New("api token not found")
+	ErrorUserNotFound       = _errors.New("user not found")
+	ErrorUserExists         = _errors.New("user already exists")
+	ErrorLastAdmin          = _errors.New("at least one admin account is required")
+	ErrorCannotDeleteSelf   = _errors.New("cannot delete the account you are logged in with")
 )
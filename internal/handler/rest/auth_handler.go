@@ -0,0 +1,162 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/basiooo/andromodem/internal/common"
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	appMiddleware "github.com/basiooo/andromodem/internal/middleware"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/auth_service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+type AuthHandler struct {
+	AuthService auth_service.IAuthService
+	Logger      *zap.Logger
+	Validator   *validator.Validate
+}
+
+func NewAuthHandler(authService auth_service.IAuthService, logger *zap.Logger, validator *validator.Validate) IAuthHandler {
+	return &AuthHandler{
+		AuthService: authService,
+		Logger:      logger,
+		Validator:   validator,
+	}
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var request model.LoginRequest
+	if err := common.ReadFromRequestBody(r, &request); err != nil {
+		common.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.Validator.Struct(request); err != nil {
+		common.ValidationErrorResponse(w, "Validation error", http.StatusBadRequest, err)
+		return
+	}
+
+	token, session, err := h.AuthService.Login(request.Username, request.Password)
+	if err != nil {
+		if errors.Is(err, andromodemError.ErrorInvalidCredentials) {
+			h.Logger.Warn("failed login attempt",
+				zap.String("username", request.Username),
+				zap.String("remote_addr", r.RemoteAddr))
+			common.ErrorResponse(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		h.Logger.Error("failed to login", zap.Error(err))
+		common.ErrorResponse(w, "Failed to login", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     appMiddleware.SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	common.SuccessResponse(w, "Logged in successfully", session, http.StatusOK)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(appMiddleware.SessionCookieName); err == nil {
+		h.AuthService.Logout(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     appMiddleware.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	common.SuccessResponse(w, "Logged out successfully", nil, http.StatusOK)
+}
+
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	principal := appMiddleware.PrincipalFromContext(r.Context())
+	common.SuccessResponse(w, "Current user retrieved successfully", principal, http.StatusOK)
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	principal := appMiddleware.PrincipalFromContext(r.Context())
+
+	var request model.ChangePasswordRequest
+	if err := common.ReadFromRequestBody(r, &request); err != nil {
+		common.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.Validator.Struct(request); err != nil {
+		common.ValidationErrorResponse(w, "Validation error", http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.AuthService.ChangePassword(principal.Username, request.CurrentPassword, request.NewPassword); err != nil {
+		if errors.Is(err, andromodemError.ErrorInvalidCredentials) {
+			common.ErrorResponse(w, "Current password is incorrect", http.StatusBadRequest)
+			return
+		}
+		h.Logger.Error("failed to change password", zap.String("username", principal.Username), zap.Error(err))
+		common.ErrorResponse(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+	common.SuccessResponse(w, "Password changed successfully", nil, http.StatusOK)
+}
+
+func (h *AuthHandler) CreateTicket(w http.ResponseWriter, r *http.Request) {
+	principal := appMiddleware.PrincipalFromContext(r.Context())
+	ticket := h.AuthService.CreateTicket(principal)
+	common.SuccessResponse(w, "Ticket created successfully", ticket, http.StatusCreated)
+}
+
+func (h *AuthHandler) ListApiTokens(w http.ResponseWriter, r *http.Request) {
+	principal := appMiddleware.PrincipalFromContext(r.Context())
+	tokens := h.AuthService.ListApiTokens(principal.Username)
+	common.SuccessResponse(w, "API tokens retrieved successfully", tokens, http.StatusOK)
+}
+
+func (h *AuthHandler) CreateApiToken(w http.ResponseWriter, r *http.Request) {
+	principal := appMiddleware.PrincipalFromContext(r.Context())
+
+	var request model.CreateApiTokenRequest
+	if err := common.ReadFromRequestBody(r, &request); err != nil {
+		common.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.Validator.Struct(request); err != nil {
+		common.ValidationErrorResponse(w, "Validation error", http.StatusBadRequest, err)
+		return
+	}
+
+	token, err := h.AuthService.CreateApiToken(principal.Username, &request)
+	if err != nil {
+		h.Logger.Error("failed to create api token", zap.String("username", principal.Username), zap.Error(err))
+		common.ErrorResponse(w, "Failed to create API token", http.StatusInternalServerError)
+		return
+	}
+	common.SuccessResponse(w, "API token created successfully", token, http.StatusCreated)
+}
+
+func (h *AuthHandler) RevokeApiToken(w http.ResponseWriter, r *http.Request) {
+	principal := appMiddleware.PrincipalFromContext(r.Context())
+	id := chi.URLParam(r, "id")
+
+	if err := h.AuthService.RevokeApiToken(principal.Username, id); err != nil {
+		if errors.Is(err, andromodemError.ErrorApiTokenNotFound) {
+			common.ErrorResponse(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		h.Logger.Error("failed to revoke api token", zap.String("username", principal.Username), zap.Error(err))
+		common.ErrorResponse(w, "Failed to revoke API token", http.StatusInternalServerError)
+		return
+	}
+	common.SuccessResponse(w, "API token revoked successfully", nil, http.StatusOK)
+}
@@ -0,0 +1,14 @@
+package rest
+
+import "net/http"
+
+type IAuthHandler interface {
+	Login(http.ResponseWriter, *http.Request)
+	Logout(http.ResponseWriter, *http.Request)
+	Me(http.ResponseWriter, *http.Request)
+	ChangePassword(http.ResponseWriter, *http.Request)
+	CreateTicket(http.ResponseWriter, *http.Request)
+	ListApiTokens(http.ResponseWriter, *http.Request)
+	CreateApiToken(http.ResponseWriter, *http.Request)
+	RevokeApiToken(http.ResponseWriter, *http.Request)
+}
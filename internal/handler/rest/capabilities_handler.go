@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/basiooo/andromodem/internal/common"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type CapabilitiesHandler struct {
+	CapabilityService capability_service.ICapabilityService
+	Logger            *zap.Logger
+}
+
+func NewCapabilitiesHandler(capabilityService capability_service.ICapabilityService, logger *zap.Logger) ICapabilitiesHandler {
+	return &CapabilitiesHandler{
+		CapabilityService: capabilityService,
+		Logger:            logger,
+	}
+}
+
+func (c *CapabilitiesHandler) GetCapabilities(writer http.ResponseWriter, request *http.Request) {
+	serial := chi.URLParam(request, "serial")
+	capabilities, err := c.CapabilityService.GetCapabilities(request.Context(), serial)
+	if err != nil {
+		c.Logger.Error("error getting device capabilities", zap.String("serial", serial), zap.Error(err))
+		common.DeviceErrorResponse(writer, err, "Error getting device capabilities")
+		return
+	}
+	common.SuccessResponse(writer, "Device capabilities retrieved successfully", capabilities, http.StatusOK)
+}
+
+func (c *CapabilitiesHandler) ProbeCapabilities(writer http.ResponseWriter, request *http.Request) {
+	serial := chi.URLParam(request, "serial")
+	capabilities, err := c.CapabilityService.ProbeCapabilities(request.Context(), serial)
+	if err != nil {
+		c.Logger.Error("error probing device capabilities", zap.String("serial", serial), zap.Error(err))
+		common.DeviceErrorResponse(writer, err, "Error probing device capabilities")
+		return
+	}
+	common.SuccessResponse(writer, "Device capabilities probed successfully", capabilities, http.StatusOK)
+}
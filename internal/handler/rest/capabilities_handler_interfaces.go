@@ -0,0 +1,8 @@
+package rest
+
+import "net/http"
+
+type ICapabilitiesHandler interface {
+	GetCapabilities(http.ResponseWriter, *http.Request)
+	ProbeCapabilities(http.ResponseWriter, *http.Request)
+}
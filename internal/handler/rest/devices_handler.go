@@ -2,12 +2,11 @@ package rest
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 
-	andromodemError "github.com/basiooo/andromodem/internal/errors"
 	"github.com/basiooo/andromodem/internal/model"
 	"github.com/basiooo/andromodem/internal/service/devices_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
 	"github.com/go-playground/validator/v10"
 
 	"github.com/basiooo/andromodem/internal/common"
@@ -29,16 +28,22 @@ func NewDevicesHandler(devicesService devices_service.IDevicesService, logger *z
 	}
 }
 
+func (d *DevicesHandler) GetDevices(writer http.ResponseWriter, request *http.Request) {
+	devices, err := d.DevicesService.ListDevices()
+	if err != nil {
+		d.Logger.Error("error listing devices", zap.Error(err))
+		common.DeviceErrorResponse(writer, err, "Error listing devices")
+		return
+	}
+	common.SuccessResponse(writer, "Devices retrieved successfully", &parser.DeviceList{Devices: devices}, http.StatusOK)
+}
+
 func (d *DevicesHandler) GetDeviceInfo(writer http.ResponseWriter, request *http.Request) {
 	serial := chi.URLParam(request, "serial")
-	deviceInfo, err := d.DevicesService.GetDeviceInfo(serial)
+	deviceInfo, err := d.DevicesService.GetDeviceInfo(request.Context(), serial)
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.DeviceNotFoundResponse(writer)
-			return
-		}
 		d.Logger.Error("error getting device info", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(writer, "Error getting device info", http.StatusInternalServerError)
+		common.DeviceErrorResponse(writer, err, "Error getting device info")
 		return
 	}
 	common.SuccessResponse(writer, "Device info retrieved successfully", deviceInfo, http.StatusOK)
@@ -46,14 +51,10 @@ func (d *DevicesHandler) GetDeviceInfo(writer http.ResponseWriter, request *http
 
 func (d *DevicesHandler) GetDeviceFeatureAvailabilities(writer http.ResponseWriter, request *http.Request) {
 	serial := chi.URLParam(request, "serial")
-	DeviceFeatuAvailabilities, err := d.DevicesService.GetDeviceFeatureAvailabilities(serial)
+	DeviceFeatuAvailabilities, err := d.DevicesService.GetDeviceFeatureAvailabilities(request.Context(), serial)
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.DeviceNotFoundResponse(writer)
-			return
-		}
 		d.Logger.Error("error getting device feature availabilities", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(writer, "Error getting device feature availabilities", http.StatusInternalServerError)
+		common.DeviceErrorResponse(writer, err, "Error getting device feature availabilities")
 		return
 	}
 	common.SuccessResponse(writer, "Device feature availabilities retrieved successfully", DeviceFeatuAvailabilities, http.StatusOK)
@@ -74,14 +75,10 @@ func (d *DevicesHandler) PowerAction(writer http.ResponseWriter, request *http.R
 		common.ErrorResponse(writer, "Error validating power action request", http.StatusBadRequest)
 		return
 	}
-	err = d.DevicesService.DevicePower(serial, devices_service.PowerAction(powerAction.Action))
+	err = d.DevicesService.DevicePower(request.Context(), serial, devices_service.PowerAction(powerAction.Action))
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.DeviceNotFoundResponse(writer)
-			return
-		}
 		d.Logger.Error("error power action", zap.String("serial", serial), zap.String("action", string(powerAction.Action)), zap.Error(err))
-		common.ErrorResponse(writer, "Error power action", http.StatusInternalServerError)
+		common.DeviceErrorResponse(writer, err, "Error power action")
 		return
 	}
 	common.SuccessResponse(writer, "Power action executed successfully", nil, http.StatusOK)
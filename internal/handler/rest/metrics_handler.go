@@ -0,0 +1,36 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/basiooo/andromodem/internal/common"
+	"github.com/basiooo/andromodem/internal/service/metrics_service"
+	"github.com/basiooo/andromodem/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+type MetricsHandler struct {
+	MetricsService metrics_service.IMetricsService
+	Logger         *zap.Logger
+}
+
+func NewMetricsHandler(metricsService metrics_service.IMetricsService, logger *zap.Logger) IMetricsHandler {
+	return &MetricsHandler{
+		MetricsService: metricsService,
+		Logger:         logger,
+	}
+}
+
+func (m *MetricsHandler) GetMetrics(writer http.ResponseWriter, request *http.Request) {
+	families, err := m.MetricsService.Collect()
+	if err != nil {
+		m.Logger.Error("error collecting metrics", zap.Error(err))
+		common.ErrorResponse(writer, "Error collecting metrics", http.StatusServiceUnavailable)
+		return
+	}
+	writer.Header().Set("Content-Type", metrics.ContentType)
+	writer.WriteHeader(http.StatusOK)
+	if err := metrics.Write(writer, families); err != nil {
+		m.Logger.Error("error writing metrics", zap.Error(err))
+	}
+}
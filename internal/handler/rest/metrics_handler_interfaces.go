@@ -0,0 +1,7 @@
+package rest
+
+import "net/http"
+
+type IMetricsHandler interface {
+	GetMetrics(http.ResponseWriter, *http.Request)
+}
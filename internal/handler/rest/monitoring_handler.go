@@ -41,7 +41,7 @@ func (h *MonitoringHandler) StartMonitoring(w http.ResponseWriter, r *http.Reque
 			return
 		}
 		h.Logger.Error("failed to start monitoring", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(w, err, "Failed to start monitoring")
 		return
 	}
 
@@ -60,7 +60,7 @@ func (h *MonitoringHandler) StopMonitoring(w http.ResponseWriter, r *http.Reques
 			return
 		}
 		h.Logger.Error("failed to stop monitoring", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(w, err, "Failed to stop monitoring")
 		return
 	}
 
@@ -76,7 +76,7 @@ func (h *MonitoringHandler) DeleteMonitoring(w http.ResponseWriter, r *http.Requ
 			return
 		}
 		h.Logger.Error("failed to delete monitoring", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(w, err, "Failed to delete monitoring")
 		return
 	}
 
@@ -88,16 +88,12 @@ func (h *MonitoringHandler) GetMonitoringStatus(w http.ResponseWriter, r *http.R
 
 	status, err := h.MonitoringService.GetMonitoringStatus(serial)
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.DeviceNotFoundResponse(w)
-			return
-		}
 		if errors.Is(err, andromodemError.ErrorTaskNotFoundInConfig) {
 			common.ErrorResponse(w, err.Error(), http.StatusNotFound)
 			return
 		}
 		h.Logger.Error("failed to get monitoring status", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(w, err, "Failed to get monitoring status")
 		return
 	}
 
@@ -109,17 +105,12 @@ func (h *MonitoringHandler) GetMonitoringConfig(w http.ResponseWriter, r *http.R
 
 	task, err := h.MonitoringService.GetMonitoringConfig(serial)
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.ErrorResponse(w, "Monitoring config not found for this device", http.StatusNotFound)
-			return
-		}
-
 		if errors.Is(err, andromodemError.ErrorTaskNotFoundInConfig) {
 			common.ErrorResponse(w, err.Error(), http.StatusNotFound)
 			return
 		}
 		h.Logger.Error("failed to get monitoring config", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(w, err, "Failed to get monitoring config")
 		return
 	}
 
@@ -144,12 +135,15 @@ func (h *MonitoringHandler) UpdateMonitoringConfig(w http.ResponseWriter, r *htt
 
 	updatedTask, err := h.MonitoringService.UpdateMonitoringConfig(serial, &request)
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.ErrorResponse(w, "Monitoring config not found for this device", http.StatusNotFound)
+		if errors.Is(err, andromodemError.ErrorTaskNotFoundInConfig) {
+			common.ErrorResponse(w, err.Error(), http.StatusNotFound)
+			return
+		} else if errors.Is(err, andromodemError.ErrorInvalidMonitoringTask) {
+			common.ErrorResponse(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		h.Logger.Error("failed to update monitoring config", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(w, err, "Failed to update monitoring config")
 		return
 	}
 
@@ -160,7 +154,7 @@ func (h *MonitoringHandler) GetAllMonitoringTasks(w http.ResponseWriter, r *http
 	tasks, err := h.MonitoringService.GetAllMonitoringTasks()
 	if err != nil {
 		h.Logger.Error("failed to get all monitoring tasks", zap.Error(err))
-		common.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(w, err, "Failed to get monitoring tasks")
 		return
 	}
 
@@ -185,7 +179,7 @@ func (h *MonitoringHandler) GetMonitoringLogs(w http.ResponseWriter, r *http.Req
 	logs, err := h.MonitoringService.GetMonitoringLogs(serial, limit)
 	if err != nil {
 		h.Logger.Error("Failed to get monitoring logs", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(w, "Failed to get monitoring logs", http.StatusInternalServerError)
+		common.DeviceErrorResponse(w, err, "Failed to get monitoring logs")
 		return
 	}
 
@@ -220,16 +214,12 @@ func (h *MonitoringHandler) CreateMonitoring(w http.ResponseWriter, r *http.Requ
 
 	createdTask, err := h.MonitoringService.CreateMonitoring(task)
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.ErrorResponse(w, "Device not found", http.StatusNotFound)
-			return
-		}
 		if errors.Is(err, andromodemError.ErrorMonitoringTaskExists) {
 			common.ErrorResponse(w, "Monitoring config already exist", http.StatusBadRequest)
 			return
 		}
 		h.Logger.Error("failed to create monitoring", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(w, err, "Failed to create monitoring")
 		return
 	}
 
@@ -241,12 +231,12 @@ func (h *MonitoringHandler) ClearMonitoringLogs(w http.ResponseWriter, r *http.R
 
 	err := h.MonitoringService.ClearMonitoringLogs(serial)
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.ErrorResponse(w, "Monitoring config not found for this device", http.StatusNotFound)
+		if errors.Is(err, andromodemError.ErrorTaskNotFoundInConfig) {
+			common.ErrorResponse(w, err.Error(), http.StatusNotFound)
 			return
 		}
 		h.Logger.Error("failed to clear monitoring logs", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(w, err, "Failed to clear monitoring logs")
 		return
 	}
 
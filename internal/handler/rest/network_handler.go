@@ -1,11 +1,9 @@
 package rest
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/basiooo/andromodem/internal/common"
-	andromodemError "github.com/basiooo/andromodem/internal/errors"
 	network_service "github.com/basiooo/andromodem/internal/service/network"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -28,29 +26,48 @@ func NewNetworkHandler(networkService network_service.INetworkService, logger *z
 
 func (n *NetworkHandler) GetNetworkInfo(writer http.ResponseWriter, request *http.Request) {
 	serial := chi.URLParam(request, "serial")
-	networkInfo, err := n.NetworkService.GetNetworkInfo(serial)
+	networkInfo, err := n.NetworkService.GetNetworkInfo(request.Context(), serial)
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.DeviceNotFoundResponse(writer)
-			return
-		}
 		n.Logger.Error("error getting network info", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(writer, "Error getting network info", http.StatusInternalServerError)
+		common.DeviceErrorResponse(writer, err, "Error getting network info")
 		return
 	}
 	common.SuccessResponse(writer, "Network info retrieved successfully", networkInfo, http.StatusOK)
 }
 
+func (n *NetworkHandler) GetTraffic(writer http.ResponseWriter, request *http.Request) {
+	serial := chi.URLParam(request, "serial")
+	traffic, err := n.NetworkService.GetTraffic(request.Context(), serial)
+	if err != nil {
+		n.Logger.Error("error getting network traffic", zap.String("serial", serial), zap.Error(err))
+		common.DeviceErrorResponse(writer, err, "Error getting network traffic")
+		return
+	}
+	common.SuccessResponse(writer, "Network traffic retrieved successfully", traffic, http.StatusOK)
+}
+
+func (n *NetworkHandler) GetAppsUsage(writer http.ResponseWriter, request *http.Request) {
+	serial := chi.URLParam(request, "serial")
+	from, to, err := parseTimeRange(request.URL.Query())
+	if err != nil {
+		common.ErrorResponse(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	appsUsage, err := n.NetworkService.GetAppsUsage(request.Context(), serial, from, to)
+	if err != nil {
+		n.Logger.Error("error getting apps usage", zap.String("serial", serial), zap.Error(err))
+		common.DeviceErrorResponse(writer, err, "Error getting apps data usage")
+		return
+	}
+	common.SuccessResponse(writer, "Apps data usage retrieved successfully", appsUsage, http.StatusOK)
+}
+
 func (n *NetworkHandler) ToggleMobileData(writer http.ResponseWriter, request *http.Request) {
 	serial := chi.URLParam(request, "serial")
-	toggleResult, err := n.NetworkService.ToggleMobileData(serial)
+	toggleResult, err := n.NetworkService.ToggleMobileData(request.Context(), serial)
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.DeviceNotFoundResponse(writer)
-			return
-		}
 		n.Logger.Error("error toggling mobile data", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(writer, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(writer, err, err.Error())
 		return
 	}
 	message := "Mobile data disabled successfully"
@@ -62,14 +79,10 @@ func (n *NetworkHandler) ToggleMobileData(writer http.ResponseWriter, request *h
 
 func (n *NetworkHandler) ToggleAirplaneMode(writer http.ResponseWriter, request *http.Request) {
 	serial := chi.URLParam(request, "serial")
-	toggleResult, err := n.NetworkService.ToggleAirplaneMode(serial)
+	toggleResult, err := n.NetworkService.ToggleAirplaneMode(request.Context(), serial)
 	if err != nil {
-		if errors.Is(err, andromodemError.ErrorDeviceNotFound) {
-			common.DeviceNotFoundResponse(writer)
-			return
-		}
 		n.Logger.Error("error toggling airplane mode", zap.String("serial", serial), zap.Error(err))
-		common.ErrorResponse(writer, err.Error(), http.StatusInternalServerError)
+		common.DeviceErrorResponse(writer, err, err.Error())
 		return
 	}
 	message := "Airplane mode disabled successfully"
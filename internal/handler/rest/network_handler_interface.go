@@ -4,6 +4,8 @@ import "net/http"
 
 type INetworkHandler interface {
 	GetNetworkInfo(http.ResponseWriter, *http.Request)
+	GetTraffic(http.ResponseWriter, *http.Request)
+	GetAppsUsage(http.ResponseWriter, *http.Request)
 	ToggleMobileData(http.ResponseWriter, *http.Request)
 	ToggleAirplaneMode(http.ResponseWriter, *http.Request)
 }
@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/basiooo/andromodem/internal/common"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/process_service"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type ProcessesHandler struct {
+	ProcessService process_service.IProcessService
+	Logger         *zap.Logger
+}
+
+func NewProcessesHandler(processService process_service.IProcessService, logger *zap.Logger) IProcessesHandler {
+	return &ProcessesHandler{
+		ProcessService: processService,
+		Logger:         logger,
+	}
+}
+
+func (p *ProcessesHandler) GetProcesses(writer http.ResponseWriter, request *http.Request) {
+	serial := chi.URLParam(request, "serial")
+	params := request.URL.Query()
+
+	query := &model.ProcessQuery{Sort: model.ProcessSortCpu}
+	switch sortBy := model.ProcessSort(params.Get("sort")); sortBy {
+	case "":
+	case model.ProcessSortCpu, model.ProcessSortMemory:
+		query.Sort = sortBy
+	default:
+		common.ErrorResponse(writer, "sort must be cpu or memory", http.StatusBadRequest)
+		return
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			common.ErrorResponse(writer, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		query.Limit = limit
+	}
+
+	processes, err := p.ProcessService.GetProcesses(request.Context(), serial, query)
+	if err != nil {
+		p.Logger.Error("error getting processes", zap.String("serial", serial), zap.Error(err))
+		common.DeviceErrorResponse(writer, err, "Error getting processes")
+		return
+	}
+	common.SuccessResponse(writer, "Processes retrieved successfully", processes, http.StatusOK)
+}
+
+func (p *ProcessesHandler) KillProcess(writer http.ResponseWriter, request *http.Request) {
+	serial := chi.URLParam(request, "serial")
+	// pid 1 is init, 0 and negative pids signal whole process groups.
+	pid, err := strconv.Atoi(chi.URLParam(request, "pid"))
+	if err != nil || pid <= 1 {
+		common.ErrorResponse(writer, "pid must be a process id above 1", http.StatusBadRequest)
+		return
+	}
+	if err := p.ProcessService.KillProcess(request.Context(), serial, pid); err != nil {
+		p.Logger.Error("error killing process", zap.String("serial", serial), zap.Int("pid", pid), zap.Error(err))
+		common.DeviceErrorResponse(writer, err, "Error killing process")
+		return
+	}
+	common.SuccessResponse(writer, "Process killed successfully", nil, http.StatusOK)
+}
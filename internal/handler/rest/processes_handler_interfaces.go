@@ -0,0 +1,8 @@
+package rest
+
+import "net/http"
+
+type IProcessesHandler interface {
+	GetProcesses(http.ResponseWriter, *http.Request)
+	KillProcess(http.ResponseWriter, *http.Request)
+}
@@ -0,0 +1,108 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/common"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/telemetry_service"
+	"github.com/basiooo/andromodem/pkg/timeseries"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const defaultHistoryRange = 24 * time.Hour
+
+type TelemetryHandler struct {
+	TelemetryService telemetry_service.ITelemetryService
+	Logger           *zap.Logger
+}
+
+func NewTelemetryHandler(telemetryService telemetry_service.ITelemetryService, logger *zap.Logger) ITelemetryHandler {
+	return &TelemetryHandler{
+		TelemetryService: telemetryService,
+		Logger:           logger,
+	}
+}
+
+// parseHistoryTime accepts RFC 3339 timestamps and Unix seconds.
+func parseHistoryTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseTimeRange reads the from and to query parameters. to defaults to now
+// and from to defaultHistoryRange before to.
+func parseTimeRange(params url.Values) (time.Time, time.Time, error) {
+	to := time.Now()
+	if value := params.Get("to"); value != "" {
+		parsed, err := parseHistoryTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be an RFC 3339 timestamp or Unix seconds")
+		}
+		to = parsed
+	}
+	from := to.Add(-defaultHistoryRange)
+	if value := params.Get("from"); value != "" {
+		parsed, err := parseHistoryTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be an RFC 3339 timestamp or Unix seconds")
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+	return from, to, nil
+}
+
+func (h *TelemetryHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	serial := chi.URLParam(r, "serial")
+	params := r.URL.Query()
+
+	query := &model.TelemetryHistoryQuery{
+		Metric: params.Get("metric"),
+	}
+	if query.Metric == "" {
+		common.ErrorResponse(w, "metric is required", http.StatusBadRequest)
+		return
+	}
+	from, to, err := parseTimeRange(params)
+	if err != nil {
+		common.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.From, query.To = from, to
+	if value := params.Get("step"); value != "" {
+		step, err := time.ParseDuration(value)
+		if err != nil || step <= 0 {
+			common.ErrorResponse(w, "step must be a positive duration such as 5m", http.StatusBadRequest)
+			return
+		}
+		query.Step = step
+	}
+	aggregation, err := timeseries.ParseAggregation(params.Get("agg"))
+	if err != nil {
+		common.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.Aggregation = aggregation
+
+	history, err := h.TelemetryService.GetHistory(serial, query)
+	if err != nil {
+		if errors.Is(err, timeseries.ErrorInvalidMetric) || errors.Is(err, timeseries.ErrorInvalidSerial) {
+			common.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.Logger.Error("Failed to get telemetry history", zap.String("serial", serial), zap.Error(err))
+		common.ErrorResponse(w, "Failed to get telemetry history", http.StatusInternalServerError)
+		return
+	}
+	common.SuccessResponse(w, "Telemetry history retrieved successfully", history, http.StatusOK)
+}
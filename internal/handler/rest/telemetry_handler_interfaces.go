@@ -0,0 +1,7 @@
+package rest
+
+import "net/http"
+
+type ITelemetryHandler interface {
+	GetHistory(http.ResponseWriter, *http.Request)
+}
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/basiooo/andromodem/internal/common"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/usage_service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// maxSimSlot is the number of SIM slots of dual SIM phones.
+const maxSimSlot = 2
+
+type UsageHandler struct {
+	UsageService usage_service.IUsageService
+	Logger       *zap.Logger
+	Validator    *validator.Validate
+}
+
+func NewUsageHandler(usageService usage_service.IUsageService, logger *zap.Logger, validator *validator.Validate) IUsageHandler {
+	return &UsageHandler{
+		UsageService: usageService,
+		Logger:       logger,
+		Validator:    validator,
+	}
+}
+
+func (h *UsageHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	serial := chi.URLParam(r, "serial")
+	usage, err := h.UsageService.GetUsage(serial)
+	if err != nil {
+		common.DeviceErrorResponse(w, err, "Error getting data usage")
+		return
+	}
+	common.SuccessResponse(w, "Data usage retrieved successfully", usage, http.StatusOK)
+}
+
+func (h *UsageHandler) SetQuota(w http.ResponseWriter, r *http.Request) {
+	serial := chi.URLParam(r, "serial")
+	simSlot, err := strconv.Atoi(chi.URLParam(r, "slot"))
+	if err != nil || simSlot < 1 || simSlot > maxSimSlot {
+		common.ErrorResponse(w, "slot must be 1 or 2", http.StatusBadRequest)
+		return
+	}
+
+	var request model.UsageQuota
+	if err := common.ReadFromRequestBody(r, &request); err != nil {
+		h.Logger.Error("failed to read request body", zap.Error(err))
+		common.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.Validator.Struct(request); err != nil {
+		h.Logger.Error("validation error", zap.Error(err))
+		common.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	simUsage, err := h.UsageService.SetQuota(serial, simSlot, &request)
+	if err != nil {
+		h.Logger.Error("failed to set data quota", zap.String("serial", serial), zap.Int("sim_slot", simSlot), zap.Error(err))
+		common.DeviceErrorResponse(w, err, "Error setting data quota")
+		return
+	}
+	common.SuccessResponse(w, "Data quota updated successfully", simUsage, http.StatusOK)
+}
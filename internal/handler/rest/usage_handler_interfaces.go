@@ -0,0 +1,8 @@
+package rest
+
+import "net/http"
+
+type IUsageHandler interface {
+	GetUsage(http.ResponseWriter, *http.Request)
+	SetQuota(http.ResponseWriter, *http.Request)
+}
@@ -0,0 +1,105 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/basiooo/andromodem/internal/common"
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	appMiddleware "github.com/basiooo/andromodem/internal/middleware"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/auth_service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+type UsersHandler struct {
+	AuthService auth_service.IAuthService
+	Logger      *zap.Logger
+	Validator   *validator.Validate
+}
+
+func NewUsersHandler(authService auth_service.IAuthService, logger *zap.Logger, validator *validator.Validate) IUsersHandler {
+	return &UsersHandler{
+		AuthService: authService,
+		Logger:      logger,
+		Validator:   validator,
+	}
+}
+
+func (h *UsersHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	common.SuccessResponse(w, "Users retrieved successfully", h.AuthService.ListUsers(), http.StatusOK)
+}
+
+func (h *UsersHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var request model.CreateUserRequest
+	if err := common.ReadFromRequestBody(r, &request); err != nil {
+		common.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.Validator.Struct(request); err != nil {
+		common.ValidationErrorResponse(w, "Validation error", http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := h.AuthService.CreateUser(&request)
+	if err != nil {
+		if errors.Is(err, andromodemError.ErrorUserExists) {
+			common.ErrorResponse(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.Logger.Error("failed to create user", zap.String("username", request.Username), zap.Error(err))
+		common.ErrorResponse(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+	common.SuccessResponse(w, "User created successfully", user, http.StatusCreated)
+}
+
+func (h *UsersHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+
+	var request model.UpdateUserRoleRequest
+	if err := common.ReadFromRequestBody(r, &request); err != nil {
+		common.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.Validator.Struct(request); err != nil {
+		common.ValidationErrorResponse(w, "Validation error", http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := h.AuthService.UpdateUserRole(username, request.Role)
+	if err != nil {
+		if errors.Is(err, andromodemError.ErrorUserNotFound) {
+			common.ErrorResponse(w, err.Error(), http.StatusNotFound)
+			return
+		} else if errors.Is(err, andromodemError.ErrorLastAdmin) {
+			common.ErrorResponse(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.Logger.Error("failed to update user role", zap.String("username", username), zap.Error(err))
+		common.ErrorResponse(w, "Failed to update user role", http.StatusInternalServerError)
+		return
+	}
+	common.SuccessResponse(w, "User role updated successfully", user, http.StatusOK)
+}
+
+func (h *UsersHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	principal := appMiddleware.PrincipalFromContext(r.Context())
+	username := chi.URLParam(r, "username")
+
+	if err := h.AuthService.DeleteUser(principal.Username, username); err != nil {
+		if errors.Is(err, andromodemError.ErrorUserNotFound) {
+			common.ErrorResponse(w, err.Error(), http.StatusNotFound)
+			return
+		} else if errors.Is(err, andromodemError.ErrorLastAdmin) || errors.Is(err, andromodemError.ErrorCannotDeleteSelf) {
+			common.ErrorResponse(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.Logger.Error("failed to delete user", zap.String("username", username), zap.Error(err))
+		common.ErrorResponse(w, "Failed to delete user", http.StatusInternalServerError)
+		return
+	}
+	common.SuccessResponse(w, "User deleted successfully", nil, http.StatusOK)
+}
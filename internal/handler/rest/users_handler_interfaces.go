@@ -0,0 +1,10 @@
+package rest
+
+import "net/http"
+
+type IUsersHandler interface {
+	ListUsers(http.ResponseWriter, *http.Request)
+	CreateUser(http.ResponseWriter, *http.Request)
+	UpdateUserRole(http.ResponseWriter, *http.Request)
+	DeleteUser(http.ResponseWriter, *http.Request)
+}
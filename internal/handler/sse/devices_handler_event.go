@@ -1,6 +1,7 @@
 package sse
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,18 +9,21 @@ import (
 	"github.com/basiooo/andromodem/internal/common"
 	"github.com/basiooo/andromodem/internal/model"
 	"github.com/basiooo/andromodem/internal/service/devices_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
 	"go.uber.org/zap"
 )
 
 type DevicesEventHandler struct {
 	DevicesEventService devices_service.IDevicesService
 	Logger              *zap.Logger
+	Ctx                 context.Context
 }
 
-func NewDevicesEventHandler(devicesEventService devices_service.IDevicesService, logger *zap.Logger) IDevicesEventHandler {
+func NewDevicesEventHandler(devicesEventService devices_service.IDevicesService, logger *zap.Logger, ctx context.Context) IDevicesEventHandler {
 	return &DevicesEventHandler{
 		DevicesEventService: devicesEventService,
 		Logger:              logger,
+		Ctx:                 ctx,
 	}
 }
 
@@ -37,6 +41,23 @@ func (d *DevicesEventHandler) ListenDevicesEvent(w http.ResponseWriter, r *http.
 		return
 	}
 	flusher.Flush()
+
+	// Start with the current set of devices, the listener only reports changes.
+	if devices, err := d.DevicesEventService.ListDevices(); err == nil {
+		res, err := json.Marshal(&parser.DeviceList{Devices: devices})
+		if err != nil {
+			d.Logger.Error("error marshaling devices:", zap.Error(err))
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", res); err != nil {
+			d.Logger.Error("error writing to response:", zap.Error(err))
+			return
+		}
+		flusher.Flush()
+	} else {
+		d.Logger.Error("error listing devices:", zap.Error(err))
+	}
+
 	err = d.DevicesEventService.DevicesListener(requestCtx, func(device *model.Device) error {
 		res, err := json.Marshal(device)
 		if err != nil {
@@ -51,8 +72,13 @@ func (d *DevicesEventHandler) ListenDevicesEvent(w http.ResponseWriter, r *http.
 		return err
 	})
 
+	if d.Ctx.Err() != nil {
+		if err := common.SSEWriteShutdownEvent(w); err != nil {
+			d.Logger.Debug("error writing shutdown event:", zap.Error(err))
+		}
+		return
+	}
 	if err != nil {
 		d.Logger.Error("Listen stopped:", zap.Error(err))
 	}
-
 }
@@ -1,6 +1,7 @@
 package sse
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -15,12 +16,14 @@ import (
 type MonitoringLogEventHandler struct {
 	MonitoringService monitoring_service.IMonitoringService
 	Logger            *zap.Logger
+	Ctx               context.Context
 }
 
-func NewMonitoringLogEventHandler(monitoringService monitoring_service.IMonitoringService, logger *zap.Logger) IMonitoringLogEventHandler {
+func NewMonitoringLogEventHandler(monitoringService monitoring_service.IMonitoringService, logger *zap.Logger, ctx context.Context) IMonitoringLogEventHandler {
 	return &MonitoringLogEventHandler{
 		MonitoringService: monitoringService,
 		Logger:            logger,
+		Ctx:               ctx,
 	}
 }
 
@@ -34,7 +37,10 @@ func (h *MonitoringLogEventHandler) ListenMonitoringLogEvent(w http.ResponseWrit
 	}
 	serial := chi.URLParam(r, "serial")
 
-	requestCtx := r.Context()
+	requestCtx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stopOnShutdown := context.AfterFunc(h.Ctx, cancel)
+	defer stopOnShutdown()
 
 	err := h.MonitoringService.ListenMonitoringLogs(requestCtx, serial, func(log *model.MonitoringLog) error {
 
@@ -53,6 +59,12 @@ func (h *MonitoringLogEventHandler) ListenMonitoringLogEvent(w http.ResponseWrit
 		return nil
 	})
 
+	if h.Ctx.Err() != nil {
+		if err := common.SSEWriteShutdownEvent(w); err != nil {
+			h.Logger.Debug("error writing shutdown event:", zap.Error(err))
+		}
+		return
+	}
 	if err != nil {
 		h.Logger.Error("Log listener stopped:", zap.Error(err))
 	}
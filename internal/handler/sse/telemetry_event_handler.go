@@ -0,0 +1,95 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/basiooo/andromodem/internal/common"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/telemetry_service"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type TelemetryEventHandler struct {
+	LiveTelemetryService telemetry_service.ILiveTelemetryService
+	Logger               *zap.Logger
+	Ctx                  context.Context
+}
+
+func NewTelemetryEventHandler(liveTelemetryService telemetry_service.ILiveTelemetryService, logger *zap.Logger, ctx context.Context) ITelemetryEventHandler {
+	return &TelemetryEventHandler{
+		LiveTelemetryService: liveTelemetryService,
+		Logger:               logger,
+		Ctx:                  ctx,
+	}
+}
+
+// ListenTelemetryEvent sends a "snapshot" event with the full device and
+// network information, followed by "update" events carrying only the fields
+// that changed as a JSON merge patch.
+func (h *TelemetryEventHandler) ListenTelemetryEvent(w http.ResponseWriter, r *http.Request) {
+	common.SSESetResponseHeader(w)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusServiceUnavailable)
+		return
+	}
+	serial := chi.URLParam(r, "serial")
+
+	subscription := h.LiveTelemetryService.Subscribe(serial)
+	defer subscription.Close()
+
+	writeEvent := func(event string, data any) error {
+		res, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, res); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	var previous *model.DeviceTelemetry
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.Ctx.Done():
+			if err := common.SSEWriteShutdownEvent(w); err != nil {
+				h.Logger.Debug("error writing shutdown event:", zap.Error(err))
+			}
+			return
+		case telemetry, ok := <-subscription.C:
+			if !ok {
+				return
+			}
+			if previous == nil {
+				if err := writeEvent("snapshot", telemetry); err != nil {
+					h.Logger.Error("error writing telemetry snapshot:", zap.String("serial", serial), zap.Error(err))
+					return
+				}
+				previous = telemetry
+				continue
+			}
+			patch, err := common.MergePatch(previous, telemetry)
+			if err != nil {
+				h.Logger.Error("error building telemetry update:", zap.String("serial", serial), zap.Error(err))
+				return
+			}
+			previous = telemetry
+			if len(patch) == 0 {
+				continue
+			}
+			if err := writeEvent("update", patch); err != nil {
+				h.Logger.Error("error writing telemetry update:", zap.String("serial", serial), zap.Error(err))
+				return
+			}
+		}
+	}
+}
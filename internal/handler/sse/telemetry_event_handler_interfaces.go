@@ -0,0 +1,7 @@
+package sse
+
+import "net/http"
+
+type ITelemetryEventHandler interface {
+	ListenTelemetryEvent(http.ResponseWriter, *http.Request)
+}
@@ -0,0 +1,69 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/basiooo/andromodem/internal/common"
+	"github.com/basiooo/andromodem/internal/service/telemetry_service"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type TrafficEventHandler struct {
+	LiveTrafficService telemetry_service.ILiveTrafficService
+	Logger             *zap.Logger
+	Ctx                context.Context
+}
+
+func NewTrafficEventHandler(liveTrafficService telemetry_service.ILiveTrafficService, logger *zap.Logger, ctx context.Context) ITrafficEventHandler {
+	return &TrafficEventHandler{
+		LiveTrafficService: liveTrafficService,
+		Logger:             logger,
+		Ctx:                ctx,
+	}
+}
+
+// ListenTrafficEvent sends a "traffic" event with the counters and
+// throughput of the cellular and tethering interfaces on every sample.
+func (h *TrafficEventHandler) ListenTrafficEvent(w http.ResponseWriter, r *http.Request) {
+	common.SSESetResponseHeader(w)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusServiceUnavailable)
+		return
+	}
+	serial := chi.URLParam(r, "serial")
+
+	subscription := h.LiveTrafficService.Subscribe(serial)
+	defer subscription.Close()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.Ctx.Done():
+			if err := common.SSEWriteShutdownEvent(w); err != nil {
+				h.Logger.Debug("error writing shutdown event:", zap.Error(err))
+			}
+			return
+		case traffic, ok := <-subscription.C:
+			if !ok {
+				return
+			}
+			res, err := json.Marshal(traffic)
+			if err != nil {
+				h.Logger.Error("error marshalling traffic:", zap.String("serial", serial), zap.Error(err))
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: traffic\ndata: %s\n\n", res); err != nil {
+				h.Logger.Error("error writing traffic event:", zap.String("serial", serial), zap.Error(err))
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package sse
+
+import "net/http"
+
+type ITrafficEventHandler interface {
+	ListenTrafficEvent(http.ResponseWriter, *http.Request)
+}
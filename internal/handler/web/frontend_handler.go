@@ -10,6 +10,9 @@ import (
 	"go.uber.org/zap"
 )
 
+//go:embed login.html
+var loginPage []byte
+
 type FrontendHandler struct {
 	Logger     *zap.Logger
 	FrontendFS embed.FS
@@ -44,3 +47,10 @@ func (f *FrontendHandler) ServeIndex(writer http.ResponseWriter, request *http.R
 		return
 	}
 }
+
+func (f *FrontendHandler) ServeLogin(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := writer.Write(loginPage); err != nil {
+		f.Logger.Error("error writing login page", zap.Error(err))
+	}
+}
@@ -5,4 +5,5 @@ import "net/http"
 type IFrontendHandler interface {
 	ServeIndex(w http.ResponseWriter, r *http.Request)
 	ServeAssets() http.Handler
+	ServeLogin(w http.ResponseWriter, r *http.Request)
 }
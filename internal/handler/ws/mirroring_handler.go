@@ -21,12 +21,13 @@ type MirroringHandler struct {
 	upgrader         websocket.Upgrader
 	Logger           *zap.Logger
 	Validator        *validator.Validate
+	Ctx              context.Context
 
 	activeConns map[string]*websocket.Conn
 	mu          sync.Mutex
 }
 
-func NewMirroringHandler(mirroringService mirroring_service.IMirroringService, logger *zap.Logger, validator *validator.Validate) *MirroringHandler {
+func NewMirroringHandler(mirroringService mirroring_service.IMirroringService, logger *zap.Logger, validator *validator.Validate, ctx context.Context) *MirroringHandler {
 	return &MirroringHandler{
 		MirroringService: mirroringService,
 		upgrader: websocket.Upgrader{
@@ -36,6 +37,7 @@ func NewMirroringHandler(mirroringService mirroring_service.IMirroringService, l
 		Logger:      logger,
 		activeConns: make(map[string]*websocket.Conn),
 		Validator:   validator,
+		Ctx:         ctx,
 	}
 }
 
@@ -94,6 +96,20 @@ func (h *MirroringHandler) StartMirroringStream(w http.ResponseWriter, r *http.R
 		zap.String("serial", serial),
 	)
 
+	// Hijacked connections are not tracked by http.Server.Shutdown, so tell
+	// the client we are going away and end the session ourselves.
+	stopOnShutdown := context.AfterFunc(h.Ctx, func() {
+		message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+		if err := conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second)); err != nil {
+			h.Logger.Warn("failed to write shutdown close message",
+				zap.String("serial", serial),
+				zap.Error(err),
+			)
+		}
+		cancel()
+	})
+	defer stopOnShutdown()
+
 	setupReceived := make(chan *model.MirroringSetupRequest, 1)
 
 	go h.handleWebSocketMessages(ctx, cancel, conn, serial, setupReceived)
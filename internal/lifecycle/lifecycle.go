@@ -0,0 +1,218 @@
+// Package lifecycle coordinates the graceful shutdown and the config reload
+// of AndroModem.
+//
+// Services register a Hook with OnShutdown while they are wired up. When a stop
+// signal arrives the application context is cancelled first, so streaming
+// handlers and workers notice it, then the hooks run in reverse registration
+// order sharing a single deadline.
+//
+// SIGHUP does not stop the process. The configuration is loaded again and
+// passed to the ReloadHook registered with OnReload, in registration order.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/config"
+	"go.uber.org/zap"
+)
+
+// ErrorReloadUnsupported is returned by Reload when no ConfigLoader was given.
+var ErrorReloadUnsupported = errors.New("config reload is not supported")
+
+// Hook releases a resource during shutdown. It must return once ctx is done.
+type Hook func(ctx context.Context) error
+
+// ReloadHook applies the reloaded configuration to a running service.
+type ReloadHook func(cfg *config.Config) error
+
+// ConfigLoader reads the configuration again from its sources.
+type ConfigLoader func() (*config.Config, error)
+
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+type namedReloadHook struct {
+	name string
+	hook ReloadHook
+}
+
+type Manager struct {
+	logger       *zap.Logger
+	ctxCancel    context.CancelFunc
+	timeout      time.Duration
+	loadConfig   ConfigLoader
+	hooks        []namedHook
+	reloadHooks  []namedReloadHook
+	mutex        sync.Mutex
+	reloadMutex  sync.Mutex
+	shutdownOnce sync.Once
+	err          error
+}
+
+// NewManager returns a manager that cancels the application context through
+// ctxCancel and gives the shutdown hooks timeout to finish. loadConfig is
+// used on SIGHUP; when nil, reloading is disabled.
+func NewManager(logger *zap.Logger, ctxCancel context.CancelFunc, timeout time.Duration, loadConfig ConfigLoader) IManager {
+	return &Manager{
+		logger:     logger,
+		ctxCancel:  ctxCancel,
+		timeout:    timeout,
+		loadConfig: loadConfig,
+	}
+}
+
+func (m *Manager) OnShutdown(name string, hook Hook) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.hooks = append(m.hooks, namedHook{name: name, hook: hook})
+}
+
+func (m *Manager) OnReload(name string, hook ReloadHook) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.reloadHooks = append(m.reloadHooks, namedReloadHook{name: name, hook: hook})
+}
+
+// Run calls start in the background and blocks until a stop signal is
+// received or start returns, then shuts the application down. SIGHUP reloads
+// the configuration instead. The error of start is returned when it failed,
+// otherwise the shutdown error.
+func (m *Manager) Run(start func() error) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer signal.Stop(sig)
+
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- start()
+	}()
+
+	for {
+		select {
+		case received := <-sig:
+			if received == syscall.SIGHUP {
+				m.logger.Info("Reload signal received")
+				_ = m.Reload()
+				continue
+			}
+			m.logger.Info("Shutdown signal received", zap.String("signal", received.String()))
+			return m.Shutdown()
+		case err := <-startErr:
+			if err != nil {
+				m.logger.Error("Server stopped unexpectedly", zap.Error(err))
+				if shutdownErr := m.Shutdown(); shutdownErr != nil {
+					m.logger.Error("Shutdown completed with errors", zap.Error(shutdownErr))
+				}
+				return err
+			}
+			return m.Shutdown()
+		}
+	}
+}
+
+// Reload loads the configuration and passes it to every reload hook. A
+// configuration that fails to load or validate is rejected as a whole and the
+// running one is kept. The outcome is logged and returned.
+func (m *Manager) Reload() error {
+	m.reloadMutex.Lock()
+	defer m.reloadMutex.Unlock()
+
+	if m.loadConfig == nil {
+		m.logger.Warn("Config reload is not supported, ignoring")
+		return ErrorReloadUnsupported
+	}
+	cfg, err := m.loadConfig()
+	if err != nil {
+		m.logger.Error("Config reload failed, keeping the current configuration", zap.Error(err))
+		return err
+	}
+
+	m.mutex.Lock()
+	hooks := make([]namedReloadHook, len(m.reloadHooks))
+	copy(hooks, m.reloadHooks)
+	m.mutex.Unlock()
+
+	var errs []error
+	for _, h := range hooks {
+		if err := h.hook(cfg); err != nil {
+			m.logger.Error("Reload hook failed", zap.String("hook", h.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		m.logger.Warn("Config reloaded with errors", zap.String("config_file", cfg.File), zap.Int("failed_hooks", len(errs)))
+		return err
+	}
+	m.logger.Info("Config reloaded", zap.String("config_file", cfg.File))
+	return nil
+}
+
+// Shutdown cancels the application context and runs the registered hooks in
+// reverse order. Only the first call does any work, later calls return the
+// same result.
+func (m *Manager) Shutdown() error {
+	m.shutdownOnce.Do(func() {
+		m.err = m.shutdown()
+	})
+	return m.err
+}
+
+func (m *Manager) shutdown() error {
+	m.logger.Info("Shutting down", zap.Duration("timeout", m.timeout))
+	m.ctxCancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	m.mutex.Lock()
+	hooks := make([]namedHook, len(m.hooks))
+	copy(hooks, m.hooks)
+	m.mutex.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		started := time.Now()
+		if err := h.hook(ctx); err != nil {
+			m.logger.Error("Shutdown hook failed", zap.String("hook", h.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			continue
+		}
+		m.logger.Info("Shutdown hook completed",
+			zap.String("hook", h.name),
+			zap.Duration("took", time.Since(started)))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	m.logger.Info("Shutdown completed")
+	return nil
+}
+
+// WaitGroup waits for wg from a Hook. It returns the error of ctx when ctx is
+// done first, leaving the goroutines of wg running.
+func WaitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
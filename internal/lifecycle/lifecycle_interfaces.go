@@ -0,0 +1,9 @@
+package lifecycle
+
+type IManager interface {
+	OnShutdown(name string, hook Hook)
+	OnReload(name string, hook ReloadHook)
+	Run(start func() error) error
+	Reload() error
+	Shutdown() error
+}
@@ -0,0 +1,167 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestShutdown_RunsHooksInReverseOrderAfterCancel(t *testing.T) {
+	t.Parallel()
+
+	appCtx, cancel := context.WithCancel(context.Background())
+	manager := NewManager(zap.NewNop(), cancel, time.Second, nil)
+
+	var order []string
+	for _, name := range []string{"logs", "workers", "http"} {
+		manager.OnShutdown(name, func(ctx context.Context) error {
+			assert.Error(t, appCtx.Err(), "application context is cancelled before hooks run")
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	require.NoError(t, manager.Shutdown())
+	assert.Equal(t, []string{"http", "workers", "logs"}, order)
+}
+
+func TestShutdown_JoinsErrorsAndContinues(t *testing.T) {
+	t.Parallel()
+
+	_, cancel := context.WithCancel(context.Background())
+	manager := NewManager(zap.NewNop(), cancel, time.Second, nil)
+
+	hookErr := errors.New("forward remove failed")
+	ran := false
+	manager.OnShutdown("first", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	manager.OnShutdown("mirroring", func(ctx context.Context) error {
+		return hookErr
+	})
+
+	err := manager.Shutdown()
+	assert.ErrorIs(t, err, hookErr)
+	assert.Contains(t, err.Error(), "mirroring")
+	assert.True(t, ran)
+	assert.Equal(t, err, manager.Shutdown(), "shutdown only runs once")
+}
+
+func TestShutdown_SharedDeadline(t *testing.T) {
+	t.Parallel()
+
+	_, cancel := context.WithCancel(context.Background())
+	manager := NewManager(zap.NewNop(), cancel, 50*time.Millisecond, nil)
+
+	manager.OnShutdown("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	started := time.Now()
+	err := manager.Shutdown()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestRun_StartFailure(t *testing.T) {
+	t.Parallel()
+
+	appCtx, cancel := context.WithCancel(context.Background())
+	manager := NewManager(zap.NewNop(), cancel, time.Second, nil)
+
+	startErr := errors.New("address already in use")
+	hookCalled := false
+	manager.OnShutdown("http", func(ctx context.Context) error {
+		hookCalled = true
+		return nil
+	})
+
+	err := manager.Run(func() error { return startErr })
+	assert.ErrorIs(t, err, startErr)
+	assert.True(t, hookCalled)
+	assert.Error(t, appCtx.Err())
+}
+
+func TestReload_PassesConfigToHooksInOrder(t *testing.T) {
+	t.Parallel()
+
+	_, cancel := context.WithCancel(context.Background())
+	loaded := config.Default()
+	loaded.Log.Level = "debug"
+	manager := NewManager(zap.NewNop(), cancel, time.Second, func() (*config.Config, error) {
+		return loaded, nil
+	})
+
+	hookErr := errors.New("task file unreadable")
+	var order []string
+	manager.OnReload("log level", func(cfg *config.Config) error {
+		assert.Equal(t, "debug", cfg.Log.Level)
+		order = append(order, "log level")
+		return nil
+	})
+	manager.OnReload("monitoring", func(cfg *config.Config) error {
+		order = append(order, "monitoring")
+		return hookErr
+	})
+	manager.OnReload("restart check", func(cfg *config.Config) error {
+		order = append(order, "restart check")
+		return nil
+	})
+
+	err := manager.Reload()
+	assert.ErrorIs(t, err, hookErr)
+	assert.Equal(t, []string{"log level", "monitoring", "restart check"}, order)
+}
+
+func TestReload_InvalidConfigSkipsHooks(t *testing.T) {
+	t.Parallel()
+
+	_, cancel := context.WithCancel(context.Background())
+	loadErr := errors.New("invalid server port 0")
+	manager := NewManager(zap.NewNop(), cancel, time.Second, func() (*config.Config, error) {
+		return nil, loadErr
+	})
+	manager.OnReload("log level", func(cfg *config.Config) error {
+		t.Error("hook must not run when the config fails to load")
+		return nil
+	})
+
+	assert.ErrorIs(t, manager.Reload(), loadErr)
+}
+
+func TestReload_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	_, cancel := context.WithCancel(context.Background())
+	manager := NewManager(zap.NewNop(), cancel, time.Second, nil)
+
+	assert.ErrorIs(t, manager.Reload(), ErrorReloadUnsupported)
+}
+
+func TestWaitGroup(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	release := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-release
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, WaitGroup(ctx, &wg), context.DeadlineExceeded)
+
+	close(release)
+	assert.NoError(t, WaitGroup(context.Background(), &wg))
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/basiooo/andromodem/internal/common"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/auth_service"
+	"go.uber.org/zap"
+)
+
+const (
+	SessionCookieName = "andromodem_session"
+	TicketQueryParam  = "ticket"
+)
+
+type principalContextKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *model.Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller set by Authenticator, or nil when
+// the request did not pass through it.
+func PrincipalFromContext(ctx context.Context) *model.Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*model.Principal)
+	return principal
+}
+
+// anonymousPrincipal is the caller of every request when authentication is
+// disabled, it has full access.
+var anonymousPrincipal = model.Principal{Username: "anonymous", Role: model.RoleAdmin, AuthMethod: model.AuthMethodNone}
+
+// Authenticator rejects requests without a valid session cookie, bearer API
+// token or single use ticket. A nil authService disables authentication,
+// every request is then made by an anonymous admin.
+func Authenticator(authService auth_service.IAuthService, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if authService == nil {
+			fn := func(writer http.ResponseWriter, request *http.Request) {
+				principal := anonymousPrincipal
+				next.ServeHTTP(writer, request.WithContext(WithPrincipal(request.Context(), &principal)))
+			}
+			return http.HandlerFunc(fn)
+		}
+		fn := func(writer http.ResponseWriter, request *http.Request) {
+			principal := authenticate(authService, request)
+			if principal == nil {
+				logger.Debug("Unauthenticated request rejected",
+					zap.String("path", request.URL.Path),
+					zap.String("remote_addr", request.RemoteAddr))
+				common.ErrorResponse(writer, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(writer, request.WithContext(WithPrincipal(request.Context(), principal)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func authenticate(authService auth_service.IAuthService, request *http.Request) *model.Principal {
+	if header := request.Header.Get("Authorization"); header != "" {
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found {
+			return nil
+		}
+		principal, err := authService.AuthenticateApiToken(strings.TrimSpace(token))
+		if err != nil {
+			return nil
+		}
+		return principal
+	}
+
+	if ticket := request.URL.Query().Get(TicketQueryParam); ticket != "" {
+		principal, err := authService.RedeemTicket(ticket)
+		if err != nil {
+			return nil
+		}
+		return principal
+	}
+
+	if cookie, err := request.Cookie(SessionCookieName); err == nil {
+		principal, err := authService.AuthenticateSession(cookie.Value)
+		if err != nil {
+			return nil
+		}
+		return principal
+	}
+	return nil
+}
+
+// RequireRole rejects callers whose role does not include required. It must be
+// mounted after Authenticator, requests without a principal are rejected.
+func RequireRole(required model.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(writer http.ResponseWriter, request *http.Request) {
+			principal := PrincipalFromContext(request.Context())
+			if principal == nil {
+				common.ErrorResponse(writer, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			if !principal.Role.Allows(required) {
+				common.ErrorResponse(writer, "Insufficient permissions, "+string(required)+" role required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(writer, request)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
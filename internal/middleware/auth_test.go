@@ -0,0 +1,157 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/middleware"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/auth_service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newAuthenticatedHandler(t *testing.T) (http.Handler, auth_service.IAuthService) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	authService, err := auth_service.NewAuthService(filepath.Join(t.TempDir(), "users.json"), time.Hour, zap.NewNop(), ctx)
+	require.NoError(t, err)
+	require.NoError(t, authService.EnsureAdmin("admin", "secret-password"))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := middleware.PrincipalFromContext(r.Context())
+		w.Header().Set("X-Auth-Method", string(principal.AuthMethod))
+		w.WriteHeader(http.StatusOK)
+	})
+	return middleware.Authenticator(authService, zap.NewNop())(next), authService
+}
+
+func TestAuthenticator_NilServiceDisablesAuth(t *testing.T) {
+	t.Parallel()
+	var principal *model.Principal
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = middleware.PrincipalFromContext(r.Context())
+	})
+
+	handler := middleware.Authenticator(nil, zap.NewNop())(middleware.RequireRole(model.RoleAdmin)(next))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, principal)
+	assert.Equal(t, model.AuthMethodNone, principal.AuthMethod)
+}
+
+func TestAuthenticator_RejectsAnonymous(t *testing.T) {
+	t.Parallel()
+	handler, _ := newAuthenticatedHandler(t)
+
+	tests := []struct {
+		name    string
+		request *http.Request
+	}{
+		{"no credentials", httptest.NewRequest("GET", "/api/devices/x", nil)},
+		{"invalid bearer", func() *http.Request {
+			req := httptest.NewRequest("GET", "/api/devices/x", nil)
+			req.Header.Set("Authorization", "Bearer amt_invalid")
+			return req
+		}()},
+		{"invalid cookie", func() *http.Request {
+			req := httptest.NewRequest("GET", "/api/devices/x", nil)
+			req.AddCookie(&http.Cookie{Name: middleware.SessionCookieName, Value: "invalid"})
+			return req
+		}()},
+		{"invalid ticket", httptest.NewRequest("GET", "/event/devices?ticket=invalid", nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.request)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Contains(t, w.Body.String(), `"success":false`)
+		})
+	}
+}
+
+func TestAuthenticator_AcceptsCredentials(t *testing.T) {
+	t.Parallel()
+	handler, authService := newAuthenticatedHandler(t)
+
+	sessionToken, _, err := authService.Login("admin", "secret-password")
+	require.NoError(t, err)
+	apiToken, err := authService.CreateApiToken("admin", &model.CreateApiTokenRequest{Name: "test"})
+	require.NoError(t, err)
+	ticket := authService.CreateTicket(&model.Principal{Username: "admin"})
+
+	sessionRequest := httptest.NewRequest("GET", "/api/devices/x", nil)
+	sessionRequest.AddCookie(&http.Cookie{Name: middleware.SessionCookieName, Value: sessionToken})
+
+	bearerRequest := httptest.NewRequest("GET", "/api/devices/x", nil)
+	bearerRequest.Header.Set("Authorization", "Bearer "+apiToken.Token)
+
+	ticketRequest := httptest.NewRequest("GET", "/ws/devices/x/mirroring?ticket="+ticket.Ticket, nil)
+
+	tests := []struct {
+		name     string
+		request  *http.Request
+		expected model.AuthMethod
+	}{
+		{"session cookie", sessionRequest, model.AuthMethodSession},
+		{"bearer token", bearerRequest, model.AuthMethodApiToken},
+		{"query ticket", ticketRequest, model.AuthMethodTicket},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.request)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, string(tt.expected), w.Header().Get("X-Auth-Method"))
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		principal  *model.Principal
+		required   model.Role
+		statusCode int
+	}{
+		{"no principal", nil, model.RoleViewer, http.StatusUnauthorized},
+		{"viewer on viewer route", &model.Principal{Role: model.RoleViewer}, model.RoleViewer, http.StatusOK},
+		{"viewer on operator route", &model.Principal{Role: model.RoleViewer}, model.RoleOperator, http.StatusForbidden},
+		{"operator on operator route", &model.Principal{Role: model.RoleOperator}, model.RoleOperator, http.StatusOK},
+		{"operator on admin route", &model.Principal{Role: model.RoleOperator}, model.RoleAdmin, http.StatusForbidden},
+		{"admin on viewer route", &model.Principal{Role: model.RoleAdmin}, model.RoleViewer, http.StatusOK},
+		{"unknown role", &model.Principal{Role: "guest"}, model.RoleViewer, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := middleware.RequireRole(tt.required)(next)
+
+			req := httptest.NewRequest("POST", "/api/devices/x/power", nil)
+			if tt.principal != nil {
+				req = req.WithContext(middleware.WithPrincipal(req.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package model
+
+import "time"
+
+type AuthMethod string
+
+const (
+	AuthMethodSession  AuthMethod = "session"
+	AuthMethodApiToken AuthMethod = "api_token"
+	AuthMethodTicket   AuthMethod = "ticket"
+	// AuthMethodNone is set on every request when authentication is disabled.
+	AuthMethodNone AuthMethod = "none"
+)
+
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// Allows reports whether r includes the permissions of required.
+// Roles are ordered: viewer < operator < admin.
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required] && r.IsValid()
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Username   string     `json:"username"`
+	Role       Role       `json:"role"`
+	AuthMethod AuthMethod `json:"auth_method"`
+}
+
+type User struct {
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateUserRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=32,alphanum"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Role     Role   `json:"role" validate:"required,oneof=viewer operator admin"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role Role `json:"role" validate:"required,oneof=viewer operator admin"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type Session struct {
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=72"`
+}
+
+type ApiToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Username   string     `json:"username"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type CreateApiTokenRequest struct {
+	Name          string `json:"name" validate:"required,max=64"`
+	ExpiresInDays int    `json:"expires_in_days" validate:"min=0,max=3650"`
+}
+
+type CreateApiTokenResponse struct {
+	ApiToken
+	// Token is only returned once, when the token is created.
+	Token string `json:"token"`
+}
+
+// Ticket is a short lived, single use credential passed as the `ticket` query
+// parameter where headers cannot be set, such as EventSource and WebSocket.
+type Ticket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+)
+
+// Access tells how a feature of a device can be used.
+type Access string
+
+const (
+	AccessNone  Access = "none"
+	AccessShell Access = "shell"
+	AccessRoot  Access = "root"
+)
+
+// DeviceCapabilities are the features probed on a device. They hold as long
+// as the build fingerprint of the device does not change.
+type DeviceCapabilities struct {
+	Serial         string    `json:"serial"`
+	Fingerprint    string    `json:"fingerprint"`
+	AndroidVersion uint8     `json:"android_version"`
+	ProbedAt       time.Time `json:"probed_at"`
+
+	// AirplaneModeCommand is set when `cmd connectivity airplane-mode`
+	// works, otherwise airplane mode is changed through settings and a
+	// broadcast as root.
+	AirplaneModeCommand bool `json:"airplane_mode_command"`
+	// SvcData is set when mobile data can be toggled with `svc data`.
+	SvcData bool   `json:"svc_data"`
+	Apn     Access `json:"apn"`
+	Sms     Access `json:"sms"`
+
+	// Su is the name of the su implementation, empty when the device is
+	// not rooted.
+	Su            string           `json:"su"`
+	SuSyntax      command.SuSyntax `json:"su_syntax,omitempty"`
+	SuShellAccess bool             `json:"su_shell_access"`
+
+	Busybox bool `json:"busybox"`
+	Ping    bool `json:"ping"`
+}
+
+// CanToggleAirplaneMode tells whether airplane mode can be changed, with the
+// command or as root.
+func (c *DeviceCapabilities) CanToggleAirplaneMode() bool {
+	return c.AirplaneModeCommand || c.SuShellAccess
+}
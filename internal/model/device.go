@@ -20,13 +20,24 @@ type Other struct {
 	KernelVersion     string `json:"kernel_version"`
 }
 
+type Cpu struct {
+	// Usage is nil until the CPU time was read twice.
+	Usage       *parser.CpuUsage       `json:"usage"`
+	Frequencies []parser.CoreFrequency `json:"frequencies"`
+	// Temperature of the hottest CPU thermal zone, nil when no zone is known
+	// to measure the CPU.
+	Temperature *float64 `json:"temperature"`
+}
+
 type DeviceInfo struct {
-	parser.DeviceProp `json:"prop"`
-	parser.Root       `json:"root"`
-	parser.Battery    `json:"battery"`
-	Other             `json:"other"`
-	parser.Memory     `json:"memory"`
-	parser.Storage    `json:"storage"`
+	parser.DeviceProp   `json:"prop"`
+	parser.Root         `json:"root"`
+	parser.Battery      `json:"battery"`
+	Other               `json:"other"`
+	parser.Memory       `json:"memory"`
+	parser.Storage      `json:"storage"`
+	Cpu                 `json:"cpu"`
+	parser.ThermalZones `json:"thermal"`
 }
 
 type FeatureAvailability struct {
@@ -46,11 +57,6 @@ type DeviceRootInfo struct {
 	ShellAccess bool
 }
 
-type DeviceSpec struct {
-	DeviceRootInfo
-	AndroidVersion uint8
-}
-
 type DevicePowerAction struct {
 	Action string `json:"action" validate:"required,oneof=power_off reboot reboot_recovery reboot_bootloader"`
 }
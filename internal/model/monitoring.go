@@ -65,4 +65,17 @@ type MonitoringStatus struct {
 	LastPingTime time.Time `json:"last_ping_time"`
 	IsRunning    bool      `json:"is_running"`
 	LastSuccess  bool      `json:"last_success"`
+	// Counters since the task was last started.
+	TotalChecks     uint64 `json:"total_checks"`
+	TotalFailures   uint64 `json:"total_failures"`
+	RecoveryActions uint64 `json:"recovery_actions"`
+	LastLatencyMs   int64  `json:"last_latency_ms"`
+}
+
+// MonitoringReloadResult lists the serials affected by re-reading the task file.
+type MonitoringReloadResult struct {
+	Added     []string `json:"added"`
+	Updated   []string `json:"updated"`
+	Removed   []string `json:"removed"`
+	Restarted []string `json:"restarted"`
 }
@@ -1,6 +1,10 @@
 package model
 
-import "github.com/basiooo/andromodem/pkg/adb_processor/parser"
+import (
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+)
 
 type Network struct {
 	AirplaneMode bool               `json:"airplane_mode"`
@@ -8,3 +12,43 @@ type Network struct {
 	APN          parser.Apn         `json:"apn"`
 	Sims         []parser.Sim       `json:"sims"`
 }
+
+// InterfaceTraffic holds the counters of an interface and the throughput
+// since the previous sample, in bytes per second.
+type InterfaceTraffic struct {
+	Name    string  `json:"name"`
+	RxBytes uint64  `json:"rx_bytes"`
+	TxBytes uint64  `json:"tx_bytes"`
+	RxRate  float64 `json:"rx_rate"`
+	TxRate  float64 `json:"tx_rate"`
+}
+
+// NetworkTraffic is the traffic of the cellular and tethering interfaces.
+type NetworkTraffic struct {
+	SampledAt time.Time `json:"sampled_at"`
+	// Interval is the time in seconds the throughput was measured over.
+	Interval   float64            `json:"interval"`
+	RxRate     float64            `json:"rx_rate"`
+	TxRate     float64            `json:"tx_rate"`
+	Interfaces []InterfaceTraffic `json:"interfaces"`
+}
+
+// AppUsage is the mobile data used by the apps running as a uid.
+type AppUsage struct {
+	Uid int `json:"uid"`
+	// Name is the package of the app, or what the uid stands for when no
+	// single package runs as it.
+	Name       string   `json:"name"`
+	Packages   []string `json:"packages"`
+	RxBytes    uint64   `json:"rx_bytes"`
+	TxBytes    uint64   `json:"tx_bytes"`
+	TotalBytes uint64   `json:"total_bytes"`
+}
+
+// AppsUsage lists the apps that used mobile data between From and To, the
+// heaviest first.
+type AppsUsage struct {
+	From time.Time  `json:"from"`
+	To   time.Time  `json:"to"`
+	Apps []AppUsage `json:"apps"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "github.com/basiooo/andromodem/pkg/adb_processor/parser"
+
+// ProcessSort is the order of a process list, the busiest process first.
+// ProcessSortCpu orders by the CPU usage over the last seconds.
+type ProcessSort string
+
+const (
+	ProcessSortCpu    ProcessSort = "cpu"
+	ProcessSortMemory ProcessSort = "memory"
+)
+
+type ProcessQuery struct {
+	Sort ProcessSort
+	// Limit is the number of processes returned, 0 returns all.
+	Limit int
+}
+
+type Processes struct {
+	// Total is the number of processes running, Processes may be limited.
+	Total     int              `json:"total"`
+	Processes []parser.Process `json:"processes"`
+}
@@ -1,8 +1,30 @@
 package model
 
 type BaseResponse struct {
-	Success bool              `json:"success"`
-	Message string            `json:"message"`
-	Data    any               `json:"data,omitempty"`
-	Errors  map[string]string `json:"errors,omitempty"`
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	// Code tells clients what went wrong without parsing Message, it is
+	// only set on errors.
+	Code   ErrorCode         `json:"code,omitempty"`
+	Data   any               `json:"data,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
 }
+
+// ErrorCode is the machine-readable class of a failed request.
+type ErrorCode string
+
+const (
+	ErrorCodeInternal                  ErrorCode = "internal_error"
+	ErrorCodeDeviceNotFound            ErrorCode = "device_not_found"
+	ErrorCodeDeviceOffline             ErrorCode = "device_offline"
+	ErrorCodeDeviceUnauthorized        ErrorCode = "device_unauthorized"
+	ErrorCodeNeedRoot                  ErrorCode = "need_root"
+	ErrorCodeNeedSuperUserPermission   ErrorCode = "need_superuser_permission"
+	ErrorCodeAndroidVersionUnsupported ErrorCode = "android_version_unsupported"
+	ErrorCodeCommandNotFound           ErrorCode = "command_not_found"
+	ErrorCodeCommandTimeout            ErrorCode = "command_timeout"
+	ErrorCodeTransportReset            ErrorCode = "transport_reset"
+	ErrorCodeAdbServerUnavailable      ErrorCode = "adb_server_unavailable"
+	ErrorCodeAirplaneModeActive        ErrorCode = "airplane_mode_active"
+	ErrorCodeProcessNotFound           ErrorCode = "process_not_found"
+)
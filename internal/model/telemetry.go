@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/timeseries"
+)
+
+type TelemetryHistoryQuery struct {
+	Metric string
+	From   time.Time
+	To     time.Time
+	// Step downsamples the points into buckets of this size, 0 returns every sample.
+	Step        time.Duration
+	Aggregation timeseries.Aggregation
+}
+
+type TelemetryHistory struct {
+	Serial      string                 `json:"serial"`
+	Metric      string                 `json:"metric"`
+	From        time.Time              `json:"from"`
+	To          time.Time              `json:"to"`
+	Step        string                 `json:"step,omitempty"`
+	Aggregation timeseries.Aggregation `json:"aggregation,omitempty"`
+	Points      []timeseries.Point     `json:"points"`
+	// Metrics lists every metric recorded for the device.
+	Metrics []string `json:"metrics"`
+}
+
+// DeviceTelemetry is the device state pushed by the live telemetry stream.
+type DeviceTelemetry struct {
+	DeviceInfo *DeviceInfo `json:"device_info"`
+	Network    *Network    `json:"network"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+)
+
+// UsageQuota is the data plan of the SIM in a slot.
+type UsageQuota struct {
+	// CycleStartDay is the day of the month the billing cycle starts on.
+	CycleStartDay int `json:"cycle_start_day" validate:"required,min=1,max=28"`
+	// Limit is the data included per cycle in bytes, 0 for no quota.
+	Limit uint64 `json:"limit"`
+	// Warnings are the percentages of Limit a warning is raised at.
+	Warnings []int `json:"warnings" validate:"dive,min=1,max=100"`
+	// Cutoff disables mobile data once Limit is reached.
+	Cutoff bool `json:"cutoff"`
+}
+
+type CycleUsage struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	RxBytes uint64    `json:"rx_bytes"`
+	TxBytes uint64    `json:"tx_bytes"`
+}
+
+func (c *CycleUsage) Total() uint64 {
+	return c.RxBytes + c.TxBytes
+}
+
+type SimUsage struct {
+	SimSlot int        `json:"sim_slot"`
+	Quota   UsageQuota `json:"quota"`
+	Cycle   CycleUsage `json:"cycle"`
+	// UsedPercent is the part of the quota used in the current cycle.
+	UsedPercent float64 `json:"used_percent"`
+	// WarningsRaised are the thresholds of the quota crossed in the current cycle.
+	WarningsRaised []int `json:"warnings_raised"`
+	// CutoffAt is when mobile data was last cut off in the current cycle. It
+	// is cut off again when turned back on over the limit.
+	CutoffAt *time.Time `json:"cutoff_at,omitempty"`
+	// History holds the previous cycles, the latest last.
+	History []CycleUsage `json:"history"`
+}
+
+// InterfaceCounters are the byte counters of an interface at the last sample.
+type InterfaceCounters struct {
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+}
+
+// DeviceUsage is the mobile data used through every SIM slot of a device.
+type DeviceUsage struct {
+	Serial    string                       `json:"serial"`
+	SampledAt time.Time                    `json:"sampled_at"`
+	Counters  map[string]InterfaceCounters `json:"counters"`
+	Sims      []*SimUsage                  `json:"sims"`
+}
@@ -3,15 +3,24 @@ package router
 import (
 	"context"
 
+	"github.com/basiooo/andromodem/internal/config"
 	"github.com/basiooo/andromodem/internal/handler/rest"
 	"github.com/basiooo/andromodem/internal/handler/ws"
 
 	"github.com/basiooo/andromodem/internal/handler/web"
+	"github.com/basiooo/andromodem/internal/lifecycle"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/auth_service"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
 	"github.com/basiooo/andromodem/internal/service/devices_service"
 	"github.com/basiooo/andromodem/internal/service/messages_service"
+	"github.com/basiooo/andromodem/internal/service/metrics_service"
 	"github.com/basiooo/andromodem/internal/service/mirroring_service"
 	"github.com/basiooo/andromodem/internal/service/monitoring_service"
 	network_service "github.com/basiooo/andromodem/internal/service/network"
+	"github.com/basiooo/andromodem/internal/service/process_service"
+	"github.com/basiooo/andromodem/internal/service/telemetry_service"
+	"github.com/basiooo/andromodem/internal/service/usage_service"
 	"github.com/basiooo/andromodem/templates"
 	"github.com/go-playground/validator/v10"
 
@@ -20,6 +29,8 @@ import (
 	SSEHandler "github.com/basiooo/andromodem/internal/handler/sse"
 	appMiddleware "github.com/basiooo/andromodem/internal/middleware"
 	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	"github.com/basiooo/andromodem/pkg/cache"
+	"github.com/basiooo/andromodem/pkg/timeseries"
 	adb "github.com/basiooo/goadb"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -33,10 +44,14 @@ type Router struct {
 	Ctx       context.Context
 	ChiRouter chi.Router
 	Validator *validator.Validate
+	Config    *config.Config
+	Lifecycle lifecycle.IManager
 }
 
-func NewRouter(adb *adb.Adb, logger *zap.Logger, ctx context.Context, validator *validator.Validate) IRouter {
+func NewRouter(adb *adb.Adb, logger *zap.Logger, ctx context.Context, validator *validator.Validate, cfg *config.Config, lifecycleManager lifecycle.IManager) IRouter {
 	return &Router{
+		Config:    cfg,
+		Lifecycle: lifecycleManager,
 		Adb:       adb,
 		Logger:    logger,
 		Ctx:       ctx,
@@ -53,71 +68,246 @@ func (r *Router) GetRouters() chi.Router {
 		cors.Handler(cors.Options{
 			AllowedOrigins: []string{"https://*", "http://*"},
 			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-Requested-With"},
 		}),
 	)
-	r.ChiRouter.Mount("/debug", middleware.Profiler())
 
-	adbProcessor := processor.NewProcessor(r.Logger)
+	var authService auth_service.IAuthService
+	if r.Config.Auth.Enabled {
+		var err error
+		authService, err = auth_service.NewAuthService(
+			r.Config.Path(r.Config.Auth.UsersFile),
+			r.Config.Auth.SessionTTL.Duration(),
+			r.Logger,
+			r.Ctx,
+		)
+		if err != nil {
+			r.Logger.Fatal("failed to initialize auth service", zap.Error(err))
+		}
+		if err := authService.EnsureAdmin(r.Config.Auth.AdminUsername, r.Config.Auth.AdminPassword); err != nil {
+			r.Logger.Fatal("failed to create initial admin account", zap.Error(err))
+		}
+	} else {
+		r.Logger.Warn("[Auth] AUTHENTICATION IS DISABLED: anyone who can reach this server can read SMS, toggle the network, power off or mirror the devices and use /debug. Set auth.enabled to true unless the server is only reachable by trusted users",
+			zap.String("address", r.Config.Address()))
+	}
+	authenticator := appMiddleware.Authenticator(authService, r.Logger)
+
+	r.ChiRouter.With(authenticator, appMiddleware.RequireRole(model.RoleAdmin)).Mount("/debug", middleware.Profiler())
+
+	if err := processor.ValidateRegistry(); err != nil {
+		r.Logger.Fatal("invalid adb command parser registry", zap.Error(err))
+	}
+	adbCache := cache.NewCache(r.Config.Cache.DefaultExpiration.Duration(), r.Config.Cache.CleanupInterval.Duration())
+	adbProcessor := processor.NewProcessorWithCache(r.Logger, adbCache)
+	deviceWatcher := processor.WatchDevices(r.Ctx, r.Adb, adbProcessor, r.Logger)
+	r.Lifecycle.OnShutdown("device watcher", deviceWatcher.Shutdown)
 
 	// Services
-	devicesService := devices_service.NewDevicesService(r.Adb, adbProcessor, r.Logger, r.Ctx)
-	messagesService := messages_service.NewMessagesService(r.Adb, adbProcessor, r.Logger, r.Ctx)
-	networkService := network_service.NewNetworkService(r.Adb, adbProcessor, r.Logger, r.Ctx)
-	monitoringService := monitoring_service.NewMonitoringService(r.Adb, adbProcessor, networkService, r.Logger, r.Ctx)
+	capabilityService, err := capability_service.NewCapabilityService(r.Adb, adbProcessor, r.Logger, r.Config.Path(r.Config.Devices.CapabilitiesFile))
+	if err != nil {
+		r.Logger.Fatal("failed to load device capabilities", zap.Error(err))
+	}
+	devicesService := devices_service.NewDevicesService(r.Adb, adbProcessor, capabilityService, r.Logger, r.Ctx)
+	messagesService := messages_service.NewMessagesService(r.Adb, adbProcessor, capabilityService, r.Logger, r.Ctx)
+	networkService := network_service.NewNetworkService(r.Adb, adbProcessor, capabilityService, r.Logger, r.Ctx)
+	processService := process_service.NewProcessService(r.Adb, adbProcessor, capabilityService, r.Logger, r.Ctx)
+	monitoringService := monitoring_service.NewMonitoringService(
+		r.Adb,
+		adbProcessor,
+		networkService,
+		r.Logger,
+		r.Ctx,
+		r.Config.Path(r.Config.Monitoring.ConfigFile),
+		r.Config.Path(r.Config.Monitoring.LogDir),
+	)
 	mirroringService := mirroring_service.NewMirroringService(r.Adb, r.Logger, r.Ctx)
+	var telemetryService telemetry_service.ITelemetryService
+	if r.Config.Telemetry.Enabled {
+		store, err := timeseries.NewStore(
+			r.Config.Path(r.Config.Telemetry.Dir),
+			r.Config.Telemetry.Retention.Duration(),
+			r.Config.Telemetry.MaxPoints,
+		)
+		if err != nil {
+			r.Logger.Fatal("failed to initialize telemetry store", zap.Error(err))
+		}
+		telemetryService = telemetry_service.NewTelemetryService(r.Adb, adbProcessor, r.Logger, r.Ctx, store, r.Config.Telemetry.Interval.Duration())
+		r.Lifecycle.OnShutdown("telemetry", telemetryService.Shutdown)
+	}
+	var usageService usage_service.IUsageService
+	if r.Config.Usage.Enabled {
+		usageService, err = usage_service.NewUsageService(
+			r.Adb,
+			adbProcessor,
+			networkService,
+			r.Logger,
+			r.Ctx,
+			r.Config.Path(r.Config.Usage.File),
+			r.Config.Usage.Interval.Duration(),
+			r.Config.Usage.CycleStartDay,
+		)
+		if err != nil {
+			r.Logger.Fatal("failed to load data usage ledger", zap.Error(err))
+		}
+		r.Lifecycle.OnShutdown("usage", usageService.Shutdown)
+	}
+
+	// Hooks run in reverse order: forwards are removed before the monitoring
+	// tasks are saved and the log listeners stopped.
+	r.Lifecycle.OnShutdown("monitoring", monitoringService.Shutdown)
+	r.Lifecycle.OnShutdown("mirroring", mirroringService.Shutdown)
+	r.Lifecycle.OnReload("monitoring", func(*config.Config) error {
+		result, err := monitoringService.ReloadTasks()
+		if result != nil {
+			r.Logger.Info("[Monitoring] Reloaded monitoring tasks",
+				zap.Strings("added", result.Added),
+				zap.Strings("updated", result.Updated),
+				zap.Strings("removed", result.Removed),
+				zap.Strings("restarted", result.Restarted))
+		}
+		return err
+	})
 
 	// Handlers
-	devicesEventHandler := SSEHandler.NewDevicesEventHandler(devicesService, r.Logger)
-	monitoringLogEventHandler := SSEHandler.NewMonitoringLogEventHandler(monitoringService, r.Logger)
+	devicesEventHandler := SSEHandler.NewDevicesEventHandler(devicesService, r.Logger, r.Ctx)
+	monitoringLogEventHandler := SSEHandler.NewMonitoringLogEventHandler(monitoringService, r.Logger, r.Ctx)
+	liveTelemetryService := telemetry_service.NewLiveTelemetryService(devicesService, networkService, r.Logger, r.Ctx, r.Config.Telemetry.LiveInterval.Duration())
+	telemetryEventHandler := SSEHandler.NewTelemetryEventHandler(liveTelemetryService, r.Logger, r.Ctx)
+	liveTrafficService := telemetry_service.NewLiveTrafficService(networkService, r.Logger, r.Ctx, r.Config.Telemetry.TrafficInterval.Duration())
+	trafficEventHandler := SSEHandler.NewTrafficEventHandler(liveTrafficService, r.Logger, r.Ctx)
 	devicesHandler := rest.NewDevicesHandler(devicesService, r.Logger, r.Validator)
+	capabilitiesHandler := rest.NewCapabilitiesHandler(capabilityService, r.Logger)
+	processesHandler := rest.NewProcessesHandler(processService, r.Logger)
 	messagesHandler := rest.NewMessagesHandler(messagesService, r.Logger, r.Validator)
 	networkHandler := rest.NewNetworkHandler(networkService, r.Logger, r.Validator)
 	monitoringHandler := rest.NewMonitoringHandler(monitoringService, r.Logger, r.Validator)
-	mirroringHandler := ws.NewMirroringHandler(mirroringService, r.Logger, r.Validator)
+	mirroringHandler := ws.NewMirroringHandler(mirroringService, r.Logger, r.Validator, r.Ctx)
+	var usageHandler rest.IUsageHandler
+	if usageService != nil {
+		usageHandler = rest.NewUsageHandler(usageService, r.Logger, r.Validator)
+	}
 
 	healthHandler := rest.NewHealthHandler()
 
 	// Frontend Handler
 	frontendHandler := web.NewFrontendHandler(r.Logger, templates.MainPage)
 
+	viewer := appMiddleware.RequireRole(model.RoleViewer)
+	operator := appMiddleware.RequireRole(model.RoleOperator)
+	admin := appMiddleware.RequireRole(model.RoleAdmin)
+
+	if r.Config.Metrics.Enabled {
+		metricsService := metrics_service.NewMetricsService(r.Adb, adbProcessor, capabilityService, monitoringService, r.Logger, r.Ctx, r.Config.Metrics.MinInterval.Duration())
+		metricsHandler := rest.NewMetricsHandler(metricsService, r.Logger)
+		r.ChiRouter.With(authenticator, viewer).Get("/metrics", metricsHandler.GetMetrics)
+	}
+
 	r.ChiRouter.Route("/api", func(chiRouter chi.Router) {
-		chiRouter.Use(appMiddleware.AdbChecker(r.Adb, r.Logger))
-		chiRouter.Route("/devices/{serial}", func(chiRouter chi.Router) {
-			chiRouter.Get("/", devicesHandler.GetDeviceInfo)
-			chiRouter.Post("/power", devicesHandler.PowerAction)
-			chiRouter.Get("/feature-availabilities", devicesHandler.GetDeviceFeatureAvailabilities)
-			chiRouter.Get("/messages", messagesHandler.GetMessages)
-			chiRouter.Get("/network", networkHandler.GetNetworkInfo)
-			chiRouter.Post("/network/mobile-data", networkHandler.ToggleMobileData)
-			chiRouter.Post("/network/airplane-mode", networkHandler.ToggleAirplaneMode)
-
-			chiRouter.Route("/monitoring", func(chiRouter chi.Router) {
-				chiRouter.Post("/", monitoringHandler.CreateMonitoring)
-				chiRouter.Get("/", monitoringHandler.GetMonitoringConfig)
-				chiRouter.Put("/", monitoringHandler.UpdateMonitoringConfig)
-				chiRouter.Post("/start", monitoringHandler.StartMonitoring)
-				chiRouter.Post("/stop", monitoringHandler.StopMonitoring)
-				chiRouter.Get("/status", monitoringHandler.GetMonitoringStatus)
-				chiRouter.Get("/logs", monitoringHandler.GetMonitoringLogs)
-				chiRouter.Delete("/logs", monitoringHandler.ClearMonitoringLogs)
+		if authService != nil {
+			authHandler := rest.NewAuthHandler(authService, r.Logger, r.Validator)
+			usersHandler := rest.NewUsersHandler(authService, r.Logger, r.Validator)
+			chiRouter.Route("/auth", func(chiRouter chi.Router) {
+				chiRouter.Post("/login", authHandler.Login)
+				chiRouter.Post("/logout", authHandler.Logout)
+				chiRouter.Group(func(chiRouter chi.Router) {
+					chiRouter.Use(authenticator, viewer)
+					chiRouter.Get("/me", authHandler.Me)
+					chiRouter.Put("/password", authHandler.ChangePassword)
+					chiRouter.Post("/ticket", authHandler.CreateTicket)
+					chiRouter.Get("/tokens", authHandler.ListApiTokens)
+					chiRouter.Post("/tokens", authHandler.CreateApiToken)
+					chiRouter.Delete("/tokens/{id}", authHandler.RevokeApiToken)
+				})
+			})
+			chiRouter.Route("/users", func(chiRouter chi.Router) {
+				chiRouter.Use(authenticator, admin)
+				chiRouter.Get("/", usersHandler.ListUsers)
+				chiRouter.Post("/", usersHandler.CreateUser)
+				chiRouter.Put("/{username}/role", usersHandler.UpdateUserRole)
+				chiRouter.Delete("/{username}", usersHandler.DeleteUser)
+			})
+		}
+
+		chiRouter.Group(func(chiRouter chi.Router) {
+			chiRouter.Use(appMiddleware.AdbChecker(r.Adb, r.Logger))
+			chiRouter.Get("/health/ping", healthHandler.Ping)
+			chiRouter.Group(func(chiRouter chi.Router) {
+				chiRouter.Use(authenticator)
+				chiRouter.With(viewer).Get("/devices", devicesHandler.GetDevices)
+				chiRouter.Route("/devices/{serial}", func(chiRouter chi.Router) {
+					// Viewers: read only device and network information, nothing
+					// about the apps on the device
+					chiRouter.Group(func(chiRouter chi.Router) {
+						chiRouter.Use(viewer)
+						chiRouter.Get("/", devicesHandler.GetDeviceInfo)
+						chiRouter.Get("/feature-availabilities", devicesHandler.GetDeviceFeatureAvailabilities)
+						chiRouter.Get("/capabilities", capabilitiesHandler.GetCapabilities)
+						chiRouter.Get("/network", networkHandler.GetNetworkInfo)
+						chiRouter.Get("/network/traffic", networkHandler.GetTraffic)
+						if telemetryService != nil {
+							telemetryHandler := rest.NewTelemetryHandler(telemetryService, r.Logger)
+							chiRouter.Get("/history", telemetryHandler.GetHistory)
+						}
+						if usageHandler != nil {
+							chiRouter.Get("/network/usage", usageHandler.GetUsage)
+						}
+					})
+
+					// Operators: running processes and data usage of apps, network
+					// toggles, probing and running the monitoring
+					chiRouter.Group(func(chiRouter chi.Router) {
+						chiRouter.Use(operator)
+						chiRouter.Get("/processes", processesHandler.GetProcesses)
+						chiRouter.Get("/network/usage/apps", networkHandler.GetAppsUsage)
+						chiRouter.Post("/capabilities/probe", capabilitiesHandler.ProbeCapabilities)
+						chiRouter.Post("/network/mobile-data", networkHandler.ToggleMobileData)
+						chiRouter.Post("/network/airplane-mode", networkHandler.ToggleAirplaneMode)
+						if usageHandler != nil {
+							chiRouter.Put("/network/usage/sims/{slot}/quota", usageHandler.SetQuota)
+						}
+						chiRouter.Get("/monitoring", monitoringHandler.GetMonitoringConfig)
+						chiRouter.Post("/monitoring/start", monitoringHandler.StartMonitoring)
+						chiRouter.Post("/monitoring/stop", monitoringHandler.StopMonitoring)
+						chiRouter.Get("/monitoring/status", monitoringHandler.GetMonitoringStatus)
+						chiRouter.Get("/monitoring/logs", monitoringHandler.GetMonitoringLogs)
+					})
+
+					// Admins: power, processes, messages and monitoring configuration
+					chiRouter.Group(func(chiRouter chi.Router) {
+						chiRouter.Use(admin)
+						chiRouter.Post("/power", devicesHandler.PowerAction)
+						chiRouter.Post("/processes/{pid}/kill", processesHandler.KillProcess)
+						chiRouter.Get("/messages", messagesHandler.GetMessages)
+						chiRouter.Post("/monitoring", monitoringHandler.CreateMonitoring)
+						chiRouter.Put("/monitoring", monitoringHandler.UpdateMonitoringConfig)
+						chiRouter.Delete("/monitoring/logs", monitoringHandler.ClearMonitoringLogs)
+					})
+				})
+				chiRouter.With(operator).Get("/monitoring", monitoringHandler.GetAllMonitoringTasks)
 			})
 		})
-		chiRouter.Get("/health/ping", healthHandler.Ping)
-		chiRouter.Get("/monitoring", monitoringHandler.GetAllMonitoringTasks)
 	})
 
 	r.ChiRouter.Route("/event", func(chiRouter chi.Router) {
-		chiRouter.Get("/devices", devicesEventHandler.ListenDevicesEvent)
+		chiRouter.Use(authenticator)
+		chiRouter.With(viewer).Get("/devices", devicesEventHandler.ListenDevicesEvent)
+		chiRouter.With(viewer, appMiddleware.AdbChecker(r.Adb, r.Logger)).Get("/devices/{serial}/telemetry", telemetryEventHandler.ListenTelemetryEvent)
+		chiRouter.With(viewer, appMiddleware.AdbChecker(r.Adb, r.Logger)).Get("/devices/{serial}/traffic", trafficEventHandler.ListenTrafficEvent)
 		chiRouter.Route("/devices/{serial}/monitoring", func(chiRouter chi.Router) {
-			chiRouter.Get("/logs", monitoringLogEventHandler.ListenMonitoringLogEvent)
+			chiRouter.With(operator).Get("/logs", monitoringLogEventHandler.ListenMonitoringLogEvent)
 		})
 	})
 
 	r.ChiRouter.Route("/ws", func(chiRouter chi.Router) {
+		chiRouter.Use(authenticator, admin)
 		chiRouter.Route("/devices/{serial}", func(chiRouter chi.Router) {
 			chiRouter.Get("/mirroring", mirroringHandler.StartMirroringStream)
 		})
 	})
+	if authService != nil {
+		r.ChiRouter.Get("/login", frontendHandler.ServeLogin)
+	}
 	r.ChiRouter.Get("/assets/*", frontendHandler.ServeAssets().ServeHTTP)
 	r.ChiRouter.Get("/", frontendHandler.ServeIndex)
 	return r.ChiRouter
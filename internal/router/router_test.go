@@ -0,0 +1,35 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/config"
+	"github.com/basiooo/andromodem/internal/lifecycle"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestGetRoutersWithoutAdb(t *testing.T) {
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	logger := zaptest.NewLogger(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	manager := lifecycle.NewManager(logger, cancel, 5*time.Second, nil)
+
+	// adb.New fails when adb is not installed, the application starts without
+	// a client and only answers that adb is missing.
+	router := NewRouter(nil, logger, ctx, validator.New(), cfg, manager).GetRouters()
+	t.Cleanup(func() {
+		assert.NoError(t, manager.Shutdown())
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/health/ping", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
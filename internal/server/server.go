@@ -2,76 +2,129 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 type Server interface {
 	Start() error
+	Shutdown(context.Context) error
+}
+
+type Options struct {
+	Addr string
+	// TLSConfig enables HTTPS when set.
+	TLSConfig *tls.Config
+	// RedirectAddr starts a plain HTTP listener redirecting to HTTPS. It is
+	// ignored when TLSConfig is nil.
+	RedirectAddr string
 }
 
 type httpServer struct {
-	server    *http.Server
-	logger    *zap.Logger
-	ctx       context.Context
-	ctxCancel context.CancelFunc
+	server         *http.Server
+	redirectServer *http.Server
+	logger         *zap.Logger
 }
 
-func NewServer(router http.Handler, log *zap.Logger, ctx context.Context, ctxCancel context.CancelFunc) Server {
-	return &httpServer{
+func NewServer(options Options, router http.Handler, log *zap.Logger) Server {
+	s := &httpServer{
 		server: &http.Server{
-			Addr:    "0.0.0.0:49153",
-			Handler: router,
+			Addr:      options.Addr,
+			Handler:   router,
+			TLSConfig: options.TLSConfig,
 		},
-		ctx:       ctx,
-		ctxCancel: ctxCancel,
-		logger:    log,
+		logger: log,
 	}
+
+	if options.TLSConfig != nil {
+		// The mirroring websocket hijacks the connection, which is only
+		// possible over HTTP/1.1.
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		s.server.Protocols = protocols
+
+		if options.RedirectAddr != "" {
+			_, port, _ := net.SplitHostPort(options.Addr)
+			s.redirectServer = &http.Server{
+				Addr:              options.RedirectAddr,
+				Handler:           redirectHandler(port),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+		}
+	}
+	return s
 }
 
+// Start serves until Shutdown is called. It returns nil after a shutdown.
 func (s *httpServer) Start() error {
 	s.logger.Info("Starting server")
-	s.logger.Info(fmt.Sprintf("Server running on %s", s.server.Addr))
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-	defer signal.Stop(sig)
-	defer close(sig)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-
-		select {
-		case <-sig:
-			s.ctxCancel()
-			s.logger.Info("Shutdown signal received")
-		case <-s.ctx.Done():
-			s.logger.Error("Context cancelled before server started")
-			return
-		}
+	scheme := "http"
+	if s.server.TLSConfig != nil {
+		scheme = "https"
+	}
+	s.logger.Info(fmt.Sprintf("Server running on %s://%s", scheme, s.server.Addr))
 
-		if err := s.server.Close(); err != nil {
-			s.logger.Error("Server forced to shutdown", zap.String("error", err.Error()))
-		}
-	}()
+	if s.redirectServer != nil {
+		go func() {
+			s.logger.Info(fmt.Sprintf("Redirecting http://%s to HTTPS", s.redirectServer.Addr))
+			if err := s.redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.logger.Error("Redirect server error", zap.String("error", err.Error()))
+			}
+		}()
+	}
 
-	err := s.server.ListenAndServe()
+	var err error
+	if s.server.TLSConfig != nil {
+		// Certificates are already part of TLSConfig.
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		s.logger.Error("Server error", zap.String("error", err.Error()))
 		return err
 	}
+	return nil
+}
 
-	wg.Wait()
+// Shutdown stops accepting connections and waits for in-flight requests until
+// ctx is done, after which the remaining connections are closed.
+func (s *httpServer) Shutdown(ctx context.Context) error {
+	var errs []error
+	if s.redirectServer != nil {
+		if err := s.redirectServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("redirect server: %w", err))
+			_ = s.redirectServer.Close()
+		}
+	}
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Warn("Server forced to shutdown", zap.String("error", err.Error()))
+		errs = append(errs, err)
+		_ = s.server.Close()
+	}
 	s.logger.Info("Server stopped")
-	return nil
+	return errors.Join(errs...)
+}
+
+// redirectHandler sends every request to the same host and path on httpsPort.
+func redirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		host := request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if net.ParseIP(host) != nil && net.ParseIP(host).To4() == nil {
+			host = "[" + host + "]"
+		}
+		target := "https://" + host + request.URL.RequestURI()
+		http.Redirect(writer, request, target, http.StatusPermanentRedirect)
+	})
 }
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		port     string
+		host     string
+		target   string
+		expected string
+	}{
+		{"custom port", "49153", "192.168.1.1:49152", "/api/devices?x=1", "https://192.168.1.1:49153/api/devices?x=1"},
+		{"host without port", "49153", "router.lan", "/", "https://router.lan:49153/"},
+		{"default https port", "443", "router.lan:80", "/login", "https://router.lan/login"},
+		{"ipv6 host", "49153", "[fe80::1]:49152", "/", "https://[fe80::1]:49153/"},
+		{"ipv6 default https port", "443", "[fe80::1]:80", "/", "https://[fe80::1]/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+
+			redirectHandler(tt.port).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+			assert.Equal(t, tt.expected, w.Header().Get("Location"))
+		})
+	}
+}
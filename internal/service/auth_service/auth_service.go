@@ -0,0 +1,566 @@
+package auth_service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	apiTokenPrefix = "amt_"
+	ticketTTL      = 30 * time.Second
+	cleanupPeriod  = time.Minute
+)
+
+type storedUser struct {
+	Username     string     `json:"username"`
+	PasswordHash string     `json:"password_hash"`
+	Role         model.Role `json:"role"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type storedApiToken struct {
+	model.ApiToken
+	TokenHash string `json:"token_hash"`
+}
+
+type authStore struct {
+	Users     []*storedUser     `json:"users"`
+	ApiTokens []*storedApiToken `json:"api_tokens"`
+}
+
+type ticketEntry struct {
+	principal model.Principal
+	expiresAt time.Time
+}
+
+type AuthService struct {
+	usersFile  string
+	sessionTTL time.Duration
+	logger     *zap.Logger
+	ctx        context.Context
+
+	mu       sync.RWMutex
+	store    authStore
+	sessions map[string]*model.Session
+	tickets  map[string]*ticketEntry
+
+	// dummyHash is compared against when the username is unknown so that
+	// login timing does not reveal which accounts exist.
+	dummyHash []byte
+}
+
+func NewAuthService(usersFile string, sessionTTL time.Duration, logger *zap.Logger, ctx context.Context) (IAuthService, error) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("andromodem"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	service := &AuthService{
+		usersFile:  usersFile,
+		sessionTTL: sessionTTL,
+		logger:     logger,
+		ctx:        ctx,
+		sessions:   make(map[string]*model.Session),
+		tickets:    make(map[string]*ticketEntry),
+		dummyHash:  dummyHash,
+	}
+	if err := service.load(); err != nil {
+		return nil, err
+	}
+	go service.cleanupLoop()
+	return service, nil
+}
+
+func (a *AuthService) EnsureAdmin(username string, password string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.store.Users) > 0 {
+		return nil
+	}
+
+	generated := password == ""
+	if generated {
+		password = randomToken(12)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	// The generated password is kept out of the log, which is persisted and
+	// may be shipped elsewhere, and written to a file only the owner can read.
+	passwordFile := a.usersFile + ".admin_password"
+	if generated {
+		if err := writeFile(passwordFile, []byte(password+"\n")); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	a.store.Users = append(a.store.Users, &storedUser{
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         model.RoleAdmin,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+	if err := a.save(); err != nil {
+		a.store.Users = nil
+		if generated {
+			_ = os.Remove(passwordFile)
+		}
+		return err
+	}
+
+	if generated {
+		a.logger.Warn("[Auth] Created initial admin account with a generated password, read it from the password file and change it after the first login",
+			zap.String("username", username),
+			zap.String("password_file", passwordFile))
+	} else {
+		a.logger.Info("[Auth] Created initial admin account", zap.String("username", username))
+	}
+	return nil
+}
+
+func (a *AuthService) Login(username string, password string) (string, *model.Session, error) {
+	// The hash is copied under the lock, ChangePassword replaces it in place.
+	a.mu.RLock()
+	var passwordHash []byte
+	user := a.findUser(username)
+	if user != nil {
+		passwordHash = []byte(user.PasswordHash)
+	}
+	a.mu.RUnlock()
+
+	if user == nil {
+		_ = bcrypt.CompareHashAndPassword(a.dummyHash, []byte(password))
+		return "", nil, andromodemError.ErrorInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(password)); err != nil {
+		return "", nil, andromodemError.ErrorInvalidCredentials
+	}
+
+	token := randomToken(32)
+	session := &model.Session{
+		Username:  username,
+		ExpiresAt: time.Now().Add(a.sessionTTL),
+	}
+	a.mu.Lock()
+	a.sessions[hashToken(token)] = session
+	a.mu.Unlock()
+
+	a.logger.Info("[Auth] User logged in", zap.String("username", username))
+	return token, session, nil
+}
+
+func (a *AuthService) Logout(sessionToken string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, hashToken(sessionToken))
+}
+
+func (a *AuthService) ChangePassword(username string, currentPassword string, newPassword string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	user := a.findUser(username)
+	if user == nil {
+		return andromodemError.ErrorUserNotFound
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return andromodemError.ErrorInvalidCredentials
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	oldHash := user.PasswordHash
+	user.PasswordHash = string(hash)
+	user.UpdatedAt = time.Now()
+	if err := a.save(); err != nil {
+		user.PasswordHash = oldHash
+		return err
+	}
+
+	// Force every other session of this user to log in again.
+	for key, session := range a.sessions {
+		if session.Username == username {
+			delete(a.sessions, key)
+		}
+	}
+	return nil
+}
+
+func (a *AuthService) AuthenticateSession(sessionToken string) (*model.Principal, error) {
+	a.mu.RLock()
+	session, ok := a.sessions[hashToken(sessionToken)]
+	a.mu.RUnlock()
+	if !ok || time.Now().After(session.ExpiresAt) {
+		return nil, andromodemError.ErrorUnauthenticated
+	}
+	return a.principalFor(session.Username, model.AuthMethodSession)
+}
+
+func (a *AuthService) AuthenticateApiToken(apiToken string) (*model.Principal, error) {
+	if !strings.HasPrefix(apiToken, apiTokenPrefix) {
+		return nil, andromodemError.ErrorUnauthenticated
+	}
+	hash := hashToken(apiToken)
+	now := time.Now()
+
+	a.mu.Lock()
+	var found *storedApiToken
+	for _, token := range a.store.ApiTokens {
+		if subtle.ConstantTimeCompare([]byte(token.TokenHash), []byte(hash)) == 1 {
+			found = token
+			break
+		}
+	}
+	if found != nil {
+		// Kept in memory only, it is written out with the next change to the store.
+		found.LastUsedAt = &now
+	}
+	a.mu.Unlock()
+
+	if found == nil || (found.ExpiresAt != nil && now.After(*found.ExpiresAt)) {
+		return nil, andromodemError.ErrorUnauthenticated
+	}
+	return a.principalFor(found.Username, model.AuthMethodApiToken)
+}
+
+func (a *AuthService) CreateApiToken(username string, request *model.CreateApiTokenRequest) (*model.CreateApiTokenResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.findUser(username) == nil {
+		return nil, andromodemError.ErrorUserNotFound
+	}
+
+	token := apiTokenPrefix + randomToken(32)
+	stored := &storedApiToken{
+		ApiToken: model.ApiToken{
+			ID:        randomToken(8),
+			Name:      request.Name,
+			Username:  username,
+			CreatedAt: time.Now(),
+		},
+		TokenHash: hashToken(token),
+	}
+	if request.ExpiresInDays > 0 {
+		expiresAt := stored.CreatedAt.AddDate(0, 0, request.ExpiresInDays)
+		stored.ExpiresAt = &expiresAt
+	}
+
+	a.store.ApiTokens = append(a.store.ApiTokens, stored)
+	if err := a.save(); err != nil {
+		a.store.ApiTokens = a.store.ApiTokens[:len(a.store.ApiTokens)-1]
+		return nil, err
+	}
+
+	a.logger.Info("[Auth] API token created",
+		zap.String("username", username),
+		zap.String("token_id", stored.ID),
+		zap.String("name", stored.Name))
+	return &model.CreateApiTokenResponse{ApiToken: stored.ApiToken, Token: token}, nil
+}
+
+func (a *AuthService) ListApiTokens(username string) []*model.ApiToken {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	tokens := make([]*model.ApiToken, 0)
+	for _, token := range a.store.ApiTokens {
+		if token.Username == username {
+			apiToken := token.ApiToken
+			tokens = append(tokens, &apiToken)
+		}
+	}
+	return tokens
+}
+
+func (a *AuthService) RevokeApiToken(username string, id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i, token := range a.store.ApiTokens {
+		if token.ID != id || token.Username != username {
+			continue
+		}
+		previous := a.store.ApiTokens
+		a.store.ApiTokens = append(append([]*storedApiToken{}, previous[:i]...), previous[i+1:]...)
+		if err := a.save(); err != nil {
+			a.store.ApiTokens = previous
+			return err
+		}
+		a.logger.Info("[Auth] API token revoked", zap.String("username", username), zap.String("token_id", id))
+		return nil
+	}
+	return andromodemError.ErrorApiTokenNotFound
+}
+
+func (a *AuthService) CreateTicket(principal *model.Principal) *model.Ticket {
+	ticket := randomToken(24)
+	expiresAt := time.Now().Add(ticketTTL)
+
+	a.mu.Lock()
+	a.tickets[hashToken(ticket)] = &ticketEntry{principal: *principal, expiresAt: expiresAt}
+	a.mu.Unlock()
+
+	return &model.Ticket{Ticket: ticket, ExpiresAt: expiresAt}
+}
+
+func (a *AuthService) RedeemTicket(ticket string) (*model.Principal, error) {
+	key := hashToken(ticket)
+
+	a.mu.Lock()
+	entry, ok := a.tickets[key]
+	delete(a.tickets, key)
+	a.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, andromodemError.ErrorUnauthenticated
+	}
+	return a.principalFor(entry.principal.Username, model.AuthMethodTicket)
+}
+
+func (a *AuthService) ListUsers() []*model.User {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	users := make([]*model.User, 0, len(a.store.Users))
+	for _, user := range a.store.Users {
+		users = append(users, user.toModel())
+	}
+	return users
+}
+
+func (a *AuthService) CreateUser(request *model.CreateUserRequest) (*model.User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.findUser(request.Username) != nil {
+		return nil, andromodemError.ErrorUserExists
+	}
+
+	now := time.Now()
+	user := &storedUser{
+		Username:     request.Username,
+		PasswordHash: string(hash),
+		Role:         request.Role,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	a.store.Users = append(a.store.Users, user)
+	if err := a.save(); err != nil {
+		a.store.Users = a.store.Users[:len(a.store.Users)-1]
+		return nil, err
+	}
+
+	a.logger.Info("[Auth] User created", zap.String("username", user.Username), zap.String("role", string(user.Role)))
+	return user.toModel(), nil
+}
+
+func (a *AuthService) UpdateUserRole(username string, role model.Role) (*model.User, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	user := a.findUser(username)
+	if user == nil {
+		return nil, andromodemError.ErrorUserNotFound
+	}
+	if user.role() == model.RoleAdmin && role != model.RoleAdmin && a.countAdmins() == 1 {
+		return nil, andromodemError.ErrorLastAdmin
+	}
+
+	previousRole, previousUpdatedAt := user.Role, user.UpdatedAt
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	if err := a.save(); err != nil {
+		user.Role, user.UpdatedAt = previousRole, previousUpdatedAt
+		return nil, err
+	}
+
+	a.logger.Info("[Auth] User role updated", zap.String("username", username), zap.String("role", string(role)))
+	return user.toModel(), nil
+}
+
+func (a *AuthService) DeleteUser(actor string, username string) error {
+	if actor == username {
+		return andromodemError.ErrorCannotDeleteSelf
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	user := a.findUser(username)
+	if user == nil {
+		return andromodemError.ErrorUserNotFound
+	}
+	if user.role() == model.RoleAdmin && a.countAdmins() == 1 {
+		return andromodemError.ErrorLastAdmin
+	}
+
+	previousUsers, previousTokens := a.store.Users, a.store.ApiTokens
+	users := make([]*storedUser, 0, len(previousUsers))
+	for _, u := range previousUsers {
+		if u.Username != username {
+			users = append(users, u)
+		}
+	}
+	tokens := make([]*storedApiToken, 0, len(previousTokens))
+	for _, token := range previousTokens {
+		if token.Username != username {
+			tokens = append(tokens, token)
+		}
+	}
+	a.store.Users, a.store.ApiTokens = users, tokens
+	if err := a.save(); err != nil {
+		a.store.Users, a.store.ApiTokens = previousUsers, previousTokens
+		return err
+	}
+
+	for key, session := range a.sessions {
+		if session.Username == username {
+			delete(a.sessions, key)
+		}
+	}
+	a.logger.Info("[Auth] User deleted", zap.String("username", username), zap.String("by", actor))
+	return nil
+}
+
+func (a *AuthService) principalFor(username string, method model.AuthMethod) (*model.Principal, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	// Accounts can be removed while sessions or tokens are still around, so the
+	// role is always read from the store rather than from the credential.
+	user := a.findUser(username)
+	if user == nil {
+		return nil, andromodemError.ErrorUnauthenticated
+	}
+	return &model.Principal{Username: username, Role: user.role(), AuthMethod: method}, nil
+}
+
+func (a *AuthService) countAdmins() int {
+	count := 0
+	for _, user := range a.store.Users {
+		if user.role() == model.RoleAdmin {
+			count++
+		}
+	}
+	return count
+}
+
+// role treats accounts created before roles existed as admins.
+func (u *storedUser) role() model.Role {
+	if u.Role == "" {
+		return model.RoleAdmin
+	}
+	return u.Role
+}
+
+func (u *storedUser) toModel() *model.User {
+	return &model.User{
+		Username:  u.Username,
+		Role:      u.role(),
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+func (a *AuthService) findUser(username string) *storedUser {
+	for _, user := range a.store.Users {
+		if user.Username == username {
+			return user
+		}
+	}
+	return nil
+}
+
+func (a *AuthService) load() error {
+	data, err := os.ReadFile(a.usersFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &a.store)
+}
+
+// save writes the store atomically. Callers must hold the write lock.
+func (a *AuthService) save() error {
+	data, err := json.MarshalIndent(a.store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(a.usersFile, data)
+}
+
+// writeFile atomically writes data to a file only the owner can read.
+func writeFile(file string, data []byte) error {
+	if dir := filepath.Dir(file); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+	tmpFile := file + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, file)
+}
+
+func (a *AuthService) cleanupLoop() {
+	ticker := time.NewTicker(cleanupPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.mu.Lock()
+			for key, session := range a.sessions {
+				if now.After(session.ExpiresAt) {
+					delete(a.sessions, key)
+				}
+			}
+			for key, ticket := range a.tickets {
+				if now.After(ticket.expiresAt) {
+					delete(a.tickets, key)
+				}
+			}
+			a.mu.Unlock()
+		}
+	}
+}
+
+func randomToken(size int) string {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
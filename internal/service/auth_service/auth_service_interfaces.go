@@ -0,0 +1,23 @@
+package auth_service
+
+import (
+	"github.com/basiooo/andromodem/internal/model"
+)
+
+type IAuthService interface {
+	EnsureAdmin(username string, password string) error
+	Login(username string, password string) (string, *model.Session, error)
+	Logout(sessionToken string)
+	ChangePassword(username string, currentPassword string, newPassword string) error
+	AuthenticateSession(sessionToken string) (*model.Principal, error)
+	AuthenticateApiToken(apiToken string) (*model.Principal, error)
+	CreateApiToken(username string, request *model.CreateApiTokenRequest) (*model.CreateApiTokenResponse, error)
+	ListApiTokens(username string) []*model.ApiToken
+	RevokeApiToken(username string, id string) error
+	CreateTicket(principal *model.Principal) *model.Ticket
+	RedeemTicket(ticket string) (*model.Principal, error)
+	ListUsers() []*model.User
+	CreateUser(request *model.CreateUserRequest) (*model.User, error)
+	UpdateUserRole(username string, role model.Role) (*model.User, error)
+	DeleteUser(actor string, username string) error
+}
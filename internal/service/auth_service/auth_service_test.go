@@ -0,0 +1,225 @@
+package auth_service_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/auth_service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestAuthService(t *testing.T, usersFile string) auth_service.IAuthService {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	service, err := auth_service.NewAuthService(usersFile, time.Hour, zap.NewNop(), ctx)
+	require.NoError(t, err)
+	return service
+}
+
+func TestAuthService_LoginAndSession(t *testing.T) {
+	t.Parallel()
+	service := newTestAuthService(t, filepath.Join(t.TempDir(), "users.json"))
+	require.NoError(t, service.EnsureAdmin("admin", "secret-password"))
+
+	_, _, err := service.Login("admin", "wrong")
+	assert.ErrorIs(t, err, andromodemError.ErrorInvalidCredentials)
+	_, _, err = service.Login("nobody", "secret-password")
+	assert.ErrorIs(t, err, andromodemError.ErrorInvalidCredentials)
+
+	token, session, err := service.Login("admin", "secret-password")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", session.Username)
+
+	principal, err := service.AuthenticateSession(token)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", principal.Username)
+	assert.Equal(t, model.AuthMethodSession, principal.AuthMethod)
+
+	service.Logout(token)
+	_, err = service.AuthenticateSession(token)
+	assert.ErrorIs(t, err, andromodemError.ErrorUnauthenticated)
+}
+
+func TestAuthService_EnsureAdminOnlySeedsEmptyStore(t *testing.T) {
+	t.Parallel()
+	usersFile := filepath.Join(t.TempDir(), "users.json")
+	service := newTestAuthService(t, usersFile)
+	require.NoError(t, service.EnsureAdmin("admin", "first-password"))
+	require.NoError(t, service.EnsureAdmin("admin", "second-password"))
+
+	reloaded := newTestAuthService(t, usersFile)
+	_, _, err := reloaded.Login("admin", "first-password")
+	assert.NoError(t, err)
+	_, _, err = reloaded.Login("admin", "second-password")
+	assert.ErrorIs(t, err, andromodemError.ErrorInvalidCredentials)
+}
+
+func TestAuthService_GeneratedAdminPasswordIsNotLogged(t *testing.T) {
+	t.Parallel()
+	usersFile := filepath.Join(t.TempDir(), "users.json")
+	core, logs := observer.New(zap.InfoLevel)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	service, err := auth_service.NewAuthService(usersFile, time.Hour, zap.New(core), ctx)
+	require.NoError(t, err)
+	require.NoError(t, service.EnsureAdmin("admin", ""))
+
+	passwordFile := usersFile + ".admin_password"
+	info, err := os.Stat(passwordFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	data, err := os.ReadFile(passwordFile)
+	require.NoError(t, err)
+	password := strings.TrimSpace(string(data))
+	require.NotEmpty(t, password)
+
+	_, _, err = service.Login("admin", password)
+	assert.NoError(t, err)
+	for _, entry := range logs.All() {
+		assert.NotContains(t, entry.Message, password)
+		for _, value := range entry.ContextMap() {
+			assert.NotEqual(t, password, value)
+		}
+	}
+}
+
+func TestAuthService_LoginDuringPasswordChange(t *testing.T) {
+	t.Parallel()
+	service := newTestAuthService(t, filepath.Join(t.TempDir(), "users.json"))
+	require.NoError(t, service.EnsureAdmin("admin", "secret-password"))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _, _ = service.Login("admin", "secret-password")
+	}()
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, service.ChangePassword("admin", "secret-password", "new-password"))
+	}()
+	wg.Wait()
+
+	_, _, err := service.Login("admin", "new-password")
+	assert.NoError(t, err)
+}
+
+func TestAuthService_ChangePasswordRevokesSessions(t *testing.T) {
+	t.Parallel()
+	service := newTestAuthService(t, filepath.Join(t.TempDir(), "users.json"))
+	require.NoError(t, service.EnsureAdmin("admin", "secret-password"))
+	token, _, err := service.Login("admin", "secret-password")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, service.ChangePassword("admin", "wrong", "new-password"), andromodemError.ErrorInvalidCredentials)
+	require.NoError(t, service.ChangePassword("admin", "secret-password", "new-password"))
+
+	_, err = service.AuthenticateSession(token)
+	assert.ErrorIs(t, err, andromodemError.ErrorUnauthenticated)
+	_, _, err = service.Login("admin", "new-password")
+	assert.NoError(t, err)
+}
+
+func TestAuthService_ApiTokens(t *testing.T) {
+	t.Parallel()
+	usersFile := filepath.Join(t.TempDir(), "users.json")
+	service := newTestAuthService(t, usersFile)
+	require.NoError(t, service.EnsureAdmin("admin", "secret-password"))
+
+	created, err := service.CreateApiToken("admin", &model.CreateApiTokenRequest{Name: "script"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.Token)
+
+	// Tokens survive a restart.
+	reloaded := newTestAuthService(t, usersFile)
+	principal, err := reloaded.AuthenticateApiToken(created.Token)
+	require.NoError(t, err)
+	assert.Equal(t, model.AuthMethodApiToken, principal.AuthMethod)
+
+	tokens := reloaded.ListApiTokens("admin")
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "script", tokens[0].Name)
+
+	assert.ErrorIs(t, reloaded.RevokeApiToken("admin", "unknown"), andromodemError.ErrorApiTokenNotFound)
+	require.NoError(t, reloaded.RevokeApiToken("admin", created.ID))
+	_, err = reloaded.AuthenticateApiToken(created.Token)
+	assert.ErrorIs(t, err, andromodemError.ErrorUnauthenticated)
+}
+
+func TestAuthService_TicketIsSingleUse(t *testing.T) {
+	t.Parallel()
+	service := newTestAuthService(t, filepath.Join(t.TempDir(), "users.json"))
+	require.NoError(t, service.EnsureAdmin("admin", "secret-password"))
+
+	ticket := service.CreateTicket(&model.Principal{Username: "admin"})
+
+	principal, err := service.RedeemTicket(ticket.Ticket)
+	require.NoError(t, err)
+	assert.Equal(t, model.AuthMethodTicket, principal.AuthMethod)
+
+	_, err = service.RedeemTicket(ticket.Ticket)
+	assert.ErrorIs(t, err, andromodemError.ErrorUnauthenticated)
+}
+
+func TestAuthService_UserRoles(t *testing.T) {
+	t.Parallel()
+	usersFile := filepath.Join(t.TempDir(), "users.json")
+	service := newTestAuthService(t, usersFile)
+	require.NoError(t, service.EnsureAdmin("admin", "secret-password"))
+
+	_, err := service.CreateUser(&model.CreateUserRequest{Username: "family", Password: "family-password", Role: model.RoleViewer})
+	require.NoError(t, err)
+	_, err = service.CreateUser(&model.CreateUserRequest{Username: "family", Password: "family-password", Role: model.RoleViewer})
+	assert.ErrorIs(t, err, andromodemError.ErrorUserExists)
+
+	token, _, err := service.Login("family", "family-password")
+	require.NoError(t, err)
+	principal, err := service.AuthenticateSession(token)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleViewer, principal.Role)
+
+	// Role changes apply to existing sessions and are persisted.
+	_, err = service.UpdateUserRole("family", model.RoleOperator)
+	require.NoError(t, err)
+	principal, err = service.AuthenticateSession(token)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleOperator, principal.Role)
+
+	reloaded := newTestAuthService(t, usersFile)
+	users := reloaded.ListUsers()
+	require.Len(t, users, 2)
+	assert.Equal(t, model.RoleAdmin, users[0].Role)
+	assert.Equal(t, model.RoleOperator, users[1].Role)
+}
+
+func TestAuthService_KeepsAnAdmin(t *testing.T) {
+	t.Parallel()
+	service := newTestAuthService(t, filepath.Join(t.TempDir(), "users.json"))
+	require.NoError(t, service.EnsureAdmin("admin", "secret-password"))
+
+	_, err := service.UpdateUserRole("admin", model.RoleViewer)
+	assert.ErrorIs(t, err, andromodemError.ErrorLastAdmin)
+	assert.ErrorIs(t, service.DeleteUser("admin", "admin"), andromodemError.ErrorCannotDeleteSelf)
+	assert.ErrorIs(t, service.DeleteUser("someone", "admin"), andromodemError.ErrorLastAdmin)
+	assert.ErrorIs(t, service.DeleteUser("admin", "missing"), andromodemError.ErrorUserNotFound)
+
+	_, err = service.CreateUser(&model.CreateUserRequest{Username: "second", Password: "second-password", Role: model.RoleAdmin})
+	require.NoError(t, err)
+	apiToken, err := service.CreateApiToken("second", &model.CreateApiTokenRequest{Name: "script"})
+	require.NoError(t, err)
+
+	require.NoError(t, service.DeleteUser("admin", "second"))
+	_, err = service.AuthenticateApiToken(apiToken.Token)
+	assert.ErrorIs(t, err, andromodemError.ErrorUnauthenticated)
+}
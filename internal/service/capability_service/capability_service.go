@@ -0,0 +1,271 @@
+package capability_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	"github.com/basiooo/andromodem/pkg/logger"
+	adb "github.com/basiooo/goadb"
+	"go.uber.org/zap"
+)
+
+// probeCommands are read in a single shell session by probe, without root.
+var probeCommands = []command.AdbCommand{
+	command.GetBuildFingerprintCommand,
+	command.GetAndroidVersionCommand,
+	command.GetAirplaneModeStatusNewCommand,
+	command.GetSvcHelpCommand,
+	command.GetApnCommand,
+	command.GetInboxAccessCommand,
+	command.GetRootCommand,
+	command.GetBusyboxCheckCommand,
+	command.GetPingCheckCommand,
+}
+
+// svcData matches the data service in the output of `svc help`.
+var svcData = regexp.MustCompile(`(?m)^\s*data\s`)
+
+type capabilityStore struct {
+	Devices map[string]*model.DeviceCapabilities `json:"devices"`
+}
+
+// CapabilityService probes the features of every device once per build
+// fingerprint and keeps the profiles in capabilitiesFile. Without a file the
+// profiles are only kept in memory.
+type CapabilityService struct {
+	Adb              *adb.Adb
+	AdbProcessor     processor.IProcessor
+	Logger           *zap.Logger
+	capabilitiesFile string
+
+	mu    sync.RWMutex
+	store capabilityStore
+}
+
+func NewCapabilityService(adb *adb.Adb, adbProcessor processor.IProcessor, logger *zap.Logger, capabilitiesFile string) (ICapabilityService, error) {
+	service := &CapabilityService{
+		Adb:              adb,
+		AdbProcessor:     adbProcessor,
+		Logger:           logger,
+		capabilitiesFile: capabilitiesFile,
+		store:            capabilityStore{Devices: make(map[string]*model.DeviceCapabilities)},
+	}
+	if err := service.load(); err != nil {
+		return nil, err
+	}
+	for serial, capabilities := range service.store.Devices {
+		adbProcessor.SetSuSyntax(serial, capabilities.SuSyntax)
+	}
+	return service, nil
+}
+
+// GetCapabilities returns the profile of the device, it is probed first when
+// the device is unknown or its build changed since.
+func (c *CapabilityService) GetCapabilities(ctx context.Context, serial string) (*model.DeviceCapabilities, error) {
+	device, err := c.Adb.GetDeviceBySerial(serial)
+	if err != nil || device == nil {
+		c.Logger.Error("error getting device by serial", zap.String("serial", serial), zap.Error(err))
+		return nil, andromodemError.ErrorDeviceNotFound
+	}
+	fingerprint, err := processor.RunRaw(ctx, c.AdbProcessor, device, command.GetBuildFingerprintCommand, false)
+	if err != nil {
+		c.Logger.Error("error getting build fingerprint", zap.String("serial", serial), zap.Error(err))
+		return nil, err
+	}
+
+	c.mu.RLock()
+	capabilities := c.store.Devices[serial]
+	c.mu.RUnlock()
+	if capabilities != nil && capabilities.Fingerprint == fingerprint {
+		return capabilities, nil
+	}
+	if capabilities != nil {
+		c.Logger.Info("[Capability] Device build changed, probing again",
+			zap.String("serial", serial),
+			zap.String("old_fingerprint", capabilities.Fingerprint),
+			zap.String("fingerprint", fingerprint))
+	}
+	return c.probe(ctx, device, serial)
+}
+
+// ProbeCapabilities probes the device again, whatever its profile.
+func (c *CapabilityService) ProbeCapabilities(ctx context.Context, serial string) (*model.DeviceCapabilities, error) {
+	device, err := c.Adb.GetDeviceBySerial(serial)
+	if err != nil || device == nil {
+		c.Logger.Error("error getting device by serial", zap.String("serial", serial), zap.Error(err))
+		return nil, andromodemError.ErrorDeviceNotFound
+	}
+	return c.probe(ctx, device, serial)
+}
+
+// probe tries every feature on the device and stores its profile. Features
+// denied to the shell are tried again as root when su grants the shell
+// access, asking su may show a prompt on the device.
+func (c *CapabilityService) probe(ctx context.Context, device *adb.Device, serial string) (*model.DeviceCapabilities, error) {
+	defer logger.LogDuration(c.Logger, "probe")()
+	ctx = processor.WithoutCache(ctx)
+	results, err := c.AdbProcessor.RunBatch(ctx, device, probeCommands...)
+	if err != nil {
+		c.Logger.Error("error probing device", zap.String("serial", serial), zap.Error(err))
+		return nil, err
+	}
+
+	capabilities := &model.DeviceCapabilities{
+		Serial:   serial,
+		ProbedAt: time.Now(),
+		Apn:      model.AccessNone,
+		Sms:      model.AccessNone,
+	}
+	for _, result := range results {
+		if err := setProbeResult(capabilities, result); err != nil {
+			return nil, err
+		}
+	}
+	// Root commands of the device, from here on, are passed to su the way it
+	// takes them.
+	c.AdbProcessor.SetSuSyntax(serial, capabilities.SuSyntax)
+
+	// The AOSP su only runs for the shell and root users, it granted the
+	// shell access by taking -v as a uid.
+	if capabilities.Su != "" && capabilities.SuSyntax != command.SuSyntaxUid {
+		if shellRootAccess, err := processor.RunWithRoot[*parser.RawParser](ctx, c.AdbProcessor, device, command.GetDeviceRootAccessCommand); err == nil {
+			capabilities.SuShellAccess = strings.Contains(shellRootAccess.Result, "1")
+		}
+	}
+	if capabilities.SuShellAccess {
+		if capabilities.Apn == model.AccessNone {
+			if _, err := processor.RunWithRoot[*parser.Apn](ctx, c.AdbProcessor, device, command.GetApnCommand); err == nil {
+				capabilities.Apn = model.AccessRoot
+			}
+		}
+		if capabilities.Sms == model.AccessNone {
+			if _, err := processor.RunWithRoot[*parser.RawParser](ctx, c.AdbProcessor, device, command.GetInboxAccessCommand); err == nil {
+				capabilities.Sms = model.AccessRoot
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store.Devices[serial] = capabilities
+	if err := c.save(); err != nil {
+		// The profile is right, it is only probed again after a restart.
+		c.Logger.Error("error saving device capabilities", zap.String("file", c.capabilitiesFile), zap.Error(err))
+	}
+	c.Logger.Info("[Capability] Device probed", zap.String("serial", serial), zap.Any("capabilities", capabilities))
+	return capabilities, nil
+}
+
+// setProbeResult stores the result of one of probeCommands. Only a missing
+// fingerprint fails the probe, a failed command means the feature is not
+// available.
+func setProbeResult(capabilities *model.DeviceCapabilities, result processor.BatchResult) error {
+	worked := result.Err == nil && result.ExitCode == 0
+	switch result.Command {
+	case command.GetBuildFingerprintCommand:
+		fingerprint, err := processor.Result[*parser.RawParser](result)
+		if err != nil {
+			return fmt.Errorf("error reading build fingerprint: %w", err)
+		}
+		capabilities.Fingerprint = fingerprint.Result
+	case command.GetAndroidVersionCommand:
+		if version, err := processor.Result[*parser.RawParser](result); err == nil {
+			if major, err := strconv.Atoi(strings.Split(version.Result, ".")[0]); err == nil {
+				capabilities.AndroidVersion = uint8(major)
+			}
+		}
+	case command.GetAirplaneModeStatusNewCommand:
+		capabilities.AirplaneModeCommand = worked
+	case command.GetSvcHelpCommand:
+		if help, err := processor.Result[*parser.RawParser](result); err == nil {
+			capabilities.SvcData = svcData.MatchString(help.Result)
+		}
+	case command.GetApnCommand:
+		if worked {
+			capabilities.Apn = model.AccessShell
+		}
+	case command.GetInboxAccessCommand:
+		if worked {
+			capabilities.Sms = model.AccessShell
+		}
+	case command.GetRootCommand:
+		root, err := processor.Result[*parser.Root](result)
+		switch {
+		case err == nil && root.IsRooted:
+			capabilities.Su = root.Name
+			capabilities.SuSyntax = command.SuSyntaxFor(root.Name)
+		case errors.Is(err, adbErrors.ErrorSuTakesUid):
+			capabilities.Su = command.SuNameAosp
+			capabilities.SuSyntax = command.SuSyntaxFor(command.SuNameAosp)
+			capabilities.SuShellAccess = true
+		}
+	case command.GetBusyboxCheckCommand:
+		if busybox, err := processor.Result[*parser.BusyboxCheck](result); err == nil {
+			capabilities.Busybox = worked && busybox.Exist
+		}
+	case command.GetPingCheckCommand:
+		if ping, err := processor.Result[*parser.RawParser](result); err == nil {
+			capabilities.Ping = worked && ping.Result != ""
+		}
+	}
+	return nil
+}
+
+func (c *CapabilityService) load() error {
+	if c.capabilitiesFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.capabilitiesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &c.store); err != nil {
+		return fmt.Errorf("%s: %w", c.capabilitiesFile, err)
+	}
+	if c.store.Devices == nil {
+		c.store.Devices = make(map[string]*model.DeviceCapabilities)
+	}
+	return nil
+}
+
+// save writes the store atomically. Callers must hold the write lock.
+func (c *CapabilityService) save() error {
+	if c.capabilitiesFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.store, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(c.capabilitiesFile); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmpFile := c.capabilitiesFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, c.capabilitiesFile)
+}
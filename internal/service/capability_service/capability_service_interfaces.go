@@ -0,0 +1,12 @@
+package capability_service
+
+import (
+	"context"
+
+	"github.com/basiooo/andromodem/internal/model"
+)
+
+type ICapabilityService interface {
+	GetCapabilities(ctx context.Context, serial string) (*model.DeviceCapabilities, error)
+	ProbeCapabilities(ctx context.Context, serial string) (*model.DeviceCapabilities, error)
+}
@@ -0,0 +1,196 @@
+package capability_service_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture/fixturetest"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	adb "github.com/basiooo/goadb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func newReplayedService(t *testing.T, capabilitiesFile string) capability_service.ICapabilityService {
+	adbClient, adbProcessor := fixturetest.NewReplayedClient(t)
+	service, err := capability_service.NewCapabilityService(adbClient, adbProcessor, zaptest.NewLogger(t), capabilitiesFile)
+	require.NoError(t, err)
+	return service
+}
+
+func TestProbeCapabilitiesReplayed(t *testing.T) {
+	t.Parallel()
+	service := newReplayedService(t, "")
+
+	tests := []struct {
+		name     string
+		serial   string
+		expected model.DeviceCapabilities
+	}{
+		{
+			name:   "samsung",
+			serial: "R58R31ABCDE",
+			expected: model.DeviceCapabilities{
+				Fingerprint:         "samsung/a52qnsxx/a52q:13/TP1A.220624.014/A525FXXS6EWJ2:user/release-keys",
+				AndroidVersion:      13,
+				AirplaneModeCommand: true,
+				SvcData:             true,
+				Apn:                 model.AccessShell,
+				Sms:                 model.AccessShell,
+				Ping:                true,
+			},
+		},
+		{
+			// The shell of this Android 9 build is denied the providers,
+			// Magisk grants them.
+			name:   "xiaomi",
+			serial: "8d1c2a3f",
+			expected: model.DeviceCapabilities{
+				Fingerprint:         "xiaomi/lavender/lavender:9/PKQ1.180904.001/V11.0.3.0.PFGMIXM:user/release-keys",
+				AndroidVersion:      9,
+				AirplaneModeCommand: true,
+				SvcData:             true,
+				Apn:                 model.AccessRoot,
+				Sms:                 model.AccessRoot,
+				Su:                  "MAGISK",
+				SuSyntax:            command.SuSyntaxMagisk,
+				SuShellAccess:       true,
+				Busybox:             true,
+				Ping:                true,
+			},
+		},
+		{
+			name:   "pixel",
+			serial: "2A111FDH200ABC",
+			expected: model.DeviceCapabilities{
+				Fingerprint:         "google/panther/panther:14/AP2A.240805.005/12025142:user/release-keys",
+				AndroidVersion:      14,
+				AirplaneModeCommand: true,
+				SvcData:             true,
+				Apn:                 model.AccessShell,
+				Sms:                 model.AccessShell,
+				Ping:                true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			capabilities, err := service.ProbeCapabilities(context.Background(), tt.serial)
+			require.NoError(t, err)
+			assert.NotZero(t, capabilities.ProbedAt)
+			tt.expected.Serial = tt.serial
+			tt.expected.ProbedAt = capabilities.ProbedAt
+			assert.Equal(t, tt.expected, *capabilities)
+		})
+	}
+}
+
+func TestGetCapabilitiesPersisted(t *testing.T) {
+	t.Parallel()
+	capabilitiesFile := filepath.Join(t.TempDir(), "capabilities.json")
+	probed, err := newReplayedService(t, capabilitiesFile).GetCapabilities(context.Background(), "8d1c2a3f")
+	require.NoError(t, err)
+
+	// A restarted service does not probe the device again.
+	capabilities, err := newReplayedService(t, capabilitiesFile).GetCapabilities(context.Background(), "8d1c2a3f")
+	require.NoError(t, err)
+	assert.True(t, probed.ProbedAt.Equal(capabilities.ProbedAt))
+	assert.Equal(t, model.AccessRoot, capabilities.Sms)
+}
+
+func TestGetCapabilitiesBuildChanged(t *testing.T) {
+	t.Parallel()
+	service := newReplayedService(t, "")
+	ctx := context.Background()
+	probed, err := service.GetCapabilities(ctx, "R58R31ABCDE")
+	require.NoError(t, err)
+	cached, err := service.GetCapabilities(ctx, "R58R31ABCDE")
+	require.NoError(t, err)
+	assert.Same(t, probed, cached)
+
+	// A profile of an older build is probed again.
+	probed.Fingerprint = "samsung/a52qnsxx/a52q:12/SP1A.210812.016/A525FXXU4BVC1:user/release-keys"
+	capabilities, err := service.GetCapabilities(ctx, "R58R31ABCDE")
+	require.NoError(t, err)
+	assert.NotSame(t, probed, capabilities)
+	assert.Equal(t, uint8(13), capabilities.AndroidVersion)
+}
+
+func TestProbeCapabilitiesUnknownDevice(t *testing.T) {
+	t.Parallel()
+	_, err := newReplayedService(t, "").ProbeCapabilities(context.Background(), "unknown")
+	assert.ErrorIs(t, err, andromodemError.ErrorDeviceNotFound)
+}
+
+// rootRecorder records the su syntax of every command run as root by the
+// executors it creates.
+type rootRecorder struct {
+	newExecutor executor.Factory
+	mu          sync.Mutex
+	syntaxes    map[command.AdbCommand]command.SuSyntax
+}
+
+func (r *rootRecorder) Executor(device *adb.Device) executor.IExecutor {
+	return &recordingExecutor{IExecutor: r.newExecutor(device), recorder: r}
+}
+
+type recordingExecutor struct {
+	executor.IExecutor
+	recorder *rootRecorder
+	syntax   command.SuSyntax
+}
+
+func (e *recordingExecutor) SetSuSyntax(syntax command.SuSyntax) {
+	e.syntax = syntax
+	e.IExecutor.SetSuSyntax(syntax)
+}
+
+func (e *recordingExecutor) Run(ctx context.Context, adbCommand command.AdbCommand) (string, error) {
+	if e.Root() {
+		e.recorder.mu.Lock()
+		e.recorder.syntaxes[adbCommand] = e.syntax
+		e.recorder.mu.Unlock()
+	}
+	return e.IExecutor.Run(ctx, adbCommand)
+}
+
+func TestProbeCapabilitiesSetsSuSyntax(t *testing.T) {
+	t.Parallel()
+	fixtures, err := fixture.Profiles()
+	require.NoError(t, err)
+	logger := zaptest.NewLogger(t)
+	recorder := &rootRecorder{newExecutor: fixture.NewReplay(fixtures...).Executor, syntaxes: make(map[command.AdbCommand]command.SuSyntax)}
+	adbProcessor := processor.NewProcessorWithExecutor(logger, recorder.Executor)
+	adbClient := &adb.Adb{Server: fixture.NewServer(fixtures...)}
+	capabilitiesFile := filepath.Join(t.TempDir(), "capabilities.json")
+	service, err := capability_service.NewCapabilityService(adbClient, adbProcessor, logger, capabilitiesFile)
+	require.NoError(t, err)
+
+	// The Redmi is rooted with Magisk, its providers are read as root.
+	_, err = service.ProbeCapabilities(context.Background(), "8d1c2a3f")
+	require.NoError(t, err)
+	assert.Equal(t, command.SuSyntaxMagisk, recorder.syntaxes[command.GetApnCommand])
+	assert.Equal(t, command.SuSyntaxMagisk, recorder.syntaxes[command.GetInboxAccessCommand])
+
+	// A restarted service passes the stored syntax on without probing.
+	restarted := processor.NewProcessorWithExecutor(logger, recorder.Executor)
+	_, err = capability_service.NewCapabilityService(adbClient, restarted, logger, capabilitiesFile)
+	require.NoError(t, err)
+	clear(recorder.syntaxes)
+	device, err := adbClient.GetDeviceBySerial("8d1c2a3f")
+	require.NoError(t, err)
+	_, err = processor.RunWithRoot[*parser.Apn](context.Background(), restarted, device, command.GetApnCommand)
+	require.NoError(t, err)
+	assert.Equal(t, command.SuSyntaxMagisk, recorder.syntaxes[command.GetApnCommand])
+}
@@ -1,53 +1,42 @@
 package common_service
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/basiooo/andromodem/internal/model"
 	"github.com/basiooo/andromodem/pkg/adb_processor/command"
 	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
 	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
-	"github.com/basiooo/andromodem/pkg/adb_processor/utils"
-	"github.com/basiooo/andromodem/pkg/cache"
 	adb "github.com/basiooo/goadb"
 )
 
-func GetAndroidVersion(device *adb.Device, adbProcessor processor.IProcessor, useCache bool) (uint8, error) {
-	serial, _ := device.Serial()
-	cacheKey := fmt.Sprintf("android_version_%s", serial)
-	cacheInstance := cache.GetInstance()
-	if useCache {
-		if cachedVersion, found := cacheInstance.Get(cacheKey); found {
-			return cachedVersion.(uint8), nil
-		}
+// GetAndroidVersion returns the major Android version of the device. Without
+// useCache the version is read from the device even when it is cached.
+func GetAndroidVersion(ctx context.Context, device *adb.Device, adbProcessor processor.IProcessor, useCache bool) (uint8, error) {
+	if !useCache {
+		ctx = processor.WithoutCache(ctx)
 	}
-	if androidVersion, err := adbProcessor.Run(device, command.GetAndroidVersionCommand, false); err == nil {
-		result := androidVersion.(*parser.RawParser)
-		v := strings.Split(result.Result, ".")[0]
+	if androidVersion, err := processor.RunRaw(ctx, adbProcessor, device, command.GetAndroidVersionCommand, false); err == nil {
+		v := strings.Split(androidVersion, ".")[0]
 		majorVersion, err := strconv.Atoi(v)
 		if err != nil {
 			return 0, err
 		}
 
-		version := uint8(majorVersion)
-		cacheInstance.Set(cacheKey, version, 5*time.Minute)
-
-		return version, nil
+		return uint8(majorVersion), nil
 	}
 	return 0, nil
 }
 
-func GetDeviceRootAndAccessInfo(device *adb.Device, adbProcessor processor.IProcessor, useCache bool) (*model.DeviceRootInfo, error) {
-	serial, _ := device.Serial()
-	cacheKey := fmt.Sprintf("device_root_info_%s", serial)
-	cacheInstance := cache.GetInstance()
-	if useCache {
-		if cachedInfo, found := cacheInstance.Get(cacheKey); found {
-			return cachedInfo.(*model.DeviceRootInfo), nil
-		}
+// GetDeviceRootAndAccessInfo tells whether the device is rooted and the shell
+// may use su. Without useCache both are read from the device even when they
+// are cached.
+func GetDeviceRootAndAccessInfo(ctx context.Context, device *adb.Device, adbProcessor processor.IProcessor, useCache bool) (*model.DeviceRootInfo, error) {
+	if !useCache {
+		ctx = processor.WithoutCache(ctx)
 	}
 	result := &model.DeviceRootInfo{
 		RootMethod:  "",
@@ -55,21 +44,72 @@ func GetDeviceRootAndAccessInfo(device *adb.Device, adbProcessor processor.IProc
 		ShellAccess: false,
 	}
 
-	rootInfo, err := adbProcessor.RunWithRoot(device, command.GetRootCommand)
+	root, err := processor.RunWithRoot[*parser.Root](ctx, adbProcessor, device, command.GetRootCommand)
 	if err != nil {
-		cacheInstance.Set(cacheKey, result, 5*time.Minute)
 		return result, nil
 	}
-	if root, ok := rootInfo.(*parser.Root); ok {
-		result.Rooted = root.IsRooted
-
-		if root.IsRooted {
-			shellRootAccess, err := adbProcessor.RunWithRoot(device, command.GetDeviceRootAccessCommand)
-			if err == nil {
-				result.ShellAccess = strings.Contains(utils.GetResultFromRaw(shellRootAccess), "1")
-			}
+	result.Rooted = root.IsRooted
+	if root.IsRooted {
+		shellRootAccess, err := processor.RunWithRoot[*parser.RawParser](ctx, adbProcessor, device, command.GetDeviceRootAccessCommand)
+		if err == nil {
+			result.ShellAccess = strings.Contains(shellRootAccess.Result, "1")
 		}
 	}
-	cacheInstance.Set(cacheKey, result, 5*time.Minute)
 	return result, nil
 }
+
+// DeviceSimsCommands returns the commands ParseDeviceSims reads, in order:
+// operator names, mobile data states and signal strengths of every SIM.
+func DeviceSimsCommands(ctx context.Context, device *adb.Device, adbProcessor processor.IProcessor) []command.AdbCommand {
+	signalCommand := command.GetSignalStrengthCommand
+	if androidVersion, err := GetAndroidVersion(ctx, device, adbProcessor, true); err == nil {
+		if androidVersion < command.MinimumAndroidGetSignalStrength {
+			// in android 9 or bellow cannot parse signal strength
+			signalCommand = command.GetSimNetworkTypeCommand
+		}
+	}
+	return []command.AdbCommand{
+		command.GetSimOperatorNameCommand,
+		command.GetMobileDataStateCommand,
+		signalCommand,
+	}
+}
+
+// ParseDeviceSims builds the SIMs from the batch results of DeviceSimsCommands.
+// A command that failed leaves its part empty.
+func ParseDeviceSims(results []processor.BatchResult) ([]parser.Sim, error) {
+	var raw [3]string
+	for i := range raw {
+		if i >= len(results) {
+			break
+		}
+		if result, err := processor.Result[*parser.RawParser](results[i]); err == nil {
+			raw[i] = result.Result
+		}
+	}
+	rawDeviceSim := parser.RawDeviceSim{
+		RawCarriersName:     raw[0],
+		RawConnectionsState: raw[1],
+		RawSignalsStrength:  raw[2],
+	}
+
+	data, err := json.Marshal(rawDeviceSim)
+	if err != nil {
+		return nil, err
+	}
+	deviceSims := &parser.DeviceSim{}
+	if err := deviceSims.Parse(string(data)); err != nil {
+		return nil, err
+	}
+	return deviceSims.Sims, nil
+}
+
+// GetDeviceSims reads the operator name, mobile data state and signal strength
+// of every SIM of the device.
+func GetDeviceSims(ctx context.Context, device *adb.Device, adbProcessor processor.IProcessor) ([]parser.Sim, error) {
+	results, err := adbProcessor.RunBatch(ctx, device, DeviceSimsCommands(ctx, device, adbProcessor)...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDeviceSims(results)
+}
@@ -0,0 +1,31 @@
+package common_service
+
+import (
+	"sync"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+)
+
+// CpuSamples keeps the last CPU time read from every device, so the CPU
+// utilisation can be worked out from the next read.
+type CpuSamples struct {
+	mutex   sync.Mutex
+	samples map[string]*parser.CpuStat
+}
+
+func NewCpuSamples() *CpuSamples {
+	return &CpuSamples{samples: make(map[string]*parser.CpuStat)}
+}
+
+// Usage stores stat as the last sample of serial and returns the utilisation
+// since the sample before. It is false for the first sample of a device.
+func (c *CpuSamples) Usage(serial string, stat *parser.CpuStat) (*parser.CpuUsage, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	previous, ok := c.samples[serial]
+	c.samples[serial] = stat
+	if !ok {
+		return nil, false
+	}
+	return stat.UsageSince(previous)
+}
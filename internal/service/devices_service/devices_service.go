@@ -2,17 +2,19 @@ package devices_service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
-	"sync"
+	"time"
 
 	andromodemError "github.com/basiooo/andromodem/internal/errors"
 	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
 	"github.com/basiooo/andromodem/internal/service/common_service"
 	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
 	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
 	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
-	"github.com/basiooo/andromodem/pkg/adb_processor/utils"
 	"github.com/basiooo/andromodem/pkg/logger"
 	adb "github.com/basiooo/goadb"
 	"go.uber.org/zap"
@@ -28,18 +30,23 @@ const (
 )
 
 type DevicesService struct {
-	Adb          *adb.Adb
-	AdbProcessor processor.IProcessor
-	Logger       *zap.Logger
-	Ctx          context.Context
+	Adb               *adb.Adb
+	AdbProcessor      processor.IProcessor
+	CapabilityService capability_service.ICapabilityService
+	Logger            *zap.Logger
+	Ctx               context.Context
+
+	cpuSamples *common_service.CpuSamples
 }
 
-func NewDevicesService(adb *adb.Adb, adbProcessor processor.IProcessor, logger *zap.Logger, ctx context.Context) IDevicesService {
+func NewDevicesService(adb *adb.Adb, adbProcessor processor.IProcessor, capabilityService capability_service.ICapabilityService, logger *zap.Logger, ctx context.Context) IDevicesService {
 	return &DevicesService{
-		Adb:          adb,
-		AdbProcessor: adbProcessor,
-		Logger:       logger,
-		Ctx:          ctx,
+		Adb:               adb,
+		AdbProcessor:      adbProcessor,
+		CapabilityService: capabilityService,
+		Logger:            logger,
+		Ctx:               ctx,
+		cpuSamples:        common_service.NewCpuSamples(),
 	}
 }
 
@@ -72,11 +79,11 @@ func (d *DevicesService) DevicesListener(requestCtx context.Context, callback fu
 				if event.NewState == adb.StateOnline {
 					device, err := d.Adb.GetDeviceBySerial(event.Serial)
 					if err == nil {
-						if modelRequest, err := d.AdbProcessor.Run(device, command.GetDeviceModelCommand, false); err == nil {
-							deviceModel.Model = utils.GetResultFromRaw(modelRequest)
+						if modelName, err := processor.RunRaw(requestCtx, d.AdbProcessor, device, command.GetDeviceModelCommand, false); err == nil {
+							deviceModel.Model = modelName
 						}
-						if productRequest, err := d.AdbProcessor.Run(device, command.GetDeviceProductCommand, false); err == nil {
-							deviceModel.Product = utils.GetResultFromRaw(productRequest)
+						if productName, err := processor.RunRaw(requestCtx, d.AdbProcessor, device, command.GetDeviceProductCommand, false); err == nil {
+							deviceModel.Product = productName
 						}
 					}
 				}
@@ -89,166 +96,184 @@ func (d *DevicesService) DevicesListener(requestCtx context.Context, callback fu
 	}
 }
 
-func (d *DevicesService) getDeviceMemory(device *adb.Device) (*parser.Memory, error) {
-	defer logger.LogDuration(d.Logger, "GetDeviceInfo: get memory")()
-	deviceMemory, err := d.AdbProcessor.Run(device, command.GetDeviceMemoryCommand, false)
+// ListDevices returns every device known to the ADB server, whatever its
+// state, as printed by `adb devices -l`.
+func (d *DevicesService) ListDevices() ([]parser.AdbDevice, error) {
+	defer logger.LogDuration(d.Logger, "ListDevices")()
+	conn, err := d.Adb.Dial()
 	if err != nil {
-		d.Logger.Error("error parsing device memory", zap.Error(err))
-		return nil, err
-	}
-	if memory, ok := deviceMemory.(*parser.Memory); ok {
-		return memory, nil
+		d.Logger.Error("error connecting to adb server", zap.Error(err))
+		return nil, fmt.Errorf("%w: %w", adbErrors.ErrorAdbServerUnavailable, err)
 	}
-	return nil, fmt.Errorf("error parsing device memory")
-}
-
-func (d *DevicesService) getDeviceStorage(device *adb.Device) (*parser.Storage, error) {
-	defer logger.LogDuration(d.Logger, "GetDeviceInfo: get storage")()
-	deviceStorage, err := d.AdbProcessor.Run(device, command.GetDeviceStorageCommand, false)
+	defer func() {
+		if err := conn.Close(); err != nil {
+			d.Logger.Error("error closing adb server connection", zap.Error(err))
+		}
+	}()
+	rawDevices, err := conn.RoundTripSingleResponse([]byte("host:devices-l"))
 	if err != nil {
-		d.Logger.Error("error parsing device storage", zap.Error(err))
+		d.Logger.Error("error listing devices", zap.Error(err))
 		return nil, err
 	}
-	if storage, ok := deviceStorage.(*parser.Storage); ok {
-		return storage, nil
-	}
-	return nil, fmt.Errorf("error parsing device storage")
-}
-
-func (d *DevicesService) getDeviceBattery(device *adb.Device) (*parser.Battery, error) {
-	defer logger.LogDuration(d.Logger, "GetDeviceInfo: get battery")()
-	deviceBattery, err := d.AdbProcessor.Run(device, command.GetBatteryCommand, false)
-	if err != nil {
-		d.Logger.Error("error parsing device battery", zap.Error(err))
+	deviceList := &parser.DeviceList{}
+	if err := deviceList.Parse(string(rawDevices)); err != nil {
+		d.Logger.Error("error parsing device list", zap.Error(err))
 		return nil, err
 	}
-	if battery, ok := deviceBattery.(*parser.Battery); ok {
-		return battery, nil
-	}
-	return nil, fmt.Errorf("error parsing device battery")
+	return deviceList.Devices, nil
 }
 
-func (d *DevicesService) getDeviceProps(device *adb.Device) (*parser.DeviceProp, error) {
-	defer logger.LogDuration(d.Logger, "GetDeviceInfo: get props")()
-	deviceProps, err := d.AdbProcessor.Run(device, command.GetDevicePropCommand, false)
-	if err != nil {
-		d.Logger.Error("error parsing device props", zap.Error(err))
-		return nil, err
-	}
-	if props, ok := deviceProps.(*parser.DeviceProp); ok {
-		return props, nil
-	}
-	return nil, fmt.Errorf("error parsing device props")
+// deviceInfoCommands are read in a single shell session by GetDeviceInfo.
+var deviceInfoCommands = []command.AdbCommand{
+	command.GetDeviceMemoryCommand,
+	command.GetDeviceStorageCommand,
+	command.GetDevicePropCommand,
+	command.GetBatteryCommand,
+	command.GetRootCommand,
+	command.GetDeviceUptimeCommand,
+	command.GetKernelVersionCommand,
+	command.GetCpuStatCommand,
+	command.GetCpuFrequencyCommand,
+	command.GetThermalZonesCommand,
 }
 
-func (d *DevicesService) getDeviceRootInfo(device *adb.Device) (*parser.Root, error) {
-	defer logger.LogDuration(d.Logger, "GetDeviceInfo: get root info")()
-	root, err := d.AdbProcessor.RunWithRoot(device, command.GetRootCommand)
+// cpuSampleInterval is the time between the two reads of the CPU time taken
+// when the device info is asked for the first time.
+const cpuSampleInterval = 500 * time.Millisecond
+
+func (d *DevicesService) GetDeviceInfo(ctx context.Context, serial string) (*model.DeviceInfo, error) {
+	defer logger.LogDuration(d.Logger, "GetDeviceInfo")()
+	device, err := d.Adb.GetDeviceBySerial(serial)
+	if err != nil || device == nil {
+		d.Logger.Error("error getting device by serial", zap.String("serial", serial), zap.Error(err))
+		return nil, andromodemError.ErrorDeviceNotFound
+	}
+	results, err := d.AdbProcessor.RunBatch(ctx, device, deviceInfoCommands...)
 	if err != nil {
-		d.Logger.Error("error parsing root", zap.Error(err))
+		d.Logger.Error("error getting device info", zap.String("serial", serial), zap.Error(err))
 		return nil, err
 	}
-	if root, ok := root.(*parser.Root); ok {
-		return root, nil
+
+	deviceInfo := &model.DeviceInfo{}
+	for _, result := range results {
+		if result.Command == command.GetCpuStatCommand {
+			deviceInfo.Cpu.Usage = d.cpuUsage(ctx, device, serial, result)
+			continue
+		}
+		if err := setDeviceInfoResult(deviceInfo, result); err != nil {
+			d.Logger.Error("error parsing device info",
+				zap.String("serial", serial),
+				zap.String("command", string(result.Command)),
+				zap.Error(err))
+		}
 	}
-	return nil, fmt.Errorf("error parsing root")
-}
 
-func (d *DevicesService) getDeviceUptime(device *adb.Device) (*parser.DeviceUptime, error) {
-	defer logger.LogDuration(d.Logger, "GetDeviceInfo: get uptime")()
-	uptime, err := d.AdbProcessor.Run(device, command.GetDeviceUptimeCommand, false)
-	if err != nil {
-		d.Logger.Error("error parsing uptime", zap.Error(err))
-		return nil, err
+	// Asking su for access may show a prompt on the device, so it is only
+	// done once the device is known to be rooted.
+	if deviceInfo.Root.IsRooted {
+		if shellRootAccess, err := processor.RunWithRoot[*parser.RawParser](ctx, d.AdbProcessor, device, command.GetDeviceRootAccessCommand); err == nil {
+			deviceInfo.SuperUserAllowShellAccess = strings.Contains(shellRootAccess.Result, "1")
+		}
 	}
-	if uptime, ok := uptime.(*parser.DeviceUptime); ok {
-		return uptime, nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("error parsing uptime")
+	return deviceInfo, nil
 }
 
-func (d *DevicesService) GetDeviceInfo(serial string) (*model.DeviceInfo, error) {
-	defer logger.LogDuration(d.Logger, "GetDeviceInfo")()
-	device, err := d.Adb.GetDeviceBySerial(serial)
-	if err != nil || device == nil {
-		d.Logger.Error("error getting device by serial", zap.String("serial", serial), zap.Error(err))
-		return nil, andromodemError.ErrorDeviceNotFound
-	}
-	deviceInfo := &model.DeviceInfo{}
-	var wg sync.WaitGroup
-	wg.Add(7)
-	go func() {
-		defer wg.Done()
-		memory, err := d.getDeviceMemory(device)
+// setDeviceInfoResult stores the result of one of deviceInfoCommands.
+func setDeviceInfoResult(deviceInfo *model.DeviceInfo, result processor.BatchResult) error {
+	switch result.Command {
+	case command.GetDeviceMemoryCommand:
+		memory, err := processor.Result[*parser.Memory](result)
 		if err != nil {
-			d.Logger.Error("error parsing device memory", zap.String("serial", serial), zap.Error(err))
-			return
+			return err
 		}
 		deviceInfo.Memory = *memory
-	}()
-	go func() {
-		defer wg.Done()
-		storage, err := d.getDeviceStorage(device)
+	case command.GetDeviceStorageCommand:
+		storage, err := processor.Result[*parser.Storage](result)
 		if err != nil {
-			d.Logger.Error("error parsing device storage", zap.String("serial", serial), zap.Error(err))
-			return
+			return err
 		}
 		deviceInfo.Storage = *storage
-	}()
-	go func() {
-		defer wg.Done()
-		props, err := d.getDeviceProps(device)
+	case command.GetDevicePropCommand:
+		props, err := processor.Result[*parser.DeviceProp](result)
 		if err != nil {
-			d.Logger.Error("error parsing device props", zap.String("serial", serial), zap.Error(err))
-			return
+			return err
 		}
 		deviceInfo.DeviceProp = *props
-	}()
-	go func() {
-		defer wg.Done()
-		battery, err := d.getDeviceBattery(device)
+	case command.GetBatteryCommand:
+		battery, err := processor.Result[*parser.Battery](result)
 		if err != nil {
-			d.Logger.Error("error parsing device battery", zap.String("serial", serial), zap.Error(err))
-			return
+			return err
 		}
 		deviceInfo.Battery = *battery
-	}()
-	go func() {
-		defer wg.Done()
-		root, err := d.getDeviceRootInfo(device)
+	case command.GetRootCommand:
+		root, err := processor.Result[*parser.Root](result)
+		if errors.Is(err, adbErrors.ErrorNeedRoot) {
+			// su is not installed, the device is not rooted.
+			return nil
+		}
 		if err != nil {
-			d.Logger.Error("error parsing root", zap.String("serial", serial), zap.Error(err))
-			return
+			return err
 		}
 		deviceInfo.Root = *root
-		if root.IsRooted {
-			if shellRootAccess, err := d.AdbProcessor.RunWithRoot(device, command.GetDeviceRootAccessCommand); err == nil {
-				deviceInfo.SuperUserAllowShellAccess = strings.Contains(utils.GetResultFromRaw(shellRootAccess.(*parser.RawParser)), "1")
-			}
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		uptime, err := d.getDeviceUptime(device)
+	case command.GetDeviceUptimeCommand:
+		uptime, err := processor.Result[*parser.DeviceUptime](result)
 		if err != nil {
-			d.Logger.Error("error parsing device uptime", zap.String("serial", serial), zap.Error(err))
-			return
+			return err
 		}
 		deviceInfo.Uptime = uptime.Uptime
 		deviceInfo.UptimeSecond = uptime.UptimeSecond
-	}()
-	go func() {
-		defer logger.LogDuration(d.Logger, "GetDeviceInfo: get kernel version")()
-		defer wg.Done()
-		_kernelVersion, err := d.AdbProcessor.Run(device, command.GetKernelVersionCommand, false)
+	case command.GetKernelVersionCommand:
+		kernelVersion, err := processor.Result[*parser.RawParser](result)
 		if err != nil {
-			d.Logger.Error("error parsing device kernel version", zap.String("serial", serial), zap.Error(err))
-		} else {
-			deviceInfo.KernelVersion = utils.GetResultFromRaw(_kernelVersion)
+			return err
 		}
-	}()
-	wg.Wait()
-	return deviceInfo, err
+		deviceInfo.KernelVersion = kernelVersion.Result
+	case command.GetCpuFrequencyCommand:
+		frequency, err := processor.Result[*parser.CpuFrequency](result)
+		if err != nil {
+			return err
+		}
+		deviceInfo.Cpu.Frequencies = frequency.Cores
+	case command.GetThermalZonesCommand:
+		thermalZones, err := processor.Result[*parser.ThermalZones](result)
+		if err != nil {
+			return err
+		}
+		deviceInfo.ThermalZones = *thermalZones
+		if temperature, ok := thermalZones.CpuTemperature(); ok {
+			deviceInfo.Cpu.Temperature = &temperature
+		}
+	}
+	return nil
+}
+
+// cpuUsage returns the CPU utilisation since the device info was last asked
+// for. The first time, the CPU time is read again after cpuSampleInterval.
+func (d *DevicesService) cpuUsage(ctx context.Context, device *adb.Device, serial string, result processor.BatchResult) *parser.CpuUsage {
+	stat, err := processor.Result[*parser.CpuStat](result)
+	if err != nil {
+		d.Logger.Error("error parsing cpu time", zap.String("serial", serial), zap.Error(err))
+		return nil
+	}
+	if usage, ok := d.cpuSamples.Usage(serial, stat); ok {
+		return usage
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(cpuSampleInterval):
+	}
+	stat, err = processor.Run[*parser.CpuStat](ctx, d.AdbProcessor, device, command.GetCpuStatCommand, false)
+	if err != nil {
+		d.Logger.Error("error getting cpu time", zap.String("serial", serial), zap.Error(err))
+		return nil
+	}
+	usage, _ := d.cpuSamples.Usage(serial, stat)
+	return usage
 }
 
 func (d *DevicesService) makeFeature(name, key string, available bool, availableMessage, unavailableMessage string) model.FeatureAvailability {
@@ -264,81 +289,63 @@ func (d *DevicesService) makeFeature(name, key string, available bool, available
 	}
 }
 
-func (d *DevicesService) GetDeviceFeatureAvailabilities(serial string) (*model.FeatureAvailabilities, error) {
+func (d *DevicesService) GetDeviceFeatureAvailabilities(ctx context.Context, serial string) (*model.FeatureAvailabilities, error) {
 	defer logger.LogDuration(d.Logger, "GetDeviceFeatureAvailabilities")()
-	device, err := d.Adb.GetDeviceBySerial(serial)
+	capabilities, err := d.CapabilityService.GetCapabilities(ctx, serial)
 	if err != nil {
-		d.Logger.Error("error getting device by serial", zap.String("serial", serial), zap.Error(err))
-		return nil, andromodemError.ErrorDeviceNotFound
+		d.Logger.Error("error getting device capabilities", zap.String("serial", serial), zap.Error(err))
+		return nil, err
 	}
 
-	deviceSpec := &model.DeviceSpec{}
-
-	wg := sync.WaitGroup{}
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		rootInfo, err := common_service.GetDeviceRootAndAccessInfo(device, d.AdbProcessor, false)
-		if err != nil {
-			d.Logger.Error("error getting device root info", zap.String("serial", serial), zap.Error(err))
-			return
-		}
-		deviceSpec.Rooted = rootInfo.Rooted
-		deviceSpec.ShellAccess = rootInfo.ShellAccess
-	}()
-	go func() {
-		defer logger.LogDuration(d.Logger, "GetDeviceFeatureAvailabilities: get android version")()
-		defer wg.Done()
-		if androidVersion, err := common_service.GetAndroidVersion(device, d.AdbProcessor, true); err == nil {
-			deviceSpec.AndroidVersion = androidVersion
-		}
-	}()
-	wg.Wait()
 	features := []model.FeatureAvailability{
 		d.makeFeature(
 			"Can Read APN",
 			"can_read_apn",
-			deviceSpec.AndroidVersion >= command.MinimumAndroidShowApn,
-			"Available",
-			fmt.Sprintf("In Android %d or below, need root access", command.MinimumAndroidShowApn-1),
+			capabilities.Apn != model.AccessNone,
+			accessMessage(capabilities.Apn),
+			"Not accessible to the shell, need root access",
 		),
 		d.makeFeature(
 			"Can Change Airplane Mode Status",
 			"can_change_airplane_mode_status",
-			deviceSpec.AndroidVersion >= command.MinimumAndroidToggleAirplaneMode,
-			"Available",
-			fmt.Sprintf("In Android %d or below, use broadcast command to change airplane mode status and need root access", command.MinimumAndroidToggleAirplaneMode-1),
+			capabilities.CanToggleAirplaneMode(),
+			airplaneModeMessage(capabilities),
+			"Airplane mode command is not supported, use broadcast command to change airplane mode status and need root access",
 		),
 		d.makeFeature(
 			"Can Change Sim Data Status",
 			"can_change_sim_data_status",
-			deviceSpec.AndroidVersion >= command.MinimumAndroidToggleMobileData,
-			"Available (requires further verification on the actual device)", fmt.Sprintf("Only available in Android %d or below", command.MinimumAndroidToggleMobileData-1),
+			capabilities.SvcData,
+			"Available", "svc data command is not supported",
 		),
 		d.makeFeature(
 			"Can Read Inbox",
 			"can_read_inbox",
-			deviceSpec.AndroidVersion >= command.MinimumAndroidShowMessages,
-			"Available",
-			fmt.Sprintf("In Android %d or below, need root access", command.MinimumAndroidShowMessages-1),
+			capabilities.Sms != model.AccessNone,
+			accessMessage(capabilities.Sms),
+			"Not accessible to the shell, need root access",
 		),
 		d.makeFeature(
 			"Can Read Sim Signal Strength",
 			"can_read_sim_signal_strength",
-			deviceSpec.AndroidVersion >= command.MinimumAndroidGetSignalStrength,
+			capabilities.AndroidVersion >= command.MinimumAndroidGetSignalStrength,
 			"Available",
 			fmt.Sprintf("Only available in Android %d or above", command.MinimumAndroidGetSignalStrength),
 		),
-	}
-
-	if deviceSpec.ShellAccess {
-		for i := range features {
-			switch features[i].Key {
-			case "can_read_apn", "can_change_airplane_mode_status", "can_read_inbox":
-				features[i].Available = true
-				features[i].Message = ""
-			}
-		}
+		d.makeFeature(
+			"Can Ping",
+			"can_ping",
+			capabilities.Ping,
+			"Available",
+			"ping is not installed",
+		),
+		d.makeFeature(
+			"Has Busybox",
+			"has_busybox",
+			capabilities.Busybox,
+			"Available",
+			"busybox is not installed",
+		),
 	}
 
 	FeatureAvailabilities := &model.FeatureAvailabilities{
@@ -347,7 +354,21 @@ func (d *DevicesService) GetDeviceFeatureAvailabilities(serial string) (*model.F
 	return FeatureAvailabilities, nil
 }
 
-func (d *DevicesService) DevicePower(serial string, powerAction PowerAction) error {
+func accessMessage(access model.Access) string {
+	if access == model.AccessRoot {
+		return "Available with root access"
+	}
+	return "Available"
+}
+
+func airplaneModeMessage(capabilities *model.DeviceCapabilities) string {
+	if capabilities.AirplaneModeCommand {
+		return "Available"
+	}
+	return "Available with root access, use broadcast command to change airplane mode status"
+}
+
+func (d *DevicesService) DevicePower(ctx context.Context, serial string, powerAction PowerAction) error {
 	defer logger.LogDuration(d.Logger, "DevicePower")()
 	device, err := d.Adb.GetDeviceBySerial(serial)
 	if err != nil {
@@ -365,8 +386,8 @@ func (d *DevicesService) DevicePower(serial string, powerAction PowerAction) err
 	case RebootBootloader:
 		powerCommand = command.RebootBootloaderCommand
 	}
-	output, err := d.AdbProcessor.Run(device, powerCommand, false)
-	if strings.TrimSpace(utils.GetResultFromRaw(output)) != "" {
+	output, err := processor.RunRaw(ctx, d.AdbProcessor, device, powerCommand, false)
+	if strings.TrimSpace(output) != "" {
 		d.Logger.Error("error execute power action", zap.String("serial", serial), zap.String("action", string(powerAction)), zap.Error(err))
 		return andromodemError.ErrorDevicePowerAction
 	}
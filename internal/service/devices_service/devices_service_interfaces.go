@@ -4,11 +4,13 @@ import (
 	"context"
 
 	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
 )
 
 type IDevicesService interface {
 	DevicesListener(context.Context, func(*model.Device) error) error
-	GetDeviceInfo(string) (*model.DeviceInfo, error)
-	GetDeviceFeatureAvailabilities(string) (*model.FeatureAvailabilities, error)
-	DevicePower(string, PowerAction) error
+	ListDevices() ([]parser.AdbDevice, error)
+	GetDeviceInfo(context.Context, string) (*model.DeviceInfo, error)
+	GetDeviceFeatureAvailabilities(context.Context, string) (*model.FeatureAvailabilities, error)
+	DevicePower(context.Context, string, PowerAction) error
 }
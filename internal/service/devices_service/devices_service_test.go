@@ -0,0 +1,165 @@
+package devices_service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
+	"github.com/basiooo/andromodem/internal/service/devices_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture/fixturetest"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	"github.com/basiooo/andromodem/pkg/simulator"
+	adb "github.com/basiooo/goadb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func newReplayedService(t *testing.T) devices_service.IDevicesService {
+	logger := zaptest.NewLogger(t)
+	adbClient, adbProcessor := fixturetest.NewReplayedClient(t)
+	capabilityService, err := capability_service.NewCapabilityService(adbClient, adbProcessor, logger, "")
+	require.NoError(t, err)
+	return devices_service.NewDevicesService(adbClient, adbProcessor, capabilityService, logger, context.Background())
+}
+
+func TestGetDeviceInfoReplayed(t *testing.T) {
+	t.Parallel()
+	service := newReplayedService(t)
+
+	tests := []struct {
+		serial         string
+		model          string
+		androidVersion string
+		batteryLevel   int
+		rooted         bool
+		shellAccess    bool
+		cores          int
+		cpuTemperature float64
+	}{
+		{serial: "R58R31ABCDE", model: "SM-A525F", androidVersion: "13", batteryLevel: 100, cores: 8, cpuTemperature: 48.6},
+		// Half of the cores are offline, the tsens driver reports whole degrees.
+		{serial: "8d1c2a3f", model: "Redmi Note 7", androidVersion: "9", batteryLevel: 73, rooted: true, shellAccess: true, cores: 4, cpuTemperature: 57},
+		{serial: "2A111FDH200ABC", model: "Pixel 7", androidVersion: "14", batteryLevel: 64, cores: 8, cpuTemperature: 46.312},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			t.Parallel()
+			deviceInfo, err := service.GetDeviceInfo(context.Background(), tt.serial)
+			require.NoError(t, err)
+			assert.Equal(t, tt.model, deviceInfo.DeviceProp.Model)
+			assert.Equal(t, tt.androidVersion, deviceInfo.AndroidVersion)
+			assert.Equal(t, tt.batteryLevel, int(deviceInfo.Battery.Level))
+			assert.Equal(t, tt.rooted, deviceInfo.Root.IsRooted)
+			assert.Equal(t, tt.shellAccess, deviceInfo.SuperUserAllowShellAccess)
+			assert.NotZero(t, deviceInfo.Memory.MemTotal)
+			assert.NotZero(t, deviceInfo.Storage.DataTotal)
+			assert.NotZero(t, deviceInfo.UptimeSecond)
+			assert.NotEmpty(t, deviceInfo.KernelVersion)
+			assert.Len(t, deviceInfo.Cpu.Frequencies, tt.cores)
+			assert.NotEmpty(t, deviceInfo.ThermalZones.Zones)
+			require.NotNil(t, deviceInfo.Cpu.Temperature)
+			assert.Equal(t, tt.cpuTemperature, *deviceInfo.Cpu.Temperature)
+			// The replayed CPU time does not change between reads.
+			assert.Nil(t, deviceInfo.Cpu.Usage)
+		})
+	}
+}
+
+func TestGetDeviceInfoCpuUsageSimulated(t *testing.T) {
+	t.Parallel()
+	fixtures, err := fixture.Profiles()
+	require.NoError(t, err)
+	logger := zaptest.NewLogger(t)
+	service := devices_service.NewDevicesService(&adb.Adb{Server: simulator.New(fixtures...)}, processor.NewProcessor(logger), nil, logger, context.Background())
+
+	// The CPU time is read twice the first time, once later on.
+	for range 2 {
+		deviceInfo, err := service.GetDeviceInfo(context.Background(), "2A111FDH200ABC")
+		require.NoError(t, err)
+		require.NotNil(t, deviceInfo.Cpu.Usage)
+		assert.Equal(t, 25.0, deviceInfo.Cpu.Usage.Usage)
+		assert.Len(t, deviceInfo.Cpu.Usage.Cores, 8)
+	}
+}
+
+func TestGetDeviceFeatureAvailabilitiesReplayed(t *testing.T) {
+	t.Parallel()
+	service := newReplayedService(t)
+
+	// Android 9 cannot read signal strength, root makes up for the APN and
+	// the inbox.
+	features, err := service.GetDeviceFeatureAvailabilities(context.Background(), "8d1c2a3f")
+	require.NoError(t, err)
+	available := map[string]bool{}
+	for _, feature := range features.FeatureAvailabilities {
+		available[feature.Key] = feature.Available
+	}
+	assert.Equal(t, map[string]bool{
+		"can_read_apn":                    true,
+		"can_change_airplane_mode_status": true,
+		"can_change_sim_data_status":      true,
+		"can_read_inbox":                  true,
+		"can_read_sim_signal_strength":    false,
+		"can_ping":                        true,
+		"has_busybox":                     true,
+	}, available)
+}
+
+func TestGetDeviceInfoUnknownDevice(t *testing.T) {
+	t.Parallel()
+	service := newReplayedService(t)
+
+	_, err := service.GetDeviceInfo(context.Background(), "unknown")
+	assert.ErrorIs(t, err, andromodemError.ErrorDeviceNotFound)
+}
+
+func TestDevicesListenerSimulated(t *testing.T) {
+	t.Parallel()
+	fixtures, err := fixture.Profiles()
+	require.NoError(t, err)
+	sim := simulator.New(fixtures...)
+	logger := zaptest.NewLogger(t)
+	service := devices_service.NewDevicesService(&adb.Adb{Server: sim}, processor.NewProcessor(logger), nil, logger, context.Background())
+
+	events := make(chan *model.Device, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = service.DevicesListener(ctx, func(device *model.Device) error {
+			events <- device
+			return nil
+		})
+	}()
+	next := func() *model.Device {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no device event")
+			return nil
+		}
+	}
+
+	models := map[string]string{}
+	for range fixtures {
+		event := next()
+		models[event.Serial] = event.Model
+	}
+	assert.Equal(t, "Pixel 7", models["2A111FDH200ABC"])
+
+	require.NoError(t, sim.SetState("8d1c2a3f", adb.StateDisconnected))
+	event := next()
+	assert.Equal(t, "8d1c2a3f", event.Serial)
+	assert.Equal(t, adb.StateDisconnected.String(), event.NewState)
+
+	require.NoError(t, sim.SetState("8d1c2a3f", adb.StateOnline))
+	event = next()
+	assert.Equal(t, adb.StateOnline.String(), event.NewState)
+	assert.Equal(t, "Redmi Note 7", event.Model)
+	assert.Equal(t, "lavender", event.Product)
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 
 	andromodemError "github.com/basiooo/andromodem/internal/errors"
-	"github.com/basiooo/andromodem/internal/service/common_service"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
 	"github.com/basiooo/andromodem/pkg/adb_processor/command"
 	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
 	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
@@ -16,22 +17,24 @@ import (
 )
 
 type MessagesService struct {
-	Adb          *adb.Adb
-	AdbProcessor processor.IProcessor
-	Logger       *zap.Logger
-	Ctx          context.Context
+	Adb               *adb.Adb
+	AdbProcessor      processor.IProcessor
+	CapabilityService capability_service.ICapabilityService
+	Logger            *zap.Logger
+	Ctx               context.Context
 }
 
-func NewMessagesService(adb *adb.Adb, adbProcessor processor.IProcessor, logger *zap.Logger, ctx context.Context) IMessagesService {
+func NewMessagesService(adb *adb.Adb, adbProcessor processor.IProcessor, capabilityService capability_service.ICapabilityService, logger *zap.Logger, ctx context.Context) IMessagesService {
 	return &MessagesService{
-		Adb:          adb,
-		AdbProcessor: adbProcessor,
-		Logger:       logger,
-		Ctx:          ctx,
+		Adb:               adb,
+		AdbProcessor:      adbProcessor,
+		CapabilityService: capabilityService,
+		Logger:            logger,
+		Ctx:               ctx,
 	}
 }
 
-func (m *MessagesService) GetMessages(serial string) (*parser.Inbox, error) {
+func (m *MessagesService) GetMessages(ctx context.Context, serial string) (*parser.Inbox, error) {
 	defer logger.LogDuration(m.Logger, "GetMessages")()
 
 	device, err := m.Adb.GetDeviceBySerial(serial)
@@ -43,40 +46,35 @@ func (m *MessagesService) GetMessages(serial string) (*parser.Inbox, error) {
 		return nil, andromodemError.ErrorDeviceNotFound
 	}
 
-	needRoot := false
-	if androidVersion, err := common_service.GetAndroidVersion(device, m.AdbProcessor, true); err == nil {
-		needRoot = androidVersion < command.MinimumAndroidShowMessages
+	capabilities, err := m.CapabilityService.GetCapabilities(ctx, serial)
+	if err != nil {
+		m.Logger.Error("error getting device capabilities",
+			zap.String("serial", serial),
+			zap.Error(err),
+		)
+		return nil, err
 	}
 
-	var inbox parser.IParser
+	var inbox *parser.Inbox
 
-	if needRoot {
-		rootInfo, err := common_service.GetDeviceRootAndAccessInfo(device, m.AdbProcessor, false)
-		if err != nil {
-			m.Logger.Error("inbox need root failed get root info",
-				zap.String("serial", serial),
-				zap.Error(err),
-			)
-			return nil, err
-		}
-		if rootInfo == nil || !rootInfo.Rooted || !rootInfo.ShellAccess {
-			m.Logger.Error("inbox need root but root info is invalid or insufficient access",
-				zap.String("serial", serial),
-			)
-			return nil, adbErrors.ErrorNeedRoot
-		}
-
-		inbox, _ = m.AdbProcessor.RunWithRoot(device, command.GetInboxCommand)
-	} else {
-		inbox, err = m.AdbProcessor.Run(device, command.GetInboxCommand, true)
-		if err != nil {
-			m.Logger.Error("error parsing inbox",
-				zap.String("serial", serial),
-				zap.Error(err),
-			)
-			return nil, err
-		}
+	switch capabilities.Sms {
+	case model.AccessShell:
+		inbox, err = processor.Run[*parser.Inbox](ctx, m.AdbProcessor, device, command.GetInboxCommand, true)
+	case model.AccessRoot:
+		inbox, err = processor.RunWithRoot[*parser.Inbox](ctx, m.AdbProcessor, device, command.GetInboxCommand)
+	default:
+		m.Logger.Error("inbox is not accessible to the shell and su grants no access",
+			zap.String("serial", serial),
+		)
+		return nil, adbErrors.ErrorNeedRoot
+	}
+	if err != nil {
+		m.Logger.Error("error parsing inbox",
+			zap.String("serial", serial),
+			zap.Error(err),
+		)
+		return nil, err
 	}
 
-	return inbox.(*parser.Inbox), nil
+	return inbox, nil
 }
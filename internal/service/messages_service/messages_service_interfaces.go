@@ -1,9 +1,11 @@
 package messages_service
 
 import (
+	"context"
+
 	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
 )
 
 type IMessagesService interface {
-	GetMessages(string) (*parser.Inbox, error)
+	GetMessages(context.Context, string) (*parser.Inbox, error)
 }
@@ -0,0 +1,45 @@
+package messages_service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/basiooo/andromodem/internal/service/capability_service"
+	"github.com/basiooo/andromodem/internal/service/messages_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture/fixturetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestGetMessagesReplayed(t *testing.T) {
+	t.Parallel()
+	logger := zaptest.NewLogger(t)
+	adbClient, adbProcessor := fixturetest.NewReplayedClient(t)
+	capabilityService, err := capability_service.NewCapabilityService(adbClient, adbProcessor, logger, "")
+	require.NoError(t, err)
+	service := messages_service.NewMessagesService(adbClient, adbProcessor, capabilityService, logger, context.Background())
+
+	tests := []struct {
+		name      string
+		serial    string
+		addresses []string
+	}{
+		{name: "samsung", serial: "R58R31ABCDE", addresses: []string{"Telkomsel", "XL-Axiata"}},
+		// The shell of this Android 9 build is denied the inbox, su is not.
+		{name: "xiaomi", serial: "8d1c2a3f", addresses: []string{"Indosat"}},
+		{name: "pixel", serial: "2A111FDH200ABC", addresses: []string{"456"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			inbox, err := service.GetMessages(context.Background(), tt.serial)
+			require.NoError(t, err)
+			addresses := make([]string, len(inbox.Messages))
+			for i, message := range inbox.Messages {
+				addresses[i] = message.Address
+			}
+			assert.Equal(t, tt.addresses, addresses)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package metrics_service
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/service/capability_service"
+	"github.com/basiooo/andromodem/internal/service/common_service"
+	"github.com/basiooo/andromodem/internal/service/monitoring_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	"github.com/basiooo/andromodem/pkg/logger"
+	"github.com/basiooo/andromodem/pkg/metrics"
+	adb "github.com/basiooo/goadb"
+	"go.uber.org/zap"
+)
+
+type deviceSnapshot struct {
+	Serial       string
+	Model        string
+	Product      string
+	Up           bool
+	Battery      *parser.Battery
+	Memory       *parser.Memory
+	Storage      *parser.Storage
+	AirplaneMode *bool
+	Sims         []parser.Sim
+}
+
+// MetricsService collects device and monitoring telemetry for the metrics
+// endpoint. Device values are read through ADB at most once per MinInterval,
+// scrapes in between are answered from the last snapshots. Monitoring values
+// are kept in memory by the worker and are always current.
+type MetricsService struct {
+	Adb               *adb.Adb
+	AdbProcessor      processor.IProcessor
+	CapabilityService capability_service.ICapabilityService
+	MonitoringService monitoring_service.IMonitoringService
+	Logger            *zap.Logger
+	Ctx               context.Context
+	MinInterval       time.Duration
+
+	collectMutex sync.Mutex
+	collectedAt  time.Time
+	adbUp        bool
+	snapshots    []*deviceSnapshot
+}
+
+func NewMetricsService(adb *adb.Adb, adbProcessor processor.IProcessor, capabilityService capability_service.ICapabilityService, monitoringService monitoring_service.IMonitoringService, logger *zap.Logger, ctx context.Context, minInterval time.Duration) IMetricsService {
+	return &MetricsService{
+		Adb:               adb,
+		AdbProcessor:      adbProcessor,
+		CapabilityService: capabilityService,
+		MonitoringService: monitoringService,
+		Logger:            logger,
+		Ctx:               ctx,
+		MinInterval:       minInterval,
+	}
+}
+
+// Collect returns the current metric families. Concurrent scrapes wait for
+// a running collection and share its result.
+func (m *MetricsService) Collect() ([]*metrics.Family, error) {
+	if err := m.Ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.collectMutex.Lock()
+	defer m.collectMutex.Unlock()
+
+	if m.collectedAt.IsZero() || time.Since(m.collectedAt) >= m.MinInterval {
+		m.refreshDevices()
+		m.collectedAt = time.Now()
+	}
+
+	families := m.deviceFamilies()
+	families = append(families, m.monitoringFamilies()...)
+
+	lastCollect := metrics.NewGauge("andromodem_metrics_last_collect_timestamp_seconds", "Unix time of the last device collection.")
+	lastCollect.Add(float64(m.collectedAt.UnixNano()) / float64(time.Second))
+	families = append(families, lastCollect)
+	return families, nil
+}
+
+func (m *MetricsService) refreshDevices() {
+	defer logger.LogDuration(m.Logger, "MetricsService: refresh devices")()
+	if m.Adb == nil {
+		m.adbUp = false
+		m.snapshots = nil
+		return
+	}
+	devices, err := m.Adb.ListDevices()
+	if err != nil {
+		m.Logger.Error("[Metrics] Failed to list devices", zap.Error(err))
+		m.adbUp = false
+		m.snapshots = nil
+		return
+	}
+	m.adbUp = true
+
+	snapshots := make([]*deviceSnapshot, len(devices))
+	var wg sync.WaitGroup
+	for i, deviceInfo := range devices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			snapshots[i] = m.collectDevice(deviceInfo)
+		}()
+	}
+	wg.Wait()
+	m.snapshots = snapshots
+}
+
+// collectDevice reads one device. The values come from the same commands the
+// device and network pages use, so a scrape costs no more than a page view.
+// The result is shared by concurrent scrapes, so it is bound to the
+// application context rather than to the scrape that started it.
+func (m *MetricsService) collectDevice(deviceInfo *adb.DeviceInfo) *deviceSnapshot {
+	ctx := m.Ctx
+	snapshot := &deviceSnapshot{
+		Serial:  deviceInfo.Serial,
+		Model:   deviceInfo.Model,
+		Product: deviceInfo.Product,
+	}
+	device, err := m.Adb.GetDeviceBySerial(deviceInfo.Serial)
+	if err != nil || device == nil {
+		m.Logger.Warn("[Metrics] Device not reachable", zap.String("serial", deviceInfo.Serial), zap.Error(err))
+		return snapshot
+	}
+
+	if battery, err := processor.Run[*parser.Battery](ctx, m.AdbProcessor, device, command.GetBatteryCommand, false); err == nil {
+		snapshot.Battery = battery
+	}
+	if memory, err := processor.Run[*parser.Memory](ctx, m.AdbProcessor, device, command.GetDeviceMemoryCommand, false); err == nil {
+		snapshot.Memory = memory
+	}
+	if storage, err := processor.Run[*parser.Storage](ctx, m.AdbProcessor, device, command.GetDeviceStorageCommand, false); err == nil {
+		snapshot.Storage = storage
+	}
+	snapshot.Up = snapshot.Battery != nil || snapshot.Memory != nil || snapshot.Storage != nil
+	if !snapshot.Up {
+		m.Logger.Warn("[Metrics] No telemetry collected from device", zap.String("serial", deviceInfo.Serial))
+		return snapshot
+	}
+
+	airplaneModeCommand := command.GetAirplaneModeStatusNewCommand
+	if capabilities, err := m.CapabilityService.GetCapabilities(ctx, deviceInfo.Serial); err != nil {
+		m.Logger.Debug("[Metrics] Failed to get device capabilities", zap.String("serial", deviceInfo.Serial), zap.Error(err))
+	} else if !capabilities.AirplaneModeCommand {
+		airplaneModeCommand = command.GetAirplaneModeStatusLegacyCommand
+	}
+	if airplaneMode, err := processor.Run[*parser.AirplaneModeState](ctx, m.AdbProcessor, device, airplaneModeCommand, false); err == nil {
+		snapshot.AirplaneMode = &airplaneMode.Enabled
+	}
+
+	sims, err := common_service.GetDeviceSims(ctx, device, m.AdbProcessor)
+	if err != nil {
+		m.Logger.Error("[Metrics] Failed to get sims", zap.String("serial", deviceInfo.Serial), zap.Error(err))
+		return snapshot
+	}
+	snapshot.Sims = sims
+	return snapshot
+}
+
+func (m *MetricsService) deviceFamilies() []*metrics.Family {
+	adbUp := metrics.NewGauge("andromodem_adb_up", "Whether the ADB server answered the last device listing.")
+	adbUp.Add(boolValue(m.adbUp))
+
+	deviceInfo := metrics.NewGauge("andromodem_device_info", "Connected device, the value is always 1.")
+	deviceUp := metrics.NewGauge("andromodem_device_up", "Whether telemetry could be read from the device.")
+	batteryLevel := metrics.NewGauge("andromodem_battery_level_percent", "Battery level in percent.")
+	batteryTemperature := metrics.NewGauge("andromodem_battery_temperature_celsius", "Battery temperature in degrees Celsius.")
+	memoryTotal := metrics.NewGauge("andromodem_memory_total_bytes", "Total memory in bytes.")
+	memoryUsed := metrics.NewGauge("andromodem_memory_used_bytes", "Used memory in bytes.")
+	storageTotal := metrics.NewGauge("andromodem_storage_total_bytes", "Total size of a storage partition in bytes.")
+	storageUsed := metrics.NewGauge("andromodem_storage_used_bytes", "Used size of a storage partition in bytes.")
+	airplaneMode := metrics.NewGauge("andromodem_airplane_mode_enabled", "Whether airplane mode is enabled.")
+	mobileData := metrics.NewGauge("andromodem_mobile_data_connected", "Whether any SIM has a connected mobile data connection.")
+	simDataState := metrics.NewGauge("andromodem_sim_data_state", "Mobile data state of a SIM: -1 unknown, 0 disconnected, 1 connecting, 2 connected, 3 suspended.")
+	signalRsrp := metrics.NewGauge("andromodem_signal_rsrp_dbm", "Reference signal received power in dBm.")
+	signalRsrq := metrics.NewGauge("andromodem_signal_rsrq_db", "Reference signal received quality in dB.")
+	signalSinr := metrics.NewGauge("andromodem_signal_sinr_db", "Signal to interference plus noise ratio in dB.")
+
+	for _, snapshot := range m.snapshots {
+		serial := snapshot.Serial
+		deviceInfo.Add(1, "serial", serial, "model", snapshot.Model, "product", snapshot.Product)
+		deviceUp.Add(boolValue(snapshot.Up), "serial", serial)
+
+		if battery := snapshot.Battery; battery != nil {
+			level := float64(battery.Level)
+			if battery.Scale > 0 {
+				level = level * 100 / float64(battery.Scale)
+			}
+			batteryLevel.Add(level, "serial", serial)
+			batteryTemperature.Add(battery.Temperature, "serial", serial)
+		}
+		if memory := snapshot.Memory; memory != nil {
+			memoryTotal.Add(kilobytes(memory.MemTotal), "serial", serial)
+			memoryUsed.Add(kilobytes(memory.MemUsed), "serial", serial)
+		}
+		if storage := snapshot.Storage; storage != nil {
+			storageTotal.Add(kilobytes(storage.DataTotal), "serial", serial, "partition", "data")
+			storageUsed.Add(kilobytes(storage.DataUsed), "serial", serial, "partition", "data")
+			storageTotal.Add(kilobytes(storage.SystemTotal), "serial", serial, "partition", "system")
+			storageUsed.Add(kilobytes(storage.SystemUsed), "serial", serial, "partition", "system")
+		}
+		if snapshot.AirplaneMode != nil {
+			airplaneMode.Add(boolValue(*snapshot.AirplaneMode), "serial", serial)
+		}
+		if snapshot.Sims == nil {
+			continue
+		}
+
+		connected := false
+		for _, sim := range snapshot.Sims {
+			slot := strconv.Itoa(int(sim.SimSlot))
+			state := dataState(sim.ConnectionState)
+			connected = connected || state == parser.DataConnected
+			simDataState.Add(float64(state), "serial", serial, "sim_slot", slot, "operator", sim.Name)
+
+			if lte := sim.CellSignalStrengthLte; lte != nil {
+				addSignal(signalRsrp, lte.Rsrp, serial, slot, sim.Name, "lte")
+				addSignal(signalRsrq, lte.Rsrq, serial, slot, sim.Name, "lte")
+				addSignal(signalSinr, lte.Rssnr, serial, slot, sim.Name, "lte")
+			}
+			if nr := sim.CellSignalStrengthNr; nr != nil {
+				addSignal(signalRsrp, nr.SsRsrp, serial, slot, sim.Name, "nr")
+				addSignal(signalRsrq, nr.SsRsrq, serial, slot, sim.Name, "nr")
+				addSignal(signalSinr, nr.SsSinr, serial, slot, sim.Name, "nr")
+			}
+		}
+		mobileData.Add(boolValue(connected), "serial", serial)
+	}
+
+	return []*metrics.Family{
+		adbUp, deviceInfo, deviceUp,
+		batteryLevel, batteryTemperature,
+		memoryTotal, memoryUsed, storageTotal, storageUsed,
+		airplaneMode, mobileData, simDataState,
+		signalRsrp, signalRsrq, signalSinr,
+	}
+}
+
+func (m *MetricsService) monitoringFamilies() []*metrics.Family {
+	if m.MonitoringService == nil {
+		return nil
+	}
+	running := metrics.NewGauge("andromodem_monitoring_running", "Whether the monitoring task of the device is running.")
+	consecutiveFailures := metrics.NewGauge("andromodem_monitoring_consecutive_failures", "Failed checks since the last successful one.")
+	checks := metrics.NewCounter("andromodem_monitoring_checks_total", "Connectivity checks performed since the task was started.")
+	failures := metrics.NewCounter("andromodem_monitoring_failures_total", "Failed connectivity checks since the task was started.")
+	recoveryActions := metrics.NewCounter("andromodem_monitoring_recovery_actions_total", "Recovery actions performed since the task was started.")
+	lastLatency := metrics.NewGauge("andromodem_monitoring_last_latency_seconds", "Duration of the last successful connectivity check.")
+	lastCheck := metrics.NewGauge("andromodem_monitoring_last_check_timestamp_seconds", "Unix time of the last connectivity check.")
+
+	for _, status := range m.MonitoringService.GetAllMonitoringStatuses() {
+		serial := status.Serial
+		running.Add(boolValue(status.IsRunning), "serial", serial)
+		consecutiveFailures.Add(float64(status.FailureCount), "serial", serial)
+		checks.Add(float64(status.TotalChecks), "serial", serial)
+		failures.Add(float64(status.TotalFailures), "serial", serial)
+		recoveryActions.Add(float64(status.RecoveryActions), "serial", serial)
+		if status.LastSuccess {
+			lastLatency.Add(float64(status.LastLatencyMs)/1000, "serial", serial)
+		}
+		if !status.LastPingTime.IsZero() {
+			lastCheck.Add(float64(status.LastPingTime.Unix()), "serial", serial)
+		}
+	}
+
+	return []*metrics.Family{running, consecutiveFailures, checks, failures, recoveryActions, lastLatency, lastCheck}
+}
+
+func addSignal(family *metrics.Family, raw string, serial string, slot string, operator string, rat string) {
+	value, ok := parser.SignalValue(raw)
+	if !ok {
+		return
+	}
+	family.Add(float64(value), "serial", serial, "sim_slot", slot, "operator", operator, "rat", rat)
+}
+
+func dataState(connectionState string) parser.State {
+	for _, state := range []parser.State{parser.DataDisconnected, parser.DataConnecting, parser.DataConnected, parser.DataSuspended} {
+		if state.String() == connectionState {
+			return state
+		}
+	}
+	return parser.DataUnknown
+}
+
+func kilobytes(value int) float64 {
+	return float64(value) * 1024
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,7 @@
+package metrics_service
+
+import "github.com/basiooo/andromodem/pkg/metrics"
+
+type IMetricsService interface {
+	Collect() ([]*metrics.Family, error)
+}
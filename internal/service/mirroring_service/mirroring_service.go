@@ -3,6 +3,7 @@ package mirroring_service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -381,3 +382,24 @@ func (m *MirroringService) GetClient(serial string) *scrcpy.Client {
 	defer m.mutex.RUnlock()
 	return m.clients[serial]
 }
+
+// Shutdown removes the adb port forwards of every running mirroring session so
+// they are not left behind on the device.
+func (m *MirroringService) Shutdown(ctx context.Context) error {
+	m.mutex.Lock()
+	clients := m.clients
+	m.clients = make(map[string]*scrcpy.Client)
+	m.mutex.Unlock()
+
+	var errs []error
+	for serial, client := range clients {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		m.Logger.Info("Stopping scrcpy mirroring on shutdown", zap.String("serial", serial))
+		if err := client.Cleanup(); err != nil {
+			errs = append(errs, fmt.Errorf("cleanup mirroring %s: %w", serial, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -16,4 +16,5 @@ type IMirroringService interface {
 	SendKeyPress(string, scrcpy.AndroidKeyCode) error
 	HandleControlMessage(string, []byte)
 	GetClient(string) *scrcpy.Client
+	Shutdown(context.Context) error
 }
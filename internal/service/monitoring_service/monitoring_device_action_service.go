@@ -1,6 +1,7 @@
 package monitoring_service
 
 import (
+	"context"
 	"time"
 
 	network_service "github.com/basiooo/andromodem/internal/service/network"
@@ -38,9 +39,9 @@ func (s *MonitoringDeviceActionService) IsDeviceOnline(serial string) bool {
 	return state == adb.StateOnline
 }
 
-func (s *MonitoringDeviceActionService) PerformRestartAction(serial string, airplaneModeDelay int) error {
+func (s *MonitoringDeviceActionService) PerformRestartAction(ctx context.Context, serial string, airplaneModeDelay int) error {
 	s.logger.Info("Performing restart action: toggle mobile data", zap.String("serial", serial))
-	first_state, err := s.networkService.ToggleAirplaneMode(serial)
+	first_state, err := s.networkService.ToggleAirplaneMode(ctx, serial)
 	if err != nil {
 		s.logger.Error("Failed to toggle airplane mode", zap.String("serial", serial), zap.Error(err))
 		s.logService.WriteLog(serial, false, "Failed to enable airplane mode during restart action")
@@ -52,9 +53,13 @@ func (s *MonitoringDeviceActionService) PerformRestartAction(serial string, airp
 
 	if first_state == nil || *first_state {
 		if airplaneModeDelay > 0 {
-			time.Sleep(time.Duration(airplaneModeDelay) * time.Second)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(airplaneModeDelay) * time.Second):
+			}
 		}
-		second_state, err := s.networkService.ToggleAirplaneMode(serial)
+		second_state, err := s.networkService.ToggleAirplaneMode(ctx, serial)
 		if err != nil {
 			s.logger.Error("Failed to toggle airplane mode", zap.String("serial", serial), zap.Error(err))
 			s.logService.WriteLog(serial, false, "Failed to disable airplane mode during restart action")
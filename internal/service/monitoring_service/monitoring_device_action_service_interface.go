@@ -1,6 +1,8 @@
 package monitoring_service
 
+import "context"
+
 type IDeviceActionService interface {
-	PerformRestartAction(string, int) error
+	PerformRestartAction(context.Context, string, int) error
 	IsDeviceOnline(string) bool
 }
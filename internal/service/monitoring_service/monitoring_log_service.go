@@ -89,19 +89,13 @@ func (s *MonitoringLogService) WriteLog(serial string, success bool, message str
 	s.writeBufferToFile(serial)
 }
 
+// Shutdown stops every log listener. Listeners observe the cancelled context
+// and unregister themselves, so their channels are never closed while
+// NotifyNewLog may still send to them.
 func (s *MonitoringLogService) Shutdown() {
 	s.cancel()
 	s.wg.Wait()
 
-	s.listenerMutex.Lock()
-	for serial, listeners := range s.logListeners {
-		for _, ch := range listeners {
-			close(ch)
-		}
-		delete(s.logListeners, serial)
-	}
-	s.listenerMutex.Unlock()
-
 	s.writeMutex.Lock()
 	for serial, item := range s.writeQueue {
 		item.writeCancel()
@@ -272,7 +266,6 @@ func (s *MonitoringLogService) LogListener(ctx context.Context, serial string, c
 				delete(s.logListeners, serial)
 			}
 		}
-		close(logChan)
 		s.logger.Debug("[MonitoringLog] Listener cleaned up", zap.String("serial", serial))
 	}()
 
@@ -293,6 +286,10 @@ func (s *MonitoringLogService) LogListener(ctx context.Context, serial string, c
 			s.logger.Debug("[MonitoringLog] Context cancelled, stopping listener", zap.String("serial", serial))
 			return ctx.Err()
 
+		case <-s.ctx.Done():
+			s.logger.Debug("[MonitoringLog] Service shutting down, stopping listener", zap.String("serial", serial))
+			return nil
+
 		case log, ok := <-logChan:
 			if !ok {
 				return nil
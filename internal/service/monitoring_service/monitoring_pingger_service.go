@@ -11,21 +11,25 @@ import (
 	"time"
 
 	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
 	adb "github.com/basiooo/goadb"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
 type MonitoringPinggerService struct {
-	adb        *adb.Adb
-	httpClient *http.Client
-	logger     *zap.Logger
+	adb          *adb.Adb
+	adbProcessor processor.IProcessor
+	httpClient   *http.Client
+	logger       *zap.Logger
 }
 
-func NewMonitoringPinggerService(adb *adb.Adb, logger *zap.Logger) IMonitoringPinggerService {
+func NewMonitoringPinggerService(adb *adb.Adb, adbProcessor processor.IProcessor, logger *zap.Logger) IMonitoringPinggerService {
 	return &MonitoringPinggerService{
-		adb:    adb,
-		logger: logger,
+		adb:          adb,
+		adbProcessor: adbProcessor,
+		logger:       logger,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 			Transport: &http.Transport{
@@ -134,8 +138,8 @@ func (s *MonitoringPinggerService) PingByDevice(ctx context.Context, serial, hos
 		return false
 	}
 
-	cmd := fmt.Sprintf("ping -c 1 -W 5 %s", host)
-	result, err := device.RunCommand(cmd)
+	cmd := command.New("ping", "-c", "1", "-W", "5", host).Build()
+	result, err := s.adbProcessor.Executor(device).Run(ctx, cmd)
 	if err != nil {
 		s.logger.Error("Failed to run ping command", zap.Error(err))
 		return false
@@ -11,7 +11,7 @@ func TestMonitoringPinggerService_parsePingResult(t *testing.T) {
 	t.Parallel()
 	adb := &adb.Adb{}
 	logger := zap.NewNop()
-	service := NewMonitoringPinggerService(adb, logger).(*MonitoringPinggerService)
+	service := NewMonitoringPinggerService(adb, nil, logger).(*MonitoringPinggerService)
 
 	tests := []struct {
 		name     string
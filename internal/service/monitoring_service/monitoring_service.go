@@ -2,6 +2,8 @@ package monitoring_service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	andromodemError "github.com/basiooo/andromodem/internal/errors"
 	"github.com/basiooo/andromodem/internal/model"
@@ -25,12 +27,14 @@ func NewMonitoringService(
 	networkService network_service.INetworkService,
 	logger *zap.Logger,
 	ctx context.Context,
+	configFile string,
+	logDir string,
 ) IMonitoringService {
 
 	taskService := NewMonitoringTaskService(logger)
-	configService := NewMonitoringConfigService("andromodem_monitoring_config.json", logger, taskService)
-	logService := NewMonitoringLogService("andromodem_logs/monitoring", logger)
-	pinggerService := NewMonitoringPinggerService(adb, logger)
+	configService := NewMonitoringConfigService(configFile, logger, taskService)
+	logService := NewMonitoringLogService(logDir, logger)
+	pinggerService := NewMonitoringPinggerService(adb, adbProcessor, logger)
 	actionService := NewMonitoringDeviceActionService(adb, networkService, logService, logger)
 	workerService := NewMonitoringWorkerService(ctx, logger, taskService, pinggerService, logService, actionService, configService)
 
@@ -103,6 +107,10 @@ func (s *MonitoringService) GetMonitoringStatus(serial string) (*model.Monitorin
 	return s.workerService.GetStatus(serial)
 }
 
+func (s *MonitoringService) GetAllMonitoringStatuses() []*model.MonitoringStatus {
+	return s.workerService.GetAllStatuses()
+}
+
 func (s *MonitoringService) DeleteMonitoring(serial string) error {
 	if err := s.StopMonitoring(serial); err != nil {
 		s.logger.Warn("[Monitoring] Failed to stop monitoring task during deletion",
@@ -188,3 +196,98 @@ func (s *MonitoringService) ClearMonitoringLogs(serial string) error {
 	}
 	return s.logService.ClearLogs(serial)
 }
+
+// Shutdown stops the workers, persists the tasks with their is_active flag
+// unchanged and stops the log listeners.
+func (s *MonitoringService) Shutdown(ctx context.Context) error {
+	err := s.workerService.Shutdown(ctx)
+	if saveErr := s.SaveTasksToFile(); saveErr != nil {
+		s.logger.Error("[Monitoring] Failed to save tasks to file on shutdown", zap.Error(saveErr))
+		err = errors.Join(err, saveErr)
+	}
+	s.logService.Shutdown()
+	return err
+}
+
+// ReloadTasks re-reads the task file and applies it to the running tasks.
+// Tasks whose settings changed are stopped and, when active, started again;
+// tasks missing from the file are stopped and removed.
+func (s *MonitoringService) ReloadTasks() (*model.MonitoringReloadResult, error) {
+	tasks, err := s.configService.LoadTasksFromFile()
+	if err != nil {
+		return nil, err
+	}
+	currentTasks, err := s.taskService.GetAllTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.MonitoringReloadResult{}
+	current := make(map[string]*model.MonitoringTask, len(currentTasks))
+	for _, task := range currentTasks {
+		current[task.Serial] = task
+	}
+	loaded := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		loaded[task.Serial] = true
+	}
+
+	for serial := range current {
+		if loaded[serial] {
+			continue
+		}
+		if s.workerService.IsRunning(serial) {
+			if err := s.workerService.StopMonitoring(serial, true); err != nil {
+				s.logger.Warn("[Monitoring] Failed to stop removed task on reload",
+					zap.String("serial", serial), zap.Error(err))
+			}
+		}
+		if err := s.taskService.DeleteTask(serial); err != nil {
+			s.logger.Warn("[Monitoring] Failed to remove task on reload",
+				zap.String("serial", serial), zap.Error(err))
+			continue
+		}
+		result.Removed = append(result.Removed, serial)
+	}
+
+	var errs []error
+	for _, task := range tasks {
+		existing, exists := current[task.Serial]
+		if exists && sameTaskSettings(existing, task) {
+			continue
+		}
+
+		if s.workerService.IsRunning(task.Serial) {
+			if err := s.workerService.StopMonitoring(task.Serial, true); err != nil {
+				s.logger.Warn("[Monitoring] Failed to stop changed task on reload",
+					zap.String("serial", task.Serial), zap.Error(err))
+			}
+		}
+		s.taskService.LoadTasks([]*model.MonitoringTask{task})
+		if exists {
+			result.Updated = append(result.Updated, task.Serial)
+		} else {
+			result.Added = append(result.Added, task.Serial)
+		}
+
+		if task.IsActive {
+			if err := s.workerService.StartMonitoring(task.Serial); err != nil {
+				errs = append(errs, fmt.Errorf("start monitoring %s: %w", task.Serial, err))
+				continue
+			}
+			result.Restarted = append(result.Restarted, task.Serial)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// sameTaskSettings reports whether a and b would run the same worker.
+func sameTaskSettings(a *model.MonitoringTask, b *model.MonitoringTask) bool {
+	return a.Host == b.Host &&
+		a.Method == b.Method &&
+		a.MaxFailures == b.MaxFailures &&
+		a.CheckingInterval == b.CheckingInterval &&
+		a.AirplaneModeDelay == b.AirplaneModeDelay &&
+		a.IsActive == b.IsActive
+}
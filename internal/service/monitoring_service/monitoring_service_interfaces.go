@@ -13,11 +13,14 @@ type IMonitoringService interface {
 	DeleteMonitoring(string) error
 	ClearMonitoringLogs(string) error
 	GetMonitoringStatus(string) (*model.MonitoringStatus, error)
+	GetAllMonitoringStatuses() []*model.MonitoringStatus
 	GetMonitoringConfig(string) (*model.MonitoringTask, error)
 	UpdateMonitoringConfig(string, *model.MonitoringTaskRequest) (*model.MonitoringTask, error)
 	GetAllMonitoringTasks() ([]*model.MonitoringTask, error)
 	GetMonitoringLogs(string, int) ([]*model.MonitoringLog, error)
 	LoadTasksFromFile() error
 	SaveTasksToFile() error
+	ReloadTasks() (*model.MonitoringReloadResult, error)
 	ListenMonitoringLogs(ctx context.Context, serial string, callback func(*model.MonitoringLog) error) error
+	Shutdown(context.Context) error
 }
@@ -7,12 +7,15 @@ import (
 	"time"
 
 	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/lifecycle"
 	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
 	"go.uber.org/zap"
 )
 
 type MonitoringWorkerService struct {
 	runningTasks   map[string]context.CancelFunc
+	workers        sync.WaitGroup
 	status         map[string]*model.MonitoringStatus
 	mutex          sync.RWMutex
 	logger         *zap.Logger
@@ -87,7 +90,11 @@ func (s *MonitoringWorkerService) StartMonitoring(serial string) error {
 	ctx, cancel := context.WithCancel(s.ctx)
 	s.runningTasks[serial] = cancel
 
-	go s.monitoringWorker(ctx, task)
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		s.monitoringWorker(ctx, task)
+	}()
 
 	s.logger.Info("[MonitoringWorker] Started monitoring task",
 		zap.String("serial", serial),
@@ -149,6 +156,19 @@ func (s *MonitoringWorkerService) GetStatus(serial string) (*model.MonitoringSta
 	return status, nil
 }
 
+// GetAllStatuses returns a copy of the status of every running task.
+func (s *MonitoringWorkerService) GetAllStatuses() []*model.MonitoringStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	statuses := make([]*model.MonitoringStatus, 0, len(s.status))
+	for _, status := range s.status {
+		statusCopy := *status
+		statuses = append(statuses, &statusCopy)
+	}
+	return statuses
+}
+
 func (s *MonitoringWorkerService) IsRunning(serial string) bool {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -233,6 +253,8 @@ func (s *MonitoringWorkerService) AutoStartTasks() error {
 }
 
 func (s *MonitoringWorkerService) monitoringWorker(ctx context.Context, task *model.MonitoringTask) {
+	// Pings and restart actions go before API requests on a busy device.
+	ctx = processor.WithPriority(ctx, processor.PriorityHigh)
 	ticker := time.NewTicker(time.Duration(task.CheckingInterval) * time.Second)
 	defer ticker.Stop()
 
@@ -288,18 +310,23 @@ func (s *MonitoringWorkerService) monitoringWorker(ctx context.Context, task *mo
 			}
 
 			pingCtx, pingCancel := context.WithTimeout(ctx, 30*time.Second)
+			pingStart := time.Now()
 			success := s.pinggerService.PerformPing(pingCtx, task)
+			latency := time.Since(pingStart)
 			pingCancel()
 
 			s.mutex.Lock()
 			if status, exists := s.status[task.Serial]; exists {
 				status.LastPingTime = time.Now()
 				status.LastSuccess = success
+				status.LastLatencyMs = latency.Milliseconds()
+				status.TotalChecks++
 				if success {
 					status.FailureCount = 0
 					failureCount = 0
 				} else {
 					status.FailureCount++
+					status.TotalFailures++
 					failureCount++
 				}
 			}
@@ -326,7 +353,14 @@ func (s *MonitoringWorkerService) monitoringWorker(ctx context.Context, task *mo
 						zap.Int("max_failures", task.MaxFailures))
 
 					for failureRestartCount <= maxFailureRestartCount {
-						if err := s.actionService.PerformRestartAction(task.Serial, task.AirplaneModeDelay); err != nil {
+						s.mutex.Lock()
+						if status, exists := s.status[task.Serial]; exists {
+							status.RecoveryActions++
+						}
+						s.mutex.Unlock()
+						// Not cancelled with the task: a restart stopped halfway would
+						// leave the device in airplane mode.
+						if err := s.actionService.PerformRestartAction(context.WithoutCancel(ctx), task.Serial, task.AirplaneModeDelay); err != nil {
 
 							s.logger.Error("Failed to perform restart action",
 								zap.String("serial", task.Serial),
@@ -366,10 +400,11 @@ func (s *MonitoringWorkerService) monitoringWorker(ctx context.Context, task *mo
 	}
 }
 
+// Shutdown stops every running worker and waits for them until ctx is done.
+// Unlike StopMonitoring it leaves is_active untouched, so the tasks are
+// started again by AutoStartTasks on the next boot.
 func (s *MonitoringWorkerService) Shutdown(ctx context.Context) error {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
 	s.logger.Info("[MonitoringWorker] Starting graceful shutdown")
 
 	for serial, cancel := range s.runningTasks {
@@ -377,17 +412,20 @@ func (s *MonitoringWorkerService) Shutdown(ctx context.Context) error {
 		s.logger.Info("[MonitoringWorker] Stopping monitoring task during shutdown",
 			zap.String("serial", serial))
 
-		if err := s.taskService.UpdateTaskStatus(serial, false); err != nil {
-			s.logger.Warn("[MonitoringWorker] Failed to update task status during shutdown",
-				zap.String("serial", serial), zap.Error(err))
-		}
-
 		if status, exists := s.status[serial]; exists {
 			status.IsRunning = false
 		}
 	}
 
 	s.runningTasks = make(map[string]context.CancelFunc)
+	s.mutex.Unlock()
+
+	// Workers may be inside StopMonitoring, which takes the mutex, so wait
+	// without holding it.
+	if err := lifecycle.WaitGroup(ctx, &s.workers); err != nil {
+		s.logger.Warn("[MonitoringWorker] Timed out waiting for workers to stop")
+		return err
+	}
 
 	s.logger.Info("[MonitoringWorker] Graceful shutdown completed")
 	return nil
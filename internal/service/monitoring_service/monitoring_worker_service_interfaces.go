@@ -10,6 +10,7 @@ type IMonitoringWorkerService interface {
 	StartMonitoring(string) error
 	StopMonitoring(string, bool) error
 	GetStatus(string) (*model.MonitoringStatus, error)
+	GetAllStatuses() []*model.MonitoringStatus
 	IsRunning(string) bool
 	AutoStartTasks() error
 	Shutdown(context.Context) error
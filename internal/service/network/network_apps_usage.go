@@ -0,0 +1,88 @@
+package network_service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	"github.com/basiooo/andromodem/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// uidNames name the uids no app package runs as, or that many system apps
+// share. Netstats books the traffic of uninstalled apps to -4 and tethered
+// clients to -5.
+var uidNames = map[int]string{
+	-5:   "tethering",
+	-4:   "removed apps",
+	0:    "root",
+	1000: "system",
+}
+
+// GetAppsUsage returns the mobile data used by every app between from and
+// to, as recorded by the netstats service in buckets of usually two hours.
+func (n *NetworkService) GetAppsUsage(ctx context.Context, serial string, from time.Time, to time.Time) (*model.AppsUsage, error) {
+	defer logger.LogDuration(n.Logger, "GetAppsUsage")()
+	device, err := n.Adb.GetDeviceBySerial(serial)
+	if err != nil || device == nil {
+		n.Logger.Error("error getting device by serial", zap.String("serial", serial), zap.Error(err))
+		return nil, andromodemError.ErrorDeviceNotFound
+	}
+
+	results, err := n.AdbProcessor.RunBatch(ctx, device, command.GetNetstatsCommand, command.GetPackagesUidCommand)
+	if err != nil {
+		n.Logger.Error("error getting apps usage", zap.String("serial", serial), zap.Error(err))
+		return nil, err
+	}
+	netstats, err := processor.Result[*parser.Netstats](results[0])
+	if err != nil {
+		n.Logger.Error("error getting netstats", zap.String("serial", serial), zap.Error(err))
+		return nil, err
+	}
+	// Without the packages the apps are listed by uid.
+	packages, err := processor.Result[*parser.Packages](results[1])
+	if err != nil {
+		n.Logger.Error("error getting packages", zap.String("serial", serial), zap.Error(err))
+		packages = &parser.Packages{}
+	}
+
+	usage := &model.AppsUsage{From: from, To: to, Apps: make([]model.AppUsage, 0)}
+	for _, traffic := range netstats.MobileTraffic(from, to) {
+		app := model.AppUsage{
+			Uid:        traffic.Uid,
+			Packages:   packages.Uids[traffic.Uid],
+			RxBytes:    traffic.RxBytes,
+			TxBytes:    traffic.TxBytes,
+			TotalBytes: traffic.RxBytes + traffic.TxBytes,
+		}
+		if app.Packages == nil {
+			app.Packages = make([]string, 0)
+		}
+		app.Name = appName(app.Uid, app.Packages)
+		usage.Apps = append(usage.Apps, app)
+	}
+	slices.SortFunc(usage.Apps, func(a, b model.AppUsage) int {
+		if byTotal := cmp.Compare(b.TotalBytes, a.TotalBytes); byTotal != 0 {
+			return byTotal
+		}
+		return cmp.Compare(a.Uid, b.Uid)
+	})
+	return usage, nil
+}
+
+func appName(uid int, packages []string) string {
+	if name, ok := uidNames[uid]; ok {
+		return name
+	}
+	if len(packages) > 0 {
+		return packages[0]
+	}
+	return fmt.Sprintf("uid %d", uid)
+}
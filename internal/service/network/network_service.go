@@ -2,7 +2,7 @@ package network_service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -12,197 +12,189 @@ import (
 
 	andromodemError "github.com/basiooo/andromodem/internal/errors"
 	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
 	"github.com/basiooo/andromodem/internal/service/common_service"
 	"github.com/basiooo/andromodem/pkg/adb_processor/command"
 	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
 	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
-	"github.com/basiooo/andromodem/pkg/adb_processor/utils"
 	"github.com/basiooo/andromodem/pkg/logger"
 	adb "github.com/basiooo/goadb"
 	"go.uber.org/zap"
 )
 
 type NetworkService struct {
-	Adb          *adb.Adb
-	AdbProcessor processor.IProcessor
-	Logger       *zap.Logger
-	Ctx          context.Context
+	Adb               *adb.Adb
+	AdbProcessor      processor.IProcessor
+	CapabilityService capability_service.ICapabilityService
+	Logger            *zap.Logger
+	Ctx               context.Context
+
+	trafficMutex   sync.Mutex
+	trafficSamples map[string]trafficSample
 }
 
-func NewNetworkService(adb *adb.Adb, adbProcessor processor.IProcessor, logger *zap.Logger, ctx context.Context) INetworkService {
+func NewNetworkService(adb *adb.Adb, adbProcessor processor.IProcessor, capabilityService capability_service.ICapabilityService, logger *zap.Logger, ctx context.Context) INetworkService {
 	return &NetworkService{
-		Adb:          adb,
-		AdbProcessor: adbProcessor,
-		Logger:       logger,
-		Ctx:          ctx,
+		Adb:               adb,
+		AdbProcessor:      adbProcessor,
+		CapabilityService: capabilityService,
+		Logger:            logger,
+		Ctx:               ctx,
+		trafficSamples:    make(map[string]trafficSample),
 	}
 }
 
-func (n *NetworkService) getIpRoutes(device *adb.Device) (*parser.IPRoute, error) {
+func (n *NetworkService) getIpRoutes(ctx context.Context, device *adb.Device) (*parser.IPRoute, error) {
 	defer logger.LogDuration(n.Logger, "getIpRoutes")()
-	ipRoutes, err := n.AdbProcessor.Run(device, command.GetIpRouterCommand, true)
+	ipRoutes, err := processor.Run[*parser.IPRoute](ctx, n.AdbProcessor, device, command.GetIpRouterCommand, true)
 	if err != nil {
 		n.Logger.Error("error parsing ip routes", zap.Error(err))
 		return nil, err
 	}
-	if result, ok := ipRoutes.(*parser.IPRoute); ok {
-		return result, nil
-	}
-	return nil, fmt.Errorf("error parsing ip routes")
+	return ipRoutes, nil
 }
 
-func (n *NetworkService) getApn(device *adb.Device) (*parser.Apn, error) {
+func (n *NetworkService) getApn(ctx context.Context, device *adb.Device, access model.Access) (*parser.Apn, error) {
 	defer logger.LogDuration(n.Logger, "getApn")()
-	needRoot := false
-	var apn parser.IParser
+	var apn *parser.Apn
 	var err error
-	if androidVersion, err := common_service.GetAndroidVersion(device, n.AdbProcessor, true); err == nil {
-		needRoot = androidVersion < command.MinimumAndroidShowApn
-	}
-	if needRoot {
-		apn, err = n.AdbProcessor.RunWithRoot(device, command.GetApnCommand)
-	} else {
-		apn, err = n.AdbProcessor.Run(device, command.GetApnCommand, false)
+	switch access {
+	case model.AccessShell:
+		apn, err = processor.Run[*parser.Apn](ctx, n.AdbProcessor, device, command.GetApnCommand, false)
+	case model.AccessRoot:
+		apn, err = processor.RunWithRoot[*parser.Apn](ctx, n.AdbProcessor, device, command.GetApnCommand)
+	default:
+		err = adbErrors.ErrorNeedRoot
 	}
 	if err != nil {
 		n.Logger.Error("error parsing apn", zap.Error(err))
 		return nil, err
 	}
-	if result, ok := apn.(*parser.Apn); ok {
-		return result, nil
+	return apn, nil
+}
+
+// airplaneModeStatusCommand returns the command reading airplane mode on a
+// device with capabilities.
+func airplaneModeStatusCommand(capabilities *model.DeviceCapabilities) command.AdbCommand {
+	if capabilities.AirplaneModeCommand {
+		return command.GetAirplaneModeStatusNewCommand
 	}
-	return nil, fmt.Errorf("error parsing apn")
+	return command.GetAirplaneModeStatusLegacyCommand
 }
 
-func (n *NetworkService) getAirplaneModeStatus(device *adb.Device) (bool, error) {
+func (n *NetworkService) getAirplaneModeStatus(ctx context.Context, device *adb.Device, capabilities *model.DeviceCapabilities) (bool, error) {
 	defer logger.LogDuration(n.Logger, "getAirplaneModeStatus")()
-	cmd := command.GetAirplaneModeStatusNewCommand
-	if androidVersion, err := common_service.GetAndroidVersion(device, n.AdbProcessor, true); err == nil {
-		if androidVersion < command.MinimumAndroidToggleAirplaneMode {
-			cmd = command.GetAirplaneModeStatusLegacyCommand
-		}
-	}
-	airplaneModeStatus, err := n.AdbProcessor.Run(device, cmd, false)
+	cmd := airplaneModeStatusCommand(capabilities)
+	airplaneModeStatus, err := processor.Run[*parser.AirplaneModeState](ctx, n.AdbProcessor, device, cmd, false)
 	if err != nil {
 		n.Logger.Error("error parsing airplane mode status", zap.Error(err))
 		return false, err
 	}
-	if result, ok := airplaneModeStatus.(*parser.AirplaneModeState); ok {
-		return result.Enabled, nil
-	}
-	return false, fmt.Errorf("error parsing airplane mode status")
-}
-
-func (n *NetworkService) getSimsRaw(device *adb.Device) (parser.RawDeviceSim, error) {
-	defer logger.LogDuration(n.Logger, "getSimsRaw")()
-	var rawDeviceSim parser.RawDeviceSim
-	var wg sync.WaitGroup
-	wg.Add(3)
-	go func() {
-		defer wg.Done()
-		if rawOperatorName, err := n.AdbProcessor.Run(device, command.GetSimOperatorNameCommand, false); err == nil {
-			rawDeviceSim.RawCarriersName = utils.GetResultFromRaw(rawOperatorName)
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		if rawConnectionState, err := n.AdbProcessor.Run(device, command.GetMobileDataStateCommand, false); err == nil {
-			rawDeviceSim.RawConnectionsState = utils.GetResultFromRaw(rawConnectionState)
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		cmd := command.GetSignalStrengthCommand
-		if androidVersion, err := common_service.GetAndroidVersion(device, n.AdbProcessor, true); err == nil {
-			if androidVersion < command.MinimumAndroidGetSignalStrength {
-				// in android 9 or bellow cannot parse signal strength
-				cmd = command.GetSimNetworkTypeCommand
-			}
-		}
-		if rawSignalsStrength, err := n.AdbProcessor.Run(device, cmd, false); err == nil {
-			rawDeviceSim.RawSignalsStrength = utils.GetResultFromRaw(rawSignalsStrength)
-		}
-	}()
-	wg.Wait()
-	return rawDeviceSim, nil
+	return airplaneModeStatus.Enabled, nil
 }
 
-func (n *NetworkService) GetNetworkInfo(serial string) (*model.Network, error) {
+func (n *NetworkService) GetNetworkInfo(ctx context.Context, serial string) (*model.Network, error) {
 	defer logger.LogDuration(n.Logger, "GetNetworkInfo")()
-	var err error
 	device, err := n.Adb.GetDeviceBySerial(serial)
 	if err != nil || device == nil {
 		n.Logger.Error("error getting device by serial", zap.String("serial", serial), zap.Error(err))
 		return nil, andromodemError.ErrorDeviceNotFound
 	}
+
+	capabilities, err := n.CapabilityService.GetCapabilities(ctx, serial)
+	if err != nil {
+		n.Logger.Error("error getting device capabilities", zap.String("serial", serial), zap.Error(err))
+		return nil, err
+	}
+	airplaneModeCommand := airplaneModeStatusCommand(capabilities)
+
+	// Everything that does not need root is read in a single shell session,
+	// the SIM commands come last.
+	commands := []command.AdbCommand{command.GetIpRouterCommand, airplaneModeCommand}
+	if capabilities.Apn == model.AccessShell {
+		commands = append(commands, command.GetApnCommand)
+	}
+	simsIndex := len(commands)
+	commands = append(commands, common_service.DeviceSimsCommands(ctx, device, n.AdbProcessor)...)
+
+	results, err := n.AdbProcessor.RunBatch(ctx, device, commands...)
+	if err != nil {
+		n.Logger.Error("error getting network info", zap.String("serial", serial), zap.Error(err))
+		return nil, err
+	}
+
 	networkInfo := &model.Network{}
-	var wg sync.WaitGroup
-	wg.Add(4)
-	go func() {
-		defer wg.Done()
-		ipRoutes, err := n.getIpRoutes(device)
-		if err != nil {
-			n.Logger.Error("error getting ip routes", zap.String("serial", serial), zap.Error(err))
-			return
-		}
-		if ipRoutes.NetworkIPs != nil {
-			networkInfo.IpRoutes = ipRoutes.NetworkIPs
-		} else {
-			networkInfo.IpRoutes = []parser.NetworkIp{}
+	for _, result := range results[:simsIndex] {
+		switch result.Command {
+		case command.GetIpRouterCommand:
+			ipRoutes, err := n.ipRoutesFromResult(ctx, device, result)
+			if err != nil {
+				n.Logger.Error("error getting ip routes", zap.String("serial", serial), zap.Error(err))
+				continue
+			}
+			if ipRoutes.NetworkIPs != nil {
+				networkInfo.IpRoutes = ipRoutes.NetworkIPs
+			} else {
+				networkInfo.IpRoutes = []parser.NetworkIp{}
+			}
+		case command.GetApnCommand:
+			apn, err := processor.Result[*parser.Apn](result)
+			if err != nil {
+				n.Logger.Error("error getting apn", zap.String("serial", serial), zap.Error(err))
+				continue
+			}
+			networkInfo.APN = *apn
+		case airplaneModeCommand:
+			airplaneMode, err := processor.Result[*parser.AirplaneModeState](result)
+			if err != nil {
+				n.Logger.Error("error getting airplane mode status", zap.String("serial", serial), zap.Error(err))
+				continue
+			}
+			networkInfo.AirplaneMode = airplaneMode.Enabled
 		}
-	}()
-	go func() {
-		defer wg.Done()
-		apn, err := n.getApn(device)
+	}
+
+	// TODO: refactor to use parser interface
+	sims, err := common_service.ParseDeviceSims(results[simsIndex:])
+	if err != nil {
+		n.Logger.Error("error getting sims", zap.String("serial", serial), zap.Error(err))
+	} else {
+		networkInfo.Sims = sims
+	}
+
+	if capabilities.Apn == model.AccessRoot {
+		apn, err := n.getApn(ctx, device, capabilities.Apn)
 		if err != nil {
 			n.Logger.Error("error getting apn", zap.String("serial", serial), zap.Error(err))
-			return
-		}
-		networkInfo.APN = *apn
-	}()
-	go func() {
-		defer wg.Done()
-		airplaneMode, err := n.getAirplaneModeStatus(device)
-		if err != nil {
-			n.Logger.Error("error getting airplane mode status", zap.String("serial", serial), zap.Error(err))
-			return
-		}
-		networkInfo.AirplaneMode = airplaneMode
-	}()
-	go func() {
-		// TODO: refactor to use parser interface
-		defer wg.Done()
-		rawSims, err := n.getSimsRaw(device)
-		if err != nil {
-			n.Logger.Error("error getting sims", zap.String("serial", serial), zap.Error(err))
-			return
-		}
-		deviceSims := parser.NewDeviceSim()
-		data, err := json.Marshal(rawSims)
-		if err != nil {
-			n.Logger.Error("error marshalling sims", zap.String("serial", serial), zap.Error(err))
-			return
-		}
-		err = deviceSims.Parse(string(data))
-		if err != nil {
-			n.Logger.Error("error parsing sims", zap.String("serial", serial), zap.Error(err))
-			return
+		} else {
+			networkInfo.APN = *apn
 		}
-		networkInfo.Sims = deviceSims.(*parser.DeviceSim).Sims
-	}()
-	wg.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return networkInfo, nil
 }
-func (n *NetworkService) hasMobileDataEnabled(device *adb.Device) (bool, error) {
+
+// ipRoutesFromResult returns the routes read by a batch, or reads them again
+// with root when the batch was denied access.
+func (n *NetworkService) ipRoutesFromResult(ctx context.Context, device *adb.Device, result processor.BatchResult) (*parser.IPRoute, error) {
+	if errors.Is(result.Err, adbErrors.ErrorNeedShellSuperUserPermission) || errors.Is(result.Err, adbErrors.ErrorNeedRoot) {
+		return n.getIpRoutes(ctx, device)
+	}
+	return processor.Result[*parser.IPRoute](result)
+}
+
+func (n *NetworkService) hasMobileDataEnabled(ctx context.Context, device *adb.Device) (bool, error) {
 	defer logger.LogDuration(n.Logger, "hasMobileDataEnabled")()
 
-	rawConnectionState, err := n.AdbProcessor.Run(device, command.GetMobileDataStateCommand, false)
+	rawConnectionState, err := processor.RunRaw(ctx, n.AdbProcessor, device, command.GetMobileDataStateCommand, false)
 	if err != nil {
 		return false, err
 	}
 
-	stateParser := parser.NewMobileDataState()
-	for _, state := range strings.Split(strings.TrimSpace(utils.GetResultFromRaw(rawConnectionState)), "\n") {
+	stateParser := &parser.MobileDataState{}
+	for _, state := range strings.Split(strings.TrimSpace(rawConnectionState), "\n") {
 		if err := stateParser.Parse(state); err != nil {
 			serial, err := device.Serial()
 			if err != nil {
@@ -212,15 +204,29 @@ func (n *NetworkService) hasMobileDataEnabled(device *adb.Device) (bool, error)
 			n.Logger.Error("error parsing mobile data state", zap.String("serial", serial), zap.Error(err))
 			return false, err
 		}
-		if currentState := stateParser.(*parser.MobileDataState).State; currentState == parser.DataConnected || currentState == parser.DataConnecting {
+		if currentState := stateParser.State; currentState == parser.DataConnected || currentState == parser.DataConnecting {
 			return true, nil
 		}
 	}
 
 	return false, nil
 }
-func (n *NetworkService) ToggleMobileData(serial string) (*bool, error) {
+func (n *NetworkService) ToggleMobileData(ctx context.Context, serial string) (*bool, error) {
 	defer logger.LogDuration(n.Logger, "ToggleMobileData")()
+	return n.setMobileData(ctx, serial, func(enabled bool) bool { return !enabled })
+}
+
+// DisableMobileData turns mobile data off, it does nothing when it is off
+// already.
+func (n *NetworkService) DisableMobileData(ctx context.Context, serial string) error {
+	defer logger.LogDuration(n.Logger, "DisableMobileData")()
+	_, err := n.setMobileData(ctx, serial, func(bool) bool { return false })
+	return err
+}
+
+// setMobileData switches mobile data to the state target returns for the
+// current one and waits until the device reports it.
+func (n *NetworkService) setMobileData(ctx context.Context, serial string, target func(enabled bool) bool) (*bool, error) {
 
 	device, err := n.Adb.GetDeviceBySerial(serial)
 	if err != nil || device == nil {
@@ -228,36 +234,51 @@ func (n *NetworkService) ToggleMobileData(serial string) (*bool, error) {
 		return nil, andromodemError.ErrorDeviceNotFound
 	}
 
-	isAirplaneMode, err := n.getAirplaneModeStatus(device)
+	capabilities, err := n.CapabilityService.GetCapabilities(ctx, serial)
+	if err != nil {
+		n.Logger.Error("error getting device capabilities", zap.String("serial", serial), zap.Error(err))
+		return nil, err
+	}
+	if !capabilities.SvcData {
+		return nil, fmt.Errorf("%w: 'svc data'", adbErrors.ErrorCommandNotFound)
+	}
+
+	isAirplaneMode, err := n.getAirplaneModeStatus(ctx, device, capabilities)
 	if err != nil {
 		n.Logger.Error("error checking airplane mode status", zap.String("serial", serial), zap.Error(err))
-		return nil, andromodemError.ErrorCheckingAirplaneModeStatus
+		return nil, fmt.Errorf("%w: %w", andromodemError.ErrorCheckingAirplaneModeStatus, err)
 	}
 	if isAirplaneMode {
 		return nil, andromodemError.ErrorAirplaneModeActive
 	}
 
-	isDataEnabled, err := n.hasMobileDataEnabled(device)
+	isDataEnabled, err := n.hasMobileDataEnabled(ctx, device)
 	if err != nil {
 		n.Logger.Error("error checking mobile data state", zap.String("serial", serial), zap.Error(err))
-		return nil, andromodemError.ErrorCheckingMobileDataState
+		return nil, fmt.Errorf("%w: %w", andromodemError.ErrorCheckingMobileDataState, err)
 	}
 
+	enable := target(isDataEnabled)
+	if enable == isDataEnabled {
+		return &isDataEnabled, nil
+	}
 	var cmd command.AdbCommand
 	newState := "enable"
-	if isDataEnabled {
+	if enable {
+		cmd = command.EnableMobileDataCommand
+	} else {
 		newState = "disable"
 		cmd = command.DisableMobileDataCommand
-	} else {
-		cmd = command.EnableMobileDataCommand
 	}
 
-	if _, err := n.AdbProcessor.Run(device, cmd, false); err != nil {
+	if _, err := n.AdbProcessor.Run(ctx, device, cmd, false); err != nil {
 		n.Logger.Error("error toggling mobile data", zap.String("serial", serial), zap.Error(err))
 		return nil, fmt.Errorf("error %s mobile data: %w", newState, err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// The state is polled from the device, a cached read would hide the
+	// change until it expires.
+	waitCtx, cancel := context.WithTimeout(processor.WithoutCache(ctx), 10*time.Second)
 	defer cancel()
 
 	ticker := time.NewTicker(1 * time.Second)
@@ -265,22 +286,25 @@ func (n *NetworkService) ToggleMobileData(serial string) (*bool, error) {
 
 	for {
 		select {
-		case <-ctx.Done():
+		case <-waitCtx.Done():
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			return nil, andromodemError.ErrorTimeoutChangeMobileData
 		case <-ticker.C:
-			currentStatus, err := n.hasMobileDataEnabled(device)
+			currentStatus, err := n.hasMobileDataEnabled(waitCtx, device)
 			if err != nil {
 				n.Logger.Error("error checking mobile data state", zap.String("serial", serial), zap.Error(err))
-				return nil, andromodemError.ErrorCheckingMobileDataState
+				return nil, fmt.Errorf("%w: %w", andromodemError.ErrorCheckingMobileDataState, err)
 			}
-			if currentStatus != isDataEnabled {
+			if currentStatus == enable {
 				return &currentStatus, nil
 			}
 		}
 	}
 }
 
-func (n *NetworkService) ToggleAirplaneMode(serial string) (*bool, error) {
+func (n *NetworkService) ToggleAirplaneMode(ctx context.Context, serial string) (*bool, error) {
 	defer logger.LogDuration(n.Logger, "ToggleAirplaneMode")()
 
 	device, err := n.Adb.GetDeviceBySerial(serial)
@@ -289,24 +313,20 @@ func (n *NetworkService) ToggleAirplaneMode(serial string) (*bool, error) {
 		return nil, andromodemError.ErrorDeviceNotFound
 	}
 
-	useLegacyCommand := false
-	if androidVersion, err := common_service.GetAndroidVersion(device, n.AdbProcessor, true); err == nil {
-		if androidVersion < command.MinimumAndroidToggleAirplaneMode {
-			rootInfo, err := common_service.GetDeviceRootAndAccessInfo(device, n.AdbProcessor, false)
-			if err != nil {
-				return nil, err
-			}
-			if !rootInfo.ShellAccess {
-				return nil, adbErrors.ErrorNeedRoot
-			}
-			useLegacyCommand = true
-		}
+	capabilities, err := n.CapabilityService.GetCapabilities(ctx, serial)
+	if err != nil {
+		n.Logger.Error("error getting device capabilities", zap.String("serial", serial), zap.Error(err))
+		return nil, err
+	}
+	if !capabilities.CanToggleAirplaneMode() {
+		return nil, adbErrors.ErrorNeedRoot
 	}
+	useLegacyCommand := !capabilities.AirplaneModeCommand
 
-	isEnabled, err := n.getAirplaneModeStatus(device)
+	isEnabled, err := n.getAirplaneModeStatus(ctx, device, capabilities)
 	if err != nil {
 		n.Logger.Error("error checking airplane mode status", zap.String("serial", serial), zap.Error(err))
-		return nil, andromodemError.ErrorCheckingAirplaneModeStatus
+		return nil, fmt.Errorf("%w: %w", andromodemError.ErrorCheckingAirplaneModeStatus, err)
 	}
 
 	var cmd command.AdbCommand
@@ -326,19 +346,21 @@ func (n *NetworkService) ToggleAirplaneMode(serial string) (*bool, error) {
 		}
 	}
 
-	if _, err := n.AdbProcessor.Run(device, cmd, false); err != nil {
+	if _, err := n.AdbProcessor.Run(ctx, device, cmd, false); err != nil {
 		n.Logger.Error("error toggling airplane mode", zap.String("serial", serial), zap.Error(err))
 		return nil, fmt.Errorf("error %s airplane mode: %w", newState, err)
 	}
 
 	if useLegacyCommand {
-		if _, err := n.AdbProcessor.RunWithRoot(device, command.BroadcastAirplaneModeLegacyCommand); err != nil {
+		if _, err := n.AdbProcessor.RunWithRoot(ctx, device, command.BroadcastAirplaneModeLegacyCommand); err != nil {
 			n.Logger.Error("error broadcasting airplane mode", zap.String("serial", serial), zap.Error(err))
 			return nil, fmt.Errorf("error broadcasting airplane mode: %w", err)
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// The state is polled from the device, a cached read would hide the
+	// change until it expires.
+	waitCtx, cancel := context.WithTimeout(processor.WithoutCache(ctx), 10*time.Second)
 	defer cancel()
 
 	ticker := time.NewTicker(1 * time.Second)
@@ -346,13 +368,16 @@ func (n *NetworkService) ToggleAirplaneMode(serial string) (*bool, error) {
 
 	for {
 		select {
-		case <-ctx.Done():
+		case <-waitCtx.Done():
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			return nil, andromodemError.ErrorTimeoutChangeAirplaneMode
 		case <-ticker.C:
-			currentStatus, err := n.getAirplaneModeStatus(device)
+			currentStatus, err := n.getAirplaneModeStatus(waitCtx, device, capabilities)
 			if err != nil {
 				n.Logger.Error("error checking airplane mode status", zap.String("serial", serial), zap.Error(err))
-				return nil, andromodemError.ErrorCheckingAirplaneModeStatus
+				return nil, fmt.Errorf("%w: %w", andromodemError.ErrorCheckingAirplaneModeStatus, err)
 			}
 			if currentStatus != isEnabled {
 				return &currentStatus, nil
@@ -1,9 +1,17 @@
 package network_service
 
-import "github.com/basiooo/andromodem/internal/model"
+import (
+	"context"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/model"
+)
 
 type INetworkService interface {
-	GetNetworkInfo(string) (*model.Network, error)
-	ToggleMobileData(string) (*bool, error)
-	ToggleAirplaneMode(string) (*bool, error)
+	GetNetworkInfo(context.Context, string) (*model.Network, error)
+	ToggleMobileData(context.Context, string) (*bool, error)
+	DisableMobileData(context.Context, string) error
+	ToggleAirplaneMode(context.Context, string) (*bool, error)
+	GetTraffic(context.Context, string) (*model.NetworkTraffic, error)
+	GetAppsUsage(context.Context, string, time.Time, time.Time) (*model.AppsUsage, error)
 }
@@ -0,0 +1,230 @@
+package network_service_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
+	network_service "github.com/basiooo/andromodem/internal/service/network"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture/fixturetest"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	"github.com/basiooo/andromodem/pkg/simulator"
+	adb "github.com/basiooo/goadb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestGetNetworkInfoReplayed(t *testing.T) {
+	t.Parallel()
+	logger := zaptest.NewLogger(t)
+	adbClient, adbProcessor := fixturetest.NewReplayedClient(t)
+	capabilityService, err := capability_service.NewCapabilityService(adbClient, adbProcessor, logger, "")
+	require.NoError(t, err)
+	service := network_service.NewNetworkService(adbClient, adbProcessor, capabilityService, logger, context.Background())
+
+	tests := []struct {
+		name       string
+		serial     string
+		apn        string
+		interfaces []string
+		sims       []string
+		hasSignal  bool
+	}{
+		{
+			name:       "samsung",
+			serial:     "R58R31ABCDE",
+			apn:        "internet",
+			interfaces: []string{"rmnet_data1", "rndis0"},
+			sims:       []string{"Telkomsel", "XL Axiata"},
+			hasSignal:  true,
+		},
+		{
+			// Android 9 reads the APN with root and only knows the network
+			// type, not the signal strength.
+			name:       "xiaomi",
+			serial:     "8d1c2a3f",
+			apn:        "indosatgprs",
+			interfaces: []string{"rmnet_data2"},
+			sims:       []string{"IM3 Ooredoo"},
+		},
+		{
+			name:       "pixel",
+			serial:     "2A111FDH200ABC",
+			apn:        "fast.t-mobile.com",
+			interfaces: []string{"rmnet1"},
+			sims:       []string{"T-Mobile"},
+			hasSignal:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			network, err := service.GetNetworkInfo(context.Background(), tt.serial)
+			require.NoError(t, err)
+			assert.False(t, network.AirplaneMode)
+			assert.Equal(t, tt.apn, network.APN.ApnName)
+
+			interfaces := make([]string, len(network.IpRoutes))
+			for i, route := range network.IpRoutes {
+				interfaces[i] = route.Interface
+			}
+			assert.Equal(t, tt.interfaces, interfaces)
+
+			sims := make([]string, len(network.Sims))
+			for i, sim := range network.Sims {
+				sims[i] = sim.Name
+			}
+			assert.Equal(t, tt.sims, sims)
+			lte := network.Sims[0].SignalStrength.CellSignalStrengthLte
+			require.NotNil(t, lte)
+			assert.Equal(t, tt.hasSignal, lte.Rsrp != "")
+		})
+	}
+}
+
+func TestToggleMobileDataSimulated(t *testing.T) {
+	t.Parallel()
+	fixtures, err := fixture.Profiles()
+	require.NoError(t, err)
+	logger := zaptest.NewLogger(t)
+	adbClient := &adb.Adb{Server: simulator.New(fixtures...)}
+	adbProcessor := processor.NewProcessor(logger)
+	capabilityService, err := capability_service.NewCapabilityService(adbClient, adbProcessor, logger, "")
+	require.NoError(t, err)
+	service := network_service.NewNetworkService(adbClient, adbProcessor, capabilityService, logger, context.Background())
+
+	enabled, err := service.ToggleMobileData(context.Background(), "2A111FDH200ABC")
+	require.NoError(t, err)
+	assert.False(t, *enabled)
+
+	enabled, err = service.ToggleAirplaneMode(context.Background(), "2A111FDH200ABC")
+	require.NoError(t, err)
+	assert.True(t, *enabled)
+}
+
+func TestToggleWaitsForSlowRadio(t *testing.T) {
+	t.Parallel()
+	fixtures, err := fixture.Profiles()
+	require.NoError(t, err)
+	logger := zaptest.NewLogger(t)
+	sim := simulator.New(fixtures...)
+	sim.SettleDelay = 2 * time.Second
+	adbClient := &adb.Adb{Server: sim}
+	adbProcessor := processor.NewProcessor(logger)
+	capabilityService, err := capability_service.NewCapabilityService(adbClient, adbProcessor, logger, "")
+	require.NoError(t, err)
+	service := network_service.NewNetworkService(adbClient, adbProcessor, capabilityService, logger, context.Background())
+
+	// Polls read the device, not the state cached by the first of them.
+	start := time.Now()
+	enabled, err := service.ToggleMobileData(context.Background(), "R58R31ABCDE")
+	require.NoError(t, err)
+	assert.False(t, *enabled)
+	assert.Less(t, time.Since(start), 4*time.Second)
+
+	start = time.Now()
+	enabled, err = service.ToggleAirplaneMode(context.Background(), "R58R31ABCDE")
+	require.NoError(t, err)
+	assert.True(t, *enabled)
+	assert.Less(t, time.Since(start), 4*time.Second)
+}
+
+func TestGetTrafficReplayed(t *testing.T) {
+	t.Parallel()
+	logger := zaptest.NewLogger(t)
+	adbClient, adbProcessor := fixturetest.NewReplayedClient(t)
+	service := network_service.NewNetworkService(adbClient, adbProcessor, nil, logger, context.Background())
+
+	traffic, err := service.GetTraffic(context.Background(), "R58R31ABCDE")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, traffic.Interval, 1.0)
+	// rmnet_ipa0 carries the traffic of rmnet_data1 again and is left out.
+	names := make([]string, len(traffic.Interfaces))
+	for i, netInterface := range traffic.Interfaces {
+		names[i] = netInterface.Name
+	}
+	assert.Equal(t, []string{"rmnet_data0", "rmnet_data1", "rndis0"}, names)
+	assert.Equal(t, uint64(9012345678), traffic.Interfaces[1].RxBytes)
+	// The replayed counters do not change between reads.
+	assert.Zero(t, traffic.RxRate)
+
+	_, err = service.GetTraffic(context.Background(), "unknown")
+	assert.ErrorIs(t, err, andromodemError.ErrorDeviceNotFound)
+}
+
+func TestGetTrafficSimulated(t *testing.T) {
+	t.Parallel()
+	fixtures, err := fixture.Profiles()
+	require.NoError(t, err)
+	logger := zaptest.NewLogger(t)
+	adbClient := &adb.Adb{Server: simulator.New(fixtures...)}
+	adbProcessor := processor.NewProcessor(logger)
+	capabilityService, err := capability_service.NewCapabilityService(adbClient, adbProcessor, logger, "")
+	require.NoError(t, err)
+	service := network_service.NewNetworkService(adbClient, adbProcessor, capabilityService, logger, context.Background())
+
+	// The simulator receives 250 kB/s and sends 40 kB/s while connected.
+	traffic, err := service.GetTraffic(context.Background(), "2A111FDH200ABC")
+	require.NoError(t, err)
+	assert.InDelta(t, 250_000, traffic.RxRate, 25_000)
+	assert.InDelta(t, 40_000, traffic.TxRate, 4_000)
+
+	_, err = service.ToggleMobileData(context.Background(), "2A111FDH200ABC")
+	require.NoError(t, err)
+	traffic, err = service.GetTraffic(context.Background(), "2A111FDH200ABC")
+	require.NoError(t, err)
+	assert.Zero(t, traffic.RxRate)
+}
+
+func TestGetAppsUsageReplayed(t *testing.T) {
+	t.Parallel()
+	logger := zaptest.NewLogger(t)
+	adbClient, adbProcessor := fixturetest.NewReplayedClient(t)
+	service := network_service.NewNetworkService(adbClient, adbProcessor, nil, logger, context.Background())
+
+	// The fixtures hold three buckets of two hours from 2025-10-09 08:00 UTC.
+	from := time.Unix(1759996800, 0)
+	to := from.Add(6 * time.Hour)
+	tests := []struct {
+		name       string
+		serial     string
+		apps       int
+		topApp     string
+		topAppUid  int
+		topAppSize uint64
+	}{
+		{name: "pixel", serial: "2A111FDH200ABC", apps: 5, topApp: "com.google.android.youtube", topAppUid: 10203, topAppSize: 292222220},
+		// Tethered clients used the most, wifi traffic is left out.
+		{name: "samsung", serial: "R58R31ABCDE", apps: 4, topApp: "tethering", topAppUid: -5, topAppSize: 1785183938},
+		{name: "xiaomi", serial: "8d1c2a3f", apps: 3, topApp: "com.android.chrome", topAppUid: 10087, topAppSize: 74938256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			usage, err := service.GetAppsUsage(context.Background(), tt.serial, from, to)
+			require.NoError(t, err)
+			require.Len(t, usage.Apps, tt.apps)
+			assert.Equal(t, tt.topApp, usage.Apps[0].Name)
+			assert.Equal(t, tt.topAppUid, usage.Apps[0].Uid)
+			assert.Equal(t, tt.topAppSize, usage.Apps[0].TotalBytes)
+			system := usage.Apps[slices.IndexFunc(usage.Apps, func(app model.AppUsage) bool { return app.Uid == 1000 })]
+			assert.Equal(t, "system", system.Name)
+			assert.Contains(t, system.Packages, "android")
+		})
+	}
+
+	// Only the middle bucket overlaps the window.
+	usage, err := service.GetAppsUsage(context.Background(), "2A111FDH200ABC", from.Add(2*time.Hour), from.Add(4*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "com.google.android.youtube", usage.Apps[0].Name)
+	assert.Equal(t, uint64(187654321), usage.Apps[0].RxBytes)
+
+	_, err = service.GetAppsUsage(context.Background(), "unknown", from, to)
+	assert.ErrorIs(t, err, andromodemError.ErrorDeviceNotFound)
+}
@@ -0,0 +1,136 @@
+package network_service
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	"github.com/basiooo/andromodem/pkg/logger"
+	adb "github.com/basiooo/goadb"
+	"go.uber.org/zap"
+)
+
+// trafficSampleInterval is the shortest time the throughput is measured
+// over. A request coming sooner after the previous sample waits for it.
+const trafficSampleInterval = time.Second
+
+var (
+	// cellularInterfaces carry the mobile data of Qualcomm and MediaTek
+	// modems, tetheringInterfaces the traffic of USB tethering.
+	cellularInterfaces  = []string{"rmnet", "ccmni"}
+	tetheringInterfaces = []string{"usb", "rndis"}
+	// rmnet_ipa carries the traffic of every rmnet_data interface again.
+	aggregateInterfaces = []string{"rmnet_ipa"}
+)
+
+type trafficSample struct {
+	netDev    *parser.NetDev
+	sampledAt time.Time
+}
+
+// GetTraffic returns the counters and throughput of the cellular and
+// tethering interfaces since the previous call for the device, the first
+// call measures over trafficSampleInterval.
+func (n *NetworkService) GetTraffic(ctx context.Context, serial string) (*model.NetworkTraffic, error) {
+	defer logger.LogDuration(n.Logger, "GetTraffic")()
+	device, err := n.Adb.GetDeviceBySerial(serial)
+	if err != nil || device == nil {
+		n.Logger.Error("error getting device by serial", zap.String("serial", serial), zap.Error(err))
+		return nil, andromodemError.ErrorDeviceNotFound
+	}
+
+	n.trafficMutex.Lock()
+	previous, ok := n.trafficSamples[serial]
+	n.trafficMutex.Unlock()
+	if !ok {
+		if previous, err = n.readTraffic(ctx, device); err != nil {
+			return nil, err
+		}
+	}
+	if wait := trafficSampleInterval - time.Since(previous.sampledAt); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	current, err := n.readTraffic(ctx, device)
+	if err != nil {
+		return nil, err
+	}
+
+	n.trafficMutex.Lock()
+	n.trafficSamples[serial] = current
+	n.trafficMutex.Unlock()
+	return networkTraffic(previous, current), nil
+}
+
+func (n *NetworkService) readTraffic(ctx context.Context, device *adb.Device) (trafficSample, error) {
+	netDev, err := processor.Run[*parser.NetDev](ctx, n.AdbProcessor, device, command.GetNetDevCommand, false)
+	if err != nil {
+		n.Logger.Error("error getting traffic counters", zap.Error(err))
+		return trafficSample{}, err
+	}
+	return trafficSample{netDev: netDev, sampledAt: time.Now()}, nil
+}
+
+// networkTraffic works out the throughput between two samples. The totals
+// only count the cellular interfaces, tethered traffic goes through them
+// too. An interface whose counters went down was reset and has no
+// throughput.
+func networkTraffic(previous, current trafficSample) *model.NetworkTraffic {
+	interval := current.sampledAt.Sub(previous.sampledAt).Seconds()
+	traffic := &model.NetworkTraffic{
+		SampledAt:  current.sampledAt,
+		Interval:   math.Round(interval*1000) / 1000,
+		Interfaces: make([]model.InterfaceTraffic, 0),
+	}
+	for _, netInterface := range current.netDev.Interfaces {
+		cellular := IsCellularInterface(netInterface.Name)
+		if !cellular && !hasPrefix(netInterface.Name, tetheringInterfaces) {
+			continue
+		}
+		interfaceTraffic := model.InterfaceTraffic{
+			Name:    netInterface.Name,
+			RxBytes: netInterface.RxBytes,
+			TxBytes: netInterface.TxBytes,
+		}
+		i := slices.IndexFunc(previous.netDev.Interfaces, func(previousInterface parser.NetInterface) bool {
+			return previousInterface.Name == netInterface.Name
+		})
+		if i != -1 && interval > 0 {
+			interfaceTraffic.RxRate = byteRate(previous.netDev.Interfaces[i].RxBytes, netInterface.RxBytes, interval)
+			interfaceTraffic.TxRate = byteRate(previous.netDev.Interfaces[i].TxBytes, netInterface.TxBytes, interval)
+		}
+		if cellular {
+			traffic.RxRate += interfaceTraffic.RxRate
+			traffic.TxRate += interfaceTraffic.TxRate
+		}
+		traffic.Interfaces = append(traffic.Interfaces, interfaceTraffic)
+	}
+	return traffic
+}
+
+func byteRate(previous, current uint64, interval float64) float64 {
+	if current < previous {
+		return 0
+	}
+	return math.Round(float64(current-previous) / interval)
+}
+
+// IsCellularInterface reports whether name carries mobile data, without
+// counting it twice.
+func IsCellularInterface(name string) bool {
+	return hasPrefix(name, cellularInterfaces) && !hasPrefix(name, aggregateInterfaces)
+}
+
+func hasPrefix(name string, prefixes []string) bool {
+	return slices.ContainsFunc(prefixes, func(prefix string) bool { return strings.HasPrefix(name, prefix) })
+}
@@ -0,0 +1,238 @@
+package process_service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	"github.com/basiooo/andromodem/pkg/logger"
+	adb "github.com/basiooo/goadb"
+	"go.uber.org/zap"
+)
+
+// processSampleMaxAge is how old the last read of the CPU time of the
+// processes may be to work out their usage since. Past it the usage would be
+// averaged over too long to tell which process is busy now.
+const processSampleMaxAge = 30 * time.Second
+
+// processSampleInterval is the time between two reads of the CPU time of the
+// processes when there is no recent one.
+const processSampleInterval = 500 * time.Millisecond
+
+type ProcessService struct {
+	Adb               *adb.Adb
+	AdbProcessor      processor.IProcessor
+	CapabilityService capability_service.ICapabilityService
+	Logger            *zap.Logger
+	Ctx               context.Context
+
+	mutex   sync.Mutex
+	samples map[string]processSample
+}
+
+// processSample is the last read of the CPU time of the processes of a
+// device.
+type processSample struct {
+	stat   *parser.ProcessStat
+	readAt time.Time
+}
+
+func NewProcessService(adb *adb.Adb, adbProcessor processor.IProcessor, capabilityService capability_service.ICapabilityService, logger *zap.Logger, ctx context.Context) IProcessService {
+	return &ProcessService{
+		Adb:               adb,
+		AdbProcessor:      adbProcessor,
+		CapabilityService: capabilityService,
+		Logger:            logger,
+		Ctx:               ctx,
+		samples:           make(map[string]processSample),
+	}
+}
+
+// GetProcesses lists the processes running on the device, the busiest first.
+func (p *ProcessService) GetProcesses(ctx context.Context, serial string, query *model.ProcessQuery) (*model.Processes, error) {
+	defer logger.LogDuration(p.Logger, "GetProcesses")()
+	device, err := p.Adb.GetDeviceBySerial(serial)
+	if err != nil || device == nil {
+		p.Logger.Error("error getting device by serial", zap.String("serial", serial), zap.Error(err))
+		return nil, andromodemError.ErrorDeviceNotFound
+	}
+
+	processList, err := processor.Run[*parser.ProcessList](ctx, p.AdbProcessor, device, command.GetDeviceProcessNewCommand, false)
+	if err != nil && ctx.Err() == nil {
+		// ps of toolbox, before Android 8, cannot select columns.
+		p.Logger.Debug("error listing processes, trying legacy ps", zap.String("serial", serial), zap.Error(err))
+		processList, err = processor.Run[*parser.ProcessList](ctx, p.AdbProcessor, device, command.GetDeviceProcessLegacyCommand, false)
+	}
+	if err != nil {
+		p.Logger.Error("error listing processes", zap.String("serial", serial), zap.Error(err))
+		return nil, err
+	}
+
+	processes := processList.Processes
+	p.setCpuUsage(ctx, device, serial, processes)
+	p.setMemoryUsage(ctx, device, processes)
+	sortProcesses(processes, query.Sort)
+	result := &model.Processes{Total: len(processes), Processes: processes}
+	if query.Limit > 0 && query.Limit < len(processes) {
+		result.Processes = processes[:query.Limit]
+	}
+	return result, nil
+}
+
+// setCpuUsage replaces the CPU usage ps printed, the average over the
+// lifetime of every process, by the usage since the CPU time of the
+// processes was last read. The usage of ps is kept when /proc cannot be
+// read.
+func (p *ProcessService) setCpuUsage(ctx context.Context, device *adb.Device, serial string, processes []parser.Process) {
+	usage, err := p.cpuUsage(ctx, device, serial)
+	if err != nil {
+		p.Logger.Warn("error getting process cpu time, processes have their average cpu usage", zap.String("serial", serial), zap.Error(err))
+		return
+	}
+	for i := range processes {
+		processes[i].CPU = usage[processes[i].PID]
+	}
+}
+
+// cpuUsage returns the CPU usage of every process by pid, since the last
+// read when it is at most processSampleMaxAge old. Otherwise the CPU time
+// is read again after processSampleInterval.
+func (p *ProcessService) cpuUsage(ctx context.Context, device *adb.Device, serial string) (map[int]float64, error) {
+	stat, err := processor.Run[*parser.ProcessStat](ctx, p.AdbProcessor, device, command.GetProcessStatCommand, false)
+	if err != nil {
+		return nil, err
+	}
+	previous, ok := p.swapSample(serial, stat)
+	if ok && time.Since(previous.readAt) <= processSampleMaxAge {
+		if usage, ok := stat.UsageSince(previous.stat); ok {
+			return usage, nil
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(processSampleInterval):
+	}
+	next, err := processor.Run[*parser.ProcessStat](ctx, p.AdbProcessor, device, command.GetProcessStatCommand, false)
+	if err != nil {
+		return nil, err
+	}
+	p.swapSample(serial, next)
+	usage, ok := next.UsageSince(stat)
+	if !ok {
+		return nil, errors.New("cpu time did not advance between reads")
+	}
+	return usage, nil
+}
+
+// swapSample stores stat as the last read of serial and returns the one
+// before.
+func (p *ProcessService) swapSample(serial string, stat *parser.ProcessStat) (processSample, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	previous, ok := p.samples[serial]
+	p.samples[serial] = processSample{stat: stat, readAt: time.Now()}
+	return previous, ok
+}
+
+// setMemoryUsage works out the memory usage of processes listed by legacy
+// ps, which only prints their resident memory.
+func (p *ProcessService) setMemoryUsage(ctx context.Context, device *adb.Device, processes []parser.Process) {
+	if !slices.ContainsFunc(processes, func(process parser.Process) bool { return process.Memory == 0 && process.RSS > 0 }) {
+		return
+	}
+	memory, err := processor.Run[*parser.Memory](ctx, p.AdbProcessor, device, command.GetDeviceMemoryCommand, false)
+	if err != nil || memory.MemTotal == 0 {
+		p.Logger.Warn("error getting memory, processes have no memory usage", zap.Error(err))
+		return
+	}
+	for i := range processes {
+		if processes[i].Memory == 0 {
+			processes[i].Memory = math.Round(float64(processes[i].RSS)*1000/float64(memory.MemTotal)) / 10
+		}
+	}
+}
+
+// sortProcesses puts the processes using the most of sortBy first, ties are
+// broken by the other resource.
+func sortProcesses(processes []parser.Process, sortBy model.ProcessSort) {
+	slices.SortStableFunc(processes, func(a, b parser.Process) int {
+		if sortBy == model.ProcessSortMemory {
+			return cmp.Or(cmp.Compare(b.Memory, a.Memory), cmp.Compare(b.CPU, a.CPU))
+		}
+		return cmp.Or(cmp.Compare(b.CPU, a.CPU), cmp.Compare(b.Memory, a.Memory))
+	})
+}
+
+// KillProcess kills the process with pid. Processes of other users can only
+// be killed as root, so the kill is run again as root when su grants the
+// shell access.
+func (p *ProcessService) KillProcess(ctx context.Context, serial string, pid int) error {
+	defer logger.LogDuration(p.Logger, "KillProcess")()
+	device, err := p.Adb.GetDeviceBySerial(serial)
+	if err != nil || device == nil {
+		p.Logger.Error("error getting device by serial", zap.String("serial", serial), zap.Error(err))
+		return andromodemError.ErrorDeviceNotFound
+	}
+
+	killCommand := command.New("kill", strconv.Itoa(pid)).Build()
+	exec := p.AdbProcessor.Executor(device)
+	output, err := exec.Run(ctx, killCommand)
+	if err != nil {
+		p.Logger.Error("error killing process", zap.String("serial", serial), zap.Int("pid", pid), zap.Error(err))
+		return err
+	}
+	err = killError(output)
+	if !errors.Is(err, adbErrors.ErrorNeedShellSuperUserPermission) {
+		return err
+	}
+
+	capabilities, err := p.CapabilityService.GetCapabilities(ctx, serial)
+	if err != nil {
+		p.Logger.Error("error getting device capabilities", zap.String("serial", serial), zap.Error(err))
+		return err
+	}
+	if !capabilities.SuShellAccess {
+		return adbErrors.ErrorNeedRoot
+	}
+	exec.SetSuSyntax(capabilities.SuSyntax)
+	exec.EnableRoot()
+	output, err = exec.Run(ctx, killCommand)
+	if err != nil {
+		p.Logger.Error("error killing process as root", zap.String("serial", serial), zap.Int("pid", pid), zap.Error(err))
+		return err
+	}
+	return killError(output)
+}
+
+// killError returns the error kill printed, kill prints nothing on success.
+func killError(output string) error {
+	output = strings.TrimSpace(output)
+	lower := strings.ToLower(output)
+	switch {
+	case output == "":
+		return nil
+	case strings.Contains(lower, "no such process"):
+		return andromodemError.ErrorProcessNotFound
+	case strings.Contains(lower, "operation not permitted"),
+		strings.Contains(lower, "permission denied"):
+		return adbErrors.ErrorNeedShellSuperUserPermission
+	default:
+		return fmt.Errorf("error killing process: %s", output)
+	}
+}
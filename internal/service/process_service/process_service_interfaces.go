@@ -0,0 +1,12 @@
+package process_service
+
+import (
+	"context"
+
+	"github.com/basiooo/andromodem/internal/model"
+)
+
+type IProcessService interface {
+	GetProcesses(ctx context.Context, serial string, query *model.ProcessQuery) (*model.Processes, error)
+	KillProcess(ctx context.Context, serial string, pid int) error
+}
@@ -0,0 +1,161 @@
+package process_service_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
+	"github.com/basiooo/andromodem/internal/service/process_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
+	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture/fixturetest"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	adb "github.com/basiooo/goadb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func newReplayedService(t *testing.T) process_service.IProcessService {
+	logger := zaptest.NewLogger(t)
+	adbClient, adbProcessor := fixturetest.NewReplayedClient(t)
+	capabilityService, err := capability_service.NewCapabilityService(adbClient, adbProcessor, logger, "")
+	require.NoError(t, err)
+	return process_service.NewProcessService(adbClient, adbProcessor, capabilityService, logger, context.Background())
+}
+
+func TestGetProcessesReplayed(t *testing.T) {
+	t.Parallel()
+	service := newReplayedService(t)
+
+	tests := []struct {
+		name  string
+		query model.ProcessQuery
+		names []string
+	}{
+		{
+			name:  "cpu",
+			query: model.ProcessQuery{Sort: model.ProcessSortCpu, Limit: 3},
+			names: []string{"com.google.android.gms", "system_server", "com.facebook.katana"},
+		},
+		{
+			name:  "memory",
+			query: model.ProcessQuery{Sort: model.ProcessSortMemory, Limit: 2},
+			names: []string{"com.facebook.katana", "system_server"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			processes, err := service.GetProcesses(context.Background(), "2A111FDH200ABC", &tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, 7, processes.Total)
+			names := make([]string, len(processes.Processes))
+			for i, process := range processes.Processes {
+				names[i] = process.Name
+			}
+			assert.Equal(t, tt.names, names)
+		})
+	}
+}
+
+// sampledExecutor answers the reads of the CPU time of the processes with
+// samples in turn, the last one over again, and every other command from
+// the fixtures.
+type sampledExecutor struct {
+	executor.IExecutor
+	mu      *sync.Mutex
+	samples *[]string
+}
+
+func (e *sampledExecutor) Run(ctx context.Context, adbCommand command.AdbCommand) (string, error) {
+	if adbCommand != command.GetProcessStatCommand {
+		return e.IExecutor.Run(ctx, adbCommand)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	sample := (*e.samples)[0]
+	if len(*e.samples) > 1 {
+		*e.samples = (*e.samples)[1:]
+	}
+	return sample, nil
+}
+
+// processStat prints the CPU time of the 8 cores of the Pixel and of the
+// processes, in jiffies.
+func processStat(coreTime uint64, processTimes map[int]uint64) string {
+	var stat strings.Builder
+	fmt.Fprintf(&stat, "cpu  %d 0 0 0 0 0 0 0\n", 8*coreTime)
+	for core := range 8 {
+		fmt.Fprintf(&stat, "cpu%d %d 0 0 0 0 0 0 0\n", core, coreTime)
+	}
+	for pid, cpuTime := range processTimes {
+		fmt.Fprintf(&stat, "%d (process %d) S 1 %d %d 0 -1 4194560 100 0 0 0 %d 0 0 0 20 0 1 0 100 0 0\n", pid, pid, pid, pid, cpuTime)
+	}
+	return stat.String()
+}
+
+func TestGetProcessesCurrentCpuUsage(t *testing.T) {
+	t.Parallel()
+	fixtures, err := fixture.Profiles()
+	require.NoError(t, err)
+	logger := zaptest.NewLogger(t)
+	// Facebook averaged 1.9% since it started, over the last read it kept
+	// 90% of a core busy.
+	samples := []string{
+		processStat(100_000, map[int]uint64{1406: 246_000, 3317: 58_000, 8842: 36_000}),
+		processStat(100_100, map[int]uint64{1406: 246_003, 3317: 58_010, 8842: 36_090}),
+	}
+	newReplayExecutor := fixture.NewReplay(fixtures...).Executor
+	var mu sync.Mutex
+	adbProcessor := processor.NewProcessorWithExecutor(logger, func(device *adb.Device) executor.IExecutor {
+		return &sampledExecutor{IExecutor: newReplayExecutor(device), mu: &mu, samples: &samples}
+	})
+	adbClient := &adb.Adb{Server: fixture.NewServer(fixtures...)}
+	service := process_service.NewProcessService(adbClient, adbProcessor, nil, logger, context.Background())
+
+	processes, err := service.GetProcesses(context.Background(), "2A111FDH200ABC", &model.ProcessQuery{Sort: model.ProcessSortCpu, Limit: 3})
+	require.NoError(t, err)
+	names := make([]string, len(processes.Processes))
+	for i, process := range processes.Processes {
+		names[i] = process.Name
+	}
+	assert.Equal(t, []string{"com.facebook.katana", "com.google.android.gms", "system_server"}, names)
+	assert.Equal(t, []float64{90, 10, 3}, []float64{processes.Processes[0].CPU, processes.Processes[1].CPU, processes.Processes[2].CPU})
+}
+
+func TestKillProcessReplayed(t *testing.T) {
+	t.Parallel()
+	service := newReplayedService(t)
+
+	tests := []struct {
+		name   string
+		serial string
+		pid    int
+		err    error
+	}{
+		// su grants the shell access, the app is killed as root.
+		{name: "root", serial: "8d1c2a3f", pid: 6403},
+		{name: "not rooted", serial: "2A111FDH200ABC", pid: 8842, err: adbErrors.ErrorNeedRoot},
+		{name: "no such process", serial: "8d1c2a3f", pid: 99999, err: andromodemError.ErrorProcessNotFound},
+		{name: "unknown device", serial: "unknown", pid: 6403, err: andromodemError.ErrorDeviceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := service.KillProcess(context.Background(), tt.serial, tt.pid)
+			if tt.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package telemetry_service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/devices_service"
+	network_service "github.com/basiooo/andromodem/internal/service/network"
+	"github.com/basiooo/andromodem/pkg/hub"
+	"go.uber.org/zap"
+)
+
+// LiveTelemetryService streams device and network information of a device
+// to any number of subscribers. A single sampler runs per device while it has
+// subscribers, so extra browser tabs add no ADB load.
+type LiveTelemetryService struct {
+	DevicesService devices_service.IDevicesService
+	NetworkService network_service.INetworkService
+	Logger         *zap.Logger
+	Interval       time.Duration
+	hub            hub.IHub[*model.DeviceTelemetry]
+}
+
+func NewLiveTelemetryService(devicesService devices_service.IDevicesService, networkService network_service.INetworkService, logger *zap.Logger, ctx context.Context, interval time.Duration) ILiveTelemetryService {
+	service := &LiveTelemetryService{
+		DevicesService: devicesService,
+		NetworkService: networkService,
+		Logger:         logger,
+		Interval:       interval,
+	}
+	service.hub = hub.New(ctx, service.sampler, 1)
+	return service
+}
+
+// Subscribe returns the telemetry updates of serial. The subscription must be
+// closed once the client is gone.
+func (l *LiveTelemetryService) Subscribe(serial string) *hub.Subscription[*model.DeviceTelemetry] {
+	return l.hub.Subscribe(serial)
+}
+
+func (l *LiveTelemetryService) sampler(ctx context.Context, serial string, publish func(*model.DeviceTelemetry)) {
+	l.Logger.Info("[Telemetry] Live sampler started", zap.String("serial", serial))
+	defer l.Logger.Info("[Telemetry] Live sampler stopped", zap.String("serial", serial))
+
+	ticker := time.NewTicker(l.Interval)
+	defer ticker.Stop()
+
+	last := &model.DeviceTelemetry{}
+	for {
+		if telemetry := l.sample(ctx, serial, last); telemetry != nil {
+			last = telemetry
+			publish(telemetry)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample reads the device and network information in parallel. A part that
+// cannot be read keeps its previous value, nil is returned when neither
+// could be read.
+func (l *LiveTelemetryService) sample(ctx context.Context, serial string, last *model.DeviceTelemetry) *model.DeviceTelemetry {
+	telemetry := &model.DeviceTelemetry{
+		DeviceInfo: last.DeviceInfo,
+		Network:    last.Network,
+	}
+	var deviceErr, networkErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var deviceInfo *model.DeviceInfo
+		if deviceInfo, deviceErr = l.DevicesService.GetDeviceInfo(ctx, serial); deviceErr == nil {
+			telemetry.DeviceInfo = deviceInfo
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var network *model.Network
+		if network, networkErr = l.NetworkService.GetNetworkInfo(ctx, serial); networkErr == nil {
+			telemetry.Network = network
+		}
+	}()
+	wg.Wait()
+
+	if deviceErr != nil && networkErr != nil {
+		l.Logger.Debug("[Telemetry] Live sample failed",
+			zap.String("serial", serial),
+			zap.NamedError("device_error", deviceErr),
+			zap.NamedError("network_error", networkErr))
+		return nil
+	}
+	return telemetry
+}
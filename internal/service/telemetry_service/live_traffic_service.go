@@ -0,0 +1,57 @@
+package telemetry_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/model"
+	network_service "github.com/basiooo/andromodem/internal/service/network"
+	"github.com/basiooo/andromodem/pkg/hub"
+	"go.uber.org/zap"
+)
+
+// LiveTrafficService streams the throughput of a device to any number of
+// subscribers, sharing one sampler per device like LiveTelemetryService.
+type LiveTrafficService struct {
+	NetworkService network_service.INetworkService
+	Logger         *zap.Logger
+	Interval       time.Duration
+	hub            hub.IHub[*model.NetworkTraffic]
+}
+
+func NewLiveTrafficService(networkService network_service.INetworkService, logger *zap.Logger, ctx context.Context, interval time.Duration) ILiveTrafficService {
+	service := &LiveTrafficService{
+		NetworkService: networkService,
+		Logger:         logger,
+		Interval:       interval,
+	}
+	service.hub = hub.New(ctx, service.sampler, 1)
+	return service
+}
+
+// Subscribe returns the throughput updates of serial. The subscription must
+// be closed once the client is gone.
+func (l *LiveTrafficService) Subscribe(serial string) *hub.Subscription[*model.NetworkTraffic] {
+	return l.hub.Subscribe(serial)
+}
+
+func (l *LiveTrafficService) sampler(ctx context.Context, serial string, publish func(*model.NetworkTraffic)) {
+	l.Logger.Info("[Traffic] Live sampler started", zap.String("serial", serial))
+	defer l.Logger.Info("[Traffic] Live sampler stopped", zap.String("serial", serial))
+
+	ticker := time.NewTicker(l.Interval)
+	defer ticker.Stop()
+
+	for {
+		if traffic, err := l.NetworkService.GetTraffic(ctx, serial); err == nil {
+			publish(traffic)
+		} else {
+			l.Logger.Debug("[Traffic] Live sample failed", zap.String("serial", serial), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
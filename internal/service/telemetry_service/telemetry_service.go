@@ -0,0 +1,266 @@
+package telemetry_service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/lifecycle"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/common_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	"github.com/basiooo/andromodem/pkg/timeseries"
+	adb "github.com/basiooo/goadb"
+	"go.uber.org/zap"
+)
+
+const pruneInterval = time.Hour
+
+// Metric names recorded for every device. Signal metrics are recorded per SIM
+// slot, e.g. sim1_rsrp, core metrics per core, e.g. cpu0_frequency, and
+// thermal metrics per zone type, e.g. thermal_battery.
+const (
+	metricBatteryLevel       = "battery_level"
+	metricBatteryTemperature = "battery_temperature"
+	metricMemoryUsed         = "memory_used"
+	metricMemoryUsedPercent  = "memory_used_percent"
+	metricSimRsrp            = "sim%d_rsrp"
+	metricSimRsrq            = "sim%d_rsrq"
+	metricSimSinr            = "sim%d_sinr"
+	metricSimGeneration      = "sim%d_network_generation"
+	metricCpuUsage           = "cpu_usage"
+	metricCpuTemperature     = "cpu_temperature"
+	metricCoreUsage          = "cpu%d_usage"
+	metricCoreFrequency      = "cpu%d_frequency"
+	metricThermalZone        = "thermal_%s"
+)
+
+// invalidMetricCharacters are replaced in zone types, e.g. cpu-0-0-usr, to
+// use them in metric names.
+var invalidMetricCharacters = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// TelemetryService samples battery, memory, CPU, thermal and signal values
+// of every connected device in the background and keeps them in a
+// time-series store, so past values can be looked up after an outage.
+type TelemetryService struct {
+	Adb          *adb.Adb
+	AdbProcessor processor.IProcessor
+	Logger       *zap.Logger
+	Ctx          context.Context
+	Store        timeseries.IStore
+	Interval     time.Duration
+
+	wg         sync.WaitGroup
+	cpuSamples *common_service.CpuSamples
+}
+
+func NewTelemetryService(adb *adb.Adb, adbProcessor processor.IProcessor, logger *zap.Logger, ctx context.Context, store timeseries.IStore, interval time.Duration) ITelemetryService {
+	service := &TelemetryService{
+		Adb:          adb,
+		AdbProcessor: adbProcessor,
+		Logger:       logger,
+		Ctx:          ctx,
+		Store:        store,
+		Interval:     interval,
+		cpuSamples:   common_service.NewCpuSamples(),
+	}
+
+	service.wg.Add(1)
+	go service.sampler()
+
+	return service
+}
+
+func (t *TelemetryService) sampler() {
+	defer t.wg.Done()
+	t.Logger.Info("[Telemetry] Sampler started", zap.Duration("interval", t.Interval))
+
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+	pruneTicker := time.NewTicker(pruneInterval)
+	defer pruneTicker.Stop()
+
+	t.sampleAll()
+	for {
+		select {
+		case <-t.Ctx.Done():
+			t.Logger.Info("[Telemetry] Sampler stopped")
+			return
+		case <-ticker.C:
+			t.sampleAll()
+		case <-pruneTicker.C:
+			if err := t.Store.Prune(); err != nil {
+				t.Logger.Error("[Telemetry] Failed to prune history", zap.Error(err))
+			}
+		}
+	}
+}
+
+// sampleAll samples every device in parallel and returns once all are done,
+// so a slow device delays the next round instead of piling up requests.
+func (t *TelemetryService) sampleAll() {
+	if t.Adb == nil {
+		return
+	}
+	serials, err := t.Adb.ListDeviceSerials()
+	if err != nil {
+		t.Logger.Error("[Telemetry] Failed to list devices", zap.Error(err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, serial := range serials {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.sampleDevice(serial)
+		}()
+	}
+	wg.Wait()
+}
+
+func (t *TelemetryService) sampleDevice(serial string) {
+	device, err := t.Adb.GetDeviceBySerial(serial)
+	if err != nil || device == nil {
+		t.Logger.Debug("[Telemetry] Device not reachable", zap.String("serial", serial), zap.Error(err))
+		return
+	}
+
+	ctx := t.Ctx
+	now := time.Now()
+	values := make(map[string]float64)
+
+	if battery, err := processor.Run[*parser.Battery](ctx, t.AdbProcessor, device, command.GetBatteryCommand, false); err == nil {
+		level := float64(battery.Level)
+		if battery.Scale > 0 {
+			level = level * 100 / float64(battery.Scale)
+		}
+		values[metricBatteryLevel] = level
+		values[metricBatteryTemperature] = battery.Temperature
+	}
+	if memory, err := processor.Run[*parser.Memory](ctx, t.AdbProcessor, device, command.GetDeviceMemoryCommand, false); err == nil && memory.MemTotal > 0 {
+		values[metricMemoryUsed] = float64(memory.MemUsed)
+		values[metricMemoryUsedPercent] = float64(memory.MemUsed) * 100 / float64(memory.MemTotal)
+	}
+	// The utilisation is the average since the last sampling round.
+	if stat, err := processor.Run[*parser.CpuStat](ctx, t.AdbProcessor, device, command.GetCpuStatCommand, false); err == nil {
+		if usage, ok := t.cpuSamples.Usage(serial, stat); ok {
+			addCpuUsageValues(values, usage)
+		}
+	}
+	if frequency, err := processor.Run[*parser.CpuFrequency](ctx, t.AdbProcessor, device, command.GetCpuFrequencyCommand, false); err == nil {
+		for _, core := range frequency.Cores {
+			values[fmt.Sprintf(metricCoreFrequency, core.Core)] = float64(core.Current)
+		}
+	}
+	if thermalZones, err := processor.Run[*parser.ThermalZones](ctx, t.AdbProcessor, device, command.GetThermalZonesCommand, false); err == nil {
+		addThermalValues(values, thermalZones)
+	}
+	if sims, err := common_service.GetDeviceSims(ctx, device, t.AdbProcessor); err == nil {
+		for _, sim := range sims {
+			addSimValues(values, sim)
+		}
+	}
+
+	if len(values) == 0 {
+		t.Logger.Debug("[Telemetry] No values sampled", zap.String("serial", serial))
+		return
+	}
+	for metric, value := range values {
+		if err := t.Store.Append(serial, metric, timeseries.Point{Timestamp: now, Value: value}); err != nil {
+			t.Logger.Error("[Telemetry] Failed to store sample",
+				zap.String("serial", serial),
+				zap.String("metric", metric),
+				zap.Error(err))
+		}
+	}
+}
+
+func addSimValues(values map[string]float64, sim parser.Sim) {
+	slot := int(sim.SimSlot)
+	if generation := sim.Generation(); generation > 0 {
+		values[fmt.Sprintf(metricSimGeneration, slot)] = float64(generation)
+	}
+
+	var rsrp, rsrq, sinr string
+	switch {
+	case sim.CellSignalStrengthNr != nil:
+		rsrp, rsrq, sinr = sim.SsRsrp, sim.SsRsrq, sim.SsSinr
+	case sim.CellSignalStrengthLte != nil:
+		rsrp, rsrq, sinr = sim.CellSignalStrengthLte.Rsrp, sim.CellSignalStrengthLte.Rsrq, sim.Rssnr
+	default:
+		return
+	}
+	if value, ok := parser.SignalValue(rsrp); ok {
+		values[fmt.Sprintf(metricSimRsrp, slot)] = float64(value)
+	}
+	if value, ok := parser.SignalValue(rsrq); ok {
+		values[fmt.Sprintf(metricSimRsrq, slot)] = float64(value)
+	}
+	if value, ok := parser.SignalValue(sinr); ok {
+		values[fmt.Sprintf(metricSimSinr, slot)] = float64(value)
+	}
+}
+
+func addCpuUsageValues(values map[string]float64, usage *parser.CpuUsage) {
+	values[metricCpuUsage] = usage.Usage
+	for _, core := range usage.Cores {
+		values[fmt.Sprintf(metricCoreUsage, core.Core)] = core.Usage
+	}
+}
+
+// addThermalValues records the temperature of every zone type, the hottest
+// zone of a type when several share it.
+func addThermalValues(values map[string]float64, thermalZones *parser.ThermalZones) {
+	if temperature, ok := thermalZones.CpuTemperature(); ok {
+		values[metricCpuTemperature] = temperature
+	}
+	for _, zone := range thermalZones.Zones {
+		zoneType := strings.Trim(invalidMetricCharacters.ReplaceAllString(strings.ToLower(zone.Type), "_"), "_")
+		if zoneType == "" {
+			zoneType = fmt.Sprintf("zone%d", zone.Zone)
+		}
+		metric := fmt.Sprintf(metricThermalZone, zoneType)
+		if temperature, ok := values[metric]; !ok || zone.Temperature > temperature {
+			values[metric] = zone.Temperature
+		}
+	}
+}
+
+func (t *TelemetryService) GetHistory(serial string, query *model.TelemetryHistoryQuery) (*model.TelemetryHistory, error) {
+	points, err := t.Store.Query(serial, query.Metric, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+	history := &model.TelemetryHistory{
+		Serial: serial,
+		Metric: query.Metric,
+		From:   query.From,
+		To:     query.To,
+		Points: points,
+	}
+	if query.Step > 0 {
+		history.Points, err = timeseries.Downsample(points, query.Step, query.Aggregation)
+		if err != nil {
+			return nil, err
+		}
+		history.Step = query.Step.String()
+		history.Aggregation = query.Aggregation
+	}
+	history.Metrics, err = t.Store.Metrics(serial)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Shutdown waits for a running sampling round to finish. The sampler itself
+// stops once the application context is cancelled.
+func (t *TelemetryService) Shutdown(ctx context.Context) error {
+	return lifecycle.WaitGroup(ctx, &t.wg)
+}
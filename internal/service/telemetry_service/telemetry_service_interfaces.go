@@ -0,0 +1,21 @@
+package telemetry_service
+
+import (
+	"context"
+
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/pkg/hub"
+)
+
+type ITelemetryService interface {
+	GetHistory(string, *model.TelemetryHistoryQuery) (*model.TelemetryHistory, error)
+	Shutdown(context.Context) error
+}
+
+type ILiveTelemetryService interface {
+	Subscribe(string) *hub.Subscription[*model.DeviceTelemetry]
+}
+
+type ILiveTrafficService interface {
+	Subscribe(string) *hub.Subscription[*model.NetworkTraffic]
+}
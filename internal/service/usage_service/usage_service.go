@@ -0,0 +1,462 @@
+package usage_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/lifecycle"
+	"github.com/basiooo/andromodem/internal/model"
+	network_service "github.com/basiooo/andromodem/internal/service/network"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	adb "github.com/basiooo/goadb"
+	"go.uber.org/zap"
+)
+
+// historyCycles is how many past billing cycles are kept per SIM slot.
+const historyCycles = 12
+
+// saveInterval is how often the ledger is written when nothing but the
+// counters changed, flash storage of routers wears out under a write per
+// sample.
+const saveInterval = 30 * time.Minute
+
+type usageStore struct {
+	Devices map[string]*model.DeviceUsage `json:"devices"`
+}
+
+// UsageService keeps a ledger of the mobile data used through every SIM slot
+// of every device, sampled from the cellular interface counters in the
+// background. The ledger is persisted together with the last counters, so
+// a counter that went down, after a reboot or a reset of the interface, is
+// counted from zero instead of being lost. It is written when a cycle ends,
+// a warning is raised, data is cut off or a quota changes, otherwise every
+// saveInterval and on Shutdown.
+type UsageService struct {
+	Adb            *adb.Adb
+	AdbProcessor   processor.IProcessor
+	NetworkService network_service.INetworkService
+	Logger         *zap.Logger
+	Ctx            context.Context
+	Interval       time.Duration
+	// CycleStartDay is the billing cycle start day of slots without a quota.
+	CycleStartDay int
+
+	wg        sync.WaitGroup
+	mutex     sync.Mutex
+	usageFile string
+	store     usageStore
+	// dirty tells the store changed since savedAt.
+	dirty   bool
+	savedAt time.Time
+}
+
+func NewUsageService(adb *adb.Adb, adbProcessor processor.IProcessor, networkService network_service.INetworkService, logger *zap.Logger, ctx context.Context, usageFile string, interval time.Duration, cycleStartDay int) (IUsageService, error) {
+	service := &UsageService{
+		Adb:            adb,
+		AdbProcessor:   adbProcessor,
+		NetworkService: networkService,
+		Logger:         logger,
+		Ctx:            ctx,
+		Interval:       interval,
+		CycleStartDay:  cycleStartDay,
+		usageFile:      usageFile,
+		store:          usageStore{Devices: make(map[string]*model.DeviceUsage)},
+	}
+	if err := service.load(); err != nil {
+		return nil, err
+	}
+
+	service.wg.Add(1)
+	go service.sampler()
+
+	return service, nil
+}
+
+// GetUsage returns the ledger of serial as of the last sample.
+func (u *UsageService) GetUsage(serial string) (*model.DeviceUsage, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	usage, ok := u.store.Devices[serial]
+	if !ok {
+		return nil, andromodemError.ErrorDeviceNotFound
+	}
+	return cloneUsage(usage), nil
+}
+
+// SetQuota replaces the quota of a SIM slot. The data used in the current
+// cycle is kept, the cycle is moved to the new start day and warnings and
+// cutoff are raised again against the new limit.
+func (u *UsageService) SetQuota(serial string, simSlot int, quota *model.UsageQuota) (*model.SimUsage, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	usage, ok := u.store.Devices[serial]
+	if !ok {
+		if device, err := u.Adb.GetDeviceBySerial(serial); err != nil || device == nil {
+			return nil, andromodemError.ErrorDeviceNotFound
+		}
+		usage = &model.DeviceUsage{Serial: serial, Counters: make(map[string]model.InterfaceCounters)}
+		u.store.Devices[serial] = usage
+	}
+
+	simUsage := u.simUsage(usage, simSlot, time.Now())
+	simUsage.Quota = *quota
+	simUsage.Quota.Warnings = slices.Compact(slices.Sorted(slices.Values(quota.Warnings)))
+	if simUsage.Quota.Warnings == nil {
+		simUsage.Quota.Warnings = make([]int, 0)
+	}
+	start := cycleStart(time.Now(), quota.CycleStartDay)
+	simUsage.Cycle.Start, simUsage.Cycle.End = start, start.AddDate(0, 1, 0)
+	simUsage.WarningsRaised = make([]int, 0)
+	simUsage.CutoffAt = nil
+	simUsage.UsedPercent = usedPercent(simUsage)
+	for _, warning := range simUsage.Quota.Warnings {
+		if simUsage.Quota.Limit > 0 && simUsage.UsedPercent >= float64(warning) {
+			simUsage.WarningsRaised = append(simUsage.WarningsRaised, warning)
+		}
+	}
+
+	if err := u.save(); err != nil {
+		return nil, err
+	}
+	return cloneSimUsage(simUsage), nil
+}
+
+func (u *UsageService) sampler() {
+	defer u.wg.Done()
+	u.Logger.Info("[Usage] Sampler started", zap.Duration("interval", u.Interval))
+
+	ticker := time.NewTicker(u.Interval)
+	defer ticker.Stop()
+
+	u.sampleAll()
+	for {
+		select {
+		case <-u.Ctx.Done():
+			u.Logger.Info("[Usage] Sampler stopped")
+			return
+		case <-ticker.C:
+			u.sampleAll()
+		}
+	}
+}
+
+func (u *UsageService) sampleAll() {
+	if u.Adb == nil {
+		return
+	}
+	serials, err := u.Adb.ListDeviceSerials()
+	if err != nil {
+		u.Logger.Error("[Usage] Failed to list devices", zap.Error(err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, serial := range serials {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u.sampleDevice(serial)
+		}()
+	}
+	wg.Wait()
+}
+
+func (u *UsageService) sampleDevice(serial string) {
+	device, err := u.Adb.GetDeviceBySerial(serial)
+	if err != nil || device == nil {
+		u.Logger.Debug("[Usage] Device not reachable", zap.String("serial", serial), zap.Error(err))
+		return
+	}
+
+	// Mobile data turned back on over the limit is cut off at this sample,
+	// not once the cached state expired.
+	results, err := u.AdbProcessor.RunBatch(processor.WithoutCache(u.Ctx), device, command.GetNetDevCommand, command.GetMobileDataStateCommand)
+	if err != nil {
+		u.Logger.Debug("[Usage] Sample failed", zap.String("serial", serial), zap.Error(err))
+		return
+	}
+	netDev, err := processor.Result[*parser.NetDev](results[0])
+	if err != nil {
+		u.Logger.Debug("[Usage] Reading the traffic counters failed", zap.String("serial", serial), zap.Error(err))
+		return
+	}
+	simSlot, connected := 1, false
+	if rawState, err := processor.Result[*parser.RawParser](results[1]); err == nil {
+		simSlot, connected = connectedSimSlot(rawState.Result)
+	}
+
+	if u.record(serial, netDev, simSlot, connected, time.Now()) {
+		if err := u.NetworkService.DisableMobileData(u.Ctx, serial); err != nil {
+			u.Logger.Error("[Usage] Failed to disable mobile data", zap.String("serial", serial), zap.Error(err))
+			return
+		}
+		u.Logger.Warn("[Usage] Mobile data disabled, data quota reached", zap.String("serial", serial), zap.Int("sim_slot", simSlot))
+		u.markCutoff(serial, simSlot, time.Now())
+	}
+}
+
+// record adds the traffic since the previous sample of serial to simSlot.
+// The first sample of a device only stores the counters. It returns true
+// when mobile data has to be cut off: the limit is reached for the first
+// time in the cycle, or mobile data was turned on again since.
+func (u *UsageService) record(serial string, netDev *parser.NetDev, simSlot int, connected bool, now time.Time) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	usage, ok := u.store.Devices[serial]
+	if !ok {
+		usage = &model.DeviceUsage{Serial: serial}
+		u.store.Devices[serial] = usage
+	}
+	sampled := !usage.SampledAt.IsZero()
+	counters := make(map[string]model.InterfaceCounters)
+	var rx, tx uint64
+	for _, netInterface := range netDev.Interfaces {
+		if !network_service.IsCellularInterface(netInterface.Name) {
+			continue
+		}
+		current := model.InterfaceCounters{RxBytes: netInterface.RxBytes, TxBytes: netInterface.TxBytes}
+		counters[netInterface.Name] = current
+		// An interface that came up since the previous sample counts from zero.
+		if sampled {
+			previous := usage.Counters[netInterface.Name]
+			rx += counterDelta(previous.RxBytes, current.RxBytes)
+			tx += counterDelta(previous.TxBytes, current.TxBytes)
+		}
+	}
+	usage.Counters = counters
+	usage.SampledAt = now
+
+	rolled := false
+	for _, simUsage := range usage.Sims {
+		rolled = u.rollCycle(simUsage, now) || rolled
+	}
+	simUsage := u.simUsage(usage, simSlot, now)
+	simUsage.Cycle.RxBytes += rx
+	simUsage.Cycle.TxBytes += tx
+	simUsage.UsedPercent = usedPercent(simUsage)
+	raised := u.raiseWarnings(serial, simUsage)
+
+	u.dirty = true
+	if rolled || raised || now.Sub(u.savedAt) >= saveInterval {
+		u.persist()
+	}
+	quota := simUsage.Quota
+	exceeded := quota.Cutoff && quota.Limit > 0 && simUsage.Cycle.Total() >= quota.Limit
+	return exceeded && (simUsage.CutoffAt == nil || connected)
+}
+
+func (u *UsageService) markCutoff(serial string, simSlot int, now time.Time) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	usage, ok := u.store.Devices[serial]
+	if !ok {
+		return
+	}
+	u.simUsage(usage, simSlot, now).CutoffAt = &now
+	u.persist()
+}
+
+// raiseWarnings logs every threshold of the quota crossed for the first time
+// in the current cycle. It returns true when one was.
+func (u *UsageService) raiseWarnings(serial string, simUsage *model.SimUsage) bool {
+	if simUsage.Quota.Limit == 0 {
+		return false
+	}
+	raised := false
+	for _, warning := range simUsage.Quota.Warnings {
+		if simUsage.UsedPercent < float64(warning) || slices.Contains(simUsage.WarningsRaised, warning) {
+			continue
+		}
+		simUsage.WarningsRaised = append(simUsage.WarningsRaised, warning)
+		raised = true
+		u.Logger.Warn("[Usage] Data quota threshold reached",
+			zap.String("serial", serial),
+			zap.Int("sim_slot", simUsage.SimSlot),
+			zap.Int("threshold", warning),
+			zap.Uint64("used", simUsage.Cycle.Total()),
+			zap.Uint64("limit", simUsage.Quota.Limit))
+	}
+	return raised
+}
+
+// simUsage returns the ledger of simSlot, creating it with the default cycle
+// start day. Callers must hold the lock.
+func (u *UsageService) simUsage(usage *model.DeviceUsage, simSlot int, now time.Time) *model.SimUsage {
+	for _, simUsage := range usage.Sims {
+		if simUsage.SimSlot == simSlot {
+			return simUsage
+		}
+	}
+	start := cycleStart(now, u.CycleStartDay)
+	simUsage := &model.SimUsage{
+		SimSlot:        simSlot,
+		Quota:          model.UsageQuota{CycleStartDay: u.CycleStartDay, Warnings: make([]int, 0)},
+		Cycle:          model.CycleUsage{Start: start, End: start.AddDate(0, 1, 0)},
+		WarningsRaised: make([]int, 0),
+		History:        make([]model.CycleUsage, 0),
+	}
+	usage.Sims = append(usage.Sims, simUsage)
+	slices.SortFunc(usage.Sims, func(a, b *model.SimUsage) int { return a.SimSlot - b.SimSlot })
+	return simUsage
+}
+
+// rollCycle moves a finished cycle to the history and starts the current one.
+// It returns true when the cycle ended.
+func (u *UsageService) rollCycle(simUsage *model.SimUsage, now time.Time) bool {
+	if now.Before(simUsage.Cycle.End) {
+		return false
+	}
+	simUsage.History = append(simUsage.History, simUsage.Cycle)
+	if len(simUsage.History) > historyCycles {
+		simUsage.History = simUsage.History[len(simUsage.History)-historyCycles:]
+	}
+	start := cycleStart(now, simUsage.Quota.CycleStartDay)
+	simUsage.Cycle = model.CycleUsage{Start: start, End: start.AddDate(0, 1, 0)}
+	simUsage.UsedPercent = 0
+	simUsage.WarningsRaised = make([]int, 0)
+	simUsage.CutoffAt = nil
+	return true
+}
+
+// cycleStart returns the start of the billing cycle now is in, midnight of
+// the last start day.
+func cycleStart(now time.Time, day int) time.Time {
+	year, month, today := now.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	if today < day {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start
+}
+
+// counterDelta is the traffic between two reads of a counter. A counter that
+// went down was reset and counts from zero.
+func counterDelta(previous, current uint64) uint64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+func usedPercent(simUsage *model.SimUsage) float64 {
+	if simUsage.Quota.Limit == 0 {
+		return 0
+	}
+	return math.Round(float64(simUsage.Cycle.Total())*1000/float64(simUsage.Quota.Limit)) / 10
+}
+
+// connectedSimSlot returns the slot whose mobile data is connected, the
+// output of GetMobileDataStateCommand holds one state per slot. It falls
+// back to the first slot, not connected.
+func connectedSimSlot(rawStates string) (int, bool) {
+	for i, rawState := range strings.Split(strings.TrimSpace(rawStates), "\n") {
+		state := &parser.MobileDataState{}
+		if err := state.Parse(strings.TrimSpace(rawState)); err == nil && state.State == parser.DataConnected {
+			return i + 1, true
+		}
+	}
+	return 1, false
+}
+
+func cloneUsage(usage *model.DeviceUsage) *model.DeviceUsage {
+	cloned := *usage
+	cloned.Counters = maps.Clone(usage.Counters)
+	cloned.Sims = make([]*model.SimUsage, len(usage.Sims))
+	for i, simUsage := range usage.Sims {
+		cloned.Sims[i] = cloneSimUsage(simUsage)
+	}
+	return &cloned
+}
+
+func cloneSimUsage(simUsage *model.SimUsage) *model.SimUsage {
+	cloned := *simUsage
+	cloned.Quota.Warnings = slices.Clone(simUsage.Quota.Warnings)
+	cloned.WarningsRaised = slices.Clone(simUsage.WarningsRaised)
+	cloned.History = slices.Clone(simUsage.History)
+	if simUsage.CutoffAt != nil {
+		cutoffAt := *simUsage.CutoffAt
+		cloned.CutoffAt = &cutoffAt
+	}
+	return &cloned
+}
+
+// Shutdown waits for a running sampling round to finish and saves the
+// counters sampled since the last write. The sampler itself stops once the
+// application context is cancelled.
+func (u *UsageService) Shutdown(ctx context.Context) error {
+	err := lifecycle.WaitGroup(ctx, &u.wg)
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.dirty {
+		u.persist()
+	}
+	return err
+}
+
+func (u *UsageService) load() error {
+	if u.usageFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(u.usageFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &u.store); err != nil {
+		return fmt.Errorf("%s: %w", u.usageFile, err)
+	}
+	if u.store.Devices == nil {
+		u.store.Devices = make(map[string]*model.DeviceUsage)
+	}
+	return nil
+}
+
+// persist saves the store, a failure is only logged: the ledger is kept in
+// memory and written again by the next save. Callers must hold the lock.
+func (u *UsageService) persist() {
+	if err := u.save(); err != nil {
+		u.Logger.Error("[Usage] Failed to save the usage ledger", zap.Error(err))
+	}
+}
+
+// save writes the store atomically. Callers must hold the lock.
+func (u *UsageService) save() error {
+	if u.usageFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(u.store, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(u.usageFile); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmpFile := u.usageFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, u.usageFile); err != nil {
+		return err
+	}
+	u.dirty = false
+	u.savedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,13 @@
+package usage_service
+
+import (
+	"context"
+
+	"github.com/basiooo/andromodem/internal/model"
+)
+
+type IUsageService interface {
+	GetUsage(string) (*model.DeviceUsage, error)
+	SetQuota(string, int, *model.UsageQuota) (*model.SimUsage, error)
+	Shutdown(context.Context) error
+}
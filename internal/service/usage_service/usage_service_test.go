@@ -0,0 +1,176 @@
+package usage_service_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	andromodemError "github.com/basiooo/andromodem/internal/errors"
+	"github.com/basiooo/andromodem/internal/model"
+	"github.com/basiooo/andromodem/internal/service/capability_service"
+	network_service "github.com/basiooo/andromodem/internal/service/network"
+	"github.com/basiooo/andromodem/internal/service/usage_service"
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture/fixturetest"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	"github.com/basiooo/andromodem/pkg/simulator"
+	adb "github.com/basiooo/goadb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+const sampleInterval = 100 * time.Millisecond
+
+func TestUsageReplayedAfterReboot(t *testing.T) {
+	t.Parallel()
+	logger := zaptest.NewLogger(t)
+	adbClient, adbProcessor := fixturetest.NewReplayedClient(t)
+
+	// The ledger was saved before the phone rebooted, with higher counters
+	// and a cycle that has ended since.
+	now := time.Now()
+	previousCycle := model.CycleUsage{Start: now.AddDate(0, -1, -1), End: now.AddDate(0, 0, -1), RxBytes: 1000, TxBytes: 500}
+	ledger := map[string]map[string]*model.DeviceUsage{
+		"devices": {
+			"R58R31ABCDE": {
+				Serial:    "R58R31ABCDE",
+				SampledAt: now.Add(-time.Hour),
+				Counters:  map[string]model.InterfaceCounters{"rmnet_data1": {RxBytes: 10_000_000_000, TxBytes: 2_000_000_000}},
+				Sims: []*model.SimUsage{{
+					SimSlot:        1,
+					Quota:          model.UsageQuota{CycleStartDay: 1, Warnings: []int{}},
+					Cycle:          previousCycle,
+					WarningsRaised: []int{},
+					History:        []model.CycleUsage{},
+				}},
+			},
+		},
+	}
+	usageFile := filepath.Join(t.TempDir(), "usage.json")
+	data, err := json.Marshal(ledger)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(usageFile, data, 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	service, err := usage_service.NewUsageService(adbClient, adbProcessor, nil, logger, ctx, usageFile, time.Hour, 1)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		usage, err := service.GetUsage("R58R31ABCDE")
+		return err == nil && len(usage.Sims[0].History) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, service.Shutdown(context.Background()))
+
+	usage, err := service.GetUsage("R58R31ABCDE")
+	require.NoError(t, err)
+	simUsage := usage.Sims[0]
+	assert.Equal(t, previousCycle.Total(), simUsage.History[0].Total())
+	// The counters went down, the new cycle counts them from zero.
+	assert.Equal(t, uint64(9012345678), simUsage.Cycle.RxBytes)
+	assert.Equal(t, uint64(1423456789), simUsage.Cycle.TxBytes)
+	assert.True(t, simUsage.Cycle.End.After(now))
+	assert.NotContains(t, usage.Counters, "rmnet_ipa0")
+
+	reloaded, err := usage_service.NewUsageService(nil, adbProcessor, nil, logger, ctx, usageFile, time.Hour, 1)
+	require.NoError(t, err)
+	require.NoError(t, reloaded.Shutdown(context.Background()))
+	reloadedUsage, err := reloaded.GetUsage("R58R31ABCDE")
+	require.NoError(t, err)
+	assert.Equal(t, simUsage.Cycle.Total(), reloadedUsage.Sims[0].Cycle.Total())
+
+	_, err = service.GetUsage("unknown")
+	assert.ErrorIs(t, err, andromodemError.ErrorDeviceNotFound)
+}
+
+func TestUsageQuotaCutoffSimulated(t *testing.T) {
+	t.Parallel()
+	fixtures, err := fixture.Profiles()
+	require.NoError(t, err)
+	logger := zaptest.NewLogger(t)
+	sim := simulator.New(fixtures...)
+	adbClient := &adb.Adb{Server: sim}
+	adbProcessor := processor.NewProcessor(logger)
+	capabilityService, err := capability_service.NewCapabilityService(adbClient, adbProcessor, logger, "")
+	require.NoError(t, err)
+	networkService := network_service.NewNetworkService(adbClient, adbProcessor, capabilityService, logger, context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service, err := usage_service.NewUsageService(adbClient, adbProcessor, networkService, logger, ctx, "", sampleInterval, 1)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, service.Shutdown(context.Background()))
+	})
+
+	// The simulator receives 250 kB/s while mobile data is connected.
+	quota := &model.UsageQuota{CycleStartDay: 5, Limit: 10_000, Warnings: []int{90, 50, 90}, Cutoff: true}
+	simUsage, err := service.SetQuota("2A111FDH200ABC", 1, quota)
+	require.NoError(t, err)
+	assert.Equal(t, []int{50, 90}, simUsage.Quota.Warnings)
+	assert.Equal(t, 5, simUsage.Cycle.Start.Day())
+
+	require.Eventually(t, func() bool {
+		usage, err := service.GetUsage("2A111FDH200ABC")
+		return err == nil && usage.Sims[0].CutoffAt != nil
+	}, 10*time.Second, 10*time.Millisecond)
+	usage, err := service.GetUsage("2A111FDH200ABC")
+	require.NoError(t, err)
+	assert.Equal(t, []int{50, 90}, usage.Sims[0].WarningsRaised)
+	assert.GreaterOrEqual(t, usage.Sims[0].Cycle.Total(), quota.Limit)
+	assert.GreaterOrEqual(t, usage.Sims[0].UsedPercent, 100.0)
+
+	output, _, err := sim.Run("2A111FDH200ABC", string(command.GetMobileDataStatusCommand))
+	require.NoError(t, err)
+	assert.Equal(t, "0", strings.TrimSpace(output))
+
+	// Turned back on over the limit, mobile data is cut off again.
+	cutoffAt := *usage.Sims[0].CutoffAt
+	_, _, err = sim.Run("2A111FDH200ABC", string(command.EnableMobileDataCommand))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		usage, err := service.GetUsage("2A111FDH200ABC")
+		return err == nil && usage.Sims[0].CutoffAt.After(cutoffAt)
+	}, 10*time.Second, 10*time.Millisecond)
+	output, _, err = sim.Run("2A111FDH200ABC", string(command.GetMobileDataStatusCommand))
+	require.NoError(t, err)
+	assert.Equal(t, "0", strings.TrimSpace(output))
+
+	_, err = service.SetQuota("unknown", 1, quota)
+	assert.ErrorIs(t, err, andromodemError.ErrorDeviceNotFound)
+}
+
+func TestUsageSavedOnlyWhenNeeded(t *testing.T) {
+	t.Parallel()
+	logger := zaptest.NewLogger(t)
+	adbClient, adbProcessor := fixturetest.NewReplayedClient(t)
+	usageFile := filepath.Join(t.TempDir(), "usage.json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	service, err := usage_service.NewUsageService(adbClient, adbProcessor, nil, logger, ctx, usageFile, sampleInterval, 1)
+	require.NoError(t, err)
+	// The first samples are written, the next ones only change the counters.
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(usageFile)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	usage, err := service.GetUsage("R58R31ABCDE")
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(usageFile))
+	require.Eventually(t, func() bool {
+		sampled, err := service.GetUsage("R58R31ABCDE")
+		return err == nil && sampled.SampledAt.After(usage.SampledAt)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NoFileExists(t, usageFile)
+
+	cancel()
+	require.NoError(t, service.Shutdown(context.Background()))
+	assert.FileExists(t, usageFile)
+}
@@ -0,0 +1,146 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// safeWord matches arguments the shell reads literally, they are left
+// unquoted to keep commands readable in logs.
+var safeWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// globWord matches patterns the shell expands to file names and nothing
+// else: safe words with *, ? and bracket expressions.
+var globWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./*?\[\]-]+$`)
+
+// Quote returns arg quoted for a POSIX shell, so it reaches the command as a
+// single argument whatever it contains.
+func Quote(arg string) string {
+	if safeWord.MatchString(arg) {
+		return arg
+	}
+	return singleQuote(arg)
+}
+
+// singleQuote wraps s in single quotes. A single quote inside s ends the
+// quoted string, is added escaped and a new quoted string is started.
+func singleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Builder builds a shell command from its name and arguments. Every argument
+// is quoted, so values from users cannot change the command.
+type Builder struct {
+	words []string
+}
+
+// New starts a command running name with args.
+func New(name string, args ...string) *Builder {
+	return (&Builder{}).Arg(name).Arg(args...)
+}
+
+// Arg appends arguments to the command.
+func (b *Builder) Arg(args ...string) *Builder {
+	for _, arg := range args {
+		b.words = append(b.words, Quote(arg))
+	}
+	return b
+}
+
+// Glob appends patterns left unquoted, for the shell to expand to the file
+// names matching them. Patterns are fixed by the caller, never values from
+// users, anything but a file name pattern panics.
+func (b *Builder) Glob(patterns ...string) *Builder {
+	for _, pattern := range patterns {
+		if !globWord.MatchString(pattern) {
+			panic(fmt.Sprintf("command: %q is not a file name pattern", pattern))
+		}
+		b.words = append(b.words, pattern)
+	}
+	return b
+}
+
+// DiscardErrors drops what the command prints to stderr, e.g. files a glob
+// matched that the shell cannot read.
+func (b *Builder) DiscardErrors() *Builder {
+	b.words = append(b.words, "2>/dev/null")
+	return b
+}
+
+func (b *Builder) String() string {
+	return strings.Join(b.words, " ")
+}
+
+// Build returns the command ready to be run.
+func (b *Builder) Build() AdbCommand {
+	return AdbCommand(b.String())
+}
+
+// Pipeline connects the output of every command to the input of the next.
+func Pipeline(commands ...*Builder) AdbCommand {
+	parts := make([]string, len(commands))
+	for i, command := range commands {
+		parts[i] = command.String()
+	}
+	return AdbCommand(strings.Join(parts, " | "))
+}
+
+// And runs every command once the previous one succeeded.
+func And(commands ...*Builder) AdbCommand {
+	parts := make([]string, len(commands))
+	for i, command := range commands {
+		parts[i] = command.String()
+	}
+	return AdbCommand(strings.Join(parts, " && "))
+}
+
+// SuSyntax is the way a su binary takes the command to run as root.
+type SuSyntax string
+
+const (
+	// SuSyntaxStandard is understood by SuperSU, Magisk, KernelSU and most
+	// other su implementations.
+	SuSyntaxStandard SuSyntax = "standard"
+	// SuSyntaxMagisk runs the command in the global mount namespace, so
+	// mounts made by Magisk modules are visible.
+	SuSyntaxMagisk SuSyntax = "magisk"
+	// SuSyntaxKernelSU is the Magisk compatible syntax of KernelSU.
+	SuSyntaxKernelSU SuSyntax = "kernelsu"
+	// SuSyntaxUid is the syntax of the AOSP su of userdebug and eng builds,
+	// which takes a uid and a command instead of -c.
+	SuSyntaxUid SuSyntax = "uid"
+)
+
+// SuNameAosp names the AOSP su, which has no `su -v` and rejects -v as an
+// invalid uid.
+const SuNameAosp = "AOSP"
+
+// SuSyntaxFor returns the syntax of the su implementation named by `su -v`,
+// e.g. "MAGISK" or "KernelSU", or SuNameAosp.
+func SuSyntaxFor(rootName string) SuSyntax {
+	switch strings.ToLower(strings.TrimSpace(rootName)) {
+	case "magisk", "magisksu":
+		return SuSyntaxMagisk
+	case "kernelsu":
+		return SuSyntaxKernelSU
+	case "aosp":
+		return SuSyntaxUid
+	default:
+		return SuSyntaxStandard
+	}
+}
+
+// WithRoot wraps adbCommand so it runs as root. The whole command, pipes and
+// multi-line scripts included, is passed to su as one quoted argument.
+func WithRoot(adbCommand AdbCommand, syntax SuSyntax) AdbCommand {
+	script := singleQuote(string(adbCommand))
+	switch syntax {
+	case SuSyntaxMagisk, SuSyntaxKernelSU:
+		return AdbCommand("su --mount-master -c " + script)
+	case SuSyntaxUid:
+		return AdbCommand("su 0 sh -c " + script)
+	default:
+		return AdbCommand("su -c " + script)
+	}
+}
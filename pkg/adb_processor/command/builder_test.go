@@ -0,0 +1,129 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuote(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		arg      string
+		expected string
+	}{
+		{"getprop", "getprop"},
+		{"ro.build.version.release", "ro.build.version.release"},
+		{"", "''"},
+		{"8.8.8.8; reboot", "'8.8.8.8; reboot'"},
+		{"it's", `'it'\''s'`},
+		{"$(id)", "'$(id)'"},
+		{"a\nb", "'a\nb'"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Quote(test.arg), test.arg)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, AdbCommand("ping -c 1 -W 5 google.com"), New("ping", "-c", "1", "-W", "5", "google.com").Build())
+	assert.Equal(t, AdbCommand("ping -c 1 'google.com; reboot'"), New("ping", "-c", "1").Arg("google.com; reboot").Build())
+	assert.Equal(t,
+		AdbCommand("dumpsys telephony.registry | grep -o 'mDataConnectionState=[^ ]*'"),
+		Pipeline(New("dumpsys", "telephony.registry"), New("grep", "-o", "mDataConnectionState=[^ ]*")),
+	)
+}
+
+func TestBuilderGlob(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t,
+		AdbCommand("cd /sys/class/thermal && grep -H . thermal_zone*/type 2>/dev/null"),
+		And(New("cd", "/sys/class/thermal"), New("grep", "-H", ".").Glob("thermal_zone*/type").DiscardErrors()),
+	)
+	assert.Equal(t, "ls cpu[0-9]*", New("ls").Glob("cpu[0-9]*").String())
+	assert.Panics(t, func() { New("ls").Glob("*; reboot") })
+	assert.Panics(t, func() { New("ls").Glob("$(id)*") })
+}
+
+func TestWithRoot(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		syntax   SuSyntax
+		expected AdbCommand
+	}{
+		{SuSyntaxStandard, `su -c 'grep -o '\''a b'\'' | sed s/x//'`},
+		{SuSyntaxMagisk, `su --mount-master -c 'grep -o '\''a b'\'' | sed s/x//'`},
+		{SuSyntaxKernelSU, `su --mount-master -c 'grep -o '\''a b'\'' | sed s/x//'`},
+		{SuSyntaxUid, `su 0 sh -c 'grep -o '\''a b'\'' | sed s/x//'`},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, WithRoot("grep -o 'a b' | sed s/x//", test.syntax), string(test.syntax))
+	}
+	assert.Equal(t, AdbCommand("su -c 'echo 1'"), GetDeviceRootAccessCommand)
+}
+
+// shellWords splits a command into the words a POSIX shell passes to it,
+// for commands quoted by Quote: single quotes and backslashes outside them.
+func shellWords(t *testing.T, commandLine string) []string {
+	var words []string
+	var word strings.Builder
+	inWord, quoted := false, false
+	for i := 0; i < len(commandLine); i++ {
+		c := commandLine[i]
+		switch {
+		case quoted && c == '\'':
+			quoted = false
+		case quoted:
+			word.WriteByte(c)
+		case c == '\'':
+			quoted, inWord = true, true
+		case c == '\\':
+			require.Less(t, i+1, len(commandLine), "trailing backslash in %q", commandLine)
+			i++
+			word.WriteByte(commandLine[i])
+			inWord = true
+		case c == ' ':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	require.False(t, quoted, "unterminated quote in %q", commandLine)
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+func TestWithRootRoundTrip(t *testing.T) {
+	t.Parallel()
+	prefixes := map[SuSyntax][]string{
+		SuSyntaxStandard: {"su", "-c"},
+		SuSyntaxMagisk:   {"su", "--mount-master", "-c"},
+		SuSyntaxKernelSU: {"su", "--mount-master", "-c"},
+		SuSyntaxUid:      {"su", "0", "sh", "-c"},
+	}
+	for syntax, prefix := range prefixes {
+		for _, adbCommand := range All() {
+			words := shellWords(t, string(WithRoot(adbCommand, syntax)))
+			// su gets the command back unchanged, as a single argument.
+			assert.Equal(t, append(prefix, string(adbCommand)), words, "%s: %s", syntax, adbCommand)
+		}
+	}
+}
+
+func TestSuSyntaxFor(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, SuSyntaxMagisk, SuSyntaxFor("MAGISK"))
+	assert.Equal(t, SuSyntaxKernelSU, SuSyntaxFor("KernelSU"))
+	assert.Equal(t, SuSyntaxUid, SuSyntaxFor(SuNameAosp))
+	assert.Equal(t, SuSyntaxStandard, SuSyntaxFor("SUPERSU"))
+}
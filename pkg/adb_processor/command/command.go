@@ -1,48 +1,270 @@
-// Package command defines constants for various ADB commands used to interact with Android devices.
+// Package command defines the ADB commands used to interact with Android devices.
 package command
 
+import (
+	"slices"
+	"time"
+)
+
 type AdbCommand string
 
-const (
-	GetBatteryCommand                  AdbCommand = "dumpsys battery"                                                        //nolint:all, get battery info
-	GetRootCommand                     AdbCommand = "su -v"                                                                  // get root version
-	GetDevicePropCommand               AdbCommand = "getprop"                                                                // get all device property
-	GetInboxCommand                    AdbCommand = "content query --uri content://sms/inbox --projection address,body,date" // get sms inbox message sort by date *Request root permission on android 9 and below
-	GetAirplaneModeStatusLegacyCommand AdbCommand = "settings get global airplane_mode_on"                                   // get airplane mode status in Android 8 and below
-	GetAirplaneModeStatusNewCommand    AdbCommand = "cmd connectivity airplane-mode"                                         // get airplane mode status in Android 9 and newer
+// Commands are built from their arguments, see Builder.
+var (
+	GetBatteryCommand                  = New("dumpsys", "battery").Build()                                                                    //nolint:all, get battery info
+	GetRootCommand                     = New("su", "-v").Build()                                                                              // get root version
+	GetDevicePropCommand               = New("getprop").Build()                                                                               // get all device property
+	GetInboxCommand                    = New("content", "query", "--uri", "content://sms/inbox", "--projection", "address,body,date").Build() // get sms inbox message sort by date *Request root permission on android 9 and below
+	GetAirplaneModeStatusLegacyCommand = New("settings", "get", "global", "airplane_mode_on").Build()                                         // get airplane mode status in Android 8 and below
+	GetAirplaneModeStatusNewCommand    = New("cmd", "connectivity", "airplane-mode").Build()                                                  // get airplane mode status in Android 9 and newer
 	// Used in android 9 and newer
-	EnableAirplaneModeNewCommand  AdbCommand = "cmd connectivity airplane-mode enable"  // enable airplane mode in Android 9 and newer
-	DisableAirplaneModeNewCommand AdbCommand = "cmd connectivity airplane-mode disable" // disable airplane mode in Android 9 and newer
+	EnableAirplaneModeNewCommand  = New("cmd", "connectivity", "airplane-mode", "enable").Build()  // enable airplane mode in Android 9 and newer
+	DisableAirplaneModeNewCommand = New("cmd", "connectivity", "airplane-mode", "disable").Build() // disable airplane mode in Android 9 and newer
 	// Used in android 8 and below
-	EnableAirplaneModeLegacyCommand  AdbCommand = "settings put global airplane_mode_on 1" // enable airplane mode in Android 8 and below
-	DisableAirplaneModeLegacyCommand AdbCommand = "settings put global airplane_mode_on 0" // disable airplane mode in Android 8 and below
+	EnableAirplaneModeLegacyCommand  = New("settings", "put", "global", "airplane_mode_on", "1").Build() // enable airplane mode in Android 8 and below
+	DisableAirplaneModeLegacyCommand = New("settings", "put", "global", "airplane_mode_on", "0").Build() // disable airplane mode in Android 8 and below
 	// Broadcast need root permission
-	BroadcastAirplaneModeLegacyCommand AdbCommand = "am broadcast -a android.intent.action.AIRPLANE_MODE" // broadcast to save airplane mode in Android 8 and below
-
-	GetMobileDataStateCommand AdbCommand = `dumpsys telephony.registry | grep -o 'mDataConnectionState=[^ ]*' | while read line; do 
-		echo "${line#*=}" 
-	done` // get mobile data state
-	GetSimOperatorNameCommand     AdbCommand = "getprop gsm.sim.operator.alpha" // get sim card operator name from device property
-	GetSimNetworkTypeCommand      AdbCommand = "getprop gsm.network.type"       // get sim network type from device property (usage for android 8 and below)
-	GetDeviceModelCommand         AdbCommand = "getprop ro.product.model"
-	GetDeviceProductCommand       AdbCommand = "getprop ro.product.name"
-	GetAndroidVersionCommand      AdbCommand = "getprop ro.build.version.release"                                                                                 // get android version from device property
-	GetSignalStrengthCommand      AdbCommand = "dumpsys telephony.registry | grep \"mSignalStrength=SignalStrength\" | sed 's/mSignalStrength=SignalStrength://'" // get signal strength
-	GetApnCommand                 AdbCommand = "content query --uri content://telephony/carriers/preferapn"                                                       // get selected APN *not work on some devices, may need root access
-	GetIpRouterCommand            AdbCommand = "ip route"                                                                                                         // get mobile data IP
-	EnableMobileDataCommand       AdbCommand = "svc data enable"                                                                                                  // Enable mobile data (usage for android 8 and newer)
-	DisableMobileDataCommand      AdbCommand = "svc data disable"                                                                                                 // Disable mobile data  (usage for android 8 and newer)
-	GetMobileDataStatusCommand    AdbCommand = "settings get global mobile_data"                                                                                  // Get mobile data status
-	RebootCommand                 AdbCommand = "reboot"                                                                                                           // reboot device
-	RebootRecoveryCommand         AdbCommand = "reboot recovery"                                                                                                  // reboot device to recovery mode
-	RebootBootloaderCommand       AdbCommand = "reboot bootloader"                                                                                                // reboot device to fastboot mode
-	PowerOffCommand               AdbCommand = "reboot -p"                                                                                                        // power off device
-	GetDeviceUptimeCommand        AdbCommand = "cat /proc/uptime"                                                                                                 // Get device uptime
-	GetDeviceRootAccessCommand    AdbCommand = "su -c 'echo 1'"                                                                                                   // Get Root access
-	GetDeviceMemoryCommand        AdbCommand = "cat /proc/meminfo"                                                                                                // Get Device Memory Info
-	GetDeviceStorageCommand       AdbCommand = "dumpsys diskstats"                                                                                                // Get Internal Storage Info
-	GetDeviceProcessNewCommand    AdbCommand = "ps -eo pid,user,%cpu,%mem,cmd,time+"                                                                              // Get Device Process (usage for android 8 and newer)
-	GetDeviceProcessLegacyCommand AdbCommand = "ps"                                                                                                               // Get Device Process (usage for android 7 and below)
-	GetBusyboxCheckCommand        AdbCommand = "which busybox"                                                                                                    // Check busybox installed or not
-	GetKernelVersionCommand       AdbCommand = "uname -a"                                                                                                         // Get kernel version
+	BroadcastAirplaneModeLegacyCommand = New("am", "broadcast", "-a", "android.intent.action.AIRPLANE_MODE").Build() // broadcast to save airplane mode in Android 8 and below
+
+	GetSimOperatorNameCommand     = New("getprop", "gsm.sim.operator.alpha").Build() // get sim card operator name from device property
+	GetSimNetworkTypeCommand      = New("getprop", "gsm.network.type").Build()       // get sim network type from device property (usage for android 8 and below)
+	GetDeviceModelCommand         = New("getprop", "ro.product.model").Build()
+	GetDeviceProductCommand       = New("getprop", "ro.product.name").Build()
+	GetAndroidVersionCommand      = New("getprop", "ro.build.version.release").Build()                                 // get android version from device property
+	GetApnCommand                 = New("content", "query", "--uri", "content://telephony/carriers/preferapn").Build() // get selected APN *not work on some devices, may need root access
+	GetIpRouterCommand            = New("ip", "route").Build()                                                         // get mobile data IP
+	EnableMobileDataCommand       = New("svc", "data", "enable").Build()                                               // Enable mobile data (usage for android 8 and newer)
+	DisableMobileDataCommand      = New("svc", "data", "disable").Build()                                              // Disable mobile data  (usage for android 8 and newer)
+	GetMobileDataStatusCommand    = New("settings", "get", "global", "mobile_data").Build()                            // Get mobile data status
+	RebootCommand                 = New("reboot").Build()                                                              // reboot device
+	RebootRecoveryCommand         = New("reboot", "recovery").Build()                                                  // reboot device to recovery mode
+	RebootBootloaderCommand       = New("reboot", "bootloader").Build()                                                // reboot device to fastboot mode
+	PowerOffCommand               = New("reboot", "-p").Build()                                                        // power off device
+	GetDeviceUptimeCommand        = New("cat", "/proc/uptime").Build()                                                 // Get device uptime
+	GetDeviceMemoryCommand        = New("cat", "/proc/meminfo").Build()                                                // Get Device Memory Info
+	GetDeviceStorageCommand       = New("dumpsys", "diskstats").Build()                                                // Get Internal Storage Info
+	GetDeviceProcessNewCommand    = New("ps", "-eo", "pid,user,%cpu,%mem,cmd,time+").Build()                           // Get Device Process (usage for android 8 and newer)
+	GetDeviceProcessLegacyCommand = New("ps").Build()                                                                  // Get Device Process (usage for android 7 and below)
+	GetBusyboxCheckCommand        = New("which", "busybox").Build()                                                    // Check busybox installed or not
+	GetKernelVersionCommand       = New("uname", "-a").Build()                                                         // Get kernel version
+	GetBuildFingerprintCommand    = New("getprop", "ro.build.fingerprint").Build()                                     // Get build fingerprint, changes with every system update
+	GetSvcHelpCommand             = New("svc", "help").Build()                                                         // List the services svc can control
+	GetPingCheckCommand           = New("which", "ping").Build()                                                       // Check ping installed or not
+	GetCpuStatCommand             = New("cat", "/proc/stat").Build()                                                   // Get CPU time of every core since boot
+	GetNetDevCommand              = New("cat", "/proc/net/dev").Build()                                                // Get traffic counters of every network interface
+	GetNetstatsCommand            = New("dumpsys", "netstats", "--full", "--uid").Build()                              // Get traffic history of every app
+	GetPackagesUidCommand         = New("pm", "list", "packages", "-U").Build()                                        // Get installed packages with their uid (usage for android 8 and newer)
+
+	// Check access to the SMS provider without reading any message
+	GetInboxAccessCommand = New("content", "query", "--uri", "content://sms/inbox", "--projection", "_id", "--where", "_id=0").Build()
+	// Get current, min and max clock of every online core
+	GetCpuFrequencyCommand = And(
+		New("cd", "/sys/devices/system/cpu"),
+		New("grep", "-H", ".").Glob("cpu[0-9]*/cpufreq/scaling_cur_freq", "cpu[0-9]*/cpufreq/cpuinfo_m*_freq").DiscardErrors(),
+	)
+	// Get type and temperature of every thermal zone, some zones cannot be read by the shell
+	GetThermalZonesCommand = And(
+		New("cd", "/sys/class/thermal"),
+		New("grep", "-H", ".").Glob("thermal_zone*/type", "thermal_zone*/temp").DiscardErrors(),
+	)
+	// Get CPU time since boot and of every process, processes exiting meanwhile are left out
+	GetProcessStatCommand = New("cat", "/proc/stat").Glob("/proc/[0-9]*/stat").DiscardErrors().Build()
+
+	// get mobile data state of every SIM, one per line
+	GetMobileDataStateCommand = Pipeline(
+		New("dumpsys", "telephony.registry"),
+		New("grep", "-o", "mDataConnectionState=[^ ]*"),
+		New("sed", "s/^[^=]*=//"),
+	)
+	// Get Root access
+	GetDeviceRootAccessCommand = WithRoot(New("echo", "1").Build(), SuSyntaxStandard)
+	// get signal strength
+	GetSignalStrengthCommand = Pipeline(
+		New("dumpsys", "telephony.registry"),
+		New("grep", "mSignalStrength=SignalStrength"),
+		New("sed", "s/mSignalStrength=SignalStrength://"),
+	)
 )
+
+// DefaultTimeout bounds every command without an entry in commandTimeouts.
+const DefaultTimeout = 10 * time.Second
+
+// commandTimeouts are the deadlines of commands that need more, or should get
+// less, time than DefaultTimeout. dumpsys and content queries can take long
+// on slow devices, plain getprop calls return right away.
+var commandTimeouts = map[AdbCommand]time.Duration{
+	GetBatteryCommand:             15 * time.Second,
+	GetInboxCommand:               30 * time.Second,
+	GetApnCommand:                 15 * time.Second,
+	GetMobileDataStateCommand:     15 * time.Second,
+	GetSignalStrengthCommand:      15 * time.Second,
+	GetDeviceStorageCommand:       30 * time.Second,
+	GetNetstatsCommand:            30 * time.Second,
+	GetPackagesUidCommand:         15 * time.Second,
+	GetDeviceProcessNewCommand:    20 * time.Second,
+	GetDeviceProcessLegacyCommand: 20 * time.Second,
+	GetSimOperatorNameCommand:     5 * time.Second,
+	GetSimNetworkTypeCommand:      5 * time.Second,
+	GetDeviceModelCommand:         5 * time.Second,
+	GetDeviceProductCommand:       5 * time.Second,
+	GetAndroidVersionCommand:      5 * time.Second,
+	GetDeviceUptimeCommand:        5 * time.Second,
+	GetKernelVersionCommand:       5 * time.Second,
+}
+
+// Timeout returns how long adbCommand may run before it is abandoned.
+func Timeout(adbCommand AdbCommand) time.Duration {
+	if timeout, ok := commandTimeouts[adbCommand]; ok {
+		return timeout
+	}
+	return DefaultTimeout
+}
+
+// commandCacheTTLs is how long the output of a command may be reused, a ttl
+// of 0 is the default expiration of the cache. Device properties hold until
+// the device reboots, readings of the battery and radio change by the
+// second. Commands without an entry, e.g. the SMS inbox, are never cached.
+// Neither are /proc/stat, the CPU time of the processes and /proc/net/dev,
+// CPU utilisation and throughput are worked out between reads.
+var commandCacheTTLs = map[AdbCommand]time.Duration{
+	GetDevicePropCommand:               6 * time.Hour,
+	GetDeviceModelCommand:              6 * time.Hour,
+	GetDeviceProductCommand:            6 * time.Hour,
+	GetAndroidVersionCommand:           6 * time.Hour,
+	GetKernelVersionCommand:            6 * time.Hour,
+	GetBuildFingerprintCommand:         6 * time.Hour,
+	GetRootCommand:                     0,
+	GetDeviceRootAccessCommand:         0,
+	GetBusyboxCheckCommand:             0,
+	GetApnCommand:                      0,
+	GetSimOperatorNameCommand:          0,
+	GetDeviceStorageCommand:            0,
+	GetPackagesUidCommand:              0,
+	GetBatteryCommand:                  10 * time.Second,
+	GetDeviceMemoryCommand:             5 * time.Second,
+	GetAirplaneModeStatusNewCommand:    5 * time.Second,
+	GetAirplaneModeStatusLegacyCommand: 5 * time.Second,
+	GetMobileDataStatusCommand:         5 * time.Second,
+	GetMobileDataStateCommand:          5 * time.Second,
+	GetSignalStrengthCommand:           5 * time.Second,
+	GetSimNetworkTypeCommand:           5 * time.Second,
+	GetIpRouterCommand:                 5 * time.Second,
+	GetCpuFrequencyCommand:             5 * time.Second,
+	GetThermalZonesCommand:             5 * time.Second,
+	GetNetstatsCommand:                 30 * time.Second,
+}
+
+// CacheTTL returns how long the output of adbCommand may be reused and
+// whether it may be cached at all.
+func CacheTTL(adbCommand AdbCommand) (time.Duration, bool) {
+	ttl, ok := commandCacheTTLs[adbCommand]
+	return ttl, ok
+}
+
+// radioReads are the reads changed by toggling airplane mode or mobile data.
+var radioReads = []AdbCommand{
+	GetAirplaneModeStatusNewCommand,
+	GetAirplaneModeStatusLegacyCommand,
+	GetMobileDataStatusCommand,
+	GetMobileDataStateCommand,
+	GetSignalStrengthCommand,
+	GetSimNetworkTypeCommand,
+	GetIpRouterCommand,
+}
+
+// invalidatedReads are the reads a command changes the output of. A reboot
+// changes about everything.
+var invalidatedReads = map[AdbCommand][]AdbCommand{
+	EnableAirplaneModeNewCommand:       radioReads,
+	DisableAirplaneModeNewCommand:      radioReads,
+	EnableAirplaneModeLegacyCommand:    radioReads,
+	DisableAirplaneModeLegacyCommand:   radioReads,
+	BroadcastAirplaneModeLegacyCommand: radioReads,
+	EnableMobileDataCommand:            radioReads,
+	DisableMobileDataCommand:           radioReads,
+	RebootCommand:                      All(),
+	RebootRecoveryCommand:              All(),
+	RebootBootloaderCommand:            All(),
+	PowerOffCommand:                    All(),
+}
+
+// Invalidates returns the reads whose cached output is stale once adbCommand
+// ran.
+func Invalidates(adbCommand AdbCommand) []AdbCommand {
+	return invalidatedReads[adbCommand]
+}
+
+// changingCommands change the state of the device, running one twice is not
+// the same as running it once.
+var changingCommands = map[AdbCommand]bool{
+	EnableAirplaneModeNewCommand:       true,
+	DisableAirplaneModeNewCommand:      true,
+	EnableAirplaneModeLegacyCommand:    true,
+	DisableAirplaneModeLegacyCommand:   true,
+	BroadcastAirplaneModeLegacyCommand: true,
+	EnableMobileDataCommand:            true,
+	DisableMobileDataCommand:           true,
+	RebootCommand:                      true,
+	RebootRecoveryCommand:              true,
+	RebootBootloaderCommand:            true,
+	PowerOffCommand:                    true,
+}
+
+// ReadOnly is true when adbCommand only reads the state of the device, so
+// concurrent runs of it may share one execution. Commands built at runtime
+// are not known to be read only.
+func ReadOnly(adbCommand AdbCommand) bool {
+	return !changingCommands[adbCommand] && slices.Contains(All(), adbCommand)
+}
+
+// All returns every command defined in this package. Commands built at
+// runtime, e.g. with user input, are not part of it.
+func All() []AdbCommand {
+	return []AdbCommand{
+		GetBatteryCommand,
+		GetRootCommand,
+		GetDevicePropCommand,
+		GetInboxCommand,
+		GetAirplaneModeStatusLegacyCommand,
+		GetAirplaneModeStatusNewCommand,
+		EnableAirplaneModeNewCommand,
+		DisableAirplaneModeNewCommand,
+		EnableAirplaneModeLegacyCommand,
+		DisableAirplaneModeLegacyCommand,
+		BroadcastAirplaneModeLegacyCommand,
+		GetSimOperatorNameCommand,
+		GetSimNetworkTypeCommand,
+		GetDeviceModelCommand,
+		GetDeviceProductCommand,
+		GetAndroidVersionCommand,
+		GetApnCommand,
+		GetIpRouterCommand,
+		EnableMobileDataCommand,
+		DisableMobileDataCommand,
+		GetMobileDataStatusCommand,
+		RebootCommand,
+		RebootRecoveryCommand,
+		RebootBootloaderCommand,
+		PowerOffCommand,
+		GetDeviceUptimeCommand,
+		GetDeviceMemoryCommand,
+		GetDeviceStorageCommand,
+		GetDeviceProcessNewCommand,
+		GetDeviceProcessLegacyCommand,
+		GetBusyboxCheckCommand,
+		GetKernelVersionCommand,
+		GetBuildFingerprintCommand,
+		GetSvcHelpCommand,
+		GetPingCheckCommand,
+		GetInboxAccessCommand,
+		GetCpuStatCommand,
+		GetProcessStatCommand,
+		GetCpuFrequencyCommand,
+		GetThermalZonesCommand,
+		GetNetDevCommand,
+		GetNetstatsCommand,
+		GetPackagesUidCommand,
+		GetMobileDataStateCommand,
+		GetDeviceRootAccessCommand,
+		GetSignalStrengthCommand,
+	}
+}
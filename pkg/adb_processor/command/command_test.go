@@ -0,0 +1,52 @@
+package command
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAllListsEveryCommand keeps All in sync with the declarations, so every
+// command is checked by the parser registry validation.
+func TestAllListsEveryCommand(t *testing.T) {
+	t.Parallel()
+	file, err := parser.ParseFile(token.NewFileSet(), "command.go", nil, 0)
+	require.NoError(t, err)
+
+	declared := 0
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || (genDecl.Tok != token.CONST && genDecl.Tok != token.VAR) {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			for _, name := range spec.(*ast.ValueSpec).Names {
+				if name.IsExported() && strings.HasSuffix(name.Name, "Command") {
+					declared++
+				}
+			}
+		}
+	}
+
+	all := All()
+	unique := make(map[AdbCommand]bool)
+	for _, adbCommand := range all {
+		assert.False(t, unique[adbCommand], "duplicate command %q", adbCommand)
+		unique[adbCommand] = true
+	}
+	assert.Equal(t, declared, len(all), "every command declared in command.go must be listed in All")
+}
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+	assert.True(t, ReadOnly(GetBatteryCommand))
+	assert.True(t, ReadOnly(GetMobileDataStateCommand))
+	assert.False(t, ReadOnly(EnableMobileDataCommand))
+	assert.False(t, ReadOnly(RebootCommand))
+	assert.False(t, ReadOnly(New("ping", "-c", "1", "example.com").Build()))
+}
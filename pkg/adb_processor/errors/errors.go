@@ -1,11 +1,29 @@
 // Package errors defines custom error types used throughout the ADB processing modules.
 package errors
 
-import _errors "errors"
+import (
+	_errors "errors"
+	"fmt"
+)
 
 var (
 	ErrorNeedRoot                        = _errors.New("cannot perform action without root")
 	ErrorNeedShellSuperUserPermission    = _errors.New("cannot perform action without allow super-user permission for 'com.android.shell'")
 	ErrorMinimumAndroidVersionNotSupport = _errors.New("cannot perform action minimum android version not supported")
 	ErrorDeviceIsNil                     = _errors.New("device is nil")
+	ErrorCommandTimeout                  = _errors.New("adb command timed out")
+	ErrorBatchIncomplete                 = _errors.New("batch ended before the command finished")
+	ErrorParserNotRegistered             = _errors.New("no parser registered for command")
+	ErrorUnexpectedResult                = _errors.New("unexpected result type for command")
+	ErrorFixtureNotFound                 = _errors.New("no recorded output for command")
+	ErrorDeviceOffline                   = _errors.New("device is offline")
+	ErrorDeviceUnauthorized              = _errors.New("device is unauthorized, accept the USB debugging prompt on the device")
+	ErrorCommandNotFound                 = _errors.New("command is not available on the device")
+	ErrorTransportReset                  = _errors.New("connection to the device was reset")
+	ErrorAdbServerUnavailable            = _errors.New("adb server is not available")
 )
+
+// ErrorSuTakesUid is reported by the AOSP su of userdebug and eng builds,
+// which takes a uid instead of options. It is an ErrorNeedRoot, the command
+// has to be run with command.SuSyntaxUid.
+var ErrorSuTakesUid = fmt.Errorf("%w: su takes a uid instead of options", ErrorNeedRoot)
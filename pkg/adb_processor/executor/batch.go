@@ -0,0 +1,135 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/goadb/wire"
+)
+
+// BatchOutput is the output of one command of a batch.
+type BatchOutput struct {
+	Output   string
+	ExitCode int
+	// Done is false when the batch ended before the command finished.
+	Done bool
+}
+
+// maxCommandLength is the longest command that can be sent to a device,
+// goadb limits requests to wire.MaxMessageLength including "shell:".
+const maxCommandLength = wire.MaxMessageLength - len("shell:")
+
+// batchTimeoutMargin is the time a session gets on top of the timeout of
+// its slowest command, for the other commands.
+const batchTimeoutMargin = 5 * time.Second
+
+// RunBatch executes adbCommands one after another in as few shell sessions
+// as the length limit of commands allows and returns their outputs in the
+// same order. A session is bounded by batchTimeout.
+func (c *Executor) RunBatch(ctx context.Context, adbCommands []command.AdbCommand) ([]BatchOutput, error) {
+	token, err := newBatchToken()
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]BatchOutput, 0, len(adbCommands))
+	for _, chunk := range c.batchChunks(token, adbCommands) {
+		name := fmt.Sprintf("batch of %d commands", len(chunk))
+		output, err := c.run(ctx, batchScript(token, chunk), batchTimeout(chunk), name)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, splitBatchOutput(output, token, len(chunk))...)
+	}
+	return outputs, nil
+}
+
+// batchTimeout is the timeout of the slowest of adbCommands plus
+// batchTimeoutMargin. A session stuck on a command does not hold the shell
+// of the device for the timeouts of all of them.
+func batchTimeout(adbCommands []command.AdbCommand) time.Duration {
+	var timeout time.Duration
+	for _, adbCommand := range adbCommands {
+		timeout = max(timeout, command.Timeout(adbCommand))
+	}
+	return timeout + batchTimeoutMargin
+}
+
+// batchChunks splits adbCommands into the batches run in one session each,
+// every batch fits maxCommandLength once wrapped for root. A command too
+// long on its own gets a batch of its own and fails when it is run.
+func (c *Executor) batchChunks(token string, adbCommands []command.AdbCommand) [][]command.AdbCommand {
+	var chunks [][]command.AdbCommand
+	start := 0
+	for end := 1; end <= len(adbCommands); end++ {
+		if end-start > 1 && len(c.commandLine(batchScript(token, adbCommands[start:end]))) > maxCommandLength {
+			chunks = append(chunks, adbCommands[start:end-1])
+			start = end - 1
+		}
+	}
+	if start < len(adbCommands) {
+		chunks = append(chunks, adbCommands[start:])
+	}
+	return chunks
+}
+
+func newBatchToken() (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return "ANDROMODEM_" + hex.EncodeToString(random), nil
+}
+
+// batchScript runs every command in a subshell, so an exit or cd in one does
+// not affect the others, and prints a delimiter line with its index and exit
+// code after it. The delimiter starts on a new line even when the output of
+// the command does not end with one. The token is stored in a variable once
+// to keep the script short.
+func batchScript(token string, adbCommands []command.AdbCommand) command.AdbCommand {
+	var script strings.Builder
+	fmt.Fprintf(&script, "t=%s\n", token)
+	for i, adbCommand := range adbCommands {
+		fmt.Fprintf(&script, "(\n%s\n) 2>&1;printf \"\\n$t %d %%d\\n\" $?\n", adbCommand, i)
+	}
+	return command.AdbCommand(script.String())
+}
+
+func splitBatchOutput(output string, token string, count int) []BatchOutput {
+	outputs := make([]BatchOutput, count)
+	delimiter := "\n" + token + " "
+	rest := output
+	for {
+		index := strings.Index(rest, delimiter)
+		if index < 0 {
+			return outputs
+		}
+		commandOutput := strings.TrimSuffix(rest[:index], "\r")
+		rest = rest[index+len(delimiter):]
+
+		line := rest
+		if end := strings.IndexByte(rest, '\n'); end >= 0 {
+			line, rest = rest[:end], rest[end+1:]
+		} else {
+			rest = ""
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		i, err := strconv.Atoi(fields[0])
+		if err != nil || i < 0 || i >= count {
+			continue
+		}
+		exitCode, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		outputs[i] = BatchOutput{Output: commandOutput, ExitCode: exitCode, Done: true}
+	}
+}
@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchScript(t *testing.T) {
+	t.Parallel()
+	script := batchScript("TOKEN", []command.AdbCommand{"getprop ro.product.model", "cat /proc/uptime"})
+	expected := "t=TOKEN\n" +
+		"(\ngetprop ro.product.model\n) 2>&1;printf \"\\n$t 0 %d\\n\" $?\n" +
+		"(\ncat /proc/uptime\n) 2>&1;printf \"\\n$t 1 %d\\n\" $?\n"
+	assert.Equal(t, command.AdbCommand(expected), script)
+}
+
+func TestSplitBatchOutput(t *testing.T) {
+	t.Parallel()
+	output := "SM-A515F\n\nTOKEN 0 0\n" +
+		"no newline\nTOKEN 1 0\n" +
+		"\nTOKEN 2 1\n" +
+		"line 1\r\nline 2\r\n\r\nTOKEN 3 0\r\n" +
+		"partial output"
+	expected := []BatchOutput{
+		{Output: "SM-A515F\n", ExitCode: 0, Done: true},
+		{Output: "no newline", ExitCode: 0, Done: true},
+		{Output: "", ExitCode: 1, Done: true},
+		{Output: "line 1\r\nline 2\r\n", ExitCode: 0, Done: true},
+		{},
+	}
+	assert.Equal(t, expected, splitBatchOutput(output, "TOKEN", 5))
+}
+
+func TestSplitBatchOutputIgnoresForeignDelimiters(t *testing.T) {
+	t.Parallel()
+	output := "TOKEN 0 0\nreal\nTOKEN 0 0\n\nTOKEN 9 0\n\nTOKEN x 0\n"
+	outputs := splitBatchOutput(output, "TOKEN", 1)
+	assert.Equal(t, []BatchOutput{{Output: "TOKEN 0 0\nreal", ExitCode: 0, Done: true}}, outputs)
+}
+
+func TestBatchTimeout(t *testing.T) {
+	t.Parallel()
+	adbCommands := []command.AdbCommand{
+		command.GetDeviceModelCommand,
+		command.GetSignalStrengthCommand,
+		command.GetApnCommand,
+		command.GetDeviceMemoryCommand,
+		command.GetKernelVersionCommand,
+	}
+	slowest := time.Duration(0)
+	for _, adbCommand := range adbCommands {
+		slowest = max(slowest, command.Timeout(adbCommand))
+	}
+	assert.Equal(t, slowest+batchTimeoutMargin, batchTimeout(adbCommands))
+	assert.Equal(t, command.DefaultTimeout+batchTimeoutMargin, batchTimeout([]command.AdbCommand{"uname -a", "uptime"}))
+}
+
+func TestBatchChunksFitRequestLimit(t *testing.T) {
+	t.Parallel()
+	adbCommands := []command.AdbCommand{
+		command.GetIpRouterCommand,
+		command.GetAirplaneModeStatusNewCommand,
+		command.GetApnCommand,
+		command.GetSimOperatorNameCommand,
+		command.GetMobileDataStateCommand,
+		command.GetSignalStrengthCommand,
+		command.GetDeviceMemoryCommand,
+		command.GetKernelVersionCommand,
+	}
+	for _, useRoot := range []bool{false, true} {
+		exec := &Executor{UseRoot: useRoot, SuSyntax: command.SuSyntaxMagisk}
+		chunks := exec.batchChunks("ANDROMODEM_0123456789abcdef", adbCommands)
+
+		var joined []command.AdbCommand
+		for _, chunk := range chunks {
+			assert.LessOrEqual(t, len(exec.commandLine(batchScript("ANDROMODEM_0123456789abcdef", chunk))), maxCommandLength)
+			joined = append(joined, chunk...)
+		}
+		assert.Equal(t, adbCommands, joined)
+		assert.Less(t, len(chunks), len(adbCommands))
+	}
+}
+
+func TestBatchChunksLongCommand(t *testing.T) {
+	t.Parallel()
+	long := command.AdbCommand("echo " + strings.Repeat("x", maxCommandLength))
+	exec := &Executor{}
+	chunks := exec.batchChunks("TOKEN", []command.AdbCommand{"uname -a", long, "uname -a"})
+	assert.Equal(t, [][]command.AdbCommand{{"uname -a"}, {long}, {"uname -a"}}, chunks)
+}
+
+func TestCommandsFitRequestLimit(t *testing.T) {
+	t.Parallel()
+	// Every command can be batched, even when run as root.
+	for _, suSyntax := range []command.SuSyntax{command.SuSyntaxStandard, command.SuSyntaxMagisk, command.SuSyntaxKernelSU, command.SuSyntaxUid} {
+		exec := &Executor{UseRoot: true, SuSyntax: suSyntax}
+		for _, adbCommand := range command.All() {
+			script := batchScript("ANDROMODEM_0123456789abcdef", []command.AdbCommand{adbCommand})
+			assert.LessOrEqual(t, len(exec.commandLine(script)), maxCommandLength, "%s as %s", adbCommand, suSyntax)
+		}
+	}
+}
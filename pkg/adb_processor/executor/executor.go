@@ -2,21 +2,31 @@
 package executor
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"strings"
+	"time"
 
 	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
 	adb "github.com/basiooo/goadb"
 )
 
 type Executor struct {
-	Device  *adb.Device
-	UseRoot bool
+	Device   *adb.Device
+	UseRoot  bool
+	SuSyntax command.SuSyntax
 }
 
+// Factory returns the executor running commands on device.
+type Factory func(device *adb.Device) IExecutor
+
 func NewExecutor(device *adb.Device) IExecutor {
 	return &Executor{
-		Device: device,
+		Device:   device,
+		SuSyntax: command.SuSyntaxStandard,
 	}
 }
 func (c *Executor) Root() bool {
@@ -29,15 +39,93 @@ func (c *Executor) DisableRoot() {
 	c.UseRoot = false
 }
 
-func (c *Executor) Run(adbCommand command.AdbCommand) (string, error) {
-	cmdStr := string(adbCommand)
+// SetSuSyntax selects how commands are passed to su when root is enabled.
+func (c *Executor) SetSuSyntax(syntax command.SuSyntax) {
+	c.SuSyntax = syntax
+}
+
+type runResult struct {
+	output string
+	err    error
+}
+
+// Run executes adbCommand and gives up once ctx is done or the timeout of
+// the command passed, whichever comes first. ErrorCommandTimeout is returned
+// when the command did not finish in time.
+func (c *Executor) Run(ctx context.Context, adbCommand command.AdbCommand) (string, error) {
+	return c.run(ctx, adbCommand, command.Timeout(adbCommand), string(adbCommand))
+}
+
+// run executes adbCommand bounded by timeout. name identifies the command in
+// timeout errors.
+func (c *Executor) run(ctx context.Context, adbCommand command.AdbCommand, timeout time.Duration, name string) (string, error) {
+	if ctx.Err() != nil {
+		return "", c.contextError(ctx, name)
+	}
+	cmdStr := c.commandLine(adbCommand)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan runResult, 1)
+	go func() {
+		// goadb cannot be cancelled, its own timeout closes the connection
+		// right after ours expired so the command does not linger.
+		output, err := c.Device.RunCommandWithTimeout(cmdStr, int(math.Ceil(timeout.Seconds())))
+		done <- runResult{output: output, err: err}
+	}()
 
-	if c.UseRoot {
-		if !strings.HasPrefix(cmdStr, "su ") {
-			cmdStr = fmt.Sprintf("su -c '%s'", cmdStr)
+	select {
+	case <-ctx.Done():
+		return "", c.contextError(ctx, name)
+	case result := <-done:
+		if result.err != nil && ctx.Err() != nil {
+			return "", c.contextError(ctx, name)
 		}
-		return c.Device.RunCommandWithTimeout(cmdStr, 10)
+		if result.err != nil {
+			return "", classifyError(result.err, name)
+		}
+		return result.output, nil
 	}
+}
+
+// classifyError wraps an error of goadb in the error of its class, so
+// callers can tell a device waiting for the USB debugging prompt from one
+// that went away. Errors of no known class are returned as they are.
+func classifyError(err error, name string) error {
+	chain := strings.ToLower(adb.ErrorWithCauseChain(err))
+	var class error
+	switch {
+	case strings.Contains(chain, "unauthorized"):
+		class = adbErrors.ErrorDeviceUnauthorized
+	case strings.Contains(chain, "offline"),
+		adb.HasErrCode(err, adb.DeviceNotFound):
+		class = adbErrors.ErrorDeviceOffline
+	case adb.HasErrCode(err, adb.CommandTimeout):
+		class = adbErrors.ErrorCommandTimeout
+	case adb.HasErrCode(err, adb.ServerNotAvailable):
+		class = adbErrors.ErrorAdbServerUnavailable
+	case adb.HasErrCode(err, adb.ConnectionResetError),
+		adb.HasErrCode(err, adb.NetworkError):
+		class = adbErrors.ErrorTransportReset
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: '%s': %w", class, name, err)
+}
 
-	return c.Device.RunCommand(cmdStr)
+// commandLine returns adbCommand as sent to the device, wrapped in su when
+// root is enabled.
+func (c *Executor) commandLine(adbCommand command.AdbCommand) string {
+	if c.UseRoot && !strings.HasPrefix(string(adbCommand), "su ") {
+		return string(command.WithRoot(adbCommand, c.SuSyntax))
+	}
+	return string(adbCommand)
+}
+
+func (c *Executor) contextError(ctx context.Context, name string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: '%s'", adbErrors.ErrorCommandTimeout, name)
+	}
+	return ctx.Err()
 }
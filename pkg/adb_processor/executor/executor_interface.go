@@ -1,10 +1,16 @@
 package executor
 
-import "github.com/basiooo/andromodem/pkg/adb_processor/command"
+import (
+	"context"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+)
 
 type IExecutor interface {
-	Run(command.AdbCommand) (string, error)
+	Run(context.Context, command.AdbCommand) (string, error)
+	RunBatch(context.Context, []command.AdbCommand) ([]BatchOutput, error)
 	EnableRoot()
 	DisableRoot()
 	Root() bool
+	SetSuSyntax(command.SuSyntax)
 }
@@ -1,14 +1,49 @@
 package executor
 
 import (
+	"context"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
 	adb "github.com/basiooo/goadb"
 	"github.com/basiooo/goadb/wire"
 	"github.com/stretchr/testify/assert"
 )
 
+// hangingServer accepts commands but never answers them, like a device
+// stuck in a hung dumpsys.
+type hangingServer struct {
+	*adb.MockServer
+	release chan struct{}
+}
+
+func (h hangingServer) Dial() (*wire.Conn, error) {
+	return wire.NewConn(h, h), nil
+}
+
+func (h hangingServer) ReadStatus(string) (string, error) {
+	<-h.release
+	return wire.StatusSuccess, nil
+}
+
+// resetServer drops every connection once the request is read, like an adb
+// server losing the device in the middle of a command.
+type resetServer struct {
+	*adb.MockServer
+}
+
+func (resetServer) Dial() (*wire.Conn, error) {
+	client, server := net.Pipe()
+	go func() {
+		defer func() { _ = server.Close() }()
+		_, _ = wire.NewScanner(server).ReadMessage()
+	}()
+	return wire.NewConn(wire.NewScanner(client), wire.NewSender(client)), nil
+}
+
 func mockDevice() (*adb.MockServer, *adb.Device) {
 	s := &adb.MockServer{
 		Status:   wire.StatusSuccess,
@@ -33,7 +68,7 @@ func TestCommandWithRoot(t *testing.T) {
 	s, device := mockDevice()
 	executor := NewExecutor(device)
 	executor.EnableRoot()
-	output1, err1 := executor.Run(command.AdbCommand("test"))
+	output1, err1 := executor.Run(context.Background(), command.AdbCommand("test"))
 	assert.NoError(t, err1)
 	assert.Equal(t, "shell:su -c 'test'", s.Requests[1])
 	assert.Equal(t, "output", output1)
@@ -44,8 +79,62 @@ func TestCommandWithoutRoot(t *testing.T) {
 	s, device := mockDevice()
 	executor := NewExecutor(device)
 	executor.DisableRoot()
-	output, err := executor.Run(command.AdbCommand("test"))
+	output, err := executor.Run(context.Background(), command.AdbCommand("test"))
 	assert.NoError(t, err)
 	assert.Equal(t, "shell:test", s.Requests[1])
 	assert.Equal(t, "output", output)
 }
+
+func TestCommandTimeout(t *testing.T) {
+	t.Parallel()
+	s := hangingServer{MockServer: &adb.MockServer{}, release: make(chan struct{})}
+	defer close(s.release)
+	device := (&adb.Adb{Server: s}).Device(adb.AnyDevice())
+	executor := NewExecutor(device)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := executor.Run(ctx, command.GetBatteryCommand)
+	assert.ErrorIs(t, err, adbErrors.ErrorCommandTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestCommandCancelled(t *testing.T) {
+	t.Parallel()
+	s := hangingServer{MockServer: &adb.MockServer{}, release: make(chan struct{})}
+	defer close(s.release)
+	device := (&adb.Adb{Server: s}).Device(adb.AnyDevice())
+	executor := NewExecutor(device)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := executor.Run(ctx, command.GetBatteryCommand)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, adbErrors.ErrorCommandTimeout)
+}
+
+func TestCommandTimeoutDefaults(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, 15*time.Second, command.Timeout(command.GetBatteryCommand))
+	assert.Equal(t, command.DefaultTimeout, command.Timeout(command.AdbCommand("test")))
+}
+
+func TestCommandWithRootQuotesScript(t *testing.T) {
+	t.Parallel()
+	s, device := mockDevice()
+	executor := NewExecutor(device)
+	executor.EnableRoot()
+	executor.SetSuSyntax(command.SuSyntaxUid)
+	_, err := executor.Run(context.Background(), command.AdbCommand("echo 'a b' | sed s/a//"))
+	assert.NoError(t, err)
+	assert.Equal(t, `shell:su 0 sh -c 'echo '\''a b'\'' | sed s/a//'`, s.Requests[1])
+}
+
+func TestCommandTransportReset(t *testing.T) {
+	t.Parallel()
+	device := (&adb.Adb{Server: resetServer{MockServer: &adb.MockServer{}}}).Device(adb.AnyDevice())
+	executor := NewExecutor(device)
+	_, err := executor.Run(context.Background(), command.GetBatteryCommand)
+	assert.ErrorIs(t, err, adbErrors.ErrorTransportReset)
+}
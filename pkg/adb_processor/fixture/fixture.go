@@ -0,0 +1,141 @@
+// Package fixture records the outputs of ADB commands run on real devices
+// and replays them, so services can run without a device attached.
+package fixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+)
+
+// Device describes the device a fixture was recorded on.
+type Device struct {
+	Serial         string `json:"serial"`
+	Manufacturer   string `json:"manufacturer"`
+	Model          string `json:"model"`
+	Product        string `json:"product"`
+	AndroidVersion string `json:"android_version"`
+}
+
+// Record is the output of one command.
+type Record struct {
+	Command command.AdbCommand `json:"command"`
+	// Root is set when the command was run as root.
+	Root     bool   `json:"root,omitempty"`
+	Output   string `json:"output"`
+	ExitCode int    `json:"exit_code,omitempty"`
+}
+
+type recordKey struct {
+	command command.AdbCommand
+	root    bool
+}
+
+// Fixture holds the outputs recorded on one device.
+type Fixture struct {
+	Device  Device   `json:"device"`
+	Records []Record `json:"records"`
+
+	mu    sync.RWMutex
+	index map[recordKey]int
+}
+
+// New returns an empty fixture of device.
+func New(device Device) *Fixture {
+	return &Fixture{Device: device}
+}
+
+// Parse reads a fixture saved by Save.
+func Parse(data []byte) (*Fixture, error) {
+	fixture := &Fixture{}
+	if err := json.Unmarshal(data, fixture); err != nil {
+		return nil, err
+	}
+	if fixture.Device.Serial == "" {
+		return nil, fmt.Errorf("fixture has no device serial")
+	}
+	return fixture, nil
+}
+
+// Load reads the fixture saved at path.
+func Load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fixture, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return fixture, nil
+}
+
+// Save writes the fixture to path. Records are sorted by command, so
+// recording a device again gives a readable diff.
+func (f *Fixture) Save(path string) error {
+	f.mu.Lock()
+	sort.SliceStable(f.Records, func(i, j int) bool {
+		if f.Records[i].Command != f.Records[j].Command {
+			return f.Records[i].Command < f.Records[j].Command
+		}
+		return !f.Records[i].Root && f.Records[j].Root
+	})
+	f.index = nil
+	data, err := json.MarshalIndent(f, "", "  ")
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Add stores record, replacing the one recorded before for the same command.
+func (f *Fixture) Add(record Record) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.buildIndex()
+	key := recordKey{command: record.Command, root: record.Root}
+	if i, ok := f.index[key]; ok {
+		f.Records[i] = record
+		return
+	}
+	f.index[key] = len(f.Records)
+	f.Records = append(f.Records, record)
+}
+
+// Lookup returns the output recorded for adbCommand.
+func (f *Fixture) Lookup(adbCommand command.AdbCommand, root bool) (Record, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.buildIndex()
+	i, ok := f.index[recordKey{command: adbCommand, root: root}]
+	if !ok {
+		return Record{}, false
+	}
+	return f.Records[i], true
+}
+
+func (f *Fixture) buildIndex() {
+	if f.index != nil {
+		return
+	}
+	f.index = make(map[recordKey]int, len(f.Records))
+	for i, record := range f.Records {
+		f.index[recordKey{command: record.Command, root: record.Root}] = i
+	}
+}
+
+var nameSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Name identifies the fixture by the device and Android version it was
+// recorded on, e.g. "google-pixel-7-android-14".
+func (f *Fixture) Name() string {
+	name := fmt.Sprintf("%s %s android %s", f.Device.Manufacturer, f.Device.Model, f.Device.AndroidVersion)
+	return strings.Trim(nameSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
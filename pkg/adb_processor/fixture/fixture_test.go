@@ -0,0 +1,248 @@
+package fixture_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
+	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	adb "github.com/basiooo/goadb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deviceExecutor answers every command with its own text, prefixed with
+// "root:" when run as root.
+type deviceExecutor struct {
+	useRoot bool
+}
+
+func (e *deviceExecutor) Run(_ context.Context, adbCommand command.AdbCommand) (string, error) {
+	if e.useRoot {
+		return "root:" + string(adbCommand), nil
+	}
+	return string(adbCommand), nil
+}
+
+func (e *deviceExecutor) RunBatch(ctx context.Context, adbCommands []command.AdbCommand) ([]executor.BatchOutput, error) {
+	outputs := make([]executor.BatchOutput, len(adbCommands))
+	for i, adbCommand := range adbCommands {
+		output, _ := e.Run(ctx, adbCommand)
+		outputs[i] = executor.BatchOutput{Output: output, ExitCode: i, Done: i == 0}
+	}
+	return outputs, nil
+}
+
+func (e *deviceExecutor) EnableRoot()                  { e.useRoot = true }
+func (e *deviceExecutor) DisableRoot()                 { e.useRoot = false }
+func (e *deviceExecutor) Root() bool                   { return e.useRoot }
+func (e *deviceExecutor) SetSuSyntax(command.SuSyntax) {}
+
+func newDevice(serials ...string) (*adb.Adb, []*fixture.Fixture) {
+	fixtures := make([]*fixture.Fixture, len(serials))
+	for i, serial := range serials {
+		fixtures[i] = fixture.New(fixture.Device{Serial: serial})
+	}
+	return &adb.Adb{Server: fixture.NewServer(fixtures...)}, fixtures
+}
+
+func TestFixtureAddReplacesRecord(t *testing.T) {
+	t.Parallel()
+	f := fixture.New(fixture.Device{Serial: "abc"})
+	f.Add(fixture.Record{Command: command.GetApnCommand, Output: "denied"})
+	f.Add(fixture.Record{Command: command.GetApnCommand, Root: true, Output: "apn"})
+	f.Add(fixture.Record{Command: command.GetApnCommand, Output: "denied again"})
+
+	assert.Len(t, f.Records, 2)
+	record, ok := f.Lookup(command.GetApnCommand, false)
+	assert.True(t, ok)
+	assert.Equal(t, "denied again", record.Output)
+	record, ok = f.Lookup(command.GetApnCommand, true)
+	assert.True(t, ok)
+	assert.Equal(t, "apn", record.Output)
+	_, ok = f.Lookup(command.GetInboxCommand, false)
+	assert.False(t, ok)
+}
+
+func TestFixtureSaveLoad(t *testing.T) {
+	t.Parallel()
+	f := fixture.New(fixture.Device{Serial: "abc", Manufacturer: "Google", Model: "Pixel 7", AndroidVersion: "14"})
+	f.Add(fixture.Record{Command: command.GetIpRouterCommand, Output: "routes"})
+	f.Add(fixture.Record{Command: command.GetBatteryCommand, Output: "battery", ExitCode: 1})
+	path := filepath.Join(t.TempDir(), f.Name()+".json")
+	require.NoError(t, f.Save(path))
+
+	loaded, err := fixture.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, f.Device, loaded.Device)
+	assert.Equal(t, []fixture.Record{
+		{Command: command.GetBatteryCommand, Output: "battery", ExitCode: 1},
+		{Command: command.GetIpRouterCommand, Output: "routes"},
+	}, loaded.Records)
+	record, ok := loaded.Lookup(command.GetIpRouterCommand, false)
+	assert.True(t, ok)
+	assert.Equal(t, "routes", record.Output)
+}
+
+func TestFixtureParseWithoutSerial(t *testing.T) {
+	t.Parallel()
+	_, err := fixture.Parse([]byte(`{"device":{},"records":[]}`))
+	assert.Error(t, err)
+}
+
+func TestFixtureName(t *testing.T) {
+	t.Parallel()
+	f := fixture.New(fixture.Device{Manufacturer: "Xiaomi", Model: "Redmi Note 7", AndroidVersion: "9"})
+	assert.Equal(t, "xiaomi-redmi-note-7-android-9", f.Name())
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+	client, _ := newDevice("abc")
+	device, err := client.GetDeviceBySerial("abc")
+	require.NoError(t, err)
+
+	recorder := fixture.NewRecorder(func(*adb.Device) executor.IExecutor {
+		return &deviceExecutor{}
+	})
+	exec := recorder.Executor(device)
+	_, err = exec.Run(context.Background(), command.GetApnCommand)
+	require.NoError(t, err)
+	exec.EnableRoot()
+	_, err = exec.Run(context.Background(), command.GetApnCommand)
+	require.NoError(t, err)
+	exec.DisableRoot()
+	_, err = recorder.Executor(device).RunBatch(context.Background(), []command.AdbCommand{command.GetBatteryCommand, command.GetInboxCommand})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	paths, err := recorder.Save(context.Background(), dir)
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+
+	// The executor answers the property commands with their own text.
+	recorded, err := fixture.Load(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, "abc", recorded.Device.Serial)
+	assert.Equal(t, string(command.GetDeviceModelCommand), recorded.Device.Model)
+
+	record, ok := recorded.Lookup(command.GetApnCommand, false)
+	assert.True(t, ok)
+	assert.Equal(t, string(command.GetApnCommand), record.Output)
+	record, ok = recorded.Lookup(command.GetApnCommand, true)
+	assert.True(t, ok)
+	assert.Equal(t, "root:"+string(command.GetApnCommand), record.Output)
+	_, ok = recorded.Lookup(command.GetBatteryCommand, false)
+	assert.True(t, ok)
+	_, ok = recorded.Lookup(command.GetInboxCommand, false)
+	assert.False(t, ok, "commands the batch did not finish are not recorded")
+	_, ok = recorded.Lookup(command.GetDeviceModelCommand, false)
+	assert.True(t, ok)
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+	client, fixtures := newDevice("abc")
+	fixtures[0].Add(fixture.Record{Command: command.GetApnCommand, Output: "denied"})
+	fixtures[0].Add(fixture.Record{Command: command.GetApnCommand, Root: true, Output: "apn"})
+	fixtures[0].Add(fixture.Record{Command: command.GetBatteryCommand, Output: "battery", ExitCode: 3})
+	device, err := client.GetDeviceBySerial("abc")
+	require.NoError(t, err)
+
+	exec := fixture.NewReplay(fixtures...).Executor(device)
+	output, err := exec.Run(context.Background(), command.GetApnCommand)
+	assert.NoError(t, err)
+	assert.Equal(t, "denied", output)
+
+	exec.EnableRoot()
+	output, err = exec.Run(context.Background(), command.GetApnCommand)
+	assert.NoError(t, err)
+	assert.Equal(t, "apn", output)
+	_, err = exec.Run(context.Background(), command.GetBatteryCommand)
+	assert.ErrorIs(t, err, adbErrors.ErrorFixtureNotFound)
+	exec.DisableRoot()
+
+	outputs, err := exec.RunBatch(context.Background(), []command.AdbCommand{command.GetBatteryCommand, command.GetApnCommand})
+	assert.NoError(t, err)
+	assert.Equal(t, []executor.BatchOutput{
+		{Output: "battery", ExitCode: 3, Done: true},
+		{Output: "denied", Done: true},
+	}, outputs)
+
+	_, err = exec.RunBatch(context.Background(), []command.AdbCommand{command.GetBatteryCommand, command.GetInboxCommand})
+	assert.ErrorIs(t, err, adbErrors.ErrorFixtureNotFound)
+}
+
+func TestReplayUnknownDevice(t *testing.T) {
+	t.Parallel()
+	client, _ := newDevice("abc")
+	device, err := client.GetDeviceBySerial("abc")
+	require.NoError(t, err)
+
+	_, err = fixture.NewReplay().Executor(device).Run(context.Background(), command.GetApnCommand)
+	assert.ErrorIs(t, err, adbErrors.ErrorFixtureNotFound)
+}
+
+func TestReplayCancelled(t *testing.T) {
+	t.Parallel()
+	client, fixtures := newDevice("abc")
+	fixtures[0].Add(fixture.Record{Command: command.GetApnCommand, Output: "apn"})
+	device, err := client.GetDeviceBySerial("abc")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = fixture.NewReplay(fixtures...).Executor(device).Run(ctx, command.GetApnCommand)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	fixtures := []*fixture.Fixture{
+		fixture.New(fixture.Device{Serial: "abc", Model: "Pixel 7", Product: "panther"}),
+		fixture.New(fixture.Device{Serial: "def", Model: "SM-A525F", Product: "a52qnsxx"}),
+	}
+	client := &adb.Adb{Server: fixture.NewServer(fixtures...)}
+
+	serials, err := client.ListDeviceSerials()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc", "def"}, serials)
+
+	devices, err := client.ListDevices()
+	assert.NoError(t, err)
+	require.Len(t, devices, 2)
+	assert.Equal(t, "Pixel_7", devices[0].Model)
+	assert.Equal(t, "a52qnsxx", devices[1].Product)
+
+	device, err := client.GetDeviceBySerial("def")
+	assert.NoError(t, err)
+	state, err := device.State()
+	assert.NoError(t, err)
+	assert.Equal(t, adb.StateOnline, state)
+
+	_, err = client.GetDeviceBySerial("ghi")
+	assert.True(t, adb.HasErrCode(err, adb.DeviceNotFound))
+}
+
+func TestProfiles(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{
+		"google-pixel-7-android-14",
+		"samsung-sm-a525f-android-13",
+		"xiaomi-redmi-note-7-android-9",
+	}, fixture.ProfileNames())
+
+	profiles, err := fixture.Profiles()
+	require.NoError(t, err)
+	for _, profile := range profiles {
+		assert.Contains(t, fixture.ProfileNames(), profile.Name())
+		_, ok := profile.Lookup(command.GetDevicePropCommand, false)
+		assert.True(t, ok, profile.Name())
+	}
+
+	_, err = fixture.Profile("nokia-3310")
+	assert.Error(t, err)
+}
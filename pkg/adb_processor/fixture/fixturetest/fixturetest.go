@@ -0,0 +1,23 @@
+// Package fixturetest sets up the bundled fixtures for the tests of services.
+// It is apart from package fixture, which the processor tests import.
+package fixturetest
+
+import (
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	"github.com/basiooo/andromodem/pkg/adb_processor/processor"
+	adb "github.com/basiooo/goadb"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// NewReplayedClient returns an ADB client listing the devices of the bundled
+// profiles and a processor replaying their outputs.
+func NewReplayedClient(t testing.TB) (*adb.Adb, processor.IProcessor) {
+	t.Helper()
+	fixtures, err := fixture.Profiles()
+	require.NoError(t, err)
+	adbProcessor := processor.NewProcessorWithExecutor(zaptest.NewLogger(t), fixture.NewReplay(fixtures...).Executor)
+	return &adb.Adb{Server: fixture.NewServer(fixtures...)}, adbProcessor
+}
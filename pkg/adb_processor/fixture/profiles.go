@@ -0,0 +1,48 @@
+package fixture
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+)
+
+// profiles hold the outputs of the devices they are named after, as saved by
+// a Recorder.
+//
+//go:embed profiles/*.json
+var profiles embed.FS
+
+// ProfileNames returns the names of the bundled device profiles.
+func ProfileNames() []string {
+	entries, _ := profiles.ReadDir("profiles")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns a new copy of the bundled profile called name.
+func Profile(name string) (*Fixture, error) {
+	data, err := profiles.ReadFile(path.Join("profiles", name+".json"))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Profiles returns a new copy of every bundled profile.
+func Profiles() ([]*Fixture, error) {
+	names := ProfileNames()
+	fixtures := make([]*Fixture, 0, len(names))
+	for _, name := range names {
+		fixture, err := Profile(name)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
@@ -0,0 +1,124 @@
+package fixture
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
+	adb "github.com/basiooo/goadb"
+)
+
+var getManufacturerCommand = command.New("getprop", "ro.product.manufacturer").Build()
+
+// Recorder records the outputs of the commands run by the executors it
+// creates, per device.
+type Recorder struct {
+	newExecutor executor.Factory
+
+	mu       sync.Mutex
+	fixtures map[string]*Fixture
+	devices  map[string]*adb.Device
+}
+
+// NewRecorder returns a recorder wrapping the executors of newExecutor.
+func NewRecorder(newExecutor executor.Factory) *Recorder {
+	return &Recorder{
+		newExecutor: newExecutor,
+		fixtures:    make(map[string]*Fixture),
+		devices:     make(map[string]*adb.Device),
+	}
+}
+
+// Executor returns an executor recording the commands run on device. It is
+// an executor.Factory.
+func (r *Recorder) Executor(device *adb.Device) executor.IExecutor {
+	exec := r.newExecutor(device)
+	serial, err := device.Serial()
+	if err != nil {
+		return exec
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fixture, ok := r.fixtures[serial]
+	if !ok {
+		fixture = New(Device{Serial: serial})
+		r.fixtures[serial] = fixture
+		r.devices[serial] = device
+	}
+	return &recordingExecutor{IExecutor: exec, fixture: fixture}
+}
+
+// Save describes every recorded device and writes its fixture to dir, named
+// after the device and its Android version. It returns the written paths.
+func (r *Recorder) Save(ctx context.Context, dir string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths := make([]string, 0, len(r.fixtures))
+	for serial, fixture := range r.fixtures {
+		exec := &recordingExecutor{IExecutor: r.newExecutor(r.devices[serial]), fixture: fixture}
+		device, err := describe(ctx, exec)
+		if err != nil {
+			return paths, err
+		}
+		device.Serial = serial
+		fixture.Device = device
+
+		path := filepath.Join(dir, fixture.Name()+".json")
+		if err := fixture.Save(path); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// describe reads the properties naming the device, their outputs are
+// recorded as well.
+func describe(ctx context.Context, exec executor.IExecutor) (Device, error) {
+	described := Device{}
+	props := map[command.AdbCommand]*string{
+		getManufacturerCommand:           &described.Manufacturer,
+		command.GetDeviceModelCommand:    &described.Model,
+		command.GetDeviceProductCommand:  &described.Product,
+		command.GetAndroidVersionCommand: &described.AndroidVersion,
+	}
+	for propCommand, value := range props {
+		output, err := exec.Run(ctx, propCommand)
+		if err != nil {
+			return Device{}, err
+		}
+		*value = strings.TrimSpace(output)
+	}
+	return described, nil
+}
+
+type recordingExecutor struct {
+	executor.IExecutor
+	fixture *Fixture
+}
+
+func (e *recordingExecutor) Run(ctx context.Context, adbCommand command.AdbCommand) (string, error) {
+	output, err := e.IExecutor.Run(ctx, adbCommand)
+	if err == nil {
+		e.fixture.Add(Record{Command: adbCommand, Root: e.Root(), Output: output})
+	}
+	return output, err
+}
+
+func (e *recordingExecutor) RunBatch(ctx context.Context, adbCommands []command.AdbCommand) ([]executor.BatchOutput, error) {
+	outputs, err := e.IExecutor.RunBatch(ctx, adbCommands)
+	if err != nil {
+		return outputs, err
+	}
+	for i, output := range outputs {
+		if output.Done {
+			e.fixture.Add(Record{Command: adbCommands[i], Root: e.Root(), Output: output.Output, ExitCode: output.ExitCode})
+		}
+	}
+	return outputs, nil
+}
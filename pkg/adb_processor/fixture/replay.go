@@ -0,0 +1,92 @@
+package fixture
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
+	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
+	adb "github.com/basiooo/goadb"
+)
+
+// Replay serves the outputs recorded in fixtures to the executors it
+// creates, the fixture of a device is found by its serial.
+type Replay struct {
+	fixtures map[string]*Fixture
+}
+
+// NewReplay returns a replay of fixtures.
+func NewReplay(fixtures ...*Fixture) *Replay {
+	replay := &Replay{fixtures: make(map[string]*Fixture, len(fixtures))}
+	for _, fixture := range fixtures {
+		replay.fixtures[fixture.Device.Serial] = fixture
+	}
+	return replay
+}
+
+// Executor returns an executor replaying the outputs recorded on device. It
+// is an executor.Factory.
+func (r *Replay) Executor(device *adb.Device) executor.IExecutor {
+	serial, _ := device.Serial()
+	return &replayExecutor{
+		serial:  serial,
+		fixture: r.fixtures[serial],
+	}
+}
+
+// replayExecutor fails commands without a recorded output, so a fixture
+// missing a command shows up in tests instead of an empty result.
+type replayExecutor struct {
+	serial  string
+	fixture *Fixture
+	useRoot bool
+}
+
+func (e *replayExecutor) Run(ctx context.Context, adbCommand command.AdbCommand) (string, error) {
+	record, err := e.lookup(ctx, adbCommand)
+	if err != nil {
+		return "", err
+	}
+	return record.Output, nil
+}
+
+func (e *replayExecutor) RunBatch(ctx context.Context, adbCommands []command.AdbCommand) ([]executor.BatchOutput, error) {
+	outputs := make([]executor.BatchOutput, len(adbCommands))
+	for i, adbCommand := range adbCommands {
+		record, err := e.lookup(ctx, adbCommand)
+		if err != nil {
+			return nil, err
+		}
+		outputs[i] = executor.BatchOutput{Output: record.Output, ExitCode: record.ExitCode, Done: true}
+	}
+	return outputs, nil
+}
+
+func (e *replayExecutor) lookup(ctx context.Context, adbCommand command.AdbCommand) (Record, error) {
+	if err := ctx.Err(); err != nil {
+		return Record{}, err
+	}
+	if e.fixture == nil {
+		return Record{}, fmt.Errorf("%w: no fixture for device '%s'", adbErrors.ErrorFixtureNotFound, e.serial)
+	}
+	record, ok := e.fixture.Lookup(adbCommand, e.useRoot)
+	if !ok {
+		return Record{}, fmt.Errorf("%w: '%s' (root: %t) on %s", adbErrors.ErrorFixtureNotFound, adbCommand, e.useRoot, e.fixture.Name())
+	}
+	return record, nil
+}
+
+func (e *replayExecutor) EnableRoot() {
+	e.useRoot = true
+}
+
+func (e *replayExecutor) DisableRoot() {
+	e.useRoot = false
+}
+
+func (e *replayExecutor) Root() bool {
+	return e.useRoot
+}
+
+func (e *replayExecutor) SetSuSyntax(command.SuSyntax) {}
@@ -0,0 +1,120 @@
+package fixture
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/basiooo/goadb/wire"
+)
+
+// Server answers the host requests goadb makes to find devices, listing the
+// devices of its fixtures as online. Shell commands are not served, they are
+// answered by the executors of a Replay. Use it as the server of an adb.Adb:
+//
+//	client := &adb.Adb{Server: fixture.NewServer(fixtures...)}
+type Server struct {
+	fixtures []*Fixture
+}
+
+// NewServer returns a server listing the devices of fixtures.
+func NewServer(fixtures ...*Fixture) *Server {
+	return &Server{fixtures: fixtures}
+}
+
+func (s *Server) Start() error {
+	return nil
+}
+
+// Dial returns a connection speaking the ADB wire protocol to the server.
+func (s *Server) Dial() (*wire.Conn, error) {
+	client, server := net.Pipe()
+	go s.serve(server)
+	return wire.NewConn(wire.NewScanner(client), wire.NewSender(client)), nil
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	request, err := readRequest(conn)
+	if err != nil {
+		return
+	}
+	response, err := s.respond(request)
+	if err != nil {
+		_, _ = io.WriteString(conn, "FAIL"+encodeMessage(err.Error()))
+		return
+	}
+	_, _ = io.WriteString(conn, "OKAY"+encodeMessage(response))
+}
+
+func (s *Server) respond(request string) (string, error) {
+	switch request {
+	case "host:devices":
+		var list strings.Builder
+		for _, fixture := range s.fixtures {
+			fmt.Fprintf(&list, "%s\tdevice\n", fixture.Device.Serial)
+		}
+		return list.String(), nil
+	case "host:devices-l":
+		var list strings.Builder
+		for i, fixture := range s.fixtures {
+			fmt.Fprintf(&list, "%-22s device product:%s model:%s device:%s transport_id:%d\n",
+				fixture.Device.Serial,
+				fixture.Device.Product,
+				strings.ReplaceAll(fixture.Device.Model, " ", "_"),
+				fixture.Device.Product,
+				i+1)
+		}
+		return list.String(), nil
+	}
+
+	serial, attribute, ok := strings.Cut(strings.TrimPrefix(request, "host-serial:"), ":")
+	if !ok || !strings.HasPrefix(request, "host-serial:") {
+		return "", fmt.Errorf("unsupported request '%s'", request)
+	}
+	if !s.hasDevice(serial) {
+		return "", fmt.Errorf("device '%s' not found", serial)
+	}
+	switch attribute {
+	case "get-serialno":
+		return serial, nil
+	case "get-state":
+		return "device", nil
+	default:
+		return "", fmt.Errorf("unsupported request '%s'", request)
+	}
+}
+
+func (s *Server) hasDevice(serial string) bool {
+	for _, fixture := range s.fixtures {
+		if fixture.Device.Serial == serial {
+			return true
+		}
+	}
+	return false
+}
+
+func readRequest(r io.Reader) (string, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", err
+	}
+	length, err := strconv.ParseUint(string(header), 16, 16)
+	if err != nil {
+		return "", err
+	}
+	request := make([]byte, length)
+	if _, err := io.ReadFull(r, request); err != nil {
+		return "", err
+	}
+	return string(request), nil
+}
+
+func encodeMessage(message string) string {
+	return fmt.Sprintf("%04x%s", len(message), message)
+}
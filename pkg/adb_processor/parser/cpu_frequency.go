@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CoreFrequency holds the clock of a core in MHz.
+type CoreFrequency struct {
+	Core    int `json:"core"`
+	Current int `json:"current"`
+	Min     int `json:"min"`
+	Max     int `json:"max"`
+}
+
+// CpuFrequency parses the cpufreq files of every core printed by
+// `grep -H .` in /sys/devices/system/cpu, one "<path>:<kHz>" per line.
+// Offline cores have no cpufreq files and are left out.
+type CpuFrequency struct {
+	Cores []CoreFrequency `json:"cores"`
+}
+
+func NewCpuFrequency() IParser {
+	return &CpuFrequency{}
+}
+
+func (c *CpuFrequency) Parse(rawData string) error {
+	c.Cores = nil
+	for _, line := range strings.Split(rawData, "\n") {
+		file, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		// cpu0/cpufreq/scaling_cur_freq
+		core, err := strconv.Atoi(strings.TrimPrefix(path.Base(path.Dir(path.Dir(file))), "cpu"))
+		if err != nil {
+			continue
+		}
+		frequency, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		frequency /= 1000
+
+		i := slices.IndexFunc(c.Cores, func(coreFrequency CoreFrequency) bool { return coreFrequency.Core == core })
+		if i == -1 {
+			c.Cores = append(c.Cores, CoreFrequency{Core: core})
+			i = len(c.Cores) - 1
+		}
+		switch path.Base(file) {
+		case "scaling_cur_freq":
+			c.Cores[i].Current = frequency
+		case "cpuinfo_min_freq":
+			c.Cores[i].Min = frequency
+		case "cpuinfo_max_freq":
+			c.Cores[i].Max = frequency
+		}
+	}
+	slices.SortFunc(c.Cores, func(a, b CoreFrequency) int { return a.Core - b.Core })
+	return nil
+}
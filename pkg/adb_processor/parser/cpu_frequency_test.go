@@ -0,0 +1,34 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCpuFrequency(t *testing.T) {
+	t.Parallel()
+	// cpu1 is offline.
+	data := `cpu0/cpufreq/scaling_cur_freq:1098000
+cpu4/cpufreq/scaling_cur_freq:1328000
+cpu0/cpufreq/cpuinfo_min_freq:300000
+cpu4/cpufreq/cpuinfo_min_freq:400000
+cpu0/cpufreq/cpuinfo_max_freq:1803000
+cpu4/cpufreq/cpuinfo_max_freq:2348000
+`
+	frequency := &parser.CpuFrequency{}
+	require.NoError(t, frequency.Parse(data))
+	assert.Equal(t, []parser.CoreFrequency{
+		{Core: 0, Current: 1098, Min: 300, Max: 1803},
+		{Core: 4, Current: 1328, Min: 400, Max: 2348},
+	}, frequency.Cores)
+}
+
+func TestParseCpuFrequencyEmpty(t *testing.T) {
+	t.Parallel()
+	frequency := &parser.CpuFrequency{}
+	require.NoError(t, frequency.Parse(""))
+	assert.Empty(t, frequency.Cores)
+}
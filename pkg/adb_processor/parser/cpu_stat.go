@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CpuTime is the time a CPU spent since boot, in jiffies.
+type CpuTime struct {
+	// Core is -1 for the sum of every core.
+	Core  int    `json:"core"`
+	Busy  uint64 `json:"busy"`
+	Total uint64 `json:"total"`
+}
+
+// CpuStat parses the cpu lines of /proc/stat. A single sample only holds
+// counters since boot, the utilisation is worked out between two samples
+// by UsageSince.
+type CpuStat struct {
+	Total CpuTime   `json:"total"`
+	Cores []CpuTime `json:"cores"`
+}
+
+type CoreUsage struct {
+	Core  int     `json:"core"`
+	Usage float64 `json:"usage"`
+}
+
+// CpuUsage is the percentage of time the CPU was busy between two samples.
+// Cores offline in either sample are left out.
+type CpuUsage struct {
+	Usage float64     `json:"usage"`
+	Cores []CoreUsage `json:"cores"`
+}
+
+func NewCpuStat() IParser {
+	return &CpuStat{}
+}
+
+func (c *CpuStat) Parse(rawData string) error {
+	c.Total = CpuTime{Core: -1}
+	c.Cores = nil
+	hasTotal := false
+	for _, line := range strings.Split(rawData, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		cpuTime, err := parseCpuTime(fields)
+		if err != nil {
+			return err
+		}
+		if cpuTime.Core == -1 {
+			c.Total = cpuTime
+			hasTotal = true
+			continue
+		}
+		c.Cores = append(c.Cores, cpuTime)
+	}
+	if !hasTotal {
+		return fmt.Errorf("unexpected /proc/stat output: no cpu line")
+	}
+	return nil
+}
+
+// parseCpuTime reads a line of user, nice, system, idle, iowait, irq,
+// softirq and steal time. Guest time is already counted in user time.
+func parseCpuTime(fields []string) (CpuTime, error) {
+	cpuTime := CpuTime{Core: -1}
+	if core := strings.TrimPrefix(fields[0], "cpu"); core != "" {
+		var err error
+		if cpuTime.Core, err = strconv.Atoi(core); err != nil {
+			return cpuTime, fmt.Errorf("unexpected cpu line %q: %w", fields[0], err)
+		}
+	}
+	for i, field := range fields[1:min(len(fields), 9)] {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return cpuTime, fmt.Errorf("unexpected %s time %q: %w", fields[0], field, err)
+		}
+		cpuTime.Total += value
+		// idle and iowait
+		if i != 3 && i != 4 {
+			cpuTime.Busy += value
+		}
+	}
+	return cpuTime, nil
+}
+
+// UsageSince returns the utilisation between previous and c. It is false
+// when no time passed between them, or the counters were reset by a reboot.
+func (c *CpuStat) UsageSince(previous *CpuStat) (*CpuUsage, bool) {
+	usage, ok := cpuTimeUsage(previous.Total, c.Total)
+	if !ok {
+		return nil, false
+	}
+	cpuUsage := &CpuUsage{Usage: usage, Cores: make([]CoreUsage, 0, len(c.Cores))}
+	for _, core := range c.Cores {
+		for _, previousCore := range previous.Cores {
+			if previousCore.Core != core.Core {
+				continue
+			}
+			if usage, ok := cpuTimeUsage(previousCore, core); ok {
+				cpuUsage.Cores = append(cpuUsage.Cores, CoreUsage{Core: core.Core, Usage: usage})
+			}
+			break
+		}
+	}
+	return cpuUsage, true
+}
+
+func cpuTimeUsage(previous, current CpuTime) (float64, bool) {
+	if current.Total <= previous.Total || current.Busy < previous.Busy {
+		return 0, false
+	}
+	busy := float64(current.Busy - previous.Busy)
+	total := float64(current.Total - previous.Total)
+	return math.Round(busy*1000/total) / 10, true
+}
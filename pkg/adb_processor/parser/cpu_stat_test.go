@@ -0,0 +1,55 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCpuStat(t *testing.T) {
+	t.Parallel()
+	data := `cpu  1254310 43211 987642 14210985 21344 98765 45321 0 0 0
+cpu0 201450 7021 160113 1688012 3120 30411 14010 0 0 0
+cpu1 198762 6890 157210 1692340 3005 12311 6120 0 0 0
+intr 412345678 0 0 0
+ctxt 823456789
+btime 1718353489
+`
+	stat := &parser.CpuStat{}
+	require.NoError(t, stat.Parse(data))
+	assert.Equal(t, parser.CpuTime{Core: -1, Busy: 2429249, Total: 16661578}, stat.Total)
+	assert.Equal(t, []parser.CpuTime{
+		{Core: 0, Busy: 413005, Total: 2104137},
+		{Core: 1, Busy: 381293, Total: 2076638},
+	}, stat.Cores)
+}
+
+func TestParseCpuStatUnexpected(t *testing.T) {
+	t.Parallel()
+	stat := &parser.CpuStat{}
+	assert.Error(t, stat.Parse("cat: /proc/stat: No such file or directory\n"))
+	assert.Error(t, stat.Parse("cpu  12 x 4 100\n"))
+}
+
+func TestCpuStatUsageSince(t *testing.T) {
+	t.Parallel()
+	previous := &parser.CpuStat{}
+	require.NoError(t, previous.Parse("cpu  100 0 50 800 50 0 0 0\ncpu0 60 0 20 400 20 0 0 0\ncpu1 40 0 30 400 30 0 0 0\n"))
+	// cpu1 went offline, cpu2 came online.
+	current := &parser.CpuStat{}
+	require.NoError(t, current.Parse("cpu  300 0 100 1500 100 0 0 0\ncpu0 200 0 60 700 40 0 0 0\ncpu2 10 0 10 80 0 0 0 0\n"))
+
+	usage, ok := current.UsageSince(previous)
+	require.True(t, ok)
+	assert.Equal(t, &parser.CpuUsage{
+		Usage: 25,
+		Cores: []parser.CoreUsage{{Core: 0, Usage: 36}},
+	}, usage)
+
+	_, ok = current.UsageSince(current)
+	assert.False(t, ok, "no time passed")
+	_, ok = previous.UsageSince(current)
+	assert.False(t, ok, "counters were reset")
+}
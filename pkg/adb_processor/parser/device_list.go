@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+type DeviceTransport string
+
+const (
+	DeviceTransportUsb     DeviceTransport = "usb"
+	DeviceTransportTcp     DeviceTransport = "tcp"
+	DeviceTransportUnknown DeviceTransport = "unknown"
+)
+
+// adb reports a device that is ready to use as "device", it is listed as online.
+const (
+	adbStateDevice    = "device"
+	DeviceStateOnline = "online"
+)
+
+// deviceListAttributes are the key:value pairs printed by `adb devices -l`.
+var deviceListAttributes = map[string]bool{
+	"usb":          true,
+	"product":      true,
+	"model":        true,
+	"device":       true,
+	"transport_id": true,
+}
+
+type AdbDevice struct {
+	Serial      string          `json:"serial"`
+	State       string          `json:"state"`
+	Model       string          `json:"model"`
+	Product     string          `json:"product"`
+	Device      string          `json:"device"`
+	Transport   DeviceTransport `json:"transport"`
+	UsbPath     string          `json:"usb_path,omitempty"`
+	TransportId string          `json:"transport_id,omitempty"`
+}
+
+// DeviceList is the output of `adb devices -l` (host:devices-l).
+type DeviceList struct {
+	Devices []AdbDevice `json:"devices"`
+}
+
+func NewDeviceList() IParser {
+	return &DeviceList{}
+}
+
+func (d *DeviceList) Parse(rawData string) error {
+	d.Devices = []AdbDevice{}
+	for _, line := range strings.Split(rawData, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(line, "List of devices") || strings.HasPrefix(line, "*") {
+			continue
+		}
+		d.Devices = append(d.Devices, parseAdbDevice(fields))
+	}
+	return nil
+}
+
+func parseAdbDevice(fields []string) AdbDevice {
+	device := AdbDevice{Serial: fields[0]}
+
+	// The state may span several words, e.g. "no permissions (missing udev
+	// rules? ...)", so everything up to the first attribute belongs to it.
+	var state []string
+	attributes := false
+	for _, field := range fields[1:] {
+		key, value, found := strings.Cut(field, ":")
+		if !found || !deviceListAttributes[key] {
+			if !attributes {
+				state = append(state, field)
+			}
+			continue
+		}
+		attributes = true
+		switch key {
+		case "usb":
+			device.UsbPath = value
+		case "product":
+			device.Product = value
+		case "model":
+			device.Model = value
+		case "device":
+			device.Device = value
+		case "transport_id":
+			device.TransportId = value
+		}
+	}
+	device.State = strings.Join(state, " ")
+	if device.State == adbStateDevice {
+		device.State = DeviceStateOnline
+	}
+	device.Transport = deviceTransport(device)
+	return device
+}
+
+func deviceTransport(device AdbDevice) DeviceTransport {
+	if device.UsbPath != "" {
+		return DeviceTransportUsb
+	}
+	// Wireless debugging devices found through mDNS.
+	if strings.Contains(device.Serial, "._adb-tls-connect._tcp") {
+		return DeviceTransportTcp
+	}
+	// Emulators are reached through a local TCP port as well.
+	if strings.HasPrefix(device.Serial, "emulator-") {
+		return DeviceTransportTcp
+	}
+	if _, port, err := net.SplitHostPort(device.Serial); err == nil {
+		if _, err := strconv.Atoi(port); err == nil {
+			return DeviceTransportTcp
+		}
+	}
+	return DeviceTransportUnknown
+}
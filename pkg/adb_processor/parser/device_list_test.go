@@ -0,0 +1,40 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDeviceList(t *testing.T) {
+	t.Parallel()
+	data := `List of devices attached
+R58M123ABC             device usb:1-1.2 product:a51nsxx model:SM_A515F device:a51 transport_id:3
+192.168.1.20:5555      offline product:sunfish model:Pixel_4a device:sunfish transport_id:5
+emulator-5554          unauthorized transport_id:6
+ZY22BCDEF              recovery usb:2-1 transport_id:7
+0123456789             no permissions (missing udev rules? user is in the plugdev group); see [http://developer.android.com/tools/device.html] usb:1-4 transport_id:8
+`
+	expected := &parser.DeviceList{
+		Devices: []parser.AdbDevice{
+			{Serial: "R58M123ABC", State: "online", Model: "SM_A515F", Product: "a51nsxx", Device: "a51", Transport: parser.DeviceTransportUsb, UsbPath: "1-1.2", TransportId: "3"},
+			{Serial: "192.168.1.20:5555", State: "offline", Model: "Pixel_4a", Product: "sunfish", Device: "sunfish", Transport: parser.DeviceTransportTcp, TransportId: "5"},
+			{Serial: "emulator-5554", State: "unauthorized", Transport: parser.DeviceTransportTcp, TransportId: "6"},
+			{Serial: "ZY22BCDEF", State: "recovery", Transport: parser.DeviceTransportUsb, UsbPath: "2-1", TransportId: "7"},
+			{Serial: "0123456789", State: "no permissions (missing udev rules? user is in the plugdev group); see [http://developer.android.com/tools/device.html]", Transport: parser.DeviceTransportUsb, UsbPath: "1-4", TransportId: "8"},
+		},
+	}
+	deviceList := parser.NewDeviceList()
+	err := deviceList.Parse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, deviceList)
+}
+
+func TestParseDeviceListEmpty(t *testing.T) {
+	t.Parallel()
+	deviceList := parser.NewDeviceList()
+	err := deviceList.Parse("")
+	assert.NoError(t, err)
+	assert.Equal(t, &parser.DeviceList{Devices: []parser.AdbDevice{}}, deviceList)
+}
@@ -28,7 +28,7 @@ func (d *DeviceProp) Parse(rawData string) error {
 		"[ro.build.version.security_patch]",
 		"[ro.product.board]",
 		"[ro.product.cpu.abi]",
-	}, true)
+	}, false)
 	d.Model = getParseListDataValue(propsData, "[ro.product.model]")
 	d.Brand = getParseListDataValue(propsData, "[ro.product.brand]")
 	d.Name = getParseListDataValue(propsData, "[ro.product.name]")
@@ -34,6 +34,20 @@ func TestParseDevicePropHasEmptyValue(t *testing.T) {
 	assert.Equal(t, expected, deviceProp)
 }
 
+func TestParseDevicePropWithSpaces(t *testing.T) {
+	t.Parallel()
+	data := "[ro.product.model]: [Redmi Note 7]\n[ro.product.brand]: [xiaomi]\n[ro.product.name]: [lavender]\n"
+	expected := &parser.DeviceProp{
+		Model: "Redmi Note 7",
+		Brand: "xiaomi",
+		Name:  "lavender",
+	}
+	deviceProp := parser.NewDeviceProp()
+	err := deviceProp.Parse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, deviceProp)
+}
+
 func BenchmarkParseDeviceProp(b *testing.B) {
 	data := "[ro.product.model]: [Phone]\n[ro.product.brand]: [Custom]\n[ro.product.name]: [vbox86p]\n,[ro.product.name]: [vbox86p]\n"
 	for i := 0; i < b.N; i++ {
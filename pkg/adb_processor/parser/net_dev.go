@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NetInterface holds the traffic counters of an interface since it came up.
+type NetInterface struct {
+	Name      string `json:"name"`
+	RxBytes   uint64 `json:"rx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	TxPackets uint64 `json:"tx_packets"`
+}
+
+// NetDev parses /proc/net/dev. Every interface line holds 8 receive columns
+// followed by 8 transmit columns, the first of each being bytes and packets.
+type NetDev struct {
+	Interfaces []NetInterface `json:"interfaces"`
+}
+
+func NewNetDev() IParser {
+	return &NetDev{}
+}
+
+func (n *NetDev) Parse(rawData string) error {
+	n.Interfaces = nil
+	hasHeader := false
+	for _, line := range strings.Split(rawData, "\n") {
+		if strings.Contains(line, "|") {
+			hasHeader = true
+			continue
+		}
+		// Older kernels print no space between the name and the counters.
+		name, counters, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 16 {
+			continue
+		}
+		netInterface := NetInterface{Name: strings.TrimSpace(name)}
+		columns := []struct {
+			index int
+			value *uint64
+		}{
+			{0, &netInterface.RxBytes},
+			{1, &netInterface.RxPackets},
+			{8, &netInterface.TxBytes},
+			{9, &netInterface.TxPackets},
+		}
+		for _, column := range columns {
+			var err error
+			if *column.value, err = strconv.ParseUint(fields[column.index], 10, 64); err != nil {
+				return fmt.Errorf("unexpected counter of %s: %w", netInterface.Name, err)
+			}
+		}
+		n.Interfaces = append(n.Interfaces, netInterface)
+	}
+	if !hasHeader {
+		return fmt.Errorf("unexpected /proc/net/dev output: no header")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNetDev(t *testing.T) {
+	t.Parallel()
+	data := `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:   904512    6120    0    0    0     0          0         0   904512    6120    0    0    0     0       0          0
+rmnet_data1: 9012345678 6998765    0    0    0     0          0         0 1423456789 4101234    0    0    0     0       0          0
+`
+	netDev := &parser.NetDev{}
+	require.NoError(t, netDev.Parse(data))
+	assert.Equal(t, []parser.NetInterface{
+		{Name: "lo", RxBytes: 904512, RxPackets: 6120, TxBytes: 904512, TxPackets: 6120},
+		{Name: "rmnet_data1", RxBytes: 9012345678, RxPackets: 6998765, TxBytes: 1423456789, TxPackets: 4101234},
+	}, netDev.Interfaces)
+}
+
+func TestParseNetDevWithoutSpace(t *testing.T) {
+	t.Parallel()
+	// Kernels before 3.x print no space after the name once the byte counter
+	// gets long.
+	data := `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+ccmni0:1234567890 987654    0    0    0     0          0         0 123456789 654321    0    0    0     0       0          0
+`
+	netDev := &parser.NetDev{}
+	require.NoError(t, netDev.Parse(data))
+	require.Len(t, netDev.Interfaces, 1)
+	assert.Equal(t, "ccmni0", netDev.Interfaces[0].Name)
+	assert.Equal(t, uint64(1234567890), netDev.Interfaces[0].RxBytes)
+	assert.Equal(t, uint64(123456789), netDev.Interfaces[0].TxBytes)
+}
+
+func TestParseNetDevUnexpected(t *testing.T) {
+	t.Parallel()
+	netDev := &parser.NetDev{}
+	assert.Error(t, netDev.Parse("cat: /proc/net/dev: Permission denied\n"))
+}
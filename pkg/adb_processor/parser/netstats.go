@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NetstatsBucket is the traffic of a uid in one bucket of its history.
+type NetstatsBucket struct {
+	Start   time.Time `json:"start"`
+	RxBytes uint64    `json:"rx_bytes"`
+	TxBytes uint64    `json:"tx_bytes"`
+}
+
+// NetstatsHistory is the traffic history of a uid on the networks of an
+// identity set. Set is DEFAULT for background and FOREGROUND for foreground
+// traffic.
+type NetstatsHistory struct {
+	Uid            int              `json:"uid"`
+	Set            string           `json:"set"`
+	Mobile         bool             `json:"mobile"`
+	BucketDuration time.Duration    `json:"bucket_duration"`
+	Buckets        []NetstatsBucket `json:"buckets"`
+}
+
+// UidTraffic is the traffic of a uid summed over its histories.
+type UidTraffic struct {
+	Uid     int    `json:"uid"`
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+}
+
+// Netstats parses the "UID stats" section of `dumpsys netstats --full --uid`.
+// Every history starts with an ident line naming the networks and the uid,
+// followed by its bucket duration and one "st=<unix seconds> rb=.. tb=.."
+// line per bucket:
+//
+//	ident=[{type=0, ratType=-1, subscriberId=510105, metered=true}] uid=10151 set=DEFAULT tag=0x0
+//	  NetworkStatsHistory: bucketDuration=7200
+//	    st=1697594400 rb=1520345 rp=1203 tb=120345 tp=890 op=0
+type Netstats struct {
+	Histories []NetstatsHistory `json:"histories"`
+}
+
+// mobileNetworkTypes are the network types of mobile data, Android 12 and
+// newer print the number of ConnectivityManager.TYPE_MOBILE.
+var mobileNetworkTypes = []string{"0", "MOBILE"}
+
+func NewNetstats() IParser {
+	return &Netstats{}
+}
+
+func (n *Netstats) Parse(rawData string) error {
+	n.Histories = nil
+	inUidStats, hasUidStats := false, false
+	var history *NetstatsHistory
+	for _, line := range strings.Split(rawData, "\n") {
+		// Sections start unindented, e.g. "Xt stats:" or "UID stats:".
+		if line != "" && line[0] != ' ' {
+			inUidStats = strings.TrimSpace(line) == "UID stats:"
+			hasUidStats = hasUidStats || inUidStats
+			history = nil
+			continue
+		}
+		if !inUidStats {
+			continue
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "ident="):
+			n.Histories = append(n.Histories, parseNetstatsIdent(line))
+			history = &n.Histories[len(n.Histories)-1]
+		case history == nil:
+			continue
+		case strings.HasPrefix(line, "NetworkStatsHistory:"):
+			if seconds, err := strconv.Atoi(netstatsField(line, "bucketDuration")); err == nil {
+				history.BucketDuration = time.Duration(seconds) * time.Second
+			}
+		case strings.HasPrefix(line, "st="):
+			bucket, err := parseNetstatsBucket(line)
+			if err != nil {
+				return fmt.Errorf("unexpected bucket of uid %d: %w", history.Uid, err)
+			}
+			history.Buckets = append(history.Buckets, bucket)
+		}
+	}
+	if !hasUidStats {
+		return fmt.Errorf("unexpected netstats output: no uid stats")
+	}
+	return nil
+}
+
+func parseNetstatsIdent(line string) NetstatsHistory {
+	// The identities hold commas and spaces, the uid comes after them.
+	identities, fields, _ := strings.Cut(line, "] ")
+	history := NetstatsHistory{Uid: -1, Set: netstatsField(fields, "set")}
+	if uid, err := strconv.Atoi(netstatsField(fields, "uid")); err == nil {
+		history.Uid = uid
+	}
+	attributes := strings.FieldsFunc(strings.TrimPrefix(identities, "ident=["), func(r rune) bool {
+		return r == '{' || r == '}' || r == ','
+	})
+	for _, attribute := range attributes {
+		key, value, _ := strings.Cut(strings.TrimSpace(attribute), "=")
+		if key == "type" && slices.Contains(mobileNetworkTypes, value) {
+			history.Mobile = true
+		}
+	}
+	return history
+}
+
+func parseNetstatsBucket(line string) (NetstatsBucket, error) {
+	start, err := strconv.ParseInt(netstatsField(line, "st"), 10, 64)
+	if err != nil {
+		return NetstatsBucket{}, err
+	}
+	bucket := NetstatsBucket{Start: time.Unix(start, 0)}
+	if bucket.RxBytes, err = strconv.ParseUint(netstatsField(line, "rb"), 10, 64); err != nil {
+		return NetstatsBucket{}, err
+	}
+	if bucket.TxBytes, err = strconv.ParseUint(netstatsField(line, "tb"), 10, 64); err != nil {
+		return NetstatsBucket{}, err
+	}
+	return bucket, nil
+}
+
+// netstatsField returns the value of a "key=value" field of a line of
+// space separated fields.
+func netstatsField(line string, key string) string {
+	for _, field := range strings.Fields(line) {
+		if value, ok := strings.CutPrefix(field, key+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// MobileTraffic sums the mobile data traffic of every uid in the buckets
+// overlapping from to to. Buckets are counted whole, the result is as
+// precise as the bucket duration.
+func (n *Netstats) MobileTraffic(from, to time.Time) []UidTraffic {
+	traffic := make([]UidTraffic, 0)
+	for _, history := range n.Histories {
+		if !history.Mobile {
+			continue
+		}
+		for _, bucket := range history.Buckets {
+			if !bucket.Start.Before(to) || !bucket.Start.Add(history.BucketDuration).After(from) {
+				continue
+			}
+			i := slices.IndexFunc(traffic, func(uidTraffic UidTraffic) bool { return uidTraffic.Uid == history.Uid })
+			if i == -1 {
+				traffic = append(traffic, UidTraffic{Uid: history.Uid})
+				i = len(traffic) - 1
+			}
+			traffic[i].RxBytes += bucket.RxBytes
+			traffic[i].TxBytes += bucket.TxBytes
+		}
+	}
+	slices.SortFunc(traffic, func(a, b UidTraffic) int { return a.Uid - b.Uid })
+	return traffic
+}
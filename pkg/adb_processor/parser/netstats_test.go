@@ -0,0 +1,104 @@
+package parser_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const netstatsOutput = `Active interfaces:
+  iface=rmnet_data1 ident=[{type=0, ratType=13, subscriberId=510105, metered=true, defaultNetwork=true, oemManaged=0}]
+Dev stats:
+  Pending bytes: 2048
+  Complete history:
+  ident=[{type=0, ratType=-1, subscriberId=510105, metered=true, defaultNetwork=true, oemManaged=0}] uid=-1 set=ALL tag=0x0
+    NetworkStatsHistory: bucketDuration=3600
+      st=1697590800 rb=99999999 rp=9999 tb=9999999 tp=999 op=0
+UID stats:
+  Pending bytes: 5612
+  Complete history:
+  ident=[{type=0, ratType=-1, subscriberId=510105, metered=true, defaultNetwork=true, oemManaged=0}] uid=10151 set=DEFAULT tag=0x0
+    NetworkStatsHistory: bucketDuration=7200
+      st=1697587200 rb=1000 rp=10 tb=100 tp=5 op=0
+      st=1697594400 rb=2000 rp=20 tb=200 tp=8 op=0
+  ident=[{type=0, ratType=-1, subscriberId=510105, metered=true, defaultNetwork=true, oemManaged=0}] uid=10151 set=FOREGROUND tag=0x0
+    NetworkStatsHistory: bucketDuration=7200
+      st=1697594400 rb=30000 rp=40 tb=3000 tp=30 op=0
+  ident=[{type=1, ratType=-1, wifiNetworkKey="Home", metered=false, defaultNetwork=true, oemManaged=0}] uid=10151 set=FOREGROUND tag=0x0
+    NetworkStatsHistory: bucketDuration=7200
+      st=1697594400 rb=500000 rp=400 tb=50000 tp=300 op=0
+  ident=[{type=0, ratType=-1, subscriberId=510105, metered=true, defaultNetwork=false, oemManaged=0}, {type=0, ratType=13, subscriberId=510105, metered=true, defaultNetwork=true, oemManaged=0}] uid=-5 set=DEFAULT tag=0x0
+    NetworkStatsHistory: bucketDuration=7200
+      st=1697594400 rb=7000 rp=7 tb=700 tp=7 op=0
+UID tag stats:
+  Pending bytes: 0
+  ident=[{type=0, ratType=-1, subscriberId=510105, metered=true, defaultNetwork=true, oemManaged=0}] uid=10151 set=DEFAULT tag=0x1
+    NetworkStatsHistory: bucketDuration=7200
+      st=1697594400 rb=1 rp=1 tb=1 tp=1 op=0
+`
+
+func TestParseNetstats(t *testing.T) {
+	t.Parallel()
+	netstats := &parser.Netstats{}
+	require.NoError(t, netstats.Parse(netstatsOutput))
+	require.Len(t, netstats.Histories, 4)
+
+	history := netstats.Histories[0]
+	assert.Equal(t, 10151, history.Uid)
+	assert.Equal(t, "DEFAULT", history.Set)
+	assert.True(t, history.Mobile)
+	assert.Equal(t, 2*time.Hour, history.BucketDuration)
+	assert.Equal(t, []parser.NetstatsBucket{
+		{Start: time.Unix(1697587200, 0), RxBytes: 1000, TxBytes: 100},
+		{Start: time.Unix(1697594400, 0), RxBytes: 2000, TxBytes: 200},
+	}, history.Buckets)
+	assert.False(t, netstats.Histories[2].Mobile)
+	assert.Equal(t, -5, netstats.Histories[3].Uid)
+	assert.True(t, netstats.Histories[3].Mobile)
+}
+
+func TestParseNetstatsLegacy(t *testing.T) {
+	t.Parallel()
+	// Android 11 and older print the name of the network type.
+	data := `UID stats:
+  Pending bytes: 1024
+  Complete history:
+  ident=[{type=MOBILE, subType=COMBINED, subscriberId=460011, metered=true, defaultNetwork=true}] uid=10087 set=DEFAULT tag=0x0
+    NetworkStatsHistory: bucketDuration=7200
+      st=1697594400 rb=4000 rp=4 tb=400 tp=4 op=0
+  ident=[{type=WIFI, subType=COMBINED, networkId="Home", metered=false, defaultNetwork=true}] uid=10087 set=DEFAULT tag=0x0
+    NetworkStatsHistory: bucketDuration=7200
+      st=1697594400 rb=8000 rp=8 tb=800 tp=8 op=0
+`
+	netstats := &parser.Netstats{}
+	require.NoError(t, netstats.Parse(data))
+	require.Len(t, netstats.Histories, 2)
+	assert.True(t, netstats.Histories[0].Mobile)
+	assert.False(t, netstats.Histories[1].Mobile)
+}
+
+func TestParseNetstatsWithoutUidStats(t *testing.T) {
+	t.Parallel()
+	netstats := &parser.Netstats{}
+	assert.Error(t, netstats.Parse("Can't find service: netstats\n"))
+}
+
+func TestNetstatsMobileTraffic(t *testing.T) {
+	t.Parallel()
+	netstats := &parser.Netstats{}
+	require.NoError(t, netstats.Parse(netstatsOutput))
+
+	// The window overlaps the second bucket only, wifi traffic and tagged
+	// traffic are left out.
+	traffic := netstats.MobileTraffic(time.Unix(1697595000, 0), time.Unix(1697600000, 0))
+	assert.Equal(t, []parser.UidTraffic{
+		{Uid: -5, RxBytes: 7000, TxBytes: 700},
+		{Uid: 10151, RxBytes: 32000, TxBytes: 3200},
+	}, traffic)
+
+	traffic = netstats.MobileTraffic(time.Unix(0, 0), time.Unix(1697587200, 0))
+	assert.Empty(t, traffic)
+}
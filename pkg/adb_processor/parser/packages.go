@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Packages parses `pm list packages -U`, one "package:<name> uid:<uid>" per
+// line. Apps of a shared user id, e.g. the system apps running as uid 1000,
+// are all listed under it. Devices with several users print one uid per
+// user, separated by commas.
+type Packages struct {
+	Uids map[int][]string `json:"uids"`
+}
+
+func NewPackages() IParser {
+	return &Packages{}
+}
+
+func (p *Packages) Parse(rawData string) error {
+	p.Uids = make(map[int][]string)
+	for _, line := range strings.Split(rawData, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		name, ok := strings.CutPrefix(fields[0], "package:")
+		if !ok {
+			continue
+		}
+		rawUids, ok := strings.CutPrefix(fields[1], "uid:")
+		if !ok {
+			continue
+		}
+		for _, rawUid := range strings.Split(rawUids, ",") {
+			if uid, err := strconv.Atoi(rawUid); err == nil {
+				p.Uids[uid] = append(p.Uids[uid], name)
+			}
+		}
+	}
+	for _, names := range p.Uids {
+		slices.Sort(names)
+	}
+	return nil
+}
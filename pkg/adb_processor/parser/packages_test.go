@@ -0,0 +1,27 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackages(t *testing.T) {
+	t.Parallel()
+	data := `package:com.android.chrome uid:10151
+package:com.android.settings uid:1000
+package:android uid:1000
+package:com.whatsapp uid:10203,1010203
+invalid line
+`
+	packages := &parser.Packages{}
+	require.NoError(t, packages.Parse(data))
+	assert.Equal(t, map[int][]string{
+		10151:   {"com.android.chrome"},
+		1000:    {"android", "com.android.settings"},
+		10203:   {"com.whatsapp"},
+		1010203: {"com.whatsapp"},
+	}, packages.Uids)
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Process struct {
+	PID  int    `json:"pid"`
+	PPID int    `json:"ppid,omitempty"`
+	User string `json:"user"`
+	// CPU and Memory are percentages, legacy ps prints neither. ps prints
+	// CPU as the average over the lifetime of the process, the process
+	// service replaces it by the usage over the last seconds.
+	CPU    float64 `json:"cpu"`
+	Memory float64 `json:"memory"`
+	// RSS is the resident memory in KB, `ps -eo` does not print it.
+	RSS  int    `json:"rss,omitempty"`
+	Name string `json:"name"`
+	Time string `json:"time,omitempty"`
+}
+
+// ProcessList parses the output of `ps -eo ...` of toybox and of the legacy
+// `ps` of toolbox. Columns are found by the header, the process name may
+// contain spaces.
+type ProcessList struct {
+	Processes []Process `json:"processes"`
+}
+
+// processNameColumns are the headers of the process name column.
+var processNameColumns = []string{"CMD", "NAME", "ARGS", "COMMAND"}
+
+func NewProcessList() IParser {
+	return &ProcessList{}
+}
+
+func (p *ProcessList) Parse(rawData string) error {
+	lines := strings.Split(strings.TrimSpace(rawData), "\n")
+	header := strings.Fields(lines[0])
+	if !slices.Contains(header, "PID") {
+		return fmt.Errorf("unexpected ps output: %q", lines[0])
+	}
+	nameIndex := slices.IndexFunc(header, func(column string) bool {
+		return slices.Contains(processNameColumns, column)
+	})
+	if nameIndex == -1 {
+		return fmt.Errorf("ps output has no process name column: %q", lines[0])
+	}
+
+	p.Processes = make([]Process, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < len(header) {
+			continue
+		}
+		process := Process{Name: processName(header, fields, nameIndex)}
+		for i, column := range header {
+			if i == nameIndex {
+				continue
+			}
+			// Columns after the name are counted from the end of the line.
+			value := fields[i]
+			if i > nameIndex {
+				value = fields[len(fields)-len(header)+i]
+			}
+			setProcessColumn(&process, column, value)
+		}
+		if process.PID == 0 {
+			continue
+		}
+		p.Processes = append(p.Processes, process)
+	}
+	return nil
+}
+
+// processName returns the name column of a line. Toolbox prints the state
+// of a process without a header right before the name, the last column.
+func processName(header, fields []string, nameIndex int) string {
+	if nameIndex == len(header)-1 && len(fields) > len(header) && len(fields[len(fields)-2]) == 1 {
+		return fields[len(fields)-1]
+	}
+	return strings.Join(fields[nameIndex:len(fields)-(len(header)-1-nameIndex)], " ")
+}
+
+func setProcessColumn(process *Process, column, value string) {
+	switch column {
+	case "PID":
+		process.PID, _ = strconv.Atoi(value)
+	case "PPID":
+		process.PPID, _ = strconv.Atoi(value)
+	case "USER":
+		process.User = value
+	case "%CPU":
+		process.CPU, _ = strconv.ParseFloat(value, 64)
+	case "%MEM":
+		process.Memory, _ = strconv.ParseFloat(value, 64)
+	case "RSS":
+		process.RSS, _ = strconv.Atoi(value)
+	case "TIME", "TIME+":
+		process.Time = value
+	}
+}
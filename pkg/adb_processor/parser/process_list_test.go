@@ -0,0 +1,74 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcessList(t *testing.T) {
+	t.Parallel()
+	data := `  PID USER          %CPU %MEM CMD                          TIME+
+    1 root           0.0  0.1 init                       0:03.44
+  912 radio          1.2  1.9 com.android.phone          5:12.07
+ 4321 u0_a217       38.5 21.4 Binder:4321_2              1:02:41.90
+`
+	processList := &parser.ProcessList{}
+	require.NoError(t, processList.Parse(data))
+	assert.Equal(t, []parser.Process{
+		{PID: 1, User: "root", CPU: 0.0, Memory: 0.1, Name: "init", Time: "0:03.44"},
+		{PID: 912, User: "radio", CPU: 1.2, Memory: 1.9, Name: "com.android.phone", Time: "5:12.07"},
+		{PID: 4321, User: "u0_a217", CPU: 38.5, Memory: 21.4, Name: "Binder:4321_2", Time: "1:02:41.90"},
+	}, processList.Processes)
+}
+
+func TestParseProcessListNameWithSpaces(t *testing.T) {
+	t.Parallel()
+	data := `  PID USER          %CPU %MEM CMD                          TIME+
+ 2190 u0_a88         3.0  2.2 Chrome_ChildIOT thread     0:12.00
+`
+	processList := &parser.ProcessList{}
+	require.NoError(t, processList.Parse(data))
+	require.Len(t, processList.Processes, 1)
+	assert.Equal(t, "Chrome_ChildIOT thread", processList.Processes[0].Name)
+	assert.Equal(t, "0:12.00", processList.Processes[0].Time)
+}
+
+func TestParseProcessListLegacy(t *testing.T) {
+	t.Parallel()
+	// Toolbox prints the state without a header.
+	data := `USER      PID   PPID  VSIZE  RSS   WCHAN              PC  NAME
+root      1     0     8928   772   SyS_epoll_ 0000000000 S /init
+radio     1021  511   2209560 84012 SyS_epoll_ 0000000000 S com.android.phone
+u0_a61    5120  511   1811340 356204 SyS_epoll_ 0000000000 R com.example.leaky
+`
+	processList := &parser.ProcessList{}
+	require.NoError(t, processList.Parse(data))
+	assert.Equal(t, []parser.Process{
+		{PID: 1, PPID: 0, User: "root", RSS: 772, Name: "/init"},
+		{PID: 1021, PPID: 511, User: "radio", RSS: 84012, Name: "com.android.phone"},
+		{PID: 5120, PPID: 511, User: "u0_a61", RSS: 356204, Name: "com.example.leaky"},
+	}, processList.Processes)
+}
+
+func TestParseProcessListToybox(t *testing.T) {
+	t.Parallel()
+	data := `USER           PID  PPID     VSZ    RSS WCHAN            ADDR S NAME
+root             1     0 10862332  11520 0                   0 S init
+shell        17342 17337 10770044   3712 0                   0 R ps
+`
+	processList := &parser.ProcessList{}
+	require.NoError(t, processList.Parse(data))
+	assert.Equal(t, []parser.Process{
+		{PID: 1, PPID: 0, User: "root", RSS: 11520, Name: "init"},
+		{PID: 17342, PPID: 17337, User: "shell", RSS: 3712, Name: "ps"},
+	}, processList.Processes)
+}
+
+func TestParseProcessListUnexpected(t *testing.T) {
+	t.Parallel()
+	processList := &parser.ProcessList{}
+	assert.Error(t, processList.Parse("bad pid '-eo'\n"))
+}
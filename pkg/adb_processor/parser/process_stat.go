@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ProcessStat parses the cpu lines of /proc/stat followed by the
+// /proc/<pid>/stat line of every process. A single sample only holds the CPU
+// time since boot and since every process started, the usage of the
+// processes is worked out between two samples by UsageSince.
+type ProcessStat struct {
+	Cpu CpuStat `json:"cpu"`
+	// Processes is the user and system time of every process in jiffies,
+	// by pid.
+	Processes map[int]uint64 `json:"processes"`
+}
+
+func NewProcessStat() IParser {
+	return &ProcessStat{}
+}
+
+func (p *ProcessStat) Parse(rawData string) error {
+	var cpuLines []string
+	p.Processes = make(map[int]uint64)
+	for _, line := range strings.Split(rawData, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "cpu") {
+			cpuLines = append(cpuLines, line)
+			continue
+		}
+		if pid, cpuTime, ok := parseProcessCpuTime(line); ok {
+			p.Processes[pid] = cpuTime
+		}
+	}
+	if err := p.Cpu.Parse(strings.Join(cpuLines, "\n")); err != nil {
+		return fmt.Errorf("unexpected process stat output: %w", err)
+	}
+	return nil
+}
+
+// parseProcessCpuTime reads the pid and the sum of utime and stime, the 14th
+// and 15th field, of a /proc/<pid>/stat line. The process name between the
+// parentheses may hold spaces and parentheses, the fields after it are
+// counted from the last one.
+func parseProcessCpuTime(line string) (int, uint64, bool) {
+	pidField, rest, ok := strings.Cut(line, " (")
+	if !ok {
+		return 0, 0, false
+	}
+	pid, err := strconv.Atoi(pidField)
+	if err != nil {
+		return 0, 0, false
+	}
+	end := strings.LastIndex(rest, ")")
+	if end == -1 {
+		return 0, 0, false
+	}
+	// state is the 3rd field
+	fields := strings.Fields(rest[end+1:])
+	if len(fields) < 13 {
+		return 0, 0, false
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return pid, utime + stime, true
+}
+
+// UsageSince returns the CPU usage of every process between previous and p
+// by pid, in percent of one core like ps and top print it. Processes started
+// since previous are counted from zero. It is false when no time passed
+// between them, or the counters were reset by a reboot.
+func (p *ProcessStat) UsageSince(previous *ProcessStat) (map[int]float64, bool) {
+	if p.Cpu.Total.Total <= previous.Cpu.Total.Total {
+		return nil, false
+	}
+	cores := max(len(p.Cpu.Cores), 1)
+	coreTime := float64(p.Cpu.Total.Total-previous.Cpu.Total.Total) / float64(cores)
+
+	usage := make(map[int]float64, len(p.Processes))
+	for pid, cpuTime := range p.Processes {
+		previousTime := previous.Processes[pid]
+		// The pid was taken by a new process.
+		if cpuTime < previousTime {
+			previousTime = 0
+		}
+		usage[pid] = math.Round(float64(cpuTime-previousTime)*1000/coreTime) / 10
+	}
+	return usage, true
+}
@@ -0,0 +1,44 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessStatUsageSince(t *testing.T) {
+	t.Parallel()
+	previous := &parser.ProcessStat{}
+	require.NoError(t, previous.Parse(`cpu  100 0 100 800 0 0 0 0
+cpu0 50 0 50 400 0 0 0 0
+cpu1 50 0 50 400 0 0 0 0
+intr 412345678 0 0 0
+1 (init) S 0 1 1 0 -1 4194560 100 0 0 0 10 5 0 0 20 0 1 0 100 0 0
+42 (Binder:42 (x)) S 1 42 42 0 -1 4194560 100 0 0 0 100 50 0 0 20 0 1 0 100 0 0
+`))
+	assert.Equal(t, map[int]uint64{1: 15, 42: 150}, previous.Processes)
+
+	current := &parser.ProcessStat{}
+	require.NoError(t, current.Parse(`cpu  300 0 100 1000 0 0 0 0
+cpu0 150 0 50 500 0 0 0 0
+cpu1 150 0 50 500 0 0 0 0
+1 (init) S 0 1 1 0 -1 4194560 100 0 0 0 10 5 0 0 20 0 1 0 100 0 0
+42 (Binder:42 (x)) S 1 42 42 0 -1 4194560 100 0 0 0 250 100 0 0 20 0 1 0 100 0 0
+77 (sh) R 1 77 77 0 -1 4194560 100 0 0 0 15 5 0 0 20 0 1 0 100 0 0
+`))
+	// 200 jiffies passed on each core, a new process counts from zero.
+	usage, ok := current.UsageSince(previous)
+	require.True(t, ok)
+	assert.Equal(t, map[int]float64{1: 0, 42: 100, 77: 10}, usage)
+
+	_, ok = current.UsageSince(current)
+	assert.False(t, ok, "no time passed")
+}
+
+func TestParseProcessStatUnexpected(t *testing.T) {
+	t.Parallel()
+	stat := &parser.ProcessStat{}
+	assert.Error(t, stat.Parse("cat: /proc/stat: Permission denied\n"))
+}
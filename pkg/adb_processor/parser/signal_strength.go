@@ -5,9 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
+// UnavailableSignalValue is reported by Android for a value the modem does not
+// provide (Integer.MAX_VALUE).
+const UnavailableSignalValue = 2147483647
+
 type SignalStrength struct {
 	*CellSignalStrengthCdma    `json:"Cdma,omitempty"`
 	*CellSignalStrengthGsm     `json:"Gsm,omitempty"`
@@ -26,6 +31,34 @@ func (s *SignalStrength) IsEmpty() bool {
 		s.CellSignalStrengthNr == nil
 }
 
+// Generation returns the mobile network generation of the primary signal:
+// 2 for GSM, 3 for CDMA, WCDMA and TD-SCDMA, 4 for LTE, 5 for NR and 0 when
+// unknown.
+func (s *SignalStrength) Generation() uint8 {
+	switch {
+	case s.CellSignalStrengthNr != nil:
+		return 5
+	case s.CellSignalStrengthLte != nil:
+		return 4
+	case s.CellSignalStrengthWcdma != nil, s.CellSignalStrengthTdscdma != nil, s.CellSignalStrengthCdma != nil:
+		return 3
+	case s.CellSignalStrengthGsm != nil:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// SignalValue converts a raw signal value such as an RSRP. ok is false when
+// the value is missing or unavailable.
+func SignalValue(raw string) (value int, ok bool) {
+	value, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || value == UnavailableSignalValue || value == -UnavailableSignalValue {
+		return 0, false
+	}
+	return value, true
+}
+
 type CellSignalStrengthCdma struct {
 	Level    int    `json:"level,omitempty"`
 	CdmaDbm  string `json:"cdmaDbm,omitempty"`
@@ -49,3 +49,24 @@ func TestParseSignalStrengthNoSim(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, signalStrength)
 }
+
+func TestSignalStrengthGeneration(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, uint8(4), (&parser.SignalStrength{CellSignalStrengthLte: &parser.CellSignalStrengthLte{}}).Generation())
+	assert.Equal(t, uint8(5), (&parser.SignalStrength{CellSignalStrengthNr: &parser.CellSignalStrengthNr{}}).Generation())
+	assert.Equal(t, uint8(3), (&parser.SignalStrength{CellSignalStrengthWcdma: &parser.CellSignalStrengthWcdma{}}).Generation())
+	assert.Equal(t, uint8(2), (&parser.SignalStrength{CellSignalStrengthGsm: &parser.CellSignalStrengthGsm{}}).Generation())
+	assert.Equal(t, uint8(0), (&parser.SignalStrength{}).Generation())
+}
+
+func TestSignalValue(t *testing.T) {
+	t.Parallel()
+	value, ok := parser.SignalValue("-95")
+	assert.True(t, ok)
+	assert.Equal(t, -95, value)
+
+	for _, raw := range []string{"", "2147483647", "-2147483647", "n/a"} {
+		_, ok := parser.SignalValue(raw)
+		assert.False(t, ok, raw)
+	}
+}
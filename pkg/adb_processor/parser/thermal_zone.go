@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ThermalZone struct {
+	Zone int    `json:"zone"`
+	Type string `json:"type"`
+	// Temperature in °C.
+	Temperature float64 `json:"temperature"`
+}
+
+// ThermalZones parses the type and temp files of the thermal zones printed
+// by `grep -H .` in /sys/class/thermal, one "<path>:<value>" per line. Zones
+// whose temperature cannot be read are left out.
+type ThermalZones struct {
+	Zones []ThermalZone `json:"zones"`
+}
+
+// cpuZoneTypes are parts of the zone types of CPU sensors, cpuClusterZones
+// the zone types of Exynos, which names its CPU zones after the clusters.
+var (
+	cpuZoneTypes    = []string{"cpu", "tsens", "soc"}
+	cpuClusterZones = []string{"big", "mid", "little"}
+)
+
+func NewThermalZones() IParser {
+	return &ThermalZones{}
+}
+
+func (t *ThermalZones) Parse(rawData string) error {
+	types := make(map[int]string)
+	temperatures := make(map[int]float64)
+	for _, line := range strings.Split(rawData, "\n") {
+		file, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		// thermal_zone0/temp
+		zone, err := strconv.Atoi(strings.TrimPrefix(path.Base(path.Dir(file)), "thermal_zone"))
+		if err != nil {
+			continue
+		}
+		switch path.Base(file) {
+		case "type":
+			types[zone] = value
+		case "temp":
+			if temperature, err := strconv.ParseFloat(value, 64); err == nil {
+				temperatures[zone] = thermalTemperature(temperature)
+			}
+		}
+	}
+
+	t.Zones = make([]ThermalZone, 0, len(temperatures))
+	for zone, temperature := range temperatures {
+		t.Zones = append(t.Zones, ThermalZone{Zone: zone, Type: types[zone], Temperature: temperature})
+	}
+	slices.SortFunc(t.Zones, func(a, b ThermalZone) int { return a.Zone - b.Zone })
+	return nil
+}
+
+// thermalTemperature converts a reading to °C. Most kernels report
+// millidegrees, some drivers whole degrees.
+func thermalTemperature(value float64) float64 {
+	if math.Abs(value) >= 1000 {
+		return value / 1000
+	}
+	return value
+}
+
+// CpuTemperature returns the temperature of the hottest CPU zone.
+func (t *ThermalZones) CpuTemperature() (float64, bool) {
+	temperature, found := 0.0, false
+	for _, zone := range t.Zones {
+		zoneType := strings.ToLower(zone.Type)
+		isCpu := slices.Contains(cpuClusterZones, zoneType) ||
+			slices.ContainsFunc(cpuZoneTypes, func(cpuType string) bool { return strings.Contains(zoneType, cpuType) })
+		if !isCpu {
+			continue
+		}
+		if !found || zone.Temperature > temperature {
+			temperature, found = zone.Temperature, true
+		}
+	}
+	return temperature, found
+}
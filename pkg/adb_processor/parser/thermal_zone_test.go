@@ -0,0 +1,56 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThermalZones(t *testing.T) {
+	t.Parallel()
+	// The temp of zone 2 cannot be read by the shell.
+	data := `thermal_zone0/type:cpu-0-0-usr
+thermal_zone1/type:cpu-1-0-usr
+thermal_zone2/type:sdm-therm
+thermal_zone10/type:battery
+thermal_zone0/temp:45300
+thermal_zone1/temp:48600
+thermal_zone10/temp:34700
+`
+	thermalZones := &parser.ThermalZones{}
+	require.NoError(t, thermalZones.Parse(data))
+	assert.Equal(t, []parser.ThermalZone{
+		{Zone: 0, Type: "cpu-0-0-usr", Temperature: 45.3},
+		{Zone: 1, Type: "cpu-1-0-usr", Temperature: 48.6},
+		{Zone: 10, Type: "battery", Temperature: 34.7},
+	}, thermalZones.Zones)
+
+	temperature, ok := thermalZones.CpuTemperature()
+	require.True(t, ok)
+	assert.Equal(t, 48.6, temperature)
+}
+
+func TestParseThermalZonesDegrees(t *testing.T) {
+	t.Parallel()
+	// Older tsens drivers report whole degrees.
+	data := `thermal_zone0/type:tsens_tz_sensor0
+thermal_zone0/temp:51
+thermal_zone1/type:battery
+thermal_zone1/temp:37100
+`
+	thermalZones := &parser.ThermalZones{}
+	require.NoError(t, thermalZones.Parse(data))
+	assert.Equal(t, []parser.ThermalZone{
+		{Zone: 0, Type: "tsens_tz_sensor0", Temperature: 51},
+		{Zone: 1, Type: "battery", Temperature: 37.1},
+	}, thermalZones.Zones)
+}
+
+func TestThermalZonesCpuTemperatureUnknown(t *testing.T) {
+	t.Parallel()
+	thermalZones := &parser.ThermalZones{Zones: []parser.ThermalZone{{Zone: 0, Type: "battery", Temperature: 34.7}}}
+	_, ok := thermalZones.CpuTemperature()
+	assert.False(t, ok)
+}
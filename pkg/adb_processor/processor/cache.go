@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adb "github.com/basiooo/goadb"
+	"go.uber.org/zap"
+)
+
+type noCacheContextKey struct{}
+
+// WithoutCache returns a context running the commands of the processor on
+// the device even when their output is cached. The fresh output is cached.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheContextKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypassed, _ := ctx.Value(noCacheContextKey{}).(bool)
+	return bypassed
+}
+
+// cachePrefix is the prefix of every key cached for device.
+func cachePrefix(device string) string {
+	return device + "/"
+}
+
+func cacheKey(device *adb.Device, root bool, adbCommand command.AdbCommand) string {
+	return fmt.Sprintf("%s%t/%s", cachePrefix(device.String()), root, adbCommand)
+}
+
+// cachedOutput returns the cached output of adbCommand on device, as long as
+// its cache policy holds.
+func (p *Processor) cachedOutput(ctx context.Context, device *adb.Device, root bool, adbCommand command.AdbCommand) (string, bool) {
+	if _, ok := command.CacheTTL(adbCommand); !ok || cacheBypassed(ctx) {
+		return "", false
+	}
+	output, ok := p.Cache.Get(cacheKey(device, root, adbCommand))
+	if !ok {
+		return "", false
+	}
+	p.Logger.Debug("using cached output", zap.String("command", string(adbCommand)))
+	return output.(string), true
+}
+
+// cacheOutput caches the output of adbCommand on device for as long as its
+// cache policy allows.
+func (p *Processor) cacheOutput(device *adb.Device, root bool, adbCommand command.AdbCommand, output string) {
+	if ttl, ok := command.CacheTTL(adbCommand); ok {
+		p.Cache.Set(cacheKey(device, root, adbCommand), output, ttl)
+	}
+}
+
+// invalidateReads drops the cached outputs on device adbCommand changed.
+func (p *Processor) invalidateReads(device *adb.Device, adbCommand command.AdbCommand) {
+	for _, read := range command.Invalidates(adbCommand) {
+		p.Cache.Delete(cacheKey(device, false, read))
+		p.Cache.Delete(cacheKey(device, true, read))
+	}
+}
+
+// Invalidate drops every cached output of the device with serial, e.g. once
+// it disconnected or rebooted.
+func (p *Processor) Invalidate(serial string) {
+	p.Cache.DeletePrefix(cachePrefix(adb.DeviceWithSerial(serial).String()))
+}
@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorCache(t *testing.T) {
+	t.Parallel()
+	p, blocking, device := newBlockingProcessor(t)
+	close(blocking.release)
+	ctx := context.Background()
+	run := func(ctx context.Context, adbCommand command.AdbCommand) {
+		t.Helper()
+		output, err := RunRaw(ctx, p, device, adbCommand, false)
+		require.NoError(t, err)
+		assert.Equal(t, string(adbCommand), output)
+	}
+
+	run(ctx, command.GetDeviceModelCommand)
+	run(ctx, command.GetDeviceModelCommand)
+	assert.Equal(t, int32(1), blocking.runs.Load(), "the model is cached")
+
+	run(WithoutCache(ctx), command.GetDeviceModelCommand)
+	assert.Equal(t, int32(2), blocking.runs.Load(), "the cache is bypassed")
+
+	run(ctx, command.GetInboxAccessCommand)
+	run(ctx, command.GetInboxAccessCommand)
+	assert.Equal(t, int32(4), blocking.runs.Load(), "SMS access is never cached")
+
+	run(ctx, command.GetMobileDataStatusCommand)
+	run(ctx, command.EnableMobileDataCommand)
+	run(ctx, command.GetMobileDataStatusCommand)
+	assert.Equal(t, int32(7), blocking.runs.Load(), "toggling mobile data invalidates its status")
+
+	p.Invalidate("R58R31ABCDE")
+	run(ctx, command.GetDeviceModelCommand)
+	assert.Equal(t, int32(8), blocking.runs.Load(), "the device is invalidated")
+}
+
+func TestProcessorBatchCache(t *testing.T) {
+	t.Parallel()
+	p, blocking, device := newBlockingProcessor(t)
+	close(blocking.release)
+	ctx := context.Background()
+
+	_, err := RunRaw(ctx, p, device, command.GetDeviceModelCommand, false)
+	require.NoError(t, err)
+
+	results, err := p.RunBatch(ctx, device, command.GetDeviceProductCommand, command.GetDeviceModelCommand)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, int32(2), blocking.runs.Load(), "only the product is read")
+	for _, result := range results {
+		output, err := Result[*parser.RawParser](result)
+		require.NoError(t, err)
+		assert.Equal(t, string(result.Command), output.Result)
+	}
+
+	_, err = p.RunBatch(ctx, device, command.GetDeviceProductCommand, command.GetDeviceModelCommand)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), blocking.runs.Load(), "both are cached")
+}
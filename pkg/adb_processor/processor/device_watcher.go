@@ -0,0 +1,87 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/basiooo/andromodem/internal/lifecycle"
+	adb "github.com/basiooo/goadb"
+	"go.uber.org/zap"
+)
+
+// watchRetryDelay is how long DeviceWatcher waits before watching again once
+// the ADB server stopped reporting device events.
+const watchRetryDelay = 5 * time.Second
+
+// DeviceWatcher drops the cached outputs of every device changing state in
+// the background, whether anyone listens to device events or not. Nothing
+// read before a device disconnected, rebooted or was reflashed is served
+// after it.
+type DeviceWatcher struct {
+	adb          *adb.Adb
+	adbProcessor IProcessor
+	logger       *zap.Logger
+	ctx          context.Context
+	wg           sync.WaitGroup
+}
+
+// WatchDevices starts a DeviceWatcher invalidating the outputs cached by
+// adbProcessor until ctx is done. Without an ADB client nothing is watched.
+func WatchDevices(ctx context.Context, adbClient *adb.Adb, adbProcessor IProcessor, logger *zap.Logger) *DeviceWatcher {
+	w := &DeviceWatcher{
+		adb:          adbClient,
+		adbProcessor: adbProcessor,
+		logger:       logger,
+		ctx:          ctx,
+	}
+	if adbClient == nil {
+		return w
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+func (w *DeviceWatcher) run() {
+	defer w.wg.Done()
+	for {
+		watcher := w.adb.NewDeviceWatcher()
+		w.watch(watcher.C())
+		watcher.Shutdown()
+		if w.ctx.Err() != nil {
+			return
+		}
+		w.logger.Warn("[Cache] Device watcher stopped, watching again", zap.Duration("delay", watchRetryDelay), zap.Error(watcher.Err()))
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-time.After(watchRetryDelay):
+		}
+	}
+}
+
+// watch invalidates the devices of events until the channel is closed or
+// the context of w is done.
+func (w *DeviceWatcher) watch(events <-chan adb.DeviceStateChangedEvent) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			w.logger.Debug("[Cache] Device changed state, dropping its cached outputs",
+				zap.String("serial", event.Serial),
+				zap.String("new_state", event.NewState.String()))
+			w.adbProcessor.Invalidate(event.Serial)
+		}
+	}
+}
+
+// Shutdown waits for the watcher to stop, once the context it was started
+// with is done.
+func (w *DeviceWatcher) Shutdown(ctx context.Context) error {
+	return lifecycle.WaitGroup(ctx, &w.wg)
+}
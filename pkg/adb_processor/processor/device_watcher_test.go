@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	"github.com/basiooo/andromodem/pkg/simulator"
+	adb "github.com/basiooo/goadb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestDeviceWatcherInvalidates(t *testing.T) {
+	t.Parallel()
+	fixtures, err := fixture.Profiles()
+	require.NoError(t, err)
+	sim := simulator.New(fixtures...)
+	adbClient := &adb.Adb{Server: sim}
+	logger := zaptest.NewLogger(t)
+	p := NewProcessor(logger).(*Processor)
+	key := cacheKey(adbClient.Device(adb.DeviceWithSerial("8d1c2a3f")), false, command.GetDeviceModelCommand)
+	cached := func() bool {
+		_, ok := p.Cache.Get(key)
+		return ok
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Cache.Set(key, "Redmi Note 7", time.Hour)
+	watcher := WatchDevices(ctx, adbClient, p, logger)
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, watcher.Shutdown(context.Background()))
+	})
+	// The device is reported online once the watcher started.
+	require.Eventually(t, func() bool { return !cached() }, 5*time.Second, 10*time.Millisecond)
+
+	// No one listens to device events, a reboot still drops the model.
+	p.Cache.Set(key, "Redmi Note 7", time.Hour)
+	require.NoError(t, sim.SetState("8d1c2a3f", adb.StateDisconnected))
+	assert.Eventually(t, func() bool { return !cached() }, 5*time.Second, 10*time.Millisecond)
+}
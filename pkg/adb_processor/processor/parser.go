@@ -1,11 +1,20 @@
 package processor
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
 	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
 )
 
-var commandParser = map[command.AdbCommand]any{
+// ParserFactory creates a parser for the output of a command.
+type ParserFactory func() parser.IParser
+
+// commandParser is the parser registry. Every command of command.All needs an
+// entry, ValidateRegistry checks it at startup and in tests.
+var commandParser = map[command.AdbCommand]ParserFactory{
 	command.GetDevicePropCommand:               parser.NewDeviceProp,
 	command.GetAndroidVersionCommand:           parser.NewRawParser,
 	command.GetDeviceModelCommand:              parser.NewRawParser,
@@ -44,4 +53,38 @@ var commandParser = map[command.AdbCommand]any{
 	command.GetSimNetworkTypeCommand: parser.NewRawParser,
 	command.GetDeviceMemoryCommand:   parser.NewMemory,
 	command.GetDeviceStorageCommand:  parser.NewStorage,
+
+	command.GetDeviceProcessNewCommand:    parser.NewProcessList,
+	command.GetDeviceProcessLegacyCommand: parser.NewProcessList,
+	command.GetBusyboxCheckCommand:        parser.NewBusyboxCheck,
+	command.GetPingCheckCommand:           parser.NewRawParser,
+
+	command.GetBuildFingerprintCommand: parser.NewRawParser,
+	command.GetSvcHelpCommand:          parser.NewRawParser,
+	command.GetInboxAccessCommand:      parser.NewRawParser,
+
+	command.GetCpuStatCommand:      parser.NewCpuStat,
+	command.GetProcessStatCommand:  parser.NewProcessStat,
+	command.GetCpuFrequencyCommand: parser.NewCpuFrequency,
+	command.GetThermalZonesCommand: parser.NewThermalZones,
+	command.GetNetDevCommand:       parser.NewNetDev,
+	command.GetNetstatsCommand:     parser.NewNetstats,
+	command.GetPackagesUidCommand:  parser.NewPackages,
+}
+
+// ValidateRegistry reports every command of command.All without a usable
+// parser, so a missing registration is noticed before a request needs it.
+func ValidateRegistry() error {
+	var errs []error
+	for _, adbCommand := range command.All() {
+		newParser, ok := commandParser[adbCommand]
+		if !ok || newParser == nil {
+			errs = append(errs, fmt.Errorf("%w: '%s'", adbErrors.ErrorParserNotRegistered, adbCommand))
+			continue
+		}
+		if newParser() == nil {
+			errs = append(errs, fmt.Errorf("parser factory of '%s' returned nil", adbCommand))
+		}
+	}
+	return errors.Join(errs...)
 }
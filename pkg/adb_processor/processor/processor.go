@@ -3,59 +3,130 @@
 package processor
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/basiooo/andromodem/pkg/adb_processor/command"
 	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
 	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
 	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/basiooo/andromodem/pkg/cache"
 	adb "github.com/basiooo/goadb"
 	"go.uber.org/zap"
 )
 
 type Processor struct {
-	Logger *zap.Logger
+	Logger      *zap.Logger
+	NewExecutor executor.Factory
+	// Cache keeps the outputs of commands for as long as their cache policy
+	// allows.
+	Cache     cache.ICache
+	scheduler *scheduler
+
+	suSyntaxMu sync.RWMutex
+	// suSyntaxes are the su syntaxes of devices set with SetSuSyntax.
+	suSyntaxes map[string]command.SuSyntax
 }
 
 func NewProcessor(logger *zap.Logger) IProcessor {
+	return NewProcessorWithExecutor(logger, executor.NewExecutor)
+}
+
+// NewProcessorWithCache returns a processor caching the outputs of commands
+// in adbCache.
+func NewProcessorWithCache(logger *zap.Logger, adbCache cache.ICache) IProcessor {
+	p := NewProcessorWithExecutor(logger, executor.NewExecutor).(*Processor)
+	p.Cache = adbCache
+	return p
+}
+
+// NewProcessorWithExecutor returns a processor running commands on the
+// executors created by newExecutor, e.g. ones replaying recorded outputs.
+func NewProcessorWithExecutor(logger *zap.Logger, newExecutor executor.Factory) IProcessor {
 	return &Processor{
-		Logger: logger,
+		Logger:      logger,
+		NewExecutor: newExecutor,
+		Cache:       cache.NewCache(5*time.Minute, 10*time.Minute),
+		scheduler:   newScheduler(DefaultMaxShells),
+		suSyntaxes:  make(map[string]command.SuSyntax),
+	}
+}
+
+// SetSuSyntax sets how commands run as root on the device with serial are
+// passed to its su, e.g. once its su was probed. Devices without one use
+// command.SuSyntaxStandard.
+func (p *Processor) SetSuSyntax(serial string, syntax command.SuSyntax) {
+	p.suSyntaxMu.Lock()
+	defer p.suSyntaxMu.Unlock()
+	p.suSyntaxes[adb.DeviceWithSerial(serial).String()] = syntax
+}
+
+func (p *Processor) suSyntax(device *adb.Device) command.SuSyntax {
+	p.suSyntaxMu.RLock()
+	defer p.suSyntaxMu.RUnlock()
+	if syntax, ok := p.suSyntaxes[device.String()]; ok && syntax != "" {
+		return syntax
+	}
+	return command.SuSyntaxStandard
+}
+
+// Executor returns the executor running commands on device through the
+// scheduler of the processor, at most DefaultMaxShells at once. Commands not
+// run by the processor itself, e.g. built at runtime, should use it too.
+// Their priority is set on the context with WithPriority. Root commands use
+// the su syntax set for the device with SetSuSyntax.
+func (p *Processor) Executor(device *adb.Device) executor.IExecutor {
+	exec := &scheduledExecutor{
+		IExecutor: p.NewExecutor(device),
+		scheduler: p.scheduler,
+		device:    device.String(),
 	}
+	exec.SetSuSyntax(p.suSyntax(device))
+	return exec
 }
 
 // GetParser returns the appropriate parser for the given ADB command.
 // Logs parser function name for debug.
 func (p *Processor) GetParser(adbCommand command.AdbCommand) (parser.IParser, error) {
-	fAny, ok := commandParser[adbCommand]
-	if !ok {
-		err := fmt.Errorf("no parser found for command: '%s'", adbCommand)
-		p.Logger.Error(err.Error())
-		return nil, err
-	}
-
-	f, ok := fAny.(func() parser.IParser)
+	newParser, ok := commandParser[adbCommand]
 	if !ok {
-		err := fmt.Errorf("parser for command '%s' is not a function", adbCommand)
+		err := fmt.Errorf("%w: '%s'", adbErrors.ErrorParserNotRegistered, adbCommand)
 		p.Logger.Error(err.Error())
 		return nil, err
 	}
 
-	parserInstance := f()
+	parserInstance := newParser()
 	p.Logger.Debug("parsing command using parser", zap.String("parser", fmt.Sprintf("%T", parserInstance)), zap.String("command", string(adbCommand)))
 	return parserInstance, nil
 }
 
-// isAvailable inspects the command output for permission-related errors.
+// shellNotFound matches the shell reporting a missing command, e.g.
+// "/system/bin/sh: su: not found" or "/system/bin/sh: ip: inaccessible or
+// not found" on newer Android versions.
+var shellNotFound = regexp.MustCompile(`(?m)^(?:/system/bin/)?sh: (?:\d+: )?([^:\s]+): (?:inaccessible or )?not found`)
+
+// isAvailable inspects the command output for permission-related errors and
+// commands missing on the device.
 func (p *Processor) isAvailable(result string) error {
+	if match := shellNotFound.FindStringSubmatch(result); match != nil {
+		if match[1] == "su" {
+			return adbErrors.ErrorNeedRoot
+		}
+		return fmt.Errorf("%w: '%s'", adbErrors.ErrorCommandNotFound, match[1])
+	}
 	lower := strings.ToLower(result)
 	switch {
 	case strings.Contains(lower, "permission denial:"),
 		strings.Contains(lower, "permission denied"):
 		return adbErrors.ErrorNeedShellSuperUserPermission
-	case strings.Contains(lower, "error while accessing provider"),
-		strings.Contains(lower, "su: invalid uid/gid"):
+	case strings.Contains(lower, "su: invalid uid/gid"):
+		return adbErrors.ErrorSuTakesUid
+	case strings.Contains(lower, "error while accessing provider"):
 		return adbErrors.ErrorNeedRoot
 	default:
 		return nil
@@ -63,8 +134,8 @@ func (p *Processor) isAvailable(result string) error {
 }
 
 // runWithPermissionCheck runs a command and checks for permission errors in output.
-func (p *Processor) runWithPermissionCheck(exec executor.IExecutor, adbCommand command.AdbCommand) (string, error) {
-	result, err := exec.Run(adbCommand)
+func (p *Processor) runWithPermissionCheck(ctx context.Context, exec executor.IExecutor, adbCommand command.AdbCommand) (string, error) {
+	result, err := exec.Run(ctx, adbCommand)
 	if err != nil {
 		return "", err
 	}
@@ -78,12 +149,12 @@ func (p *Processor) runWithPermissionCheck(exec executor.IExecutor, adbCommand c
 
 // runCommand is a helper method to execute ADB commands and process the results.
 // This method extracts common logic from Run and RunWithRoot.
-func (p *Processor) runCommand(device *adb.Device, adbCommand command.AdbCommand, useRoot bool) (parser.IParser, error) {
+func (p *Processor) runCommand(ctx context.Context, device *adb.Device, adbCommand command.AdbCommand, useRoot bool) (parser.IParser, error) {
 	if device == nil {
 		return nil, adbErrors.ErrorDeviceIsNil
 	}
 
-	exec := executor.NewExecutor(device)
+	exec := p.Executor(device)
 
 	parserInstance, err := p.GetParser(adbCommand)
 	if err != nil {
@@ -94,10 +165,15 @@ func (p *Processor) runCommand(device *adb.Device, adbCommand command.AdbCommand
 		exec.EnableRoot()
 	}
 
-	result, err := p.runWithPermissionCheck(exec, adbCommand)
-	if err != nil {
-		p.Logger.Error("failed to execute command", zap.Error(err), zap.Bool("with_root", useRoot))
-		return nil, err
+	result, ok := p.cachedOutput(ctx, device, useRoot, adbCommand)
+	if !ok {
+		result, err = p.runWithPermissionCheck(ctx, exec, adbCommand)
+		p.invalidateReads(device, adbCommand)
+		if err != nil {
+			p.Logger.Error("failed to execute command", zap.Error(err), zap.Bool("with_root", useRoot))
+			return nil, err
+		}
+		p.cacheOutput(device, useRoot, adbCommand, result)
 	}
 
 	if err := parserInstance.Parse(result); err != nil {
@@ -109,18 +185,107 @@ func (p *Processor) runCommand(device *adb.Device, adbCommand command.AdbCommand
 }
 
 // Run executes an ADB command on the given device, optionally using root if permission denied.
+// The command is abandoned once ctx is done or its timeout passed.
 // Returns the parsed result or an error.
-func (p *Processor) Run(device *adb.Device, adbCommand command.AdbCommand, userRootIfDenied bool) (parser.IParser, error) {
-	result, err := p.runCommand(device, adbCommand, false)
+func (p *Processor) Run(ctx context.Context, device *adb.Device, adbCommand command.AdbCommand, userRootIfDenied bool) (parser.IParser, error) {
+	result, err := p.runCommand(ctx, device, adbCommand, false)
 	if err != nil && userRootIfDenied && (errors.Is(err, adbErrors.ErrorNeedShellSuperUserPermission) || errors.Is(err, adbErrors.ErrorNeedRoot)) {
 		// Try again with root if permission denied and userRootIfDenied is true
-		return p.runCommand(device, adbCommand, true)
+		return p.runCommand(ctx, device, adbCommand, true)
 	}
 	return result, err
 }
 
 // RunWithRoot executes an ADB command on the given device with root privileges enabled.
 // Returns the parsed result or an error.
-func (p *Processor) RunWithRoot(device *adb.Device, adbCommand command.AdbCommand) (parser.IParser, error) {
-	return p.runCommand(device, adbCommand, true)
+func (p *Processor) RunWithRoot(ctx context.Context, device *adb.Device, adbCommand command.AdbCommand) (parser.IParser, error) {
+	return p.runCommand(ctx, device, adbCommand, true)
+}
+
+// BatchResult is the parsed result of one command run by RunBatch.
+type BatchResult struct {
+	Command  command.AdbCommand
+	Result   parser.IParser
+	ExitCode int
+	Err      error
+}
+
+// RunBatch executes adbCommands in a single shell session on the given
+// device and parses the output of each with its registered parser. Results
+// are returned in the order of adbCommands, a command that failed only sets
+// the Err of its result. The returned error is set when the session itself
+// failed, e.g. on timeout.
+func (p *Processor) RunBatch(ctx context.Context, device *adb.Device, adbCommands ...command.AdbCommand) ([]BatchResult, error) {
+	if device == nil {
+		return nil, adbErrors.ErrorDeviceIsNil
+	}
+
+	results := make([]BatchResult, len(adbCommands))
+	parsers := make([]parser.IParser, len(adbCommands))
+	for i, adbCommand := range adbCommands {
+		results[i].Command = adbCommand
+		parsers[i], results[i].Err = p.GetParser(adbCommand)
+	}
+
+	outputs, err := p.runBatchCached(ctx, device, adbCommands)
+	if err != nil {
+		p.Logger.Error("failed to execute batch", zap.Error(err), zap.Int("commands", len(adbCommands)))
+		return nil, err
+	}
+
+	for i, output := range outputs {
+		result := &results[i]
+		if result.Err != nil {
+			continue
+		}
+		if !output.Done {
+			result.Err = adbErrors.ErrorBatchIncomplete
+			continue
+		}
+		result.ExitCode = output.ExitCode
+		if result.Err = p.isAvailable(output.Output); result.Err != nil {
+			continue
+		}
+		if result.Err = parsers[i].Parse(output.Output); result.Err != nil {
+			p.Logger.Error("failed to parse result", zap.Error(result.Err), zap.String("command", string(result.Command)))
+			continue
+		}
+		result.Result = parsers[i]
+	}
+	return results, nil
+}
+
+// runBatchCached runs the commands of adbCommands without a cached output in
+// one batch and returns the outputs of all of them. Outputs of commands that
+// finished without error are cached.
+func (p *Processor) runBatchCached(ctx context.Context, device *adb.Device, adbCommands []command.AdbCommand) ([]executor.BatchOutput, error) {
+	outputs := make([]executor.BatchOutput, len(adbCommands))
+	var uncached []command.AdbCommand
+	var uncachedIndexes []int
+	for i, adbCommand := range adbCommands {
+		if output, ok := p.cachedOutput(ctx, device, false, adbCommand); ok {
+			outputs[i] = executor.BatchOutput{Output: output, Done: true}
+			continue
+		}
+		uncached = append(uncached, adbCommand)
+		uncachedIndexes = append(uncachedIndexes, i)
+	}
+	if len(uncached) == 0 {
+		return outputs, nil
+	}
+
+	ran, err := p.Executor(device).RunBatch(ctx, uncached)
+	for _, adbCommand := range uncached {
+		p.invalidateReads(device, adbCommand)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, output := range ran {
+		outputs[uncachedIndexes[i]] = output
+		if output.Done && output.ExitCode == 0 && p.isAvailable(output.Output) == nil {
+			p.cacheOutput(device, false, uncached[i], output.Output)
+		}
+	}
+	return outputs, nil
 }
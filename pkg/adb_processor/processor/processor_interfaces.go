@@ -1,13 +1,20 @@
 package processor
 
 import (
+	"context"
+
 	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
 	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
 	adb "github.com/basiooo/goadb"
 )
 
 type IProcessor interface {
 	GetParser(command.AdbCommand) (parser.IParser, error)
-	Run(*adb.Device, command.AdbCommand, bool) (parser.IParser, error)
-	RunWithRoot(*adb.Device, command.AdbCommand) (parser.IParser, error)
+	Run(context.Context, *adb.Device, command.AdbCommand, bool) (parser.IParser, error)
+	RunWithRoot(context.Context, *adb.Device, command.AdbCommand) (parser.IParser, error)
+	RunBatch(context.Context, *adb.Device, ...command.AdbCommand) ([]BatchResult, error)
+	Executor(*adb.Device) executor.IExecutor
+	Invalidate(serial string)
+	SetSuSyntax(serial string, syntax command.SuSyntax)
 }
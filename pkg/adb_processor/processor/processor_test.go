@@ -1,6 +1,10 @@
 package processor_test
 
 import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/basiooo/andromodem/pkg/adb_processor/command"
@@ -16,7 +20,7 @@ import (
 func TestProcessorWithoutDevice(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 	processor := adbproccesor.NewProcessor(logger)
-	_, err := processor.Run(nil, command.GetDeviceUptimeCommand, false)
+	_, err := processor.Run(context.Background(), nil, command.GetDeviceUptimeCommand, false)
 	assert.Error(t, err)
 }
 
@@ -28,7 +32,7 @@ func TestProcessor(t *testing.T) {
 	device := (&adb.Adb{Server: s}).Device(adb.AnyDevice())
 	logger := zaptest.NewLogger(t)
 	processor := adbproccesor.NewProcessor(logger)
-	result, err := processor.Run(device, command.GetAndroidVersionCommand, false)
+	result, err := processor.Run(context.Background(), device, command.GetAndroidVersionCommand, false)
 	assert.NoError(t, err)
 	androidVersion := result.(*parser.RawParser)
 	assert.Equal(t, "10", androidVersion.Result)
@@ -42,7 +46,7 @@ func TestProcessorUnknown(t *testing.T) {
 	device := (&adb.Adb{Server: s}).Device(adb.AnyDevice())
 	logger := zaptest.NewLogger(t)
 	processor := adbproccesor.NewProcessor(logger)
-	_, err := processor.Run(device, "boooom", false)
+	_, err := processor.Run(context.Background(), device, "boooom", false)
 	assert.Error(t, err)
 }
 
@@ -54,7 +58,7 @@ func TestProcessorNeedSuperUserPermission(t *testing.T) {
 	device := (&adb.Adb{Server: s}).Device(adb.AnyDevice())
 	logger := zaptest.NewLogger(t)
 	processor := adbproccesor.NewProcessor(logger)
-	_, err := processor.Run(device, command.GetApnCommand, false)
+	_, err := processor.Run(context.Background(), device, command.GetApnCommand, false)
 	assert.ErrorIs(t, err, adbError.ErrorNeedShellSuperUserPermission)
 }
 
@@ -77,6 +81,160 @@ func TestProcessorNeedRoot(t *testing.T) {
 	device := (&adb.Adb{Server: s}).Device(adb.AnyDevice())
 	logger := zaptest.NewLogger(t)
 	processor := adbproccesor.NewProcessor(logger)
-	_, err := processor.Run(device, command.GetApnCommand, false)
+	_, err := processor.Run(context.Background(), device, command.GetApnCommand, false)
 	assert.ErrorIs(t, err, adbError.ErrorNeedRoot)
 }
+
+func TestProcessorCommandNotFound(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		command command.AdbCommand
+		err     error
+	}{
+		{"toybox", "/system/bin/sh: iptables: inaccessible or not found", command.GetIpRouterCommand, adbError.ErrorCommandNotFound},
+		{"mksh", "sh: 1: iptables: not found", command.GetIpRouterCommand, adbError.ErrorCommandNotFound},
+		{"su", "/system/bin/sh: su: not found", command.GetRootCommand, adbError.ErrorNeedRoot},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &adb.MockServer{
+				Status:   wire.StatusSuccess,
+				Messages: []string{test.output},
+			}
+			device := (&adb.Adb{Server: s}).Device(adb.AnyDevice())
+			processor := adbproccesor.NewProcessor(zaptest.NewLogger(t))
+			_, err := processor.Run(context.Background(), device, test.command, false)
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
+
+func TestProcessorRunWithRootSuSyntax(t *testing.T) {
+	tests := []struct {
+		name     string
+		syntax   command.SuSyntax
+		expected string
+	}{
+		{"not probed", "", "shell:su -c 'cat /proc/uptime'"},
+		{"magisk", command.SuSyntaxMagisk, "shell:su --mount-master -c 'cat /proc/uptime'"},
+		{"uid", command.SuSyntaxUid, "shell:su 0 sh -c 'cat /proc/uptime'"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &adb.MockServer{
+				Status:   wire.StatusSuccess,
+				Messages: []string{"1.00 2.00"},
+			}
+			device := (&adb.Adb{Server: s}).Device(adb.DeviceWithSerial("8d1c2a3f"))
+			processor := adbproccesor.NewProcessor(zaptest.NewLogger(t))
+			if test.syntax != "" {
+				processor.SetSuSyntax("8d1c2a3f", test.syntax)
+			}
+			_, err := processor.RunWithRoot(context.Background(), device, command.GetDeviceUptimeCommand)
+			assert.NoError(t, err)
+			assert.Contains(t, s.Requests, test.expected)
+		})
+	}
+}
+
+var batchTokenPattern = regexp.MustCompile(`ANDROMODEM_[0-9a-f]+`)
+
+// batchServer answers every batch script with the next outputs, one per
+// command of the script, delimited with the token found in the script.
+type batchServer struct {
+	*adb.MockServer
+	outputs []string
+}
+
+func (b *batchServer) Dial() (*wire.Conn, error) {
+	return wire.NewConn(b, b), nil
+}
+
+func (b *batchServer) ReadUntilEof() ([]byte, error) {
+	script := b.Requests[len(b.Requests)-1]
+	token := batchTokenPattern.FindString(script)
+	var output string
+	for i := 0; i < strings.Count(script, ") 2>&1;") && len(b.outputs) > 0; i++ {
+		output += fmt.Sprintf("%s\n%s %d 0\n", b.outputs[0], token, i)
+		b.outputs = b.outputs[1:]
+	}
+	return []byte(output), nil
+}
+
+func TestProcessorRunBatch(t *testing.T) {
+	s := &batchServer{
+		MockServer: &adb.MockServer{Status: wire.StatusSuccess},
+		outputs:    []string{"11", "permission denied", "10", "boom"},
+	}
+	device := (&adb.Adb{Server: s}).Device(adb.AnyDevice())
+	logger := zaptest.NewLogger(t)
+	processor := adbproccesor.NewProcessor(logger)
+	results, err := processor.RunBatch(context.Background(), device,
+		command.GetAndroidVersionCommand,
+		command.GetApnCommand,
+		command.GetDeviceUptimeCommand,
+		"boooom",
+	)
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+	// The last command does not fit the length limit of the first session.
+	assert.Len(t, s.Requests, 4)
+
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "11", results[0].Result.(*parser.RawParser).Result)
+	assert.ErrorIs(t, results[1].Err, adbError.ErrorNeedShellSuperUserPermission)
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, command.GetDeviceUptimeCommand, results[2].Command)
+	assert.Error(t, results[3].Err)
+}
+
+func TestProcessorRunBatchIncomplete(t *testing.T) {
+	s := &batchServer{
+		MockServer: &adb.MockServer{Status: wire.StatusSuccess},
+		outputs:    []string{"11"},
+	}
+	device := (&adb.Adb{Server: s}).Device(adb.AnyDevice())
+	processor := adbproccesor.NewProcessor(zaptest.NewLogger(t))
+	results, err := processor.RunBatch(context.Background(), device, command.GetAndroidVersionCommand, command.GetDeviceModelCommand)
+	assert.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, adbError.ErrorBatchIncomplete)
+}
+
+func TestValidateRegistry(t *testing.T) {
+	assert.NoError(t, adbproccesor.ValidateRegistry())
+}
+
+func TestProcessorTypedRun(t *testing.T) {
+	s := &adb.MockServer{
+		Status:   wire.StatusSuccess,
+		Messages: []string{"10"},
+	}
+	device := (&adb.Adb{Server: s}).Device(adb.AnyDevice())
+	processor := adbproccesor.NewProcessor(zaptest.NewLogger(t))
+
+	androidVersion, err := adbproccesor.RunRaw(context.Background(), processor, device, command.GetAndroidVersionCommand, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "10", androidVersion)
+
+	// A mismatching type is reported without running the command.
+	_, err = adbproccesor.Run[*parser.Battery](context.Background(), processor, device, command.GetAndroidVersionCommand, false)
+	assert.ErrorIs(t, err, adbError.ErrorUnexpectedResult)
+	assert.Len(t, s.Requests, 2)
+
+	_, err = adbproccesor.Run[*parser.RawParser](context.Background(), processor, device, "boooom", false)
+	assert.ErrorIs(t, err, adbError.ErrorParserNotRegistered)
+}
+
+func TestProcessorTypedResult(t *testing.T) {
+	battery, err := adbproccesor.Result[*parser.Battery](adbproccesor.BatchResult{Command: command.GetBatteryCommand, Result: &parser.Battery{Level: 80}})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(80), battery.Level)
+
+	_, err = adbproccesor.Result[*parser.Battery](adbproccesor.BatchResult{Command: command.GetBatteryCommand, Err: adbError.ErrorNeedRoot})
+	assert.ErrorIs(t, err, adbError.ErrorNeedRoot)
+
+	_, err = adbproccesor.Result[*parser.Memory](adbproccesor.BatchResult{Command: command.GetBatteryCommand, Result: &parser.Battery{}})
+	assert.ErrorIs(t, err, adbError.ErrorUnexpectedResult)
+}
@@ -0,0 +1,228 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
+	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
+)
+
+// Priority orders the commands waiting for a shell on a busy device.
+type Priority int
+
+const (
+	// PriorityNormal is the priority of commands run for API requests.
+	PriorityNormal Priority = iota
+	// PriorityHigh is the priority of monitoring and recovery commands, they
+	// get the next free shell before any normal command.
+	PriorityHigh
+)
+
+// DefaultMaxShells is how many shells the processor opens on one device at
+// once. adbd of older devices drops connections under much more.
+const DefaultMaxShells = 4
+
+type priorityContextKey struct{}
+
+// WithPriority returns a context running the commands of the processor with
+// priority.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, priority)
+}
+
+// priorityFromContext returns the priority set by WithPriority, commands are
+// of normal priority by default.
+func priorityFromContext(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityContextKey{}).(Priority)
+	return min(max(priority, PriorityNormal), PriorityHigh)
+}
+
+// scheduler bounds the shells open on every device and shares the execution
+// of identical read only commands running at the same time.
+type scheduler struct {
+	maxShells int
+
+	mu       sync.Mutex
+	devices  map[string]*deviceShells
+	inflight map[string]*inflightCall
+}
+
+// deviceShells are the open shells of a device and the commands waiting for
+// one, per priority in order of arrival.
+type deviceShells struct {
+	open    int
+	waiting [PriorityHigh + 1][]chan struct{}
+}
+
+// inflightCall is a shared execution.
+type inflightCall struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+func newScheduler(maxShells int) *scheduler {
+	return &scheduler{
+		maxShells: maxShells,
+		devices:   make(map[string]*deviceShells),
+		inflight:  make(map[string]*inflightCall),
+	}
+}
+
+// acquire waits for a free shell on device. A released shell is handed to the
+// oldest command of the highest priority waiting for it.
+func (s *scheduler) acquire(ctx context.Context, device string, priority Priority) error {
+	s.mu.Lock()
+	shells, ok := s.devices[device]
+	if !ok {
+		shells = &deviceShells{}
+		s.devices[device] = shells
+	}
+	if shells.open < s.maxShells {
+		shells.open++
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	shells.waiting[priority] = append(shells.waiting[priority], ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if i := slices.Index(shells.waiting[priority], ready); i >= 0 {
+			shells.waiting[priority] = slices.Delete(shells.waiting[priority], i, i+1)
+			s.mu.Unlock()
+			return ctx.Err()
+		}
+		s.mu.Unlock()
+		// The shell was handed over meanwhile, pass it on.
+		s.release(device)
+		return ctx.Err()
+	}
+}
+
+// release frees a shell acquired on device.
+func (s *scheduler) release(device string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shells := s.devices[device]
+	for priority := PriorityHigh; priority >= PriorityNormal; priority-- {
+		if queue := shells.waiting[priority]; len(queue) > 0 {
+			shells.waiting[priority] = queue[1:]
+			close(queue[0])
+			return
+		}
+	}
+	shells.open--
+	if shells.open == 0 {
+		delete(s.devices, device)
+	}
+}
+
+// share runs fn once for all callers with the same key at the same time, they
+// all get its result. fn is not cancelled with ctx, one caller giving up does
+// not fail the others, it is still bounded by the timeouts of its commands.
+func (s *scheduler) share(ctx context.Context, key string, fn func(context.Context) (any, error)) (any, error) {
+	s.mu.Lock()
+	call, ok := s.inflight[key]
+	if !ok {
+		call = &inflightCall{done: make(chan struct{})}
+		s.inflight[key] = call
+		go func() {
+			call.value, call.err = fn(context.WithoutCancel(ctx))
+			s.mu.Lock()
+			delete(s.inflight, key)
+			s.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// scheduledExecutor runs the commands of an executor through the scheduler:
+// each takes one of the shells of the device and read only commands share
+// the execution of an identical one in flight.
+type scheduledExecutor struct {
+	executor.IExecutor
+	scheduler *scheduler
+	device    string
+	suSyntax  command.SuSyntax
+}
+
+func (e *scheduledExecutor) SetSuSyntax(syntax command.SuSyntax) {
+	e.suSyntax = syntax
+	e.IExecutor.SetSuSyntax(syntax)
+}
+
+func (e *scheduledExecutor) Run(ctx context.Context, adbCommand command.AdbCommand) (string, error) {
+	return schedule(ctx, e, string(adbCommand), command.ReadOnly(adbCommand), func(ctx context.Context) (string, error) {
+		return e.IExecutor.Run(ctx, adbCommand)
+	})
+}
+
+func (e *scheduledExecutor) RunBatch(ctx context.Context, adbCommands []command.AdbCommand) ([]executor.BatchOutput, error) {
+	readOnly := len(adbCommands) > 0
+	names := make([]string, len(adbCommands))
+	for i, adbCommand := range adbCommands {
+		readOnly = readOnly && command.ReadOnly(adbCommand)
+		names[i] = string(adbCommand)
+	}
+	name := fmt.Sprintf("batch of %d commands", len(adbCommands))
+	return schedule(ctx, e, name, readOnly, func(ctx context.Context) ([]executor.BatchOutput, error) {
+		return e.IExecutor.RunBatch(ctx, adbCommands)
+	}, names...)
+}
+
+// schedule runs fn on a shell of the device of e, shared with identical
+// calls in flight when readOnly. name identifies the call in timeout errors,
+// it and keys tell identical calls apart. Only calls of the same priority
+// are shared, a monitoring read does not wait for a shell in the queue of
+// the API read it would join.
+func schedule[T any](ctx context.Context, e *scheduledExecutor, name string, readOnly bool, fn func(context.Context) (T, error), keys ...string) (T, error) {
+	var zero T
+	priority := priorityFromContext(ctx)
+	run := func(ctx context.Context) (T, error) {
+		if err := e.scheduler.acquire(ctx, e.device, priority); err != nil {
+			return zero, scheduleError(err, name)
+		}
+		defer e.scheduler.release(e.device)
+		return fn(ctx)
+	}
+	if !readOnly {
+		return run(ctx)
+	}
+
+	key := strings.Join(append([]string{e.device, fmt.Sprint(e.Root(), e.suSyntax, priority), name}, keys...), "\x00")
+	value, err := e.scheduler.share(ctx, key, func(ctx context.Context) (any, error) {
+		return run(ctx)
+	})
+	if err != nil {
+		return zero, scheduleError(err, name)
+	}
+	return value.(T), nil
+}
+
+// scheduleError reports a caller whose deadline passed while waiting like a
+// command that did not finish in time.
+func scheduleError(err error, name string) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: '%s'", adbErrors.ErrorCommandTimeout, name)
+	}
+	return err
+}
@@ -0,0 +1,211 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
+	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
+	adb "github.com/basiooo/goadb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// blockingExecutor holds every command until release is closed and counts
+// how many it ran and how many ran at once.
+type blockingExecutor struct {
+	release chan struct{}
+	runs    atomic.Int32
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (b *blockingExecutor) Run(ctx context.Context, adbCommand command.AdbCommand) (string, error) {
+	b.runs.Add(1)
+	running := b.running.Add(1)
+	defer b.running.Add(-1)
+	for peak := b.peak.Load(); running > peak && !b.peak.CompareAndSwap(peak, running); peak = b.peak.Load() {
+	}
+	<-b.release
+	return string(adbCommand), nil
+}
+
+func (b *blockingExecutor) RunBatch(ctx context.Context, adbCommands []command.AdbCommand) ([]executor.BatchOutput, error) {
+	outputs := make([]executor.BatchOutput, len(adbCommands))
+	for i, adbCommand := range adbCommands {
+		output, err := b.Run(ctx, adbCommand)
+		if err != nil {
+			return nil, err
+		}
+		outputs[i] = executor.BatchOutput{Output: output, Done: true}
+	}
+	return outputs, nil
+}
+
+func (b *blockingExecutor) EnableRoot()                  {}
+func (b *blockingExecutor) DisableRoot()                 {}
+func (b *blockingExecutor) Root() bool                   { return false }
+func (b *blockingExecutor) SetSuSyntax(command.SuSyntax) {}
+
+func newBlockingProcessor(t *testing.T) (*Processor, *blockingExecutor, *adb.Device) {
+	blocking := &blockingExecutor{release: make(chan struct{})}
+	p := NewProcessorWithExecutor(zaptest.NewLogger(t), func(*adb.Device) executor.IExecutor {
+		return blocking
+	}).(*Processor)
+	device := (&adb.Adb{Server: &adb.MockServer{}}).Device(adb.DeviceWithSerial("R58R31ABCDE"))
+	return p, blocking, device
+}
+
+func TestSchedulerLimitsShells(t *testing.T) {
+	t.Parallel()
+	p, blocking, device := newBlockingProcessor(t)
+
+	var wg sync.WaitGroup
+	for i := range 3 * DefaultMaxShells {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			adbCommand := command.New("echo", string(rune('a'+i))).Build()
+			output, err := p.Executor(device).Run(context.Background(), adbCommand)
+			assert.NoError(t, err)
+			assert.Equal(t, string(adbCommand), output)
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return blocking.running.Load() == DefaultMaxShells
+	}, 2*time.Second, time.Millisecond)
+	close(blocking.release)
+	wg.Wait()
+	assert.Equal(t, int32(DefaultMaxShells), blocking.peak.Load())
+	assert.Equal(t, int32(3*DefaultMaxShells), blocking.runs.Load())
+	assert.Empty(t, p.scheduler.devices)
+}
+
+func TestSchedulerCoalescesReads(t *testing.T) {
+	t.Parallel()
+	p, blocking, device := newBlockingProcessor(t)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		output, err := p.Executor(device).Run(context.Background(), command.GetBatteryCommand)
+		assert.NoError(t, err)
+		assert.Equal(t, string(command.GetBatteryCommand), output)
+	}()
+	require.Eventually(t, func() bool { return blocking.runs.Load() == 1 }, 2*time.Second, time.Millisecond)
+	// Readers giving up joined the read in flight, shells are free for them
+	// to run one of their own.
+	for range 4 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := p.Executor(device).Run(ctx, command.GetBatteryCommand)
+		cancel()
+		assert.ErrorIs(t, err, adbErrors.ErrorCommandTimeout)
+	}
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.Executor(device).Run(context.Background(), command.EnableMobileDataCommand)
+			assert.NoError(t, err)
+		}()
+	}
+	close(blocking.release)
+	wg.Wait()
+	// Every toggle ran, the battery was read once for all.
+	assert.Equal(t, int32(6), blocking.runs.Load())
+	assert.Empty(t, p.scheduler.inflight)
+}
+
+func TestSchedulerSharesReadsOfSamePriority(t *testing.T) {
+	t.Parallel()
+	p, blocking, device := newBlockingProcessor(t)
+	for range DefaultMaxShells {
+		go func() {
+			_, _ = p.Executor(device).Run(context.Background(), command.RebootCommand)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return blocking.running.Load() == DefaultMaxShells
+	}, 2*time.Second, time.Millisecond)
+
+	var wg sync.WaitGroup
+	for _, priority := range []Priority{PriorityNormal, PriorityHigh} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.Executor(device).Run(WithPriority(context.Background(), priority), command.GetBatteryCommand)
+			assert.NoError(t, err)
+		}()
+	}
+	// The monitoring read waits in its own queue, it is served first.
+	assert.Eventually(t, func() bool {
+		p.scheduler.mu.Lock()
+		defer p.scheduler.mu.Unlock()
+		waiting := p.scheduler.devices[device.String()].waiting
+		return len(waiting[PriorityNormal]) == 1 && len(waiting[PriorityHigh]) == 1
+	}, 2*time.Second, time.Millisecond)
+	close(blocking.release)
+	wg.Wait()
+	assert.Equal(t, int32(DefaultMaxShells+2), blocking.runs.Load())
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	t.Parallel()
+	s := newScheduler(1)
+	ctx := context.Background()
+	require.NoError(t, s.acquire(ctx, "device", PriorityNormal))
+
+	order := make(chan Priority, 3)
+	waiting := func(priority Priority, count int) func() bool {
+		return func() bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return len(s.devices["device"].waiting[priority]) == count
+		}
+	}
+	for i, priority := range []Priority{PriorityNormal, PriorityNormal, PriorityHigh} {
+		go func() {
+			if s.acquire(ctx, "device", priority) == nil {
+				order <- priority
+				s.release("device")
+			}
+		}()
+		count := 1
+		if priority == PriorityNormal {
+			count = i + 1
+		}
+		require.Eventually(t, waiting(priority, count), 2*time.Second, time.Millisecond)
+	}
+	s.release("device")
+	assert.Equal(t, PriorityHigh, <-order)
+	assert.Equal(t, PriorityNormal, <-order)
+	assert.Equal(t, PriorityNormal, <-order)
+}
+
+func TestSchedulerWaitTimeout(t *testing.T) {
+	t.Parallel()
+	p, blocking, device := newBlockingProcessor(t)
+	defer close(blocking.release)
+	for range DefaultMaxShells {
+		go func() {
+			_, _ = p.Executor(device).Run(context.Background(), command.RebootCommand)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return blocking.running.Load() == DefaultMaxShells
+	}, 2*time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(WithPriority(context.Background(), PriorityHigh), 20*time.Millisecond)
+	defer cancel()
+	_, err := p.Executor(device).Run(ctx, command.PowerOffCommand)
+	assert.ErrorIs(t, err, adbErrors.ErrorCommandTimeout)
+	p.scheduler.mu.Lock()
+	defer p.scheduler.mu.Unlock()
+	assert.Empty(t, p.scheduler.devices[device.String()].waiting[PriorityHigh])
+}
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	adb "github.com/basiooo/goadb"
+)
+
+// Run executes adbCommand like IProcessor.Run and returns the result as T,
+// the parser type registered for the command. A T that does not match the
+// registration is reported before the command is run.
+func Run[T parser.IParser](ctx context.Context, p IProcessor, device *adb.Device, adbCommand command.AdbCommand, useRootIfDenied bool) (T, error) {
+	var zero T
+	if err := checkResultType[T](adbCommand); err != nil {
+		return zero, err
+	}
+	result, err := p.Run(ctx, device, adbCommand, useRootIfDenied)
+	if err != nil {
+		return zero, err
+	}
+	return resultAs[T](adbCommand, result)
+}
+
+// RunWithRoot executes adbCommand like IProcessor.RunWithRoot and returns the
+// result as T.
+func RunWithRoot[T parser.IParser](ctx context.Context, p IProcessor, device *adb.Device, adbCommand command.AdbCommand) (T, error) {
+	var zero T
+	if err := checkResultType[T](adbCommand); err != nil {
+		return zero, err
+	}
+	result, err := p.RunWithRoot(ctx, device, adbCommand)
+	if err != nil {
+		return zero, err
+	}
+	return resultAs[T](adbCommand, result)
+}
+
+// RunRaw executes a command registered with the raw parser and returns its
+// output.
+func RunRaw(ctx context.Context, p IProcessor, device *adb.Device, adbCommand command.AdbCommand, useRootIfDenied bool) (string, error) {
+	result, err := Run[*parser.RawParser](ctx, p, device, adbCommand, useRootIfDenied)
+	if err != nil {
+		return "", err
+	}
+	return result.Result, nil
+}
+
+// Result returns the parsed result of a RunBatch command as T.
+func Result[T parser.IParser](result BatchResult) (T, error) {
+	var zero T
+	if result.Err != nil {
+		return zero, result.Err
+	}
+	return resultAs[T](result.Command, result.Result)
+}
+
+func checkResultType[T parser.IParser](adbCommand command.AdbCommand) error {
+	newParser, ok := commandParser[adbCommand]
+	if !ok {
+		return fmt.Errorf("%w: '%s'", adbErrors.ErrorParserNotRegistered, adbCommand)
+	}
+	if _, ok := newParser().(T); !ok {
+		var zero T
+		return fmt.Errorf("%w: '%s' is parsed by %T, not %T", adbErrors.ErrorUnexpectedResult, adbCommand, newParser(), zero)
+	}
+	return nil
+}
+
+func resultAs[T parser.IParser](adbCommand command.AdbCommand, result parser.IParser) (T, error) {
+	typed, ok := result.(T)
+	if !ok || result == nil {
+		var zero T
+		return zero, fmt.Errorf("%w: '%s' returned %T, not %T", adbErrors.ErrorUnexpectedResult, adbCommand, result, zero)
+	}
+	return typed, nil
+}
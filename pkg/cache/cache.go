@@ -1,7 +1,7 @@
 package cache
 
 import (
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -12,25 +12,12 @@ type Cache struct {
 	cache *cache.Cache
 }
 
-var (
-	instance *Cache
-	once     sync.Once
-)
-
+// NewCache returns an empty cache. Entries set with an expiration of 0
+// expire after defaultExpiration.
 func NewCache(defaultExpiration, cleanupInterval time.Duration) ICache {
-	once.Do(func() {
-		instance = &Cache{
-			cache: cache.New(defaultExpiration, cleanupInterval),
-		}
-	})
-	return instance
-}
-
-func GetInstance() ICache {
-	if instance == nil {
-		return NewCache(5*time.Minute, 10*time.Minute)
+	return &Cache{
+		cache: cache.New(defaultExpiration, cleanupInterval),
 	}
-	return instance
 }
 
 func (c *Cache) Get(key string) (interface{}, bool) {
@@ -45,6 +32,15 @@ func (c *Cache) Delete(key string) {
 	c.cache.Delete(key)
 }
 
+// DeletePrefix deletes every entry whose key starts with prefix.
+func (c *Cache) DeletePrefix(prefix string) {
+	for key := range c.cache.Items() {
+		if strings.HasPrefix(key, prefix) {
+			c.cache.Delete(key)
+		}
+	}
+}
+
 func (c *Cache) Flush() {
 	c.cache.Flush()
 }
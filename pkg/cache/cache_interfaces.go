@@ -6,5 +6,6 @@ type ICache interface {
 	Get(key string) (interface{}, bool)
 	Set(key string, value interface{}, expiration time.Duration)
 	Delete(key string)
+	DeletePrefix(prefix string)
 	Flush()
 }
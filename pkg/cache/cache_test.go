@@ -82,49 +82,40 @@ func TestCache_Expiration(t *testing.T) {
 	assert.False(t, found)
 }
 
-func TestCache_Singleton(t *testing.T) {
-
-	mu := &sync.Mutex{}
-	mu.Lock()
-	originalInstance := instance
-	instance = nil
-	once = sync.Once{}
-	mu.Unlock()
-
-	defer func() {
-		mu.Lock()
-		instance = originalInstance
-		once = sync.Once{}
-		mu.Unlock()
-	}()
+func TestNewCache(t *testing.T) {
+	t.Parallel()
 
 	cache1 := NewCache(5*time.Minute, 10*time.Minute)
-	cache2 := NewCache(10*time.Minute, 20*time.Minute)
+	cache2 := NewCache(time.Millisecond, 10*time.Minute)
+
+	cache1.Set("key", "value", 0)
+	cache2.Set("key", "value", 0)
+	time.Sleep(10 * time.Millisecond)
 
-	assert.Equal(t, cache1, cache2)
+	_, found := cache1.Get("key")
+	assert.True(t, found)
+	_, found = cache2.Get("key")
+	assert.False(t, found)
 }
 
-func TestGetInstance(t *testing.T) {
+func TestCache_DeletePrefix(t *testing.T) {
+	t.Parallel()
 
-	mu := &sync.Mutex{}
-	mu.Lock()
-	originalInstance := instance
-	instance = nil
-	once = sync.Once{}
-	mu.Unlock()
+	cache := createTestCache()
 
-	defer func() {
-		mu.Lock()
-		instance = originalInstance
-		once = sync.Once{}
-		mu.Unlock()
-	}()
+	cache.Set("device_a/battery", "100", time.Minute)
+	cache.Set("device_a/model", "Pixel 7", time.Minute)
+	cache.Set("device_ab/model", "Redmi Note 7", time.Minute)
 
-	cache := GetInstance()
-	require.NotNil(t, cache)
+	cache.DeletePrefix("device_a/")
 
-	cache2 := GetInstance()
-	assert.Equal(t, cache, cache2)
+	_, found := cache.Get("device_a/battery")
+	assert.False(t, found)
+	_, found = cache.Get("device_a/model")
+	assert.False(t, found)
+	value, found := cache.Get("device_ab/model")
+	require.True(t, found)
+	assert.Equal(t, "Redmi Note 7", value)
 }
 
 func TestCache_SetWithDifferentTypes(t *testing.T) {
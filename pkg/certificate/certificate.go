@@ -0,0 +1,176 @@
+// Package certificate loads the TLS certificate used by the HTTP server, or
+// generates and persists a self-signed one when none exists yet.
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const validity = 10 * 365 * 24 * time.Hour
+
+// LoadOrGenerate returns the key pair stored in certFile and keyFile. When
+// neither file exists a self-signed certificate valid for hosts, localhost and
+// every local interface address is generated and written to them. The second
+// return value reports whether a new certificate was generated.
+func LoadOrGenerate(certFile string, keyFile string, hosts []string) (tls.Certificate, bool, error) {
+	_, certErr := os.Stat(certFile)
+	_, keyErr := os.Stat(keyFile)
+
+	if certErr == nil && keyErr == nil {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return tls.Certificate{}, false, fmt.Errorf("load tls key pair: %w", err)
+		}
+		return cert, false, nil
+	}
+	if !errors.Is(certErr, os.ErrNotExist) || !errors.Is(keyErr, os.ErrNotExist) {
+		return tls.Certificate{}, false, fmt.Errorf("only one of %s and %s exists", certFile, keyFile)
+	}
+
+	certPEM, keyPEM, err := Generate(hosts)
+	if err != nil {
+		return tls.Certificate{}, false, err
+	}
+	if err := writeKeyPair(certFile, certPEM, keyFile, keyPEM); err != nil {
+		return tls.Certificate{}, false, err
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, false, err
+	}
+	return cert, true, nil
+}
+
+// Generate creates a PEM encoded self-signed ECDSA certificate and private key.
+func Generate(hosts []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate serial number: %w", err)
+	}
+
+	notBefore := time.Now().Add(-time.Hour)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"AndroModem"}, CommonName: "AndroModem"},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range defaultHosts(hosts) {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the leaf certificate, used
+// by clients to verify a self-signed certificate.
+func Fingerprint(cert tls.Certificate) string {
+	if len(cert.Certificate) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(cert.Certificate[0])
+	return hex.EncodeToString(sum[:])
+}
+
+func defaultHosts(hosts []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0)
+	add := func(host string) {
+		if host != "" && !seen[host] {
+			seen[host] = true
+			result = append(result, host)
+		}
+	}
+
+	for _, host := range hosts {
+		add(host)
+	}
+	add("localhost")
+	if hostname, err := os.Hostname(); err == nil {
+		add(hostname)
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				add(ipNet.IP.String())
+			}
+		}
+	}
+	add("127.0.0.1")
+	add("::1")
+	return result
+}
+
+// writeKeyPair writes the certificate and the key next to their files first
+// and only moves them in place once both were written. A lone certificate
+// would make every later start fail with "only one of ... exists".
+func writeKeyPair(certFile string, certPEM []byte, keyFile string, keyPEM []byte) error {
+	certTmp, err := writeTmpFile(certFile, certPEM, 0644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(certTmp)
+	keyTmp, err := writeTmpFile(keyFile, keyPEM, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(keyTmp)
+
+	if err := os.Rename(keyTmp, keyFile); err != nil {
+		return err
+	}
+	if err := os.Rename(certTmp, certFile); err != nil {
+		_ = os.Remove(keyFile)
+		return err
+	}
+	return nil
+}
+
+func writeTmpFile(name string, data []byte, perm os.FileMode) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		return "", err
+	}
+	tmpFile := name + ".tmp"
+	if err := os.WriteFile(tmpFile, data, perm); err != nil {
+		return "", err
+	}
+	return tmpFile, nil
+}
@@ -0,0 +1,101 @@
+package certificate
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrGenerate_GeneratesAndPersists(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls", "cert.pem")
+	keyFile := filepath.Join(dir, "tls", "key.pem")
+
+	cert, generated, err := LoadOrGenerate(certFile, keyFile, []string{"andromodem.lan", "192.168.1.1"})
+	require.NoError(t, err)
+	assert.True(t, generated)
+
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Contains(t, leaf.DNSNames, "andromodem.lan")
+	assert.Contains(t, leaf.DNSNames, "localhost")
+	assert.True(t, containsIP(leaf.IPAddresses, "192.168.1.1"))
+	assert.True(t, containsIP(leaf.IPAddresses, "127.0.0.1"))
+	assert.NoError(t, leaf.VerifyHostname("andromodem.lan"))
+
+	reloaded, generated, err := LoadOrGenerate(certFile, keyFile, nil)
+	require.NoError(t, err)
+	assert.False(t, generated)
+	assert.Equal(t, Fingerprint(cert), Fingerprint(reloaded))
+}
+
+func TestLoadOrGenerate_PartialFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, []byte("cert"), 0644))
+
+	_, _, err := LoadOrGenerate(certFile, keyFile, nil)
+	assert.Error(t, err)
+}
+
+func TestLoadOrGenerate_KeyWriteFails(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	// The key cannot be written over a directory.
+	require.NoError(t, os.Mkdir(keyFile+".tmp", 0700))
+
+	_, _, err := LoadOrGenerate(certFile, keyFile, nil)
+	require.Error(t, err)
+	assert.NoFileExists(t, certFile)
+	assert.NoFileExists(t, certFile+".tmp")
+
+	require.NoError(t, os.Remove(keyFile+".tmp"))
+	_, generated, err := LoadOrGenerate(certFile, keyFile, nil)
+	require.NoError(t, err)
+	assert.True(t, generated)
+}
+
+func TestLoadOrGenerate_InvalidFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, []byte("cert"), 0644))
+	require.NoError(t, os.WriteFile(keyFile, []byte("key"), 0600))
+
+	_, _, err := LoadOrGenerate(certFile, keyFile, nil)
+	assert.Error(t, err)
+}
+
+func TestFingerprint_Empty(t *testing.T) {
+	t.Parallel()
+	assert.Empty(t, Fingerprint(tls.Certificate{}))
+}
+
+func containsIP(ips []net.IP, want string) bool {
+	for _, ip := range ips {
+		if ip.Equal(net.ParseIP(want)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+// Package hub shares one producer per key between any number of subscribers.
+//
+// The producer of a key is started by its first subscriber and stopped when
+// the last one leaves. Every subscriber receives the latest value right away
+// and then each new one. A subscriber that falls behind skips intermediate
+// values instead of blocking the producer, it always ends up with the newest.
+package hub
+
+import (
+	"context"
+	"sync"
+)
+
+// Producer publishes values for key until ctx is cancelled. When it returns
+// on its own, the subscriptions of key are closed.
+type Producer[T any] func(ctx context.Context, key string, publish func(T))
+
+type Subscription[T any] struct {
+	// C receives the published values. It is closed when the subscription is
+	// closed or the producer stopped.
+	C     <-chan T
+	close func()
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription[T]) Close() {
+	s.close()
+}
+
+type topic[T any] struct {
+	cancel      context.CancelFunc
+	subscribers map[chan T]struct{}
+	last        T
+	hasLast     bool
+}
+
+type Hub[T any] struct {
+	ctx      context.Context
+	producer Producer[T]
+	buffer   int
+	mutex    sync.Mutex
+	topics   map[string]*topic[T]
+}
+
+// New returns a hub running producer for every subscribed key. Producers are
+// stopped when ctx is cancelled. buffer is the channel size per subscriber.
+func New[T any](ctx context.Context, producer Producer[T], buffer int) IHub[T] {
+	return &Hub[T]{
+		ctx:      ctx,
+		producer: producer,
+		buffer:   max(buffer, 1),
+		topics:   make(map[string]*topic[T]),
+	}
+}
+
+func (h *Hub[T]) Subscribe(key string) *Subscription[T] {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	current, ok := h.topics[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(h.ctx)
+		current = &topic[T]{
+			cancel:      cancel,
+			subscribers: make(map[chan T]struct{}),
+		}
+		h.topics[key] = current
+		go h.run(ctx, key, current)
+	}
+
+	ch := make(chan T, h.buffer)
+	current.subscribers[ch] = struct{}{}
+	if current.hasLast {
+		ch <- current.last
+	}
+
+	var once sync.Once
+	return &Subscription[T]{
+		C: ch,
+		close: func() {
+			once.Do(func() {
+				h.unsubscribe(key, current, ch)
+			})
+		},
+	}
+}
+
+func (h *Hub[T]) Subscribers(key string) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if current, ok := h.topics[key]; ok {
+		return len(current.subscribers)
+	}
+	return 0
+}
+
+func (h *Hub[T]) run(ctx context.Context, key string, current *topic[T]) {
+	h.producer(ctx, key, func(value T) {
+		h.publish(key, current, value)
+	})
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	current.cancel()
+	if h.topics[key] != current {
+		return
+	}
+	delete(h.topics, key)
+	for ch := range current.subscribers {
+		close(ch)
+	}
+	current.subscribers = nil
+}
+
+func (h *Hub[T]) publish(key string, current *topic[T], value T) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.topics[key] != current {
+		// The last subscriber left, the producer is about to stop.
+		return
+	}
+	current.last = value
+	current.hasLast = true
+	for ch := range current.subscribers {
+		select {
+		case ch <- value:
+			continue
+		default:
+		}
+		// Drop the oldest queued value to make room for the newest.
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- value:
+		default:
+		}
+	}
+}
+
+func (h *Hub[T]) unsubscribe(key string, current *topic[T], ch chan T) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := current.subscribers[ch]; !ok {
+		return
+	}
+	delete(current.subscribers, ch)
+	close(ch)
+	if len(current.subscribers) == 0 && h.topics[key] == current {
+		delete(h.topics, key)
+		current.cancel()
+	}
+}
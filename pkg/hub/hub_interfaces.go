@@ -0,0 +1,6 @@
+package hub
+
+type IHub[T any] interface {
+	Subscribe(key string) *Subscription[T]
+	Subscribers(key string) int
+}
@@ -0,0 +1,108 @@
+package hub
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case value, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return value
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for value")
+	}
+	var zero T
+	return zero
+}
+
+func TestHub_SharesOneProducerPerKey(t *testing.T) {
+	t.Parallel()
+
+	var started atomic.Int32
+	stopped := make(chan string, 1)
+	publishNext := make(chan int)
+	h := New(context.Background(), func(ctx context.Context, key string, publish func(int)) {
+		started.Add(1)
+		defer func() { stopped <- key }()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case value := <-publishNext:
+				publish(value)
+			}
+		}
+	}, 4)
+
+	first := h.Subscribe("R58M123")
+	second := h.Subscribe("R58M123")
+	assert.Equal(t, 2, h.Subscribers("R58M123"))
+
+	publishNext <- 1
+	assert.Equal(t, 1, receive(t, first.C))
+	assert.Equal(t, 1, receive(t, second.C))
+
+	// A late subscriber gets the latest value right away.
+	third := h.Subscribe("R58M123")
+	assert.Equal(t, 1, receive(t, third.C))
+	assert.Equal(t, int32(1), started.Load())
+
+	first.Close()
+	first.Close()
+	second.Close()
+	assert.Equal(t, 1, h.Subscribers("R58M123"))
+
+	third.Close()
+	assert.Equal(t, 0, h.Subscribers("R58M123"))
+	select {
+	case key := <-stopped:
+		assert.Equal(t, "R58M123", key)
+	case <-time.After(time.Second):
+		t.Fatal("producer was not stopped after the last subscriber left")
+	}
+}
+
+func TestHub_SlowSubscriberGetsNewestValue(t *testing.T) {
+	t.Parallel()
+
+	published := make(chan struct{})
+	h := New(context.Background(), func(ctx context.Context, key string, publish func(int)) {
+		for i := 1; i <= 5; i++ {
+			publish(i)
+		}
+		close(published)
+		<-ctx.Done()
+	}, 1)
+
+	sub := h.Subscribe("R58M123")
+	defer sub.Close()
+	<-published
+	assert.Equal(t, 5, receive(t, sub.C))
+}
+
+func TestHub_ProducerExitClosesSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	h := New(context.Background(), func(ctx context.Context, key string, publish func(string)) {
+		publish("gone")
+	}, 1)
+
+	sub := h.Subscribe("emulator-5554")
+	assert.Equal(t, "gone", receive(t, sub.C))
+	select {
+	case _, ok := <-sub.C:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not closed")
+	}
+	sub.Close()
+	assert.Equal(t, 0, h.Subscribers("emulator-5554"))
+}
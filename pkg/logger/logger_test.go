@@ -69,6 +69,38 @@ func TestNewLogger(t *testing.T) {
 	assert.NoError(t, err, "Log file should be created")
 }
 
+func TestNewLoggerWithConfig(t *testing.T) {
+	t.Parallel()
+
+	logFile := filepath.Join(t.TempDir(), "logs", "custom.log")
+
+	logger := NewLoggerWithConfig(logFile, "debug")
+	require.NotNil(t, logger)
+	assert.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	logger.Info("test message")
+	time.Sleep(100 * time.Millisecond)
+
+	_, err := os.Stat(logFile)
+	assert.NoError(t, err, "Log file should be created at the configured path")
+}
+
+func TestNewLoggerWithLevel_ChangesAtRuntime(t *testing.T) {
+	t.Parallel()
+
+	level := NewAtomicLevel("info")
+	logger := NewLoggerWithLevel(filepath.Join(t.TempDir(), "andromodem.log"), level)
+	require.NotNil(t, logger)
+	assert.False(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	SetLevel(level, "debug")
+	assert.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	SetLevel(level, "error")
+	assert.False(t, logger.Core().Enabled(zapcore.WarnLevel))
+	assert.Equal(t, zapcore.ErrorLevel, level.Level())
+}
+
 func TestLogDuration(t *testing.T) {
 	t.Parallel()
 
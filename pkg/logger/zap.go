@@ -27,12 +27,33 @@ func getLoggerLevel(level string) zapcore.Level {
 }
 
 func NewLogger() *zap.Logger {
-	logLevel := getLoggerLevel("info")
+	appDir, _ := os.Getwd()
+	return NewLoggerWithConfig(filepath.Join(appDir, "andromodem_logs/andromodem.log"), "info")
+}
+
+// NewAtomicLevel returns level as a zap.AtomicLevel that can be changed while
+// the logger is running, e.g. on config reload.
+func NewAtomicLevel(level string) zap.AtomicLevel {
+	return zap.NewAtomicLevelAt(getLoggerLevel(level))
+}
+
+// SetLevel changes atomicLevel to level. Unknown levels fall back to debug,
+// like NewLoggerWithConfig.
+func SetLevel(atomicLevel zap.AtomicLevel, level string) {
+	atomicLevel.SetLevel(getLoggerLevel(level))
+}
+
+// NewLoggerWithConfig writes JSON logs to logOutput, rotated by lumberjack.
+func NewLoggerWithConfig(logOutput string, level string) *zap.Logger {
+	return NewLoggerWithLevel(logOutput, NewAtomicLevel(level))
+}
+
+// NewLoggerWithLevel is NewLoggerWithConfig with a level that can be changed
+// at runtime through logLevel.
+func NewLoggerWithLevel(logOutput string, logLevel zap.AtomicLevel) *zap.Logger {
 	zapConfig := zap.NewProductionEncoderConfig()
 	var writeSyncer zapcore.WriteSyncer
-	appDir, _ := os.Getwd()
 
-	logOutput := filepath.Join(appDir, "andromodem_logs/andromodem.log")
 	lumberjackLogger := &lumberjack.Logger{
 		Filename:   logOutput,
 		MaxSize:    1,
@@ -0,0 +1,115 @@
+// Package metrics writes metric families in the Prometheus text exposition
+// format (version 0.0.4), without pulling in the Prometheus client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the Content-Type header of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type MetricType string
+
+const (
+	Gauge   MetricType = "gauge"
+	Counter MetricType = "counter"
+)
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+type Family struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Samples []Sample
+}
+
+func NewGauge(name string, help string) *Family {
+	return &Family{Name: name, Help: help, Type: Gauge}
+}
+
+func NewCounter(name string, help string) *Family {
+	return &Family{Name: name, Help: help, Type: Counter}
+}
+
+// Add appends a sample. labels are name/value pairs, e.g.
+// Add(1, "serial", "abc", "sim_slot", "1"); a trailing name without value is
+// ignored.
+func (f *Family) Add(value float64, labels ...string) {
+	sample := Sample{Value: value}
+	for i := 0; i+1 < len(labels); i += 2 {
+		sample.Labels = append(sample.Labels, Label{Name: labels[i], Value: labels[i+1]})
+	}
+	f.Samples = append(f.Samples, sample)
+}
+
+// Write writes families in order. Families without samples are skipped.
+func Write(w io.Writer, families []*Family) error {
+	var writer strings.Builder
+	for _, family := range families {
+		if family == nil || len(family.Samples) == 0 {
+			continue
+		}
+		if family.Help != "" {
+			fmt.Fprintf(&writer, "# HELP %s %s\n", family.Name, escapeHelp(family.Help))
+		}
+		fmt.Fprintf(&writer, "# TYPE %s %s\n", family.Name, family.Type)
+		for _, sample := range family.Samples {
+			writer.WriteString(family.Name)
+			if len(sample.Labels) > 0 {
+				writer.WriteByte('{')
+				for i, label := range sample.Labels {
+					if i > 0 {
+						writer.WriteByte(',')
+					}
+					fmt.Fprintf(&writer, "%s=\"%s\"", label.Name, escapeLabelValue(label.Value))
+				}
+				writer.WriteByte('}')
+			}
+			writer.WriteByte(' ')
+			writer.WriteString(formatValue(sample.Value))
+			writer.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, writer.String())
+	return err
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelReplacer.Replace(value)
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	battery := NewGauge("andromodem_battery_level_percent", "Battery level in percent.")
+	battery.Add(85, "serial", "R58M123")
+	battery.Add(42.5, "serial", "emulator-5554")
+
+	checks := NewCounter("andromodem_monitoring_checks_total", "Monitoring checks performed.")
+	checks.Add(12, "serial", "R58M123")
+
+	empty := NewGauge("andromodem_unused", "Never written.")
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, []*Family{battery, empty, checks, nil}))
+
+	expected := `# HELP andromodem_battery_level_percent Battery level in percent.
+# TYPE andromodem_battery_level_percent gauge
+andromodem_battery_level_percent{serial="R58M123"} 85
+andromodem_battery_level_percent{serial="emulator-5554"} 42.5
+# HELP andromodem_monitoring_checks_total Monitoring checks performed.
+# TYPE andromodem_monitoring_checks_total counter
+andromodem_monitoring_checks_total{serial="R58M123"} 12
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWrite_EscapingAndSpecialValues(t *testing.T) {
+	t.Parallel()
+
+	family := NewGauge("test_metric", "Help with \\ and\nnewline.")
+	family.Add(math.NaN(), "operator", `My "Carrier"`+"\n"+`\x`)
+	family.Add(math.Inf(1))
+	family.Add(math.Inf(-1), "dangling")
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, []*Family{family}))
+
+	expected := `# HELP test_metric Help with \\ and\nnewline.
+# TYPE test_metric gauge
+test_metric{operator="My \"Carrier\"\n\\x"} NaN
+test_metric +Inf
+test_metric -Inf
+`
+	assert.Equal(t, expected, buf.String())
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"sync"
 	"time"
 
 	adb "github.com/basiooo/goadb"
@@ -27,6 +28,9 @@ type Client struct {
 
 	VideoHandler   *VideoStreamHandler
 	ControlHandler *ControlHandler
+
+	cleanupOnce sync.Once
+	cleanupErr  error
 }
 
 func NewClient(ctx context.Context, device *adb.Device, config *Config, logger *zap.Logger) *Client {
@@ -117,7 +121,16 @@ func (c *Client) ResetState() {
 	c.VideoHandler.Reset()
 }
 
+// Cleanup removes the port forward of the client. It is called when the
+// client context ends and on application shutdown; only the first call acts.
 func (c *Client) Cleanup() error {
+	c.cleanupOnce.Do(func() {
+		c.cleanupErr = c.cleanup()
+	})
+	return c.cleanupErr
+}
+
+func (c *Client) cleanup() error {
 	c.Logger.Info("Cleaning up scrcpy client resources")
 	c.ResetState()
 
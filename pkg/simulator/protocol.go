@@ -0,0 +1,258 @@
+package simulator
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	adb "github.com/basiooo/goadb"
+)
+
+// serverVersion is the version of the ADB server the simulator claims to be.
+const serverVersion = 41
+
+// forward is a port forward set up by a forward request. Connections to
+// the local address are not served, forwards are only listed.
+type forward struct {
+	serial string
+	remote string
+}
+
+// serve answers the requests of one connection. A transport request switches
+// the connection to a device, the requests that follow it are served by
+// that device.
+func (s *Simulator) serve(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var transport *device
+	for {
+		request, err := readRequest(conn)
+		if err != nil {
+			return
+		}
+
+		switch {
+		case request == "host:version":
+			_ = okay(conn, fmt.Sprintf("%04x", serverVersion))
+			return
+		case request == "host:devices":
+			_ = okay(conn, s.deviceList(false))
+			return
+		case request == "host:devices-l":
+			_ = okay(conn, s.deviceList(true))
+			return
+		case request == "host:track-devices":
+			s.track(conn)
+			return
+		case strings.HasPrefix(request, "host:transport"):
+			d, err := s.device(transportSerial(request))
+			if err != nil {
+				_ = fail(conn, err)
+				return
+			}
+			if _, err := io.WriteString(conn, "OKAY"); err != nil {
+				return
+			}
+			transport = d
+		case strings.HasPrefix(request, "shell:") && transport != nil:
+			output, _ := s.shell(transport, strings.TrimPrefix(request, "shell:"))
+			if _, err := io.WriteString(conn, "OKAY"); err != nil {
+				return
+			}
+			_, _ = io.WriteString(conn, output)
+			return
+		case request == "sync:" && transport != nil:
+			if _, err := io.WriteString(conn, "OKAY"); err != nil {
+				return
+			}
+			serveSync(conn, transport)
+			return
+		default:
+			response, err := s.hostRequest(request)
+			if err != nil {
+				_ = fail(conn, err)
+				return
+			}
+			if response == nil {
+				_, _ = io.WriteString(conn, "OKAY")
+			} else {
+				_ = okay(conn, *response)
+			}
+			return
+		}
+	}
+}
+
+// hostRequest answers the requests about one device, "host-serial:<serial>:"
+// followed by the attribute. A nil response is acknowledged without a
+// message.
+func (s *Simulator) hostRequest(request string) (*string, error) {
+	serial, attribute, ok := strings.Cut(strings.TrimPrefix(request, "host-serial:"), ":")
+	if request == "host:list-forward" {
+		serial, attribute, ok = "", "list-forward", true
+	} else if !ok || !strings.HasPrefix(request, "host-serial:") {
+		return nil, fmt.Errorf("unsupported request '%s'", request)
+	}
+
+	response := func(message string) (*string, error) {
+		return &message, nil
+	}
+	switch {
+	case attribute == "get-serialno":
+		if s.State(serial) == adb.StateDisconnected {
+			return nil, fmt.Errorf("device '%s' not found", serial)
+		}
+		return response(serial)
+	case attribute == "get-state":
+		if _, err := s.device(serial); err != nil {
+			return nil, err
+		}
+		return response("device")
+	case attribute == "get-devpath":
+		if s.State(serial) == adb.StateDisconnected {
+			return nil, fmt.Errorf("device '%s' not found", serial)
+		}
+		return response("usb:1-1")
+	case strings.HasPrefix(attribute, "forward:"):
+		if _, err := s.device(serial); err != nil {
+			return nil, err
+		}
+		local, remote, ok := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(attribute, "forward:"), "norebind:"), ";")
+		if !ok {
+			return nil, fmt.Errorf("malformed forward spec '%s'", attribute)
+		}
+		s.setForward(local, &forward{serial: serial, remote: remote})
+		return nil, nil
+	case strings.HasPrefix(attribute, "killforward:"):
+		local := strings.TrimPrefix(attribute, "killforward:")
+		if !s.setForward(local, nil) {
+			return nil, fmt.Errorf("listener '%s' not found", local)
+		}
+		return nil, nil
+	case attribute == "killforward-all":
+		s.removeForwards(serial)
+		return nil, nil
+	case attribute == "list-forward":
+		return response(s.listForwards(serial))
+	default:
+		return nil, fmt.Errorf("unsupported request '%s'", request)
+	}
+}
+
+// track sends the device list whenever it changes, until the client closes
+// the connection.
+func (s *Simulator) track(conn net.Conn) {
+	changed := s.watch()
+	defer s.unwatch(changed)
+
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+
+	if _, err := io.WriteString(conn, "OKAY"); err != nil {
+		return
+	}
+	for {
+		if _, err := io.WriteString(conn, encodeMessage(s.deviceList(false))); err != nil {
+			return
+		}
+		select {
+		case <-closed:
+			return
+		case <-changed:
+		}
+	}
+}
+
+// setForward sets the forward of local, or removes it when f is nil. It
+// returns false when there was nothing to remove.
+func (s *Simulator) setForward(local string, f *forward) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f != nil {
+		s.forwards[local] = *f
+		return true
+	}
+	if _, ok := s.forwards[local]; !ok {
+		return false
+	}
+	delete(s.forwards, local)
+	return true
+}
+
+func (s *Simulator) removeForwards(serial string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for local, f := range s.forwards {
+		if f.serial == serial {
+			delete(s.forwards, local)
+		}
+	}
+}
+
+// listForwards lists the forwards of serial, or of every device when serial
+// is empty, as `adb forward --list` does.
+func (s *Simulator) listForwards(serial string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	locals := make([]string, 0, len(s.forwards))
+	for local, f := range s.forwards {
+		if serial == "" || f.serial == serial {
+			locals = append(locals, local)
+		}
+	}
+	sort.Strings(locals)
+	var list strings.Builder
+	for _, local := range locals {
+		f := s.forwards[local]
+		fmt.Fprintf(&list, "%s %s %s\n", f.serial, local, f.remote)
+	}
+	return list.String()
+}
+
+// transportSerial returns the serial of a transport request, empty for
+// host:transport-any and the other requests picking the only device.
+func transportSerial(request string) string {
+	serial, _ := strings.CutPrefix(request, "host:transport:")
+	if serial == request {
+		return ""
+	}
+	return serial
+}
+
+func readRequest(r io.Reader) (string, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", err
+	}
+	length, err := strconv.ParseUint(string(header), 16, 16)
+	if err != nil {
+		return "", err
+	}
+	request := make([]byte, length)
+	if _, err := io.ReadFull(r, request); err != nil {
+		return "", err
+	}
+	return string(request), nil
+}
+
+func okay(w io.Writer, message string) error {
+	_, err := io.WriteString(w, "OKAY"+encodeMessage(message))
+	return err
+}
+
+func fail(w io.Writer, err error) error {
+	_, err = io.WriteString(w, "FAIL"+encodeMessage(err.Error()))
+	return err
+}
+
+func encodeMessage(message string) string {
+	return fmt.Sprintf("%04x%s", len(message), message)
+}
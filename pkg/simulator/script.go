@@ -0,0 +1,34 @@
+package simulator
+
+import (
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	adb "github.com/basiooo/goadb"
+)
+
+// Step changes the state of a device once After has passed since the step
+// before.
+type Step struct {
+	After  time.Duration
+	Serial string
+	State  adb.DeviceState
+}
+
+// DemoScript unplugs and plugs the last of fixtures back in every few
+// minutes, going through the states a USB device does, and has it refuse
+// the host key once. The other devices stay online.
+func DemoScript(fixtures []*fixture.Fixture) []Step {
+	if len(fixtures) == 0 {
+		return nil
+	}
+	serial := fixtures[len(fixtures)-1].Device.Serial
+	return []Step{
+		{After: 2 * time.Minute, Serial: serial, State: adb.StateDisconnected},
+		{After: 30 * time.Second, Serial: serial, State: adb.StateOffline},
+		{After: 2 * time.Second, Serial: serial, State: adb.StateUnauthorized},
+		{After: 10 * time.Second, Serial: serial, State: adb.StateOnline},
+		{After: 2 * time.Minute, Serial: serial, State: adb.StateOffline},
+		{After: 15 * time.Second, Serial: serial, State: adb.StateOnline},
+	}
+}
@@ -0,0 +1,467 @@
+package simulator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	adb "github.com/basiooo/goadb"
+)
+
+// device is a simulated device. Commands are answered from its fixture,
+// except the ones reading or changing the radio state kept here, and the
+// CPU time and traffic counters, which advance between reads.
+type device struct {
+	fixture *fixture.Fixture
+	state   adb.DeviceState
+
+	mu         sync.Mutex
+	airplane   bool
+	mobileData bool
+	cpuReads   int
+	files      map[string][]byte
+	// online is how long mobile data was connected between reads of the
+	// traffic counters, up to lastNetDev.
+	online     time.Duration
+	lastNetDev time.Time
+}
+
+func newDevice(f *fixture.Fixture) *device {
+	d := &device{
+		fixture:    f,
+		state:      adb.StateOnline,
+		mobileData: true,
+		files:      make(map[string][]byte),
+	}
+	if record, ok := f.Lookup(command.GetAirplaneModeStatusNewCommand, false); ok {
+		d.airplane = strings.TrimSpace(record.Output) == "enabled"
+	} else if record, ok := f.Lookup(command.GetAirplaneModeStatusLegacyCommand, false); ok {
+		d.airplane = strings.TrimSpace(record.Output) == "1"
+	}
+	if record, ok := f.Lookup(command.GetMobileDataStateCommand, false); ok {
+		d.mobileData = firstLine(record.Output) == "2"
+	}
+	return d
+}
+
+// rooted is true when su is installed on the device.
+func (d *device) rooted() bool {
+	record, ok := d.fixture.Lookup(command.GetRootCommand, false)
+	return ok && record.ExitCode == 0
+}
+
+// connected is true when mobile data can be used.
+func (d *device) connected() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.mobileData && !d.airplane
+}
+
+var suPrefixes = []string{"su --mount-master -c ", "su 0 sh -c ", "su -c "}
+
+// shell runs commandLine on d, as it is sent by the executor: a command,
+// possibly wrapped in su, or a batch script.
+func (s *Simulator) shell(d *device, commandLine string) (string, int) {
+	for _, prefix := range suPrefixes {
+		script, ok := strings.CutPrefix(commandLine, prefix)
+		if !ok {
+			continue
+		}
+		if record, ok := d.fixture.Lookup(command.AdbCommand(commandLine), true); ok {
+			return record.Output, record.ExitCode
+		}
+		if !d.rooted() {
+			return "/system/bin/sh: su: not found\n", 127
+		}
+		if script, ok = unquote(script); !ok {
+			return "/system/bin/sh: syntax error: unterminated quoted string\n", 2
+		}
+		return s.script(d, script, true)
+	}
+	return s.script(d, commandLine, false)
+}
+
+var assignment = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(\S*)$`)
+
+// script runs the lines of a shell script. Besides plain commands it
+// understands what the batch scripts of the executor are made of: variable
+// assignments, commands grouped in a subshell and printf statements after
+// them.
+func (s *Simulator) script(d *device, script string, root bool) (string, int) {
+	if !strings.Contains(script, "\n") {
+		return s.command(d, script, root)
+	}
+
+	var output strings.Builder
+	vars := map[string]string{}
+	status := 0
+	lines := strings.Split(script, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if match := assignment.FindStringSubmatch(line); match != nil {
+			vars[match[1]] = match[2]
+			continue
+		}
+		if line != "(" {
+			if strings.TrimSpace(line) != "" {
+				var out string
+				out, status = s.command(d, line, root)
+				output.WriteString(out)
+			}
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && !strings.HasPrefix(lines[end], ")") {
+			end++
+		}
+		if end == len(lines) {
+			return output.String() + "/system/bin/sh: syntax error: '(' unmatched\n", 2
+		}
+		var out string
+		out, status = s.script(d, strings.Join(lines[i+1:end], "\n"), root)
+		output.WriteString(out)
+
+		statements := strings.Split(strings.TrimPrefix(lines[end], ")"), ";")
+		for _, statement := range statements[1:] {
+			if format, ok := strings.CutPrefix(statement, "printf "); ok {
+				output.WriteString(printf(format, vars, status))
+			}
+		}
+		i = end
+	}
+	return output.String(), status
+}
+
+// command answers one command line, from the radio state when it reads or
+// changes it, otherwise from the fixture.
+func (s *Simulator) command(d *device, line string, root bool) (string, int) {
+	adbCommand := command.AdbCommand(strings.TrimSpace(line))
+	if output, exitCode, ok := s.stateCommand(d, adbCommand); ok {
+		return output, exitCode
+	}
+	if record, ok := d.fixture.Lookup(adbCommand, root); ok {
+		return record.Output, record.ExitCode
+	}
+	if record, ok := d.fixture.Lookup(adbCommand, !root); ok {
+		return record.Output, record.ExitCode
+	}
+
+	fields := strings.Fields(string(adbCommand))
+	if len(fields) == 0 {
+		return "", 0
+	}
+	switch fields[0] {
+	case "getprop":
+		if len(fields) == 2 {
+			return getprop(d.fixture, fields[1]), 0
+		}
+	case "echo":
+		return strings.Join(fields[1:], " ") + "\n", 0
+	case "ping":
+		return ping(fields[len(fields)-1], d.connected())
+	}
+	return fmt.Sprintf("/system/bin/sh: %s: inaccessible or not found\n", fields[0]), 127
+}
+
+// stateCommand answers the commands reading or changing the airplane mode,
+// mobile data and power state, and the CPU time and traffic counters of d.
+func (s *Simulator) stateCommand(d *device, adbCommand command.AdbCommand) (string, int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch adbCommand {
+	case command.GetAirplaneModeStatusNewCommand:
+		if d.airplane {
+			return "enabled\n", 0, true
+		}
+		return "disabled\n", 0, true
+	case command.GetAirplaneModeStatusLegacyCommand:
+		return boolSetting(d.airplane), 0, true
+	case command.EnableAirplaneModeNewCommand, command.EnableAirplaneModeLegacyCommand:
+		s.settle(d, func() { d.airplane = true })
+		return "", 0, true
+	case command.DisableAirplaneModeNewCommand, command.DisableAirplaneModeLegacyCommand:
+		s.settle(d, func() { d.airplane = false })
+		return "", 0, true
+	case command.BroadcastAirplaneModeLegacyCommand:
+		return "Broadcasting: Intent { act=android.intent.action.AIRPLANE_MODE flg=0x400000 }\nBroadcast completed: result=0\n", 0, true
+	case command.EnableMobileDataCommand:
+		s.settle(d, func() { d.mobileData = true })
+		return "", 0, true
+	case command.DisableMobileDataCommand:
+		s.settle(d, func() { d.mobileData = false })
+		return "", 0, true
+	case command.GetMobileDataStatusCommand:
+		return boolSetting(d.mobileData), 0, true
+	case command.GetMobileDataStateCommand:
+		return d.mobileDataState(), 0, true
+	case command.GetIpRouterCommand:
+		record, ok := d.fixture.Lookup(adbCommand, false)
+		if !ok {
+			return "", 0, false
+		}
+		if d.mobileData && !d.airplane {
+			return record.Output, record.ExitCode, true
+		}
+		return withoutMobileRoutes(record.Output), 0, true
+	case command.RebootCommand, command.RebootRecoveryCommand, command.RebootBootloaderCommand:
+		s.reboot(d.fixture.Device.Serial)
+		return "", 0, true
+	case command.GetCpuStatCommand:
+		record, ok := d.fixture.Lookup(adbCommand, false)
+		if !ok {
+			return "", 0, false
+		}
+		d.cpuReads++
+		return advanceCpuStat(record.Output, d.cpuReads), record.ExitCode, true
+	case command.GetNetDevCommand:
+		record, ok := d.fixture.Lookup(adbCommand, false)
+		if !ok {
+			return "", 0, false
+		}
+		now := time.Now()
+		if !d.lastNetDev.IsZero() && d.mobileData && !d.airplane {
+			d.online += now.Sub(d.lastNetDev)
+		}
+		d.lastNetDev = now
+		return advanceNetDev(record.Output, d.mobileInterface(), d.online), record.ExitCode, true
+	case command.PowerOffCommand:
+		go func() {
+			_ = s.SetState(d.fixture.Device.Serial, adb.StateDisconnected)
+		}()
+		return "", 0, true
+	}
+	return "", 0, false
+}
+
+// settle applies change to the radio state of d after the settle delay.
+// Callers hold the lock of d.
+func (s *Simulator) settle(d *device, change func()) {
+	if s.SettleDelay <= 0 {
+		change()
+		return
+	}
+	time.AfterFunc(s.SettleDelay, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		change()
+	})
+}
+
+// mobileDataState is the data connection state of every SIM, the first
+// follows the radio state and the others keep the recorded one.
+func (d *device) mobileDataState() string {
+	states := []string{"0"}
+	if record, ok := d.fixture.Lookup(command.GetMobileDataStateCommand, false); ok {
+		states = strings.Fields(record.Output)
+		if len(states) == 0 {
+			states = []string{"0"}
+		}
+	}
+	states[0] = "0"
+	if d.mobileData && !d.airplane {
+		states[0] = "2"
+	}
+	return strings.Join(states, "\n") + "\n"
+}
+
+// cpuTicks is the user, nice, system and idle time every core spends
+// between two reads of the CPU time, a quarter of it busy.
+var cpuTicks = []uint64{20, 0, 5, 75}
+
+// advanceCpuStat adds reads times cpuTicks to the recorded CPU time of
+// every core, and to the sum of them.
+func advanceCpuStat(stat string, reads int) string {
+	lines := strings.Split(stat, "\n")
+	cores := uint64(0)
+	for _, line := range lines {
+		if strings.HasPrefix(line, "cpu") && !strings.HasPrefix(line, "cpu ") {
+			cores++
+		}
+	}
+
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) <= len(cpuTicks) || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		factor := uint64(reads)
+		if fields[0] == "cpu" {
+			factor *= cores
+		}
+		for j, ticks := range cpuTicks {
+			if value, err := strconv.ParseUint(fields[j+1], 10, 64); err == nil {
+				fields[j+1] = strconv.FormatUint(value+ticks*factor, 10)
+			}
+		}
+		lines[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Throughput of the mobile data connection, in bytes per second.
+const (
+	simulatedRxRate = 250_000
+	simulatedTxRate = 40_000
+)
+
+// mobileInterface is the cellular interface of the recorded routes.
+func (d *device) mobileInterface() string {
+	record, ok := d.fixture.Lookup(command.GetIpRouterCommand, false)
+	if !ok {
+		return ""
+	}
+	for _, line := range strings.Split(record.Output, "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[i] == "dev" && (strings.HasPrefix(fields[i+1], "rmnet") || strings.HasPrefix(fields[i+1], "ccmni")) {
+				return fields[i+1]
+			}
+		}
+	}
+	return ""
+}
+
+// advanceNetDev adds the traffic of online time at the simulated rates to
+// the recorded counters of iface, and of rmnet_ipa0 carrying it again.
+func advanceNetDev(netDev string, iface string, online time.Duration) string {
+	rx := uint64(online.Seconds() * simulatedRxRate)
+	tx := uint64(online.Seconds() * simulatedTxRate)
+	lines := strings.Split(netDev, "\n")
+	for i, line := range lines {
+		name, counters, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || (name != iface && name != "rmnet_ipa0") {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 16 {
+			continue
+		}
+		for j, added := range map[int]uint64{0: rx, 8: tx} {
+			if value, err := strconv.ParseUint(fields[j], 10, 64); err == nil {
+				fields[j] = strconv.FormatUint(value+added, 10)
+			}
+		}
+		lines[i] = name + ": " + strings.Join(fields, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reboot takes the device offline for the reboot delay. The state changes
+// after the command returned, as the connection of a rebooting device
+// drops.
+func (s *Simulator) reboot(serial string) {
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = s.SetState(serial, adb.StateOffline)
+		time.Sleep(s.RebootDelay)
+		if s.State(serial) == adb.StateOffline {
+			_ = s.SetState(serial, adb.StateOnline)
+		}
+	}()
+}
+
+func boolSetting(on bool) string {
+	if on {
+		return "1\n"
+	}
+	return "0\n"
+}
+
+// withoutMobileRoutes drops the routes of the cellular interfaces, which go
+// away with the data connection.
+func withoutMobileRoutes(routes string) string {
+	var kept strings.Builder
+	for _, line := range strings.SplitAfter(routes, "\n") {
+		if !strings.Contains(line, "rmnet") && !strings.Contains(line, "ccmni") {
+			kept.WriteString(line)
+		}
+	}
+	return kept.String()
+}
+
+var propLine = regexp.MustCompile(`^\[([^\]]*)\]: \[(.*)\]$`)
+
+// getprop reads a property from the full getprop output of the fixture, an
+// unknown property is empty as on a device.
+func getprop(f *fixture.Fixture, key string) string {
+	record, ok := f.Lookup(command.GetDevicePropCommand, false)
+	if !ok {
+		return "\n"
+	}
+	for _, line := range strings.Split(record.Output, "\n") {
+		if match := propLine.FindStringSubmatch(strings.TrimSpace(line)); match != nil && match[1] == key {
+			return match[2] + "\n"
+		}
+	}
+	return "\n"
+}
+
+// ping answers a ping of host as toybox does, it only gets through while
+// the device has a data connection.
+func ping(host string, connected bool) (string, int) {
+	if !connected {
+		return "connect: Network is unreachable\n", 2
+	}
+	return fmt.Sprintf("PING %[1]s (142.250.4.100) 56(84) bytes of data.\n"+
+		"64 bytes from 142.250.4.100: icmp_seq=1 ttl=115 time=38.2 ms\n\n"+
+		"--- %[1]s ping statistics ---\n"+
+		"1 packets transmitted, 1 received, 0%% packet loss, time 0ms\n"+
+		"rtt min/avg/max/mdev = 38.212/38.212/38.212/0.000 ms\n", host), 0
+}
+
+// printf runs a printf statement of a batch script: a double quoted format
+// with escapes and variables, followed by $? for its %d.
+func printf(statement string, vars map[string]string, status int) string {
+	statement = strings.TrimSpace(statement)
+	if !strings.HasPrefix(statement, `"`) {
+		return ""
+	}
+	end := strings.LastIndex(statement, `"`)
+	if end == 0 {
+		return ""
+	}
+	format := statement[1:end]
+	format = strings.ReplaceAll(format, `\n`, "\n")
+	for name, value := range vars {
+		format = strings.ReplaceAll(format, "$"+name, value)
+	}
+	if strings.TrimSpace(statement[end+1:]) == "$?" {
+		format = strings.Replace(format, "%d", strconv.Itoa(status), 1)
+	}
+	return strings.ReplaceAll(format, "%%", "%")
+}
+
+// unquote returns the single argument s is quoted to by the command builder.
+func unquote(s string) (string, bool) {
+	var unquoted strings.Builder
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, `\'`):
+			unquoted.WriteByte('\'')
+			s = s[2:]
+		case s[0] == '\'':
+			end := strings.IndexByte(s[1:], '\'')
+			if end < 0 {
+				return "", false
+			}
+			unquoted.WriteString(s[1 : end+1])
+			s = s[end+2:]
+		default:
+			return "", false
+		}
+	}
+	return unquoted.String(), true
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
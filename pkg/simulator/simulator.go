@@ -0,0 +1,230 @@
+// Package simulator is an in-process ADB server serving devices played from
+// fixtures, so AndroModem can be demoed and developed without phones. It
+// speaks the host protocol goadb uses, the device list, device tracking,
+// shell, sync and forward requests, and keeps the airplane mode and mobile
+// data of every device so toggling them is seen by later commands.
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	adb "github.com/basiooo/goadb"
+	"github.com/basiooo/goadb/wire"
+)
+
+// DefaultRebootDelay is how long a device stays offline after a reboot
+// command.
+const DefaultRebootDelay = 10 * time.Second
+
+// stateNames are the states as listed by `adb devices`. Disconnected devices
+// are not listed.
+var stateNames = map[adb.DeviceState]string{
+	adb.StateOffline:      "offline",
+	adb.StateOnline:       "device",
+	adb.StateUnauthorized: "unauthorized",
+	adb.StateAuthorizing:  "authorizing",
+	adb.StateRecovery:     "recovery",
+}
+
+// Simulator is the server of an adb.Adb serving simulated devices:
+//
+//	client := &adb.Adb{Server: simulator.New(fixtures...)}
+type Simulator struct {
+	// RebootDelay is how long a device stays offline after a reboot command.
+	RebootDelay time.Duration
+	// SettleDelay is how long a device takes to apply a change of airplane
+	// mode or mobile data, zero applies it at once.
+	SettleDelay time.Duration
+
+	mu       sync.Mutex
+	devices  map[string]*device
+	serials  []string
+	watchers map[chan struct{}]struct{}
+	forwards map[string]forward
+}
+
+// New returns a simulator serving one online device per fixture.
+func New(fixtures ...*fixture.Fixture) *Simulator {
+	s := &Simulator{
+		RebootDelay: DefaultRebootDelay,
+		devices:     make(map[string]*device, len(fixtures)),
+		watchers:    make(map[chan struct{}]struct{}),
+		forwards:    make(map[string]forward),
+	}
+	for _, f := range fixtures {
+		serial := f.Device.Serial
+		if _, ok := s.devices[serial]; !ok {
+			s.serials = append(s.serials, serial)
+		}
+		s.devices[serial] = newDevice(f)
+	}
+	sort.Strings(s.serials)
+	return s
+}
+
+func (s *Simulator) Start() error {
+	return nil
+}
+
+// Dial returns a connection speaking the ADB wire protocol to the simulator.
+func (s *Simulator) Dial() (*wire.Conn, error) {
+	client, server := net.Pipe()
+	go s.serve(server)
+	return wire.NewConn(wire.NewScanner(client), wire.NewSender(client)), nil
+}
+
+// SetState changes the state of the device with serial, watchers of the
+// device list are told about the change. Disconnected devices are removed
+// from the list until they are set to another state.
+func (s *Simulator) SetState(serial string, state adb.DeviceState) error {
+	if _, ok := stateNames[state]; !ok && state != adb.StateDisconnected {
+		return fmt.Errorf("unsupported device state %s", state)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.devices[serial]
+	if !ok {
+		return fmt.Errorf("device '%s' not found", serial)
+	}
+	if d.state == state {
+		return nil
+	}
+	d.state = state
+	for watcher := range s.watchers {
+		select {
+		case watcher <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// State returns the state of the device with serial.
+func (s *Simulator) State(serial string) adb.DeviceState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.devices[serial]
+	if !ok {
+		return adb.StateDisconnected
+	}
+	return d.state
+}
+
+// Run shells the command line on the device with serial as the device would
+// and returns its output and exit code, without going through goadb.
+func (s *Simulator) Run(serial string, commandLine string) (string, int, error) {
+	d, err := s.device(serial)
+	if err != nil {
+		return "", 0, err
+	}
+	output, exitCode := s.shell(d, commandLine)
+	return output, exitCode, nil
+}
+
+// Play changes device states as scripted by steps until ctx is done. Every
+// step waits for its delay after the one before, the script starts over
+// when loop is set.
+func (s *Simulator) Play(ctx context.Context, steps []Step, loop bool) error {
+	for {
+		for _, step := range steps {
+			timer := time.NewTimer(step.After)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+			if err := s.SetState(step.Serial, step.State); err != nil {
+				return err
+			}
+		}
+		if !loop || len(steps) == 0 {
+			return nil
+		}
+	}
+}
+
+// device returns the online device with serial, the error is the one adb
+// gives for devices that cannot be used.
+func (s *Simulator) device(serial string) (*device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if serial == "" {
+		var online []*device
+		for _, serial := range s.serials {
+			if d := s.devices[serial]; d.state == adb.StateOnline {
+				online = append(online, d)
+			}
+		}
+		switch len(online) {
+		case 0:
+			return nil, fmt.Errorf("no devices/emulators found")
+		case 1:
+			return online[0], nil
+		default:
+			return nil, fmt.Errorf("more than one device/emulator")
+		}
+	}
+	d, ok := s.devices[serial]
+	if !ok || d.state == adb.StateDisconnected {
+		return nil, fmt.Errorf("device '%s' not found", serial)
+	}
+	switch d.state {
+	case adb.StateOnline:
+		return d, nil
+	case adb.StateUnauthorized:
+		return nil, fmt.Errorf("device unauthorized.\nThis adb server's $ADB_VENDOR_KEYS is not set")
+	default:
+		return nil, fmt.Errorf("device %s", stateNames[d.state])
+	}
+}
+
+// deviceList is the device list as sent by host:devices and
+// host:track-devices, long adds the product and model of every device.
+// Unlike adb the serials are not padded, goadb clips messages to
+// wire.MaxMessageLength and the padding would cut off the last device.
+func (s *Simulator) deviceList(long bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var list strings.Builder
+	for i, serial := range s.serials {
+		d := s.devices[serial]
+		name, ok := stateNames[d.state]
+		if !ok {
+			continue
+		}
+		if !long {
+			fmt.Fprintf(&list, "%s\t%s\n", serial, name)
+			continue
+		}
+		fmt.Fprintf(&list, "%s %s product:%s model:%s device:%s transport_id:%d\n",
+			serial,
+			name,
+			d.fixture.Device.Product,
+			strings.ReplaceAll(d.fixture.Device.Model, " ", "_"),
+			d.fixture.Device.Product,
+			i+1)
+	}
+	return list.String()
+}
+
+func (s *Simulator) watch() chan struct{} {
+	changed := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.watchers[changed] = struct{}{}
+	s.mu.Unlock()
+	return changed
+}
+
+func (s *Simulator) unwatch(changed chan struct{}) {
+	s.mu.Lock()
+	delete(s.watchers, changed)
+	s.mu.Unlock()
+}
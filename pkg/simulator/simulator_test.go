@@ -0,0 +1,274 @@
+package simulator_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/basiooo/andromodem/pkg/adb_processor/command"
+	adbErrors "github.com/basiooo/andromodem/pkg/adb_processor/errors"
+	"github.com/basiooo/andromodem/pkg/adb_processor/executor"
+	"github.com/basiooo/andromodem/pkg/adb_processor/fixture"
+	"github.com/basiooo/andromodem/pkg/adb_processor/parser"
+	"github.com/basiooo/andromodem/pkg/simulator"
+	adb "github.com/basiooo/goadb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	pixelSerial  = "2A111FDH200ABC"
+	xiaomiSerial = "8d1c2a3f"
+)
+
+func newSimulator(t *testing.T) (*simulator.Simulator, *adb.Adb) {
+	profiles, err := fixture.Profiles()
+	require.NoError(t, err)
+	sim := simulator.New(profiles...)
+	return sim, &adb.Adb{Server: sim}
+}
+
+func newExecutor(t *testing.T, client *adb.Adb, serial string) executor.IExecutor {
+	device, err := client.GetDeviceBySerial(serial)
+	require.NoError(t, err)
+	return executor.NewExecutor(device)
+}
+
+func TestDevices(t *testing.T) {
+	t.Parallel()
+	sim, client := newSimulator(t)
+
+	serials, err := client.ListDeviceSerials()
+	require.NoError(t, err)
+	assert.Equal(t, []string{pixelSerial, xiaomiSerial, "R58R31ABCDE"}, serials)
+
+	devices, err := client.ListDevices()
+	require.NoError(t, err)
+	require.Len(t, devices, 3)
+	assert.Equal(t, "Redmi_Note_7", devices[1].Model)
+	assert.Equal(t, "a52qnsxx", devices[2].DeviceInfo)
+
+	require.NoError(t, sim.SetState(xiaomiSerial, adb.StateUnauthorized))
+	state, err := client.Device(adb.DeviceWithSerial(xiaomiSerial)).State()
+	require.NoError(t, err)
+	assert.Equal(t, adb.StateUnauthorized, state)
+	_, err = newExecutor(t, client, xiaomiSerial).Run(context.Background(), command.GetDeviceModelCommand)
+	assert.ErrorIs(t, err, adbErrors.ErrorDeviceUnauthorized)
+
+	require.NoError(t, sim.SetState(xiaomiSerial, adb.StateOffline))
+	_, err = newExecutor(t, client, xiaomiSerial).Run(context.Background(), command.GetDeviceModelCommand)
+	assert.ErrorIs(t, err, adbErrors.ErrorDeviceOffline)
+
+	require.NoError(t, sim.SetState(xiaomiSerial, adb.StateDisconnected))
+	serials, err = client.ListDeviceSerials()
+	require.NoError(t, err)
+	assert.Equal(t, []string{pixelSerial, "R58R31ABCDE"}, serials)
+	_, err = client.GetDeviceBySerial(xiaomiSerial)
+	assert.True(t, adb.HasErrCode(err, adb.DeviceNotFound))
+
+	assert.Error(t, sim.SetState("unknown", adb.StateOnline))
+}
+
+func TestTrackDevices(t *testing.T) {
+	t.Parallel()
+	sim, client := newSimulator(t)
+	watcher := client.NewDeviceWatcher()
+	defer watcher.Shutdown()
+
+	next := func() adb.DeviceStateChangedEvent {
+		select {
+		case event := <-watcher.C():
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no device event")
+			return adb.DeviceStateChangedEvent{}
+		}
+	}
+	online := map[string]bool{}
+	for range 3 {
+		event := next()
+		assert.Equal(t, adb.StateOnline, event.NewState)
+		online[event.Serial] = true
+	}
+	assert.Len(t, online, 3)
+
+	require.NoError(t, sim.SetState(pixelSerial, adb.StateDisconnected))
+	assert.Equal(t, adb.DeviceStateChangedEvent{Serial: pixelSerial, OldState: adb.StateOnline, NewState: adb.StateDisconnected}, next())
+	require.NoError(t, sim.SetState(pixelSerial, adb.StateOffline))
+	assert.Equal(t, adb.DeviceStateChangedEvent{Serial: pixelSerial, OldState: adb.StateDisconnected, NewState: adb.StateOffline}, next())
+	require.NoError(t, sim.SetState(pixelSerial, adb.StateOnline))
+	assert.Equal(t, adb.DeviceStateChangedEvent{Serial: pixelSerial, OldState: adb.StateOffline, NewState: adb.StateOnline}, next())
+}
+
+func TestPlay(t *testing.T) {
+	t.Parallel()
+	sim, _ := newSimulator(t)
+	err := sim.Play(context.Background(), []simulator.Step{
+		{Serial: pixelSerial, State: adb.StateOffline},
+		{After: time.Millisecond, Serial: xiaomiSerial, State: adb.StateDisconnected},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, adb.StateOffline, sim.State(pixelSerial))
+	assert.Equal(t, adb.StateDisconnected, sim.State(xiaomiSerial))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = sim.Play(ctx, []simulator.Step{{After: time.Hour, Serial: pixelSerial, State: adb.StateOnline}}, true)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	profiles, err := fixture.Profiles()
+	require.NoError(t, err)
+	assert.NotEmpty(t, simulator.DemoScript(profiles))
+}
+
+func TestShell(t *testing.T) {
+	t.Parallel()
+	_, client := newSimulator(t)
+	ctx := context.Background()
+
+	pixel := newExecutor(t, client, pixelSerial)
+	output, err := pixel.Run(ctx, command.GetDeviceModelCommand)
+	require.NoError(t, err)
+	assert.Equal(t, "Pixel 7\n", output)
+	output, err = pixel.Run(ctx, command.New("getprop", "ro.board.platform").Build())
+	require.NoError(t, err)
+	assert.Equal(t, "gs201\n", output)
+	output, err = pixel.Run(ctx, command.GetDeviceRootAccessCommand)
+	require.NoError(t, err)
+	assert.Equal(t, "/system/bin/sh: su: not found\n", output)
+
+	outputs, err := pixel.RunBatch(ctx, []command.AdbCommand{command.GetDeviceModelCommand, "unknown-command", command.GetBatteryCommand})
+	require.NoError(t, err)
+	require.Len(t, outputs, 3)
+	assert.Equal(t, executor.BatchOutput{Output: "Pixel 7\n", Done: true}, outputs[0])
+	assert.Equal(t, 127, outputs[1].ExitCode)
+	assert.Contains(t, outputs[2].Output, "Current Battery Service state")
+
+	// The Redmi is rooted with Magisk, its APN can only be read as root.
+	xiaomi := newExecutor(t, client, xiaomiSerial)
+	xiaomi.EnableRoot()
+	xiaomi.SetSuSyntax(command.SuSyntaxMagisk)
+	outputs, err = xiaomi.RunBatch(ctx, []command.AdbCommand{command.GetApnCommand, command.GetDeviceModelCommand})
+	require.NoError(t, err)
+	require.Len(t, outputs, 2)
+	assert.Contains(t, outputs[0].Output, "Indosat Internet")
+	assert.Equal(t, "Redmi Note 7\n", outputs[1].Output)
+	output, err = xiaomi.Run(ctx, command.GetDeviceRootAccessCommand)
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", output)
+}
+
+func TestRadioState(t *testing.T) {
+	t.Parallel()
+	sim, client := newSimulator(t)
+	ctx := context.Background()
+	exec := newExecutor(t, client, "R58R31ABCDE")
+	ping := command.New("ping", "-c", "1", "-W", "5", "google.com").Build()
+
+	run := func(adbCommand command.AdbCommand) string {
+		output, err := exec.Run(ctx, adbCommand)
+		require.NoError(t, err)
+		return output
+	}
+	assert.Equal(t, "2\n0\n", run(command.GetMobileDataStateCommand))
+	assert.Contains(t, run(ping), "1 received")
+
+	run(command.DisableMobileDataCommand)
+	assert.Equal(t, "0\n0\n", run(command.GetMobileDataStateCommand))
+	assert.Equal(t, "0\n", run(command.GetMobileDataStatusCommand))
+	assert.NotContains(t, run(command.GetIpRouterCommand), "rmnet")
+	output, exitCode, err := sim.Run("R58R31ABCDE", string(ping))
+	require.NoError(t, err)
+	assert.Equal(t, 2, exitCode)
+	assert.Contains(t, output, "unreachable")
+
+	run(command.EnableMobileDataCommand)
+	run(command.EnableAirplaneModeNewCommand)
+	assert.Equal(t, "enabled\n", run(command.GetAirplaneModeStatusNewCommand))
+	assert.Equal(t, "1\n", run(command.GetAirplaneModeStatusLegacyCommand))
+	assert.Equal(t, "0\n0\n", run(command.GetMobileDataStateCommand))
+
+	run(command.DisableAirplaneModeLegacyCommand)
+	assert.Equal(t, "disabled\n", run(command.GetAirplaneModeStatusNewCommand))
+	assert.Equal(t, "2\n0\n", run(command.GetMobileDataStateCommand))
+	assert.Contains(t, run(command.GetIpRouterCommand), "rmnet")
+}
+
+func TestCpuStat(t *testing.T) {
+	t.Parallel()
+	_, client := newSimulator(t)
+	exec := newExecutor(t, client, xiaomiSerial)
+
+	read := func() *parser.CpuStat {
+		output, err := exec.Run(context.Background(), command.GetCpuStatCommand)
+		require.NoError(t, err)
+		stat := &parser.CpuStat{}
+		require.NoError(t, stat.Parse(output))
+		return stat
+	}
+	previous := read()
+	usage, ok := read().UsageSince(previous)
+	require.True(t, ok)
+	assert.Equal(t, 25.0, usage.Usage)
+	assert.Len(t, usage.Cores, 4)
+}
+
+func TestReboot(t *testing.T) {
+	t.Parallel()
+	sim, client := newSimulator(t)
+	sim.RebootDelay = 50 * time.Millisecond
+
+	_, err := newExecutor(t, client, pixelSerial).Run(context.Background(), command.RebootCommand)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return sim.State(pixelSerial) == adb.StateOffline
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return sim.State(pixelSerial) == adb.StateOnline
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+	_, client := newSimulator(t)
+	device := client.Device(adb.DeviceWithSerial(pixelSerial))
+
+	_, err := device.Stat("/data/local/tmp/server.jar")
+	assert.True(t, adb.HasErrCode(err, adb.FileNoExistError))
+
+	writer, err := device.OpenWrite("/data/local/tmp/server.jar", 0644, time.Now())
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("jar"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	entry, err := device.Stat("/data/local/tmp/server.jar")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), entry.Size)
+
+	reader, err := device.OpenRead("/data/local/tmp/server.jar")
+	require.NoError(t, err)
+	var data bytes.Buffer
+	_, err = io.Copy(&data, reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "jar", data.String())
+}
+
+func TestForward(t *testing.T) {
+	t.Parallel()
+	_, client := newSimulator(t)
+	device := client.Device(adb.DeviceWithSerial(pixelSerial))
+
+	require.NoError(t, device.ForwardAbstract(27183, "scrcpy"))
+	forwards, err := device.ForwardList()
+	require.NoError(t, err)
+	require.Len(t, forwards, 1)
+	assert.Equal(t, "tcp:27183", forwards[0].Local)
+	assert.Equal(t, "localabstract:scrcpy", forwards[0].Remote)
+
+	require.NoError(t, device.ForwardRemovePort(27183))
+	assert.Error(t, device.ForwardRemovePort(27183))
+}
@@ -0,0 +1,141 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+)
+
+// regularFileMode is the mode of pushed files as adb reports it, a regular
+// file readable by everyone.
+const regularFileMode = 0100644
+
+// serveSync answers the file requests of a connection switched to sync
+// mode. Pushed files are kept in memory, so they can be stat'ed and pulled
+// back.
+func serveSync(conn io.ReadWriter, d *device) {
+	for {
+		id, path, err := readSyncRequest(conn)
+		if err != nil {
+			return
+		}
+		switch id {
+		case "STAT":
+			d.mu.Lock()
+			data, ok := d.files[path]
+			d.mu.Unlock()
+			var mode, size, mtime int32
+			if ok {
+				mode, size, mtime = regularFileMode, int32(len(data)), int32(time.Now().Unix())
+			}
+			if writeSync(conn, "STAT", mode, size, mtime) != nil {
+				return
+			}
+		case "LIST":
+			if writeSync(conn, "DONE", 0, 0, 0, 0) != nil {
+				return
+			}
+		case "SEND":
+			// The path is followed by the file mode after the last comma.
+			if i := strings.LastIndexByte(path, ','); i >= 0 {
+				path = path[:i]
+			}
+			data, err := readSyncData(conn)
+			if err != nil {
+				return
+			}
+			d.mu.Lock()
+			d.files[path] = data
+			d.mu.Unlock()
+			// The file is stored before its modification time is read, the
+			// client is done writing once the time is read and may look
+			// for the file right away.
+			var mtime int32
+			if binary.Read(conn, binary.LittleEndian, &mtime) != nil {
+				return
+			}
+			// goadb closes the connection without reading the status.
+			_ = writeSync(conn, "OKAY", 0)
+			return
+		case "RECV":
+			d.mu.Lock()
+			data, ok := d.files[path]
+			d.mu.Unlock()
+			if !ok {
+				_ = writeSyncFail(conn, "No such file or directory")
+				return
+			}
+			if writeSync(conn, "DATA", int32(len(data))) != nil {
+				return
+			}
+			if _, err := conn.Write(data); err != nil {
+				return
+			}
+			if writeSync(conn, "DONE", 0) != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func readSyncRequest(r io.Reader) (string, string, error) {
+	id := make([]byte, 4)
+	if _, err := io.ReadFull(r, id); err != nil {
+		return "", "", err
+	}
+	var length int32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return "", "", err
+	}
+	path := make([]byte, length)
+	if _, err := io.ReadFull(r, path); err != nil {
+		return "", "", err
+	}
+	return string(id), string(path), nil
+}
+
+// readSyncData reads the DATA chunks of a pushed file up to the id of the
+// DONE chunk, its modification time is left to read.
+func readSyncData(r io.Reader) ([]byte, error) {
+	var data bytes.Buffer
+	for {
+		id := make([]byte, 4)
+		if _, err := io.ReadFull(r, id); err != nil {
+			return nil, err
+		}
+		if string(id) == "DONE" {
+			return data.Bytes(), nil
+		}
+		var length int32
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, err
+		}
+		if _, err := io.CopyN(&data, r, int64(length)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func writeSync(w io.Writer, id string, values ...int32) error {
+	if _, err := io.WriteString(w, id); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if err := binary.Write(w, binary.LittleEndian, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSyncFail(w io.Writer, message string) error {
+	if err := writeSync(w, "FAIL", int32(len(message))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, message)
+	return err
+}
@@ -0,0 +1,407 @@
+// Package timeseries stores numeric samples per device and metric in JSON
+// lines files, bounded by age and by number of points.
+//
+// Every series lives in <dir>/<serial>/<metric>.jsonl. Samples are appended
+// as they arrive; once enough expired or trimmed lines accumulate the file is
+// rewritten with only the retained points, so a file never grows far beyond
+// the configured maximum.
+package timeseries
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const fileExtension = ".jsonl"
+
+var (
+	ErrorInvalidMetric      = errors.New("invalid metric name")
+	ErrorInvalidSerial      = errors.New("invalid serial")
+	ErrorInvalidStep        = errors.New("step must be positive")
+	ErrorInvalidAggregation = errors.New("invalid aggregation, expected one of: avg, min, max, last")
+)
+
+var metricName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// serialReplacer keeps serials of TCP devices (host:port) usable as directory
+// names on every platform.
+var serialReplacer = strings.NewReplacer("/", "_", `\`, "_", ":", "_")
+
+type Point struct {
+	Timestamp time.Time `json:"t"`
+	Value     float64   `json:"v"`
+}
+
+type series struct {
+	points []Point
+	// fileLines counts the lines in the file, retained or not.
+	fileLines int
+}
+
+type Store struct {
+	dir       string
+	retention time.Duration
+	maxPoints int
+	mutex     sync.Mutex
+	series    map[string]*series
+	now       func() time.Time
+}
+
+// NewStore returns a store writing below dir. Points older than retention
+// are dropped, and at most maxPoints are kept per series.
+func NewStore(dir string, retention time.Duration, maxPoints int) (IStore, error) {
+	if retention <= 0 {
+		return nil, fmt.Errorf("retention must be positive")
+	}
+	if maxPoints <= 0 {
+		return nil, fmt.Errorf("max points must be positive")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create time-series directory: %w", err)
+	}
+	return &Store{
+		dir:       dir,
+		retention: retention,
+		maxPoints: maxPoints,
+		series:    make(map[string]*series),
+		now:       time.Now,
+	}, nil
+}
+
+func (s *Store) Append(serial string, metric string, point Point) error {
+	path, err := s.path(serial, metric)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, err := s.load(path)
+	if err != nil {
+		return err
+	}
+	current.points = append(current.points, point)
+	s.trim(current)
+
+	if err := appendPoint(path, point); err != nil {
+		return err
+	}
+	current.fileLines++
+
+	if current.fileLines-len(current.points) >= s.compactThreshold() {
+		return s.compact(path, current)
+	}
+	return nil
+}
+
+// Query returns the points of the series between from and to, both
+// inclusive. A zero from or to leaves that side open.
+func (s *Store) Query(serial string, metric string, from time.Time, to time.Time) ([]Point, error) {
+	path, err := s.path(serial, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, err := s.load(path)
+	if err != nil {
+		return nil, err
+	}
+	s.trim(current)
+
+	points := make([]Point, 0, len(current.points))
+	for _, point := range current.points {
+		if !from.IsZero() && point.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && point.Timestamp.After(to) {
+			continue
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// Metrics lists the metric names recorded for serial, sorted.
+func (s *Store) Metrics(serial string) ([]string, error) {
+	dir, err := s.serialDir(serial)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name, found := strings.CutSuffix(entry.Name(), fileExtension)
+		if entry.IsDir() || !found || !metricName.MatchString(name) {
+			continue
+		}
+		metrics = append(metrics, name)
+	}
+	sort.Strings(metrics)
+	return metrics, nil
+}
+
+// Prune removes the files of series that received no point within the
+// retention, such as those of devices that were disconnected for good, and
+// the directories left empty.
+func (s *Store) Prune() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	deadline := s.now().Add(-s.retention)
+	serialDirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, serialDir := range serialDirs {
+		if !serialDir.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.dir, serialDir.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		remaining := len(entries)
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || entry.IsDir() || !info.ModTime().Before(deadline) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if err := os.Remove(path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			delete(s.series, path)
+			remaining--
+		}
+		if remaining == 0 {
+			if err := os.Remove(dir); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Store) serialDir(serial string) (string, error) {
+	name := serialReplacer.Replace(serial)
+	if name == "" || name == "." || name == ".." {
+		return "", ErrorInvalidSerial
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *Store) path(serial string, metric string) (string, error) {
+	if !metricName.MatchString(metric) {
+		return "", ErrorInvalidMetric
+	}
+	dir, err := s.serialDir(serial)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, metric+fileExtension), nil
+}
+
+// load returns the series stored in path, reading the file on first use.
+func (s *Store) load(path string) (*series, error) {
+	if current, ok := s.series[path]; ok {
+		return current, nil
+	}
+
+	current := &series{}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.series[path] = current
+		return current, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		current.fileLines++
+		var point Point
+		if err := json.Unmarshal(scanner.Bytes(), &point); err != nil {
+			continue
+		}
+		current.points = append(current.points, point)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(current.points, func(i, j int) bool {
+		return current.points[i].Timestamp.Before(current.points[j].Timestamp)
+	})
+	s.trim(current)
+	s.series[path] = current
+	return current, nil
+}
+
+// trim drops expired points and the oldest ones above maxPoints.
+func (s *Store) trim(current *series) {
+	deadline := s.now().Add(-s.retention)
+	start := sort.Search(len(current.points), func(i int) bool {
+		return !current.points[i].Timestamp.Before(deadline)
+	})
+	if excess := len(current.points) - start - s.maxPoints; excess > 0 {
+		start += excess
+	}
+	if start > 0 {
+		current.points = append(current.points[:0:0], current.points[start:]...)
+	}
+}
+
+func (s *Store) compactThreshold() int {
+	return max(s.maxPoints/2, 1)
+}
+
+// compact rewrites the file with the retained points only. The new content
+// is written to a temporary file first so a crash never loses the series.
+func (s *Store) compact(path string, current *series) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, point := range current.points {
+		if err := encoder.Encode(point); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	current.fileLines = len(current.points)
+	return nil
+}
+
+func appendPoint(path string, point Point) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(point)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+type Aggregation string
+
+const (
+	AggregationAvg  Aggregation = "avg"
+	AggregationMin  Aggregation = "min"
+	AggregationMax  Aggregation = "max"
+	AggregationLast Aggregation = "last"
+)
+
+// ParseAggregation returns the aggregation named value, avg when empty.
+func ParseAggregation(value string) (Aggregation, error) {
+	switch aggregation := Aggregation(strings.ToLower(value)); aggregation {
+	case "":
+		return AggregationAvg, nil
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationLast:
+		return aggregation, nil
+	default:
+		return "", ErrorInvalidAggregation
+	}
+}
+
+// Downsample groups points into buckets of step, aligned to the Unix epoch,
+// and reduces every bucket to a single point stamped with the bucket start.
+// points must be sorted by time.
+func Downsample(points []Point, step time.Duration, aggregation Aggregation) ([]Point, error) {
+	if step <= 0 {
+		return nil, ErrorInvalidStep
+	}
+	if _, err := ParseAggregation(string(aggregation)); err != nil {
+		return nil, err
+	}
+
+	result := make([]Point, 0)
+	var bucket time.Time
+	var sum float64
+	var count int
+	var current Point
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		if aggregation == AggregationAvg || aggregation == "" {
+			current.Value = sum / float64(count)
+		}
+		current.Timestamp = bucket
+		result = append(result, current)
+	}
+
+	for _, point := range points {
+		start := time.Unix(0, point.Timestamp.UnixNano()-point.Timestamp.UnixNano()%int64(step)).In(point.Timestamp.Location())
+		if count == 0 || !start.Equal(bucket) {
+			flush()
+			bucket = start
+			sum = 0
+			count = 0
+			current = Point{Value: point.Value}
+		}
+		sum += point.Value
+		count++
+		switch aggregation {
+		case AggregationMin:
+			current.Value = math.Min(current.Value, point.Value)
+		case AggregationMax:
+			current.Value = math.Max(current.Value, point.Value)
+		case AggregationLast:
+			current.Value = point.Value
+		}
+	}
+	flush()
+	return result, nil
+}
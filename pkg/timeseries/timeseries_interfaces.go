@@ -0,0 +1,10 @@
+package timeseries
+
+import "time"
+
+type IStore interface {
+	Append(serial string, metric string, point Point) error
+	Query(serial string, metric string, from time.Time, to time.Time) ([]Point, error)
+	Metrics(serial string) ([]string, error)
+	Prune() error
+}
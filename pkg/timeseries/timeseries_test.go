@@ -0,0 +1,152 @@
+package timeseries
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var baseTime = time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T, retention time.Duration, maxPoints int) (*Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := NewStore(dir, retention, maxPoints)
+	require.NoError(t, err)
+	s := store.(*Store)
+	s.now = func() time.Time { return baseTime.Add(time.Hour) }
+	return s, dir
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = file.Close()
+	}()
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+func TestStore_AppendAndQuery(t *testing.T) {
+	t.Parallel()
+
+	store, dir := newTestStore(t, 24*time.Hour, 100)
+	for i := range 5 {
+		require.NoError(t, store.Append("R58M123", "battery_level", Point{Timestamp: baseTime.Add(time.Duration(i) * time.Minute), Value: float64(90 - i)}))
+	}
+
+	points, err := store.Query("R58M123", "battery_level", baseTime.Add(time.Minute), baseTime.Add(3*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+	assert.Equal(t, 89.0, points[0].Value)
+	assert.Equal(t, 87.0, points[2].Value)
+
+	// A new store reads the series back from disk.
+	reopened, err := NewStore(dir, 24*time.Hour, 100)
+	require.NoError(t, err)
+	reopened.(*Store).now = store.now
+	points, err = reopened.Query("R58M123", "battery_level", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, points, 5)
+
+	metrics, err := reopened.Metrics("R58M123")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"battery_level"}, metrics)
+}
+
+func TestStore_BoundedByMaxPointsAndRetention(t *testing.T) {
+	t.Parallel()
+
+	store, dir := newTestStore(t, 30*time.Minute, 4)
+	for i := range 20 {
+		require.NoError(t, store.Append("192.168.1.5:5555", "sim1_rsrp", Point{Timestamp: baseTime.Add(time.Duration(i) * 3 * time.Minute), Value: float64(-90 - i)}))
+	}
+
+	points, err := store.Query("192.168.1.5:5555", "sim1_rsrp", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, points, 4)
+	assert.Equal(t, -109.0, points[3].Value)
+
+	path := filepath.Join(dir, "192.168.1.5_5555", "sim1_rsrp.jsonl")
+	assert.LessOrEqual(t, countLines(t, path), 4+store.compactThreshold())
+
+	// Points older than the retention are dropped even below maxPoints.
+	store.now = func() time.Time { return baseTime.Add(87 * time.Minute) }
+	points, err = store.Query("192.168.1.5:5555", "sim1_rsrp", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, points, 1)
+}
+
+func TestStore_InvalidNames(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newTestStore(t, time.Hour, 10)
+	assert.ErrorIs(t, store.Append("R58M123", "../etc", Point{}), ErrorInvalidMetric)
+	_, err := store.Query("..", "battery_level", time.Time{}, time.Time{})
+	assert.ErrorIs(t, err, ErrorInvalidSerial)
+}
+
+func TestStore_Prune(t *testing.T) {
+	t.Parallel()
+
+	store, dir := newTestStore(t, time.Hour, 10)
+	require.NoError(t, store.Append("old", "battery_level", Point{Timestamp: baseTime, Value: 50}))
+	require.NoError(t, store.Append("new", "battery_level", Point{Timestamp: baseTime, Value: 50}))
+
+	stale := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "old", "battery_level.jsonl"), stale, stale))
+	store.now = time.Now
+
+	require.NoError(t, store.Prune())
+	assert.NoDirExists(t, filepath.Join(dir, "old"))
+	assert.FileExists(t, filepath.Join(dir, "new", "battery_level.jsonl"))
+}
+
+func TestDownsample(t *testing.T) {
+	t.Parallel()
+
+	points := []Point{
+		{Timestamp: baseTime, Value: -100},
+		{Timestamp: baseTime.Add(2 * time.Minute), Value: -90},
+		{Timestamp: baseTime.Add(4 * time.Minute), Value: -80},
+		{Timestamp: baseTime.Add(6 * time.Minute), Value: -120},
+	}
+
+	tests := []struct {
+		aggregation Aggregation
+		expected    []float64
+	}{
+		{AggregationAvg, []float64{-90, -120}},
+		{AggregationMin, []float64{-100, -120}},
+		{AggregationMax, []float64{-80, -120}},
+		{AggregationLast, []float64{-80, -120}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.aggregation), func(t *testing.T) {
+			t.Parallel()
+			result, err := Downsample(points, 5*time.Minute, tt.aggregation)
+			require.NoError(t, err)
+			require.Len(t, result, len(tt.expected))
+			for i, value := range tt.expected {
+				assert.Equal(t, value, result[i].Value)
+			}
+			assert.True(t, result[1].Timestamp.Equal(baseTime.Add(5*time.Minute)))
+		})
+	}
+
+	_, err := Downsample(points, 0, AggregationAvg)
+	assert.ErrorIs(t, err, ErrorInvalidStep)
+	_, err = ParseAggregation("median")
+	assert.ErrorIs(t, err, ErrorInvalidAggregation)
+}
@@ -7,4 +7,14 @@ export const apiClient = axios.create({
     headers: {
         "Content-type": "application/json"
     }
-})
\ No newline at end of file
+})
+apiClient.interceptors.response.use(
+    (response) => response,
+    (error) => {
+        // Authentication is optional, the login page only exists when it is enabled.
+        if (error?.response?.status === 401 && !error.config?.url?.startsWith("auth/")) {
+            location.href = "/login"
+        }
+        return Promise.reject(error)
+    }
+)
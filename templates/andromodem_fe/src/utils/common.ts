@@ -13,7 +13,8 @@ export const getBaseUrlWs = (): string => {
         // TODO: Move the development server URL to environment variables (.env) file
         return "ws://localhost:49153"
     }
-    return `ws://${location.host}`
+    const protocol = location.protocol === "https:" ? "wss:" : "ws:"
+    return `${protocol}//${location.host}`
 }
 
 export const showModal = (modal_id: string) => {